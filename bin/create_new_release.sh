@@ -17,10 +17,16 @@ SEMVER=${BUMP_SEMVER_PATCH}-rc.${BUMP_SEMVER_RC}
 echo "tag id is: "${SEMVER}
 echo "creating plugin tarball"
 tar -czvf build/hcunit_plugin.tgz --directory=build hcunit_osx hcunit.exe hcunit_unix plugin.yaml
+echo "creating checksums"
+(cd build && sha256sum hcunit_osx hcunit.exe hcunit_unix hcunit_plugin.tgz > checksums.txt)
+if [ -n "${RELEASE_SIGNING_KEY}" ]; then
+  echo "signing checksums"
+  openssl pkeyutl -sign -rawin -inkey ${RELEASE_SIGNING_KEY} -in build/checksums.txt -out build/checksums.txt.sig
+fi
 echo "creating release"
 github-release release -t ${SEMVER} -p
 echo "uploading files"
-for file in `ls build | grep '^hcunit'`
+for file in `ls build | grep -e '^hcunit' -e '^checksums'`
 do
   github-release upload -t ${SEMVER} -f build/${file} -n ${file}
 done
@@ -2,6 +2,8 @@ package main
 
 import (
 	"os"
+	"reflect"
+	"strings"
 
 	flags "github.com/jessevdk/go-flags"
 	"github.com/xchapter7x/hcunit/pkg/commands"
@@ -10,6 +12,7 @@ import (
 var Version = "0.0.0-localdev"
 var Buildtime = "localdev-time"
 var Platform = "localdev-platform"
+var ReleasePublicKey = ""
 
 type Options struct{}
 
@@ -17,13 +20,16 @@ var options Options
 var parser = flags.NewParser(&options, flags.Default)
 
 func main() {
+	envFlags(parser.Command)
 	_, err := parser.Parse()
 	if err != nil {
-		if flagsErr, ok := err.(*flags.Error); ok && flagsErr.Type == flags.ErrHelp {
-			os.Exit(0)
-		} else {
-			os.Exit(1)
+		if flagsErr, ok := err.(*flags.Error); ok {
+			if flagsErr.Type == flags.ErrHelp {
+				os.Exit(commands.ExitOK)
+			}
+			os.Exit(commands.ExitUsage)
 		}
+		os.Exit(commands.ExitCode(err))
 	}
 }
 
@@ -44,10 +50,136 @@ func init() {
 			Platform:  Platform,
 		},
 	)
+	parser.AddCommand(
+		"self-update",
+		"update hcunit to the latest release",
+		"checks the github releases of hcunit for a newer version and replaces the running binary with it, after verifying its checksum and the signature of the release's checksums",
+		&commands.SelfUpdateCommand{
+			Version:   Version,
+			PublicKey: ReleasePublicKey,
+		},
+	)
 	parser.AddCommand(
 		"eval",
 		"evaluate a policy on a chart + values",
 		"given a OPA/Rego Policy one can evaluate if the rendered templates of a chart using a given values file meet the defined rules of the policy or not",
-		new(commands.EvalCommand),
+		&commands.EvalCommand{Version: Version},
+	)
+	parser.AddCommand(
+		"hooks",
+		"show the order helm runs a chart's hooks in",
+		"renders the chart and prints, for every release operation (install, upgrade, rollback, delete and test), its hooks in the order helm runs them: by phase, then hook weight, then name",
+		new(commands.HooksCommand),
+	)
+	parser.AddCommand(
+		"compare",
+		"compare the results of two eval runs",
+		"given the --results-file of an older and a newer eval run (hcunit compare old.json new.json), reports the rules which newly fail, newly pass and still fail, failing only when a rule newly fails",
+		new(commands.CompareCommand),
+	)
+	parser.AddCommand(
+		"compare-chart",
+		"compare the rendered objects and policy results of two chart versions",
+		"renders a --base and a --head chart (directories, .tgz archives, https:// archives or oci:// references) with the same values, reports the objects added, removed and changed, and with --policy fails when the head chart newly violates a rule",
+		new(commands.CompareChartCommand),
 	)
+	parser.AddCommand(
+		"trends",
+		"show rule pass rates over the results history",
+		"reads the results history stored by eval --history and shows, per chart, how often each rule passed and a timeline of its latest outcomes, chronically violated rules first",
+		new(commands.TrendsCommand),
+	)
+	parser.AddCommand(
+		"diagnostics",
+		"evaluate a chart and print the failures as editor diagnostics",
+		"evaluates the chart like eval and prints policy failures, render errors and values errors as lsp diagnostics (textDocument/publishDiagnostics params) positioned on the rego, template and values files they come from",
+		&commands.DiagnosticsCommand{EvalCommand: commands.EvalCommand{Version: Version}},
+	)
+	parser.AddCommand(
+		"lint",
+		"run helm lint on a chart",
+		"given a chart (or a template path inside of it) and values it will run helm's linter and report its findings",
+		new(commands.LintCommand),
+	)
+	parser.AddCommand(
+		"generate-policy",
+		"generate a starting policy from a chart's rendered output",
+		"renders the chart with the given values and emits rego assertions capturing what it renders today (objects, replica counts, image registries, resource limits) for you to refine",
+		new(commands.GeneratePolicyCommand),
+	)
+	parser.AddCommand(
+		"schema",
+		"generate a draft values.schema.json for a chart",
+		"analyzes the .Values references of a chart's templates and emits a draft json schema for its values, typed after the chart's defaults and how the templates use each value",
+		new(commands.SchemaCommand),
+	)
+	parser.AddCommand(
+		"vet",
+		"find problems in a chart across values scenarios",
+		"renders the chart under every given values scenario and reports problems no single render shows, like templates which never render anything",
+		new(commands.VetCommand),
+	)
+	parser.AddCommand(
+		"example-chart",
+		"write a sample chart with a policy per rule style",
+		"writes a tiny chart plus policies demonstrating every supported rule style (expect, assert, deny, warn, params, per document rules and metadata), ready to evaluate with hcunit eval",
+		new(commands.ExampleChartCommand),
+	)
+	parser.AddCommand(
+		"digest",
+		"print the digests of a chart and a policy set",
+		"prints the stable content digests hcunit identifies charts (directories or packaged archives alike) and policy sets by in provenance, audit logs and attestations",
+		new(commands.DigestCommand),
+	)
+	policy, _ := parser.AddCommand(
+		"policy",
+		"manage policy sources and their lifecycle",
+		"resolve the remote policy sources declared in .hcunit.yaml, verify the local cache against the lock file and list deprecated rules",
+		new(commands.PolicyCommand),
+	)
+	policy.AddCommand(
+		"update",
+		"fetch policy sources and update the lock file",
+		"fetches every policy source declared in .hcunit.yaml into .hcunit/policies and records their digests in .hcunit.lock",
+		new(commands.PolicyUpdateCommand),
+	)
+	policy.AddCommand(
+		"verify",
+		"verify the policy cache matches the lock file",
+		"recomputes the digest of every cached policy source and fails if any does not match .hcunit.lock",
+		new(commands.PolicyVerifyCommand),
+	)
+	policy.AddCommand(
+		"deprecated",
+		"list the deprecated rules of policies",
+		"lists every rule of the given policies whose metadata marks it deprecated or gives it a remove_after date, flagging those past their sunset",
+		new(commands.PolicyDeprecatedCommand),
+	)
+}
+
+// envFlags - lets every long flag be set with an HCUNIT_* environment
+// variable named after it, e.g. HCUNIT_NO_COLOR for --no-color. Flags given
+// on the command line take precedence over the environment, which takes
+// precedence over the config. Lists are comma separated, unless the flag
+// declares another env-delim
+func envFlags(command *flags.Command) {
+	groups := []*flags.Group{command.Group}
+	for len(groups) > 0 {
+		group := groups[0]
+		groups = append(groups[1:], group.Groups()...)
+		for _, option := range group.Options() {
+			if option.LongName == "" || option.EnvDefaultKey != "" {
+				continue
+			}
+
+			option.EnvDefaultKey = "HCUNIT_" + strings.ToUpper(strings.Replace(option.LongName, "-", "_", -1))
+			if option.EnvDefaultDelim == "" && option.Field().Type.Kind() == reflect.Slice {
+				option.EnvDefaultDelim = ","
+			}
+		}
+	}
+
+	for _, subcommand := range command.Commands() {
+		envFlags(subcommand)
+	}
 }
@@ -2,7 +2,10 @@ package main
 
 import (
 	"bytes"
+	"io/ioutil"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -11,6 +14,22 @@ import (
 	"github.com/onsi/gomega/gexec"
 )
 
+// TestMain - keeps the commands run by the tests off the caches and the
+// config of the machine running them, e.g. the parsed policy modules hcunit
+// caches under $XDG_CACHE_HOME and the user config under $XDG_CONFIG_HOME
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "hcunit-xdg")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("XDG_CACHE_HOME", filepath.Join(dir, "cache"))
+	os.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "config"))
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
 func TestCommands(t *testing.T) {
 	gomega.RegisterTestingT(t)
 	pathToCLI, err := gexec.Build("github.com/xchapter7x/hcunit/cmd/hcunit")
@@ -125,6 +144,50 @@ func TestCommands(t *testing.T) {
 		}
 	})
 
+	t.Run("hcunit eval configured with HCUNIT_* environment variables", func(t *testing.T) {
+		for _, tt := range []struct {
+			name          string
+			args          []string
+			expectFailure bool
+		}{
+			{"the environment sets flags which aren't given", []string{}, true},
+			{"flags take precedence over the environment", []string{"-p", "testdata/policy/passing"}, false},
+		} {
+			t.Run(tt.name, func(t *testing.T) {
+				command := exec.Command(
+					pathToCLI,
+					append([]string{"eval", "-t", "testdata/templates/something.yml"}, tt.args...)...,
+				)
+				command.Env = append(os.Environ(),
+					"HCUNIT_VALUES=testdata/values.yml",
+					"HCUNIT_POLICY=testdata/policy/failing",
+					"HCUNIT_NO_COLOR=true",
+				)
+				errOut := new(bytes.Buffer)
+				stdOut := new(bytes.Buffer)
+				session, err := gexec.Start(command, stdOut, errOut)
+				if err != nil {
+					t.Fatalf("failed running command: %v", err)
+				}
+
+				session.Wait(120 * time.Second)
+				if failed := session.ExitCode() != 0; failed != tt.expectFailure {
+					t.Errorf(
+						"expected failure %v, got: %v %v %v",
+						tt.expectFailure,
+						session.ExitCode(),
+						string(session.Out.Contents()),
+						string(session.Err.Contents()),
+					)
+				}
+
+				if strings.Contains(stdOut.String(), "\x1b[") {
+					t.Errorf("expected output without colors, got:\n%s", stdOut.String())
+				}
+			})
+		}
+	})
+
 	t.Run("hcunit render -t xxx -c xxx", func(t *testing.T) {
 		command := exec.Command(pathToCLI, "render", "-t", "testdata/templates/something.yml", "-c", "testdata/values.yml")
 		errOut := new(bytes.Buffer)
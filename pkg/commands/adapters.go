@@ -0,0 +1,97 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// InvalidAdapter - --adapter names no built-in adapter nor one of the
+// config, or is combined with --manifests
+var InvalidAdapter = errors.New("invalid --adapter")
+
+// adaptersDir - where the manifests adapters print are written to be
+// evaluated, next to the results history
+const adaptersDir = ".hcunit/adapters"
+
+// Adapter - a tool producing the manifests evaluated instead of rendering a
+// chart, e.g. cdk8s or jsonnet. The manifests are read from the Output
+// directory after the Command ran, or from what it printed when no Output
+// is given
+type Adapter struct {
+	Name    string   `yaml:"name"`
+	Command []string `yaml:"command"`
+	Output  string   `yaml:"output"`
+}
+
+// builtinAdapters - the adapters known without declaring them in the
+// config, which can also override them by name
+var builtinAdapters = []Adapter{
+	{Name: "cdk8s", Command: []string{"cdk8s", "synth"}, Output: "dist"},
+	{Name: "jsonnet", Command: []string{"jsonnet", "--yaml-stream", "main.jsonnet"}},
+}
+
+// adapter - the adapter of the config, or else the built-in adapter, with
+// the given name
+func (s *EvalCommand) adapter(name string) (Adapter, error) {
+	adapters := append([]Adapter{}, builtinAdapters...)
+	if s.config != nil {
+		adapters = append(append([]Adapter{}, s.config.Adapters...), builtinAdapters...)
+	}
+
+	names := make([]string, 0, len(adapters))
+	seen := make(map[string]bool, len(adapters))
+	for _, adapter := range adapters {
+		if adapter.Name == name {
+			if len(adapter.Command) == 0 {
+				return Adapter{}, fmt.Errorf("%w: adapter %s has no command", InvalidAdapter, name)
+			}
+			return adapter, nil
+		}
+
+		if !seen[adapter.Name] {
+			seen[adapter.Name] = true
+			names = append(names, adapter.Name)
+		}
+	}
+	sort.Strings(names)
+	return Adapter{}, fmt.Errorf("%w: %q is not one of %s", InvalidAdapter, name, strings.Join(names, ", "))
+}
+
+// runAdapter - runs the command of the --adapter in the current directory
+// and returns the path of the manifests it produced: its output directory,
+// or the file its output was written to under .hcunit/adapters
+func (s *EvalCommand) runAdapter() (string, error) {
+	if s.Manifests != "" {
+		return "", fmt.Errorf("%w: it can't be combined with --manifests", InvalidAdapter)
+	}
+
+	adapter, err := s.adapter(s.Adapter)
+	if err != nil {
+		return "", err
+	}
+
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	cmd := exec.Command(adapter.Command[0], adapter.Command[1:]...)
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	if err := cmd.Run(); err != nil {
+		return "", &RenderError{Template: adapter.Name, Err: fmt.Errorf("%s failed: %w: %s", strings.Join(adapter.Command, " "), err, stderr)}
+	}
+
+	if adapter.Output != "" {
+		return adapter.Output, nil
+	}
+
+	if err := os.MkdirAll(adaptersDir, 0755); err != nil {
+		return "", err
+	}
+
+	output := filepath.Join(adaptersDir, adapter.Name+".yaml")
+	return output, ioutil.WriteFile(output, stdout.Bytes(), 0644)
+}
@@ -0,0 +1,147 @@
+package commands_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+// TestAdapterProcess - stands in for the command of a manifest adapter when
+// run by the adapter tests: it prints a manifest, copies one into an output
+// directory like cdk8s synth, or fails
+func TestAdapterProcess(t *testing.T) {
+	if os.Getenv("HCUNIT_TEST_ADAPTER") != "1" {
+		return
+	}
+
+	args := os.Args
+	for i, arg := range args {
+		if arg == "--" {
+			args = args[i+1:]
+			break
+		}
+	}
+
+	switch args[0] {
+	case "print":
+		b, _ := ioutil.ReadFile(args[1])
+		os.Stdout.Write(b)
+	case "synth":
+		b, _ := ioutil.ReadFile(args[1])
+		os.MkdirAll(args[2], 0755)
+		ioutil.WriteFile(filepath.Join(args[2], filepath.Base(args[1])), b, 0644)
+	default:
+		fmt.Fprintln(os.Stderr, "synth failed: main.ts not found")
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func TestEvalAdapter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hcunit-adapter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer os.RemoveAll(".hcunit")
+
+	os.Setenv("HCUNIT_TEST_ADAPTER", "1")
+	defer os.Unsetenv("HCUNIT_TEST_ADAPTER")
+
+	adapter := func(args ...string) string {
+		command := append([]string{os.Args[0], "-test.run=TestAdapterProcess", "--"}, args...)
+		for i, arg := range command {
+			command[i] = fmt.Sprintf("%q", filepath.ToSlash(arg))
+		}
+		return "[" + strings.Join(command, ", ") + "]"
+	}
+
+	output := filepath.ToSlash(filepath.Join(dir, "dist"))
+	config := fmt.Sprintf(`adapters:
+  - name: printer
+    command: %s
+  - name: cdk8s
+    command: %s
+    output: %s
+  - name: broken
+    command: %s
+`, adapter("print", "testdata/manifests/worker/deployment.yaml"), adapter("synth", "testdata/manifests/app/deployment.yaml", output), output, adapter("fail"))
+
+	configPath := filepath.Join(dir, ".hcunit.yaml")
+	if err := ioutil.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name      string
+		adapter   string
+		manifests string
+		failsWith error
+		renderErr bool
+		contains  []string
+	}{
+		{
+			name:      "the printed manifests of an adapter are evaluated",
+			adapter:   "printer",
+			failsWith: commands.PolicyFailure,
+			contains:  []string{"REPRODUCE: \x1b[0mhcunit eval --adapter printer"},
+		},
+		{
+			name:     "the output directory of an adapter is evaluated, and config adapters override built-in ones",
+			adapter:  "cdk8s",
+			contains: []string{"[SUCCESS]"},
+		},
+		{
+			name:      "failing adapters are render errors",
+			adapter:   "broken",
+			renderErr: true,
+		},
+		{
+			name:      "unknown adapters are refused",
+			adapter:   "kustomize",
+			failsWith: commands.InvalidAdapter,
+		},
+		{
+			name:      "adapters can't be combined with --manifests",
+			adapter:   "printer",
+			manifests: "testdata/manifests",
+			failsWith: commands.InvalidAdapter,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			stdOut := new(bytes.Buffer)
+			evalCmd := &commands.EvalCommand{
+				Stdout:    stdOut,
+				Adapter:   tt.adapter,
+				Manifests: tt.manifests,
+				Config:    configPath,
+				Policy:    []string{"testdata/policy/individuals/manifests_pinned_images.rego"},
+			}
+			err := evalCmd.Execute([]string{})
+			if tt.renderErr {
+				var renderErr *commands.RenderError
+				if !errors.As(err, &renderErr) || !strings.Contains(err.Error(), "main.ts not found") {
+					t.Fatalf("expected a render error with the adapter's output, got: %v", err)
+				}
+				return
+			}
+
+			if tt.failsWith == nil && err != nil || !errors.Is(err, tt.failsWith) {
+				t.Fatalf("expected error:\n%v\ngot:\n%v", tt.failsWith, err)
+			}
+
+			for _, expected := range tt.contains {
+				if !strings.Contains(stdOut.String(), expected) {
+					t.Errorf("expected the output to contain %q, got:\n%s", expected, stdOut.String())
+				}
+			}
+		})
+	}
+}
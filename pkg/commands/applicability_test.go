@@ -0,0 +1,39 @@
+package commands_test
+
+import (
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestEvalRuleApplicability(t *testing.T) {
+	evaluated := make([]string, 0)
+	evalCmd := &commands.EvalCommand{
+		Stdout:   ioutil.Discard,
+		Template: "testdata/workloads",
+		Values:   []string{"testdata/values.yml"},
+		Policy:   []string{"testdata/policy/individuals/applies_to.rego"},
+		Hooks: &commands.Hooks{
+			BeforeRule: func(rule string) error {
+				evaluated = append(evaluated, rule)
+				return nil
+			},
+		},
+	}
+	if err := evalCmd.Execute([]string{}); err != nil {
+		t.Fatalf("expected rules to only run on the objects they apply to, got: %v", err)
+	}
+
+	sort.Strings(evaluated)
+	expected := []string{
+		`data.main.expect["the chart renders"]`,
+		`data.main.expect["web objects are labeled"] on Deployment/hcunit-name-web in deployment.yml`,
+		`data.main.expect["workloads set replicas"] on Deployment/hcunit-name-web in deployment.yml`,
+	}
+	if !reflect.DeepEqual(evaluated, expected) {
+		t.Errorf("expected the rules to run:\n%v\ngot:\n%v", expected, evaluated)
+	}
+}
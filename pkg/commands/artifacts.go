@@ -0,0 +1,132 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/topdown"
+)
+
+// maxArtifactNameLength - failing rule directories are named after the rule,
+// truncated to stay well within filesystem limits
+const maxArtifactNameLength = 80
+
+var artifactNameUnsafe = regexp.MustCompile(`[^a-z0-9]+`)
+
+// ruleLocations - the location (and source) of every expect/assert rule of
+// the modules, keyed like ruleQueries
+func ruleLocations(mods map[string]*ast.Module) map[string]*ast.Location {
+	locations := make(map[string]*ast.Location)
+	for _, mod := range mods {
+		for _, rule := range mod.Rules {
+			if rule.Location != nil {
+				locations[fmt.Sprintf("%s[%s]", rule.Head.Name, rule.Head.Key)] = rule.Location
+			}
+		}
+	}
+	return locations
+}
+
+// failureDetail - collects the source of a failed rule, prefixed with the
+// file and line it came from, the trace of the failed run and the input
+// documents its evaluation referenced
+func failureDetail(location *ast.Location, events []*topdown.Event) FailureDetail {
+	trace := new(bytes.Buffer)
+	topdown.PrettyTrace(trace, events)
+	detail := FailureDetail{Trace: trace.String(), Documents: referencedDocuments(events)}
+	if location != nil {
+		detail.File, detail.Line = location.File, location.Row
+		detail.Source = fmt.Sprintf("# %s:%d\n%s\n", location.File, location.Row, location.Text)
+	}
+	return detail
+}
+
+// referencedDocuments - the names of the input documents referenced as
+// input["name"] or input.name while evaluating a rule
+func referencedDocuments(events []*topdown.Event) []string {
+	seen := make(map[string]bool)
+	for _, event := range events {
+		if event.Node == nil {
+			continue
+		}
+
+		ast.WalkRefs(event.Node, func(ref ast.Ref) bool {
+			if len(ref) < 2 || !ref[0].Equal(ast.InputRootDocument) {
+				return false
+			}
+
+			if name, ok := ref[1].Value.(ast.String); ok {
+				seen[string(name)] = true
+			}
+			return false
+		})
+	}
+
+	documents := make([]string, 0, len(seen))
+	for name := range seen {
+		documents = append(documents, name)
+	}
+	sort.Strings(documents)
+	return documents
+}
+
+// writeFailureArtifacts - writes a directory per failed rule holding the
+// rule's source, the rendered documents it referenced, its trace, any
+// assertion diffs and a command reproducing just that rule
+func writeFailureArtifacts(dir string, violation *ViolationError, rendered map[string]string, repro func(string) string, redact func(string) string) error {
+	for i, name := range violation.Failed {
+		ruleDir := filepath.Join(dir, fmt.Sprintf("%02d-%s", i+1, artifactName(name)))
+		if err := os.MkdirAll(ruleDir, 0755); err != nil {
+			return fmt.Errorf("creating artifacts dir failed: %w", err)
+		}
+
+		detail := violation.Details[name]
+		documents := new(bytes.Buffer)
+		for _, document := range detail.Documents {
+			if content, ok := rendered[document]; ok {
+				fmt.Fprintf(documents, "---\n#%s\n%s\n", document, redactSecretDocuments(content))
+			}
+		}
+
+		files := map[string]string{
+			"rule.txt":       name + "\n",
+			"rule.rego":      detail.Source,
+			"documents.yaml": documents.String(),
+			"trace.txt":      redact(detail.Trace),
+			"repro.sh":       "#!/bin/sh\n" + repro(name) + "\n",
+		}
+
+		if diffs := violation.Diffs[name]; len(diffs) > 0 {
+			files["diff.txt"] = strings.Join(redactDiffs(diffs, redact), "\n")
+		}
+
+		for filename, content := range files {
+			mode := os.FileMode(0644)
+			if filepath.Ext(filename) == ".sh" {
+				mode = 0755
+			}
+
+			if err := ioutil.WriteFile(filepath.Join(ruleDir, filename), []byte(content), mode); err != nil {
+				return fmt.Errorf("writing artifact %s failed: %w", filename, err)
+			}
+		}
+	}
+	return nil
+}
+
+// artifactName - a filesystem safe directory name for a rule
+func artifactName(rule string) string {
+	name := strings.TrimPrefix(rule, "data.")
+	name = strings.Trim(artifactNameUnsafe.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if len(name) > maxArtifactNameLength {
+		name = strings.TrimRight(name[:maxArtifactNameLength], "-")
+	}
+	return name
+}
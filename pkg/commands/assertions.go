@@ -0,0 +1,151 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/types"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// assertionRecorder - collects the diffs of failed hcunit.assert_equal calls
+// made while evaluating a single query
+type assertionRecorder struct {
+	diffs []string
+}
+
+func (s *assertionRecorder) reset() {
+	s.diffs = make([]string, 0)
+}
+
+// assertEqualFunction - the declaration of hcunit.assert_equal
+var assertEqualFunction = &rego.Function{
+	Name: "hcunit.assert_equal",
+	Decl: types.NewFunction(types.Args(types.A, types.A), types.B),
+}
+
+// builtin - exposes hcunit.assert_equal(actual, expected) to policies. It is
+// true when both values are equal, otherwise it records a diff of their yaml
+// form for the reporter and is false
+func (s *assertionRecorder) builtin() func(*rego.Rego) {
+	return rego.Function2(
+		assertEqualFunction,
+		func(_ rego.BuiltinContext, actual, expected *ast.Term) (*ast.Term, error) {
+			if actual.Equal(expected) {
+				return ast.BooleanTerm(true), nil
+			}
+
+			diff := lineDiff(assertionYAML(expected), assertionYAML(actual))
+			for _, recorded := range s.diffs {
+				if recorded == diff {
+					return ast.BooleanTerm(false), nil
+				}
+			}
+			s.diffs = append(s.diffs, diff)
+			return ast.BooleanTerm(false), nil
+		},
+	)
+}
+
+// redactDiffs - the diffs with the values passed through redact, e.g. to
+// hide the values of Secrets compared by hcunit.assert_equal. Diffs are
+// yaml, so the scalar of every line is checked as the json string redact
+// replaces
+func redactDiffs(diffs []string, redact func(string) string) []string {
+	redacted := make([]string, len(diffs))
+	for i, diff := range diffs {
+		lines := strings.SplitAfter(diff, "\n")
+		for j, line := range lines {
+			lines[j] = redactDiffLine(line, redact)
+		}
+		redacted[i] = strings.Join(lines, "")
+	}
+	return redacted
+}
+
+// redactDiffLine - the line of a diff with its scalar, the value of a key or
+// list item, redacted
+func redactDiffLine(line string, redact func(string) string) string {
+	body := strings.TrimRight(line, "\n")
+	start := strings.LastIndex(body, ": ")
+	if start < 0 {
+		start = strings.LastIndex(body, "- ")
+	}
+
+	if start >= 0 {
+		scalar := strings.Trim(body[start+2:], `'"`)
+		if quoted := `"` + scalar + `"`; scalar != "" && redact(quoted) != quoted {
+			return body[:start+2] + redactedValue + line[len(body):]
+		}
+	}
+	return redact(line)
+}
+
+// assertionYAML - the yaml form of a term. The term's json is re-read as
+// yaml so numbers keep their type instead of becoming quoted json.Numbers
+func assertionYAML(term *ast.Term) string {
+	var value interface{}
+	if err := yaml.Unmarshal([]byte(term.String()), &value); err != nil {
+		return term.String() + "\n"
+	}
+
+	out := new(bytes.Buffer)
+	encoder := yaml.NewEncoder(out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(value); err != nil {
+		return term.String() + "\n"
+	}
+	return out.String()
+}
+
+// plainDiff - renders a lineDiff with the side of each changed line
+// spelled out instead of colored, indented by the given prefix
+func plainDiff(diff, indent string, messages messageCatalog) string {
+	expected, actual := messages.text("expected")+":", messages.text("actual")+":"
+	width := len([]rune(expected))
+	if n := len([]rune(actual)); n > width {
+		width = n
+	}
+
+	pad := func(side string) string {
+		return side + strings.Repeat(" ", width+1-len([]rune(side)))
+	}
+
+	out := new(strings.Builder)
+	for _, line := range strings.SplitAfter(diff, "\n") {
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "- "):
+			fmt.Fprintf(out, "%s%s%s\n", indent, pad(expected), strings.TrimPrefix(line, "- "))
+		case strings.HasPrefix(line, "+ "):
+			fmt.Fprintf(out, "%s%s%s\n", indent, pad(actual), strings.TrimPrefix(line, "+ "))
+		default:
+			fmt.Fprintf(out, "%s%s%s\n", indent, pad(""), strings.TrimPrefix(line, "  "))
+		}
+	}
+	return out.String()
+}
+
+// colorDiff - renders a lineDiff as a colorized unified diff of expected vs
+// actual. Color codes are written directly since the diffed yaml may contain
+// brackets colorstring would try to interpret
+func colorDiff(diff string) string {
+	out := new(strings.Builder)
+	fmt.Fprintf(out, "\x1b[31m--- expected\x1b[0m\n\x1b[32m+++ actual\x1b[0m\n")
+	for _, line := range strings.SplitAfter(diff, "\n") {
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "- "):
+			fmt.Fprintf(out, "\x1b[31m%s\x1b[0m\n", strings.TrimSuffix(line, "\n"))
+		case strings.HasPrefix(line, "+ "):
+			fmt.Fprintf(out, "\x1b[32m%s\x1b[0m\n", strings.TrimSuffix(line, "\n"))
+		default:
+			out.WriteString(line)
+		}
+	}
+	return out.String()
+}
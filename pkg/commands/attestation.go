@@ -0,0 +1,86 @@
+package commands
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	inTotoStatementType = "https://in-toto.io/Statement/v0.1"
+	policyPredicateType = "https://github.com/xchapter7x/hcunit/policy-evaluation/v1"
+)
+
+// Attestation - an in-toto statement about the chart hcunit evaluated, for
+// signing with e.g. `cosign sign-blob` or `cosign attest`
+type Attestation struct {
+	Type          string               `json:"_type"`
+	Subject       []AttestationSubject `json:"subject"`
+	PredicateType string               `json:"predicateType"`
+	Predicate     PolicyPredicate      `json:"predicate"`
+}
+
+type AttestationSubject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// PolicyPredicate - the outcome of a policy evaluation and what it was
+// evaluated with
+type PolicyPredicate struct {
+	Passed      bool         `json:"passed"`
+	EvaluatedAt Timestamp    `json:"evaluatedAt"`
+	Provenance  Provenance   `json:"provenance"`
+	Results     []RuleReport `json:"results"`
+	Error       string       `json:"error,omitempty"`
+}
+
+// writeAttestation - writes an in-toto statement of this run's results. Its
+// subject is the packaged chart given with --attestation-subject, or the
+// chart directory by its tree digest
+func (s *EvalCommand) writeAttestation(runErr error) error {
+	provenance := s.provenance()
+	subject := AttestationSubject{
+		Name:   provenance.Chart.Name,
+		Digest: map[string]string{"sha256": strings.TrimPrefix(provenance.Chart.Digest, "sha256:")},
+	}
+
+	if subject.Name == "" {
+		subject.Name = filepath.Base(s.Template)
+	}
+
+	if s.AttestationSubject != "" {
+		b, err := ioutil.ReadFile(s.AttestationSubject)
+		if err != nil {
+			return fmt.Errorf("reading attestation subject failed: %w", err)
+		}
+		subject.Name = filepath.Base(s.AttestationSubject)
+		subject.Digest = map[string]string{"sha256": fmt.Sprintf("%x", sha256.Sum256(b))}
+	}
+
+	predicate := PolicyPredicate{
+		Passed:      runErr == nil,
+		EvaluatedAt: newTimestamp(time.Now(), s.TimestampFormat),
+		Provenance:  provenance,
+		Results:     ruleReports(s.results),
+	}
+
+	if runErr != nil {
+		predicate.Error = runErr.Error()
+	}
+
+	b, err := json.MarshalIndent(Attestation{
+		Type:          inTotoStatementType,
+		Subject:       []AttestationSubject{subject},
+		PredicateType: policyPredicateType,
+		Predicate:     predicate,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("couldnt marshal attestation: %w", err)
+	}
+	return ioutil.WriteFile(s.Attestation, append(b, '\n'), 0644)
+}
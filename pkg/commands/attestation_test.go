@@ -0,0 +1,86 @@
+package commands_test
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestEvalAttestation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hcunit-attestation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	packaged := filepath.Join(dir, "mychart-0.1.0.tgz")
+	if err := ioutil.WriteFile(packaged, []byte("packaged chart"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name        string
+		policy      string
+		subject     string
+		passed      bool
+		subjectName string
+		digest      string
+	}{
+		{
+			name:        "a passing chart directory",
+			policy:      "testdata/policy/individuals/tests_in_input.rego",
+			passed:      true,
+			subjectName: "mychart",
+		},
+		{
+			name:        "a failing packaged chart",
+			policy:      "testdata/policy/individuals/per_document.rego",
+			subject:     packaged,
+			passed:      false,
+			subjectName: "mychart-0.1.0.tgz",
+			digest:      fmt.Sprintf("%x", sha256.Sum256([]byte("packaged chart"))),
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			attestationPath := filepath.Join(dir, "attestation.json")
+			evalCmd := &commands.EvalCommand{
+				Stdout:             ioutil.Discard,
+				Template:           "testdata/mychart",
+				Values:             []string{"testdata/mychart/values.yaml"},
+				Policy:             []string{tt.policy},
+				Attestation:        attestationPath,
+				AttestationSubject: tt.subject,
+			}
+			evalCmd.Execute([]string{})
+
+			content, err := ioutil.ReadFile(attestationPath)
+			if err != nil {
+				t.Fatalf("expected an attestation, got: %v", err)
+			}
+
+			attestation := commands.Attestation{}
+			if err := json.Unmarshal(content, &attestation); err != nil {
+				t.Fatalf("expected a json attestation, got %q: %v", content, err)
+			}
+
+			if attestation.Type != "https://in-toto.io/Statement/v0.1" || attestation.PredicateType == "" {
+				t.Errorf("expected an in-toto statement, got: %s", content)
+			}
+
+			subject := attestation.Subject[0]
+			if subject.Name != tt.subjectName || len(subject.Digest["sha256"]) != 64 || (tt.digest != "" && subject.Digest["sha256"] != tt.digest) {
+				t.Errorf("expected subject %s with digest %q, got: %+v", tt.subjectName, tt.digest, subject)
+			}
+
+			if attestation.Predicate.Passed != tt.passed || len(attestation.Predicate.Results) == 0 {
+				t.Errorf("expected passed: %v with results, got: %+v", tt.passed, attestation.Predicate)
+			}
+		})
+	}
+}
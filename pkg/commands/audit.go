@@ -0,0 +1,74 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// AuditRecord - one line of the --audit-log, recording which policies were
+// enforced on which chart and values, and with what outcome
+type AuditRecord struct {
+	Timestamp  Timestamp    `json:"timestamp"`
+	Provenance Provenance   `json:"provenance"`
+	Results    AuditSummary `json:"results"`
+	ExitCode   int          `json:"exitCode"`
+	Error      string       `json:"error,omitempty"`
+}
+
+type AuditSummary struct {
+	Passed int      `json:"passed"`
+	Failed int      `json:"failed"`
+	Warned int      `json:"warned"`
+	DryRun int      `json:"dryRun"`
+	Rules  []string `json:"failedRules,omitempty"`
+}
+
+// appendAuditRecord - appends the record of this run to the audit log
+func (s *EvalCommand) appendAuditRecord(runErr error) error {
+	record := AuditRecord{
+		Timestamp:  newTimestamp(time.Now(), s.TimestampFormat),
+		Provenance: s.provenance(),
+		Results:    summarizeResults(s.results),
+		ExitCode:   ExitCode(runErr),
+	}
+
+	if runErr != nil {
+		record.Error = runErr.Error()
+	}
+
+	b, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("couldnt marshal audit record: %w", err)
+	}
+
+	f, err := os.OpenFile(s.AuditLog, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("opening audit log failed: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("writing audit log failed: %w", err)
+	}
+	return nil
+}
+
+func summarizeResults(results []RuleResult) AuditSummary {
+	summary := AuditSummary{}
+	for _, result := range results {
+		switch {
+		case result.Warning:
+			summary.Warned++
+		case result.DryRun:
+			summary.DryRun++
+		case result.Passed:
+			summary.Passed++
+		default:
+			summary.Failed++
+			summary.Rules = append(summary.Rules, result.Name)
+		}
+	}
+	return summary
+}
@@ -0,0 +1,87 @@
+package commands_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestEvalAuditLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hcunit-audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	auditLog := filepath.Join(dir, "audit.jsonl")
+	for _, policy := range []string{"testdata/policy/passing", "testdata/policy/failing"} {
+		evalCmd := &commands.EvalCommand{
+			Stdout:   ioutil.Discard,
+			Template: "testdata/templates",
+			Values:   []string{"testdata/values.yml"},
+			Policy:   []string{policy},
+			AuditLog: auditLog,
+		}
+		evalCmd.Execute([]string{})
+	}
+
+	mychart := &commands.EvalCommand{
+		Stdout:   ioutil.Discard,
+		Template: "testdata/mychart",
+		Values:   []string{"testdata/mychart/values.yaml"},
+		Policy:   []string{"testdata/policy/individuals/warn_ingress.rego"},
+		AuditLog: auditLog,
+	}
+	mychart.Execute([]string{})
+
+	f, err := os.Open(auditLog)
+	if err != nil {
+		t.Fatalf("expected an audit log, got: %v", err)
+	}
+	defer f.Close()
+
+	records := make([]commands.AuditRecord, 0)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		record := commands.AuditRecord{}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("expected json lines, got %q: %v", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+
+	if len(records) != 3 {
+		t.Fatalf("expected a record per run, got %d", len(records))
+	}
+
+	passing, failing, chart := records[0], records[1], records[2]
+	if passing.ExitCode != commands.ExitOK || passing.Results.Passed == 0 || passing.Results.Failed != 0 {
+		t.Errorf("expected a passing record, got: %+v", passing)
+	}
+
+	if failing.ExitCode != commands.ExitFailure || failing.Results.Failed == 0 || len(failing.Results.Rules) != failing.Results.Failed || failing.Error == "" {
+		t.Errorf("expected a failing record, got: %+v", failing)
+	}
+
+	for _, record := range records {
+		for _, digest := range []string{record.Provenance.Chart.Digest, record.Provenance.PolicyDigest, record.Provenance.ValuesDigest} {
+			if !strings.HasPrefix(digest, "sha256:") {
+				t.Errorf("expected sha256 digests, got: %+v", record)
+			}
+		}
+	}
+
+	if passing.Provenance.Chart != failing.Provenance.Chart || passing.Provenance.ValuesDigest != failing.Provenance.ValuesDigest || passing.Provenance.PolicyDigest == failing.Provenance.PolicyDigest {
+		t.Errorf("expected digests to identify the chart, values and policies evaluated, got:\n%+v\n%+v", passing, failing)
+	}
+
+	if chart.Provenance.Chart.Name != "mychart" || chart.Provenance.Chart.Version != "0.1.0" {
+		t.Errorf("expected the chart's name and version, got: %+v", chart.Provenance.Chart)
+	}
+}
@@ -0,0 +1,102 @@
+package commands
+
+const autoscalingHashName = "autoscaling"
+
+type autoscalingModel struct {
+	Autoscalers []autoscaler         `json:"autoscalers"`
+	Workloads   []autoscaledWorkload `json:"workloads"`
+}
+
+type autoscaler struct {
+	Ref         string        `json:"ref"`
+	Name        string        `json:"name"`
+	Namespace   string        `json:"namespace"`
+	Target      string        `json:"target"`
+	TargetFound bool          `json:"targetFound"`
+	MinReplicas interface{}   `json:"minReplicas"`
+	MaxReplicas interface{}   `json:"maxReplicas"`
+	Metrics     []interface{} `json:"metrics"`
+}
+
+type autoscaledWorkload struct {
+	Ref         string      `json:"ref"`
+	Kind        string      `json:"kind"`
+	Name        string      `json:"name"`
+	Namespace   string      `json:"namespace"`
+	Replicas    interface{} `json:"replicas"`
+	Autoscalers []string    `json:"autoscalers"`
+}
+
+// buildAutoscalingModel - joins the rendered HorizontalPodAutoscalers with
+// the workloads they scale, so policies can check replicas aren't set on
+// autoscaled workloads without resolving scaleTargetRefs in rego
+func buildAutoscalingModel(objects []map[string]interface{}) autoscalingModel {
+	model := autoscalingModel{
+		Autoscalers: make([]autoscaler, 0),
+		Workloads:   make([]autoscaledWorkload, 0),
+	}
+
+	for _, obj := range objects {
+		switch objectKind(obj) {
+		case "Deployment", "StatefulSet", "ReplicaSet", "ReplicationController":
+			model.Workloads = append(model.Workloads, autoscaledWorkload{
+				Ref:         objectRef(obj),
+				Kind:        objectKind(obj),
+				Name:        objectName(obj),
+				Namespace:   objectNamespace(obj),
+				Replicas:    getField(obj, "spec", "replicas"),
+				Autoscalers: make([]string, 0),
+			})
+		}
+	}
+
+	for _, obj := range objects {
+		if objectKind(obj) != "HorizontalPodAutoscaler" {
+			continue
+		}
+
+		hpa := newAutoscaler(obj)
+		for i, workload := range model.Workloads {
+			if workload.Ref == hpa.Target && workload.Namespace == hpa.Namespace {
+				hpa.TargetFound = true
+				model.Workloads[i].Autoscalers = append(model.Workloads[i].Autoscalers, hpa.Name)
+			}
+		}
+		model.Autoscalers = append(model.Autoscalers, hpa)
+	}
+	return model
+}
+
+func newAutoscaler(obj map[string]interface{}) autoscaler {
+	hpa := autoscaler{
+		Ref:         objectRef(obj),
+		Name:        objectName(obj),
+		Namespace:   objectNamespace(obj),
+		Target:      getString(obj, "spec", "scaleTargetRef", "kind") + "/" + getString(obj, "spec", "scaleTargetRef", "name"),
+		MinReplicas: getField(obj, "spec", "minReplicas"),
+		MaxReplicas: getField(obj, "spec", "maxReplicas"),
+		Metrics:     getSlice(obj, "spec", "metrics"),
+	}
+
+	// the api server defaults minReplicas to 1
+	if hpa.MinReplicas == nil {
+		hpa.MinReplicas = 1
+	}
+
+	// autoscaling/v1 only targets cpu, expressed the way autoscaling/v2
+	// metrics are so policies check a single shape
+	if utilization := getField(obj, "spec", "targetCPUUtilizationPercentage"); utilization != nil {
+		hpa.Metrics = append(hpa.Metrics, map[string]interface{}{
+			"type": "Resource",
+			"resource": map[string]interface{}{
+				"name":   "cpu",
+				"target": map[string]interface{}{"type": "Utilization", "averageUtilization": utilization},
+			},
+		})
+	}
+
+	if hpa.Metrics == nil {
+		hpa.Metrics = make([]interface{}, 0)
+	}
+	return hpa
+}
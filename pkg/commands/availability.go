@@ -0,0 +1,89 @@
+package commands
+
+const availabilityHashName = "availability"
+
+type availabilityModel struct {
+	Budgets   []disruptionBudget     `json:"budgets"`
+	Workloads []availabilityWorkload `json:"workloads"`
+}
+
+type disruptionBudget struct {
+	Ref            string      `json:"ref"`
+	Name           string      `json:"name"`
+	Namespace      string      `json:"namespace"`
+	MinAvailable   interface{} `json:"minAvailable"`
+	MaxUnavailable interface{} `json:"maxUnavailable"`
+	Workloads      []string    `json:"workloads"`
+}
+
+type availabilityWorkload struct {
+	Ref            string      `json:"ref"`
+	Kind           string      `json:"kind"`
+	Name           string      `json:"name"`
+	Namespace      string      `json:"namespace"`
+	Replicas       interface{} `json:"replicas"`
+	Covered        bool        `json:"covered"`
+	Budgets        []string    `json:"budgets"`
+	MinAvailable   interface{} `json:"minAvailable"`
+	MaxUnavailable interface{} `json:"maxUnavailable"`
+}
+
+// buildAvailabilityModel - joins the rendered PodDisruptionBudgets with the
+// pod templates they select, so policies can check every workload has a
+// budget without matching selectors in rego
+func buildAvailabilityModel(objects []map[string]interface{}) availabilityModel {
+	model := availabilityModel{
+		Budgets:   make([]disruptionBudget, 0),
+		Workloads: make([]availabilityWorkload, 0),
+	}
+
+	labels := make([]map[string]interface{}, 0)
+	for _, obj := range objects {
+		template := podTemplate(obj)
+		if template == nil {
+			continue
+		}
+
+		labels = append(labels, orEmpty(getMap(template, "metadata", "labels")))
+		model.Workloads = append(model.Workloads, availabilityWorkload{
+			Ref:       objectRef(obj),
+			Kind:      objectKind(obj),
+			Name:      objectName(obj),
+			Namespace: objectNamespace(obj),
+			Replicas:  getField(obj, "spec", "replicas"),
+			Budgets:   make([]string, 0),
+		})
+	}
+
+	for _, obj := range objects {
+		if objectKind(obj) != "PodDisruptionBudget" {
+			continue
+		}
+
+		budget := disruptionBudget{
+			Ref:            objectRef(obj),
+			Name:           objectName(obj),
+			Namespace:      objectNamespace(obj),
+			MinAvailable:   getField(obj, "spec", "minAvailable"),
+			MaxUnavailable: getField(obj, "spec", "maxUnavailable"),
+			Workloads:      make([]string, 0),
+		}
+
+		// a budget without a selector selects no pods, an empty one all of
+		// the pods in its namespace
+		selector, ok := getField(obj, "spec", "selector").(map[string]interface{})
+		for i, workload := range model.Workloads {
+			if ok && workload.Namespace == budget.Namespace && labelSelectorMatches(selector, labels[i]) {
+				budget.Workloads = append(budget.Workloads, workload.Ref)
+				if !workload.Covered {
+					model.Workloads[i].Covered = true
+					model.Workloads[i].MinAvailable = budget.MinAvailable
+					model.Workloads[i].MaxUnavailable = budget.MaxUnavailable
+				}
+				model.Workloads[i].Budgets = append(model.Workloads[i].Budgets, budget.Name)
+			}
+		}
+		model.Budgets = append(model.Budgets, budget)
+	}
+	return model
+}
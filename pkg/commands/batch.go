@@ -0,0 +1,124 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mitchellh/colorstring"
+	"github.com/open-policy-agent/opa/rego"
+)
+
+var InvalidValuesSet = errors.New("invalid --values-set")
+
+// ValuesSet - a named set of values files, merged over --values to render
+// and evaluate the chart with as one run of a batch
+type ValuesSet struct {
+	Name   string
+	Values []string
+}
+
+// BatchResult - the results of evaluating the chart with one values set,
+// and the error that run ended with
+type BatchResult struct {
+	Name    string
+	Results []RuleResult
+	Err     error
+}
+
+// EvaluateBatch - evaluates the chart once per values set (along with any
+// --values-set flags), compiling the policies and preparing their queries
+// once for the whole batch. Returns the results of every set in order, and
+// the error of the batch as Execute would
+func (s *EvalCommand) EvaluateBatch(sets []ValuesSet) ([]BatchResult, error) {
+	s.valuesSets = sets
+	err := s.Execute(nil)
+	return s.batch, err
+}
+
+// batchSets - the values sets given to EvaluateBatch and with --values-set,
+// the latter as name=a.yml,b.yml
+func (s *EvalCommand) batchSets() ([]ValuesSet, error) {
+	sets := append([]ValuesSet{}, s.valuesSets...)
+	for _, flag := range s.ValuesSets {
+		parts := strings.SplitN(flag, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("%w: %q is not given as name=a.yml,b.yml", InvalidValuesSet, flag)
+		}
+
+		set := ValuesSet{Name: strings.TrimSpace(parts[0])}
+		for _, file := range strings.Split(parts[1], ",") {
+			if file = strings.TrimSpace(file); file != "" {
+				set.Values = append(set.Values, file)
+			}
+		}
+		sets = append(sets, set)
+	}
+
+	names := make(map[string]bool, len(sets))
+	for _, set := range sets {
+		if set.Name == "" || names[set.Name] {
+			return nil, fmt.Errorf("%w: every values set needs a unique name, got %q", InvalidValuesSet, set.Name)
+		}
+		names[set.Name] = true
+	}
+	return sets, nil
+}
+
+// evaluateBatch - renders and evaluates the chart once per values set (and
+// kubernetes version), sharing the prepared policies across the sets, then
+// prints which sets passed
+func (s *EvalCommand) evaluateBatch(sets []ValuesSet, options []func(*rego.Rego), kubeVersions []string) error {
+	s.batch = make([]BatchResult, 0, len(sets))
+	for _, set := range sets {
+		valuesConfig, err := mergeValues(append(append([]string{}, s.Values...), set.Values...))
+		if err != nil {
+			return fmt.Errorf("failed merging values files of values set %s %w ", set.Name, err)
+		}
+
+		if err := s.setValues(valuesConfig); err != nil {
+			return err
+		}
+
+		if s.PartialEval {
+			// residuals only hold for the values they were specialized to
+			s.specialized, s.prepared = nil, nil
+		}
+
+		colorstring.Fprintln(s.Stdout, fmt.Sprintf("[bold]== values set %s ==", set.Name))
+		recorded := len(s.results)
+		if len(kubeVersions) > 0 {
+			err = s.evaluateMatrix(valuesConfig, options, kubeVersions)
+		} else {
+			err = s.evaluate(valuesConfig, options, s.KubeVersion)
+		}
+
+		for i := range s.results[recorded:] {
+			s.results[recorded+i].ValuesSet = set.Name
+		}
+		s.batch = append(s.batch, BatchResult{Name: set.Name, Results: append([]RuleResult{}, s.results[recorded:]...), Err: err})
+	}
+
+	colorstring.Fprintln(s.Stdout, "[bold]== values sets ==")
+	failed := make([]string, 0)
+	var firstErr error
+	for _, result := range s.batch {
+		if result.Err == nil {
+			colorstring.Fprint(s.Stdout, "[green]PASS: ")
+			fmt.Fprintf(s.Stdout, "values set %s\n", result.Name)
+			continue
+		}
+
+		if firstErr == nil {
+			firstErr = result.Err
+		}
+		failed = append(failed, result.Name)
+		colorstring.Fprint(s.Stdout, "[red]FAIL: ")
+		fmt.Fprintf(s.Stdout, "values set %s: %v\n", result.Name, result.Err)
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed with values sets %s: %w", strings.Join(failed, ", "), firstErr)
+	}
+	return nil
+}
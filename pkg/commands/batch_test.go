@@ -0,0 +1,106 @@
+package commands_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestEvalBatch(t *testing.T) {
+	failedRules := func(results []commands.RuleResult) []string {
+		failed := make([]string, 0)
+		for _, result := range results {
+			if !result.Passed {
+				failed = append(failed, result.Name)
+			}
+		}
+		return failed
+	}
+
+	for _, partialEval := range []bool{false, true} {
+		name := "every values set is evaluated with its own results"
+		if partialEval {
+			name += " with --partial-eval"
+		}
+
+		t.Run(name, func(t *testing.T) {
+			stdOut := new(bytes.Buffer)
+			evalCmd := &commands.EvalCommand{
+				Stdout:      stdOut,
+				Template:    "testdata/templates",
+				Values:      []string{"testdata/values.yml"},
+				Policy:      []string{"testdata/policy/partial"},
+				PartialEval: partialEval,
+			}
+			batch, err := evalCmd.EvaluateBatch([]commands.ValuesSet{
+				{Name: "default"},
+				{Name: "ingress", Values: []string{"testdata/added_values.yml"}},
+			})
+			if !errors.Is(err, commands.PolicyFailure) {
+				t.Fatalf("expected error:\n%v\ngot:\n%v", commands.PolicyFailure, err)
+			}
+
+			if len(batch) != 2 || batch[0].Name != "default" || batch[1].Name != "ingress" {
+				t.Fatalf("expected the results of both values sets, got: %+v", batch)
+			}
+
+			if batch[0].Err != nil || len(batch[0].Results) != 4 {
+				t.Errorf("expected the default values to pass all 4 rules, got %v: %+v", batch[0].Err, batch[0].Results)
+			}
+
+			expected := `data.main.deny["the ui ingress is enabled without tls"]`
+			if failed := failedRules(batch[1].Results); !errors.Is(batch[1].Err, commands.PolicyFailure) || strings.Join(failed, ", ") != expected {
+				t.Errorf("expected the ingress values to fail %s, got %v: %v", expected, batch[1].Err, failed)
+			}
+
+			for _, line := range []string{"PASS: \x1b[0mvalues set default", "FAIL: \x1b[0mvalues set ingress"} {
+				if !strings.Contains(stdOut.String(), line) {
+					t.Errorf("expected the output to contain %q, got:\n%s", line, stdOut.String())
+				}
+			}
+
+			if specialized := strings.Count(stdOut.String(), "PARTIAL EVAL: "); partialEval && specialized != 2 {
+				t.Errorf("expected the rules to be specialized to each values set, got %d specializations", specialized)
+			}
+		})
+	}
+
+	for _, tt := range []struct {
+		name      string
+		sets      []string
+		failsWith error
+	}{
+		{
+			name:      "values sets can be given as flags",
+			sets:      []string{"default=", "ingress=testdata/added_values.yml"},
+			failsWith: commands.PolicyFailure,
+		},
+		{
+			name:      "values sets need a name",
+			sets:      []string{"testdata/added_values.yml"},
+			failsWith: commands.InvalidValuesSet,
+		},
+		{
+			name:      "values set names are unique",
+			sets:      []string{"ingress=testdata/values.yml", "ingress=testdata/added_values.yml"},
+			failsWith: commands.InvalidValuesSet,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			evalCmd := &commands.EvalCommand{
+				Stdout:     ioutil.Discard,
+				Template:   "testdata/templates",
+				Values:     []string{"testdata/values.yml"},
+				Policy:     []string{"testdata/policy/partial"},
+				ValuesSets: tt.sets,
+			}
+			if err := evalCmd.Execute([]string{}); !errors.Is(err, tt.failsWith) {
+				t.Fatalf("expected error:\n%v\ngot:\n%v", tt.failsWith, err)
+			}
+		})
+	}
+}
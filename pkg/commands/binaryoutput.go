@@ -0,0 +1,79 @@
+package commands
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mitchellh/colorstring"
+)
+
+const binaryHashName = "binary"
+
+// binaryDocument - what policies get to see of rendered output which isn't
+// text: which document of the template it was, its size and its sha256
+type binaryDocument struct {
+	Document int    `json:"document"`
+	Size     int    `json:"size"`
+	SHA256   string `json:"sha256"`
+}
+
+// isBinary - whether rendered output isn't text: not valid utf-8, or
+// holding NUL bytes, as when a chart renders a b64dec'd keystore
+func isBinary(content string) bool {
+	return !utf8.ValidString(content) || strings.IndexByte(content, 0) >= 0
+}
+
+// excludeBinaryOutput - removes the documents of the rendered templates
+// which aren't text, which would otherwise fail to parse or corrupt the
+// input, and returns them by template for input.binary. Only the binary
+// documents of a yaml file are removed, every other document of it is
+// parsed as usual; any other file is removed as a whole. Every removed
+// document is warned about
+func excludeBinaryOutput(writer io.Writer, rendered map[string]string) (map[string]string, map[string][]binaryDocument) {
+	names := make([]string, 0, len(rendered))
+	for name := range rendered {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	text := make(map[string]string, len(rendered))
+	binary := make(map[string][]binaryDocument)
+	for _, name := range names {
+		content := rendered[name]
+		if !isBinary(content) {
+			text[name] = content
+			continue
+		}
+
+		documents := []string{content}
+		yamlFile := false
+		switch strings.ToLower(filepath.Ext(name)) {
+		case ".yml", ".yaml":
+			documents, yamlFile = splitDocuments(content), true
+		}
+
+		kept := make([]string, 0, len(documents))
+		for i, doc := range documents {
+			if !isBinary(doc) {
+				kept = append(kept, doc)
+				continue
+			}
+
+			sum := sha256.Sum256([]byte(doc))
+			binary[documentName(name)] = append(binary[documentName(name)], binaryDocument{Document: i, Size: len(doc), SHA256: hex.EncodeToString(sum[:])})
+			colorstring.Fprint(writer, "[yellow]BINARY OUTPUT: ")
+			fmt.Fprintf(writer, "document %d of %s (%d bytes) is not text, it is excluded from parsing and only its size and sha256 are in input.binary\n", i, documentName(name), len(doc))
+		}
+
+		if yamlFile && len(kept) > 0 {
+			text[name] = strings.Join(kept, "\n---\n")
+		}
+	}
+	return text, binary
+}
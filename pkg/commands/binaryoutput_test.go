@@ -0,0 +1,49 @@
+package commands_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestEvalBinaryOutput(t *testing.T) {
+	for _, tt := range []struct {
+		name         string
+		policy       string
+		memoryBudget string
+	}{
+		{
+			name:   "binary output is excluded from parsing and described in input.binary",
+			policy: "testdata/policy/individuals/binary_in_input.rego",
+		},
+		{
+			name:         "binary output is described when evaluating within a memory budget",
+			policy:       "testdata/policy/individuals/binary_per_document.rego",
+			memoryBudget: "1",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			stdOut := new(bytes.Buffer)
+			evalCmd := &commands.EvalCommand{
+				Stdout:       stdOut,
+				Template:     "testdata/binary",
+				Policy:       []string{tt.policy},
+				MemoryBudget: tt.memoryBudget,
+			}
+			if err := evalCmd.Execute([]string{}); err != nil {
+				t.Fatalf("expected no error, got:\n%v\n%s", err, stdOut)
+			}
+
+			for _, warning := range []string{
+				"BINARY OUTPUT: \x1b[0mdocument 0 of keystore.bin (9 bytes) is not text",
+				"BINARY OUTPUT: \x1b[0mdocument 1 of secret.yaml (",
+			} {
+				if !strings.Contains(stdOut.String(), warning) {
+					t.Errorf("expected %q in:\n%s", warning, stdOut)
+				}
+			}
+		})
+	}
+}
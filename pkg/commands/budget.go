@@ -0,0 +1,347 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mitchellh/colorstring"
+	"github.com/open-policy-agent/opa/rego"
+)
+
+var InvalidMemoryBudget = errors.New("invalid --memory-budget")
+var MemoryBudgetExceeded = errors.New("the rendered chart exceeds --memory-budget")
+
+// sizeSuffixes - the suffixes of sizes like 512Mi, kubernetes style
+var sizeSuffixes = []struct {
+	suffix string
+	factor int64
+}{
+	{"Ki", 1 << 10},
+	{"Mi", 1 << 20},
+	{"Gi", 1 << 30},
+	{"K", 1000},
+	{"M", 1000 * 1000},
+	{"G", 1000 * 1000 * 1000},
+}
+
+// parseSize - a size in bytes given as a number of bytes or with a suffix
+// like 512Mi or 300M, wrapping invalid when it is neither
+func parseSize(size string, invalid error) (int64, error) {
+	number, factor := size, int64(1)
+	for _, s := range sizeSuffixes {
+		if strings.HasSuffix(size, s.suffix) {
+			number, factor = strings.TrimSuffix(size, s.suffix), s.factor
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("%w: %q is not a size like 512Mi", invalid, size)
+	}
+	return n * factor, nil
+}
+
+func renderedSize(rendered map[string]string) int64 {
+	size := int64(0)
+	for _, content := range rendered {
+		size += int64(len(content))
+	}
+	return size
+}
+
+// renderSpill - rendered templates written to a temporary directory, to be
+// read back one at a time instead of held in memory together
+type renderSpill struct {
+	dir   string
+	names []string
+}
+
+// spillRendered - moves the rendered templates to disk, removing them from
+// the given map
+func spillRendered(rendered map[string]string) (*renderSpill, error) {
+	dir, err := ioutil.TempDir("", "hcunit-spill")
+	if err != nil {
+		return nil, err
+	}
+
+	spill := &renderSpill{dir: dir, names: make([]string, 0, len(rendered))}
+	for name := range rendered {
+		spill.names = append(spill.names, name)
+	}
+	sort.Strings(spill.names)
+
+	for i, name := range spill.names {
+		if err := ioutil.WriteFile(spill.path(i), []byte(rendered[name]), 0600); err != nil {
+			spill.remove()
+			return nil, err
+		}
+		delete(rendered, name)
+	}
+	return spill, nil
+}
+
+func (s *renderSpill) path(i int) string {
+	return filepath.Join(s.dir, strconv.Itoa(i))
+}
+
+// read - the rendered template i, parsed like the templates of a whole chart
+func (s *renderSpill) read(i int) (map[string]interface{}, error) {
+	b, err := ioutil.ReadFile(s.path(i))
+	if err != nil {
+		return nil, err
+	}
+	return UnmarshalYamlMap(map[string]string{s.names[i]: string(b)})
+}
+
+// documents - the rendered templates with the given input names, e.g. to
+// write the documents a failed rule referenced
+func (s *renderSpill) documents(names []string) (map[string]string, error) {
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		wanted[name] = true
+	}
+
+	documents := make(map[string]string)
+	for i, name := range s.names {
+		if !wanted[documentName(name)] {
+			continue
+		}
+
+		b, err := ioutil.ReadFile(s.path(i))
+		if err != nil {
+			return nil, err
+		}
+		documents[name] = string(b)
+	}
+	return documents, nil
+}
+
+func (s *renderSpill) remove() error {
+	return os.RemoveAll(s.dir)
+}
+
+// evaluateWithinBudget - evaluates a rendered chart larger than
+// --memory-budget one template at a time: the templates are spilled to disk
+// and read back one by one, and only per document rules, which see a single
+// rendered object, are evaluated. Rules needing the whole chart fail the run.
+// The conventions, the schemas of custom resources and the expectations of
+// the profile are checked one template at a time too
+func (s *EvalCommand) evaluateWithinBudget(rendered, defines map[string]string, binary map[string][]binaryDocument, valuesConfig map[string]interface{}, options []func(*rego.Rego), kubeVersion string) error {
+	colorstring.Fprint(s.Stdout, "[yellow]MEMORY BUDGET: ")
+	fmt.Fprintf(s.Stdout, "the rendered chart (%d bytes) exceeds --memory-budget %s, evaluating per document rules one template at a time\n", renderedSize(rendered), s.MemoryBudget)
+	spill, err := spillRendered(rendered)
+	if err != nil {
+		return fmt.Errorf("spilling rendered templates to disk failed: %w", err)
+	}
+	defer spill.remove()
+
+	s.rbac = buildRBACModel(nil)
+	base := map[string]interface{}{
+		valuesHashName:       valuesConfig,
+		kubeVersionHashName:  kubeGitVersion(kubeVersion),
+		definesHashName:      defines,
+		testsHashName:        map[string]interface{}{},
+		networkHashName:      buildNetworkModel(nil),
+		podspecsHashName:     buildPodSpecs(nil),
+		autoscalingHashName:  buildAutoscalingModel(nil),
+		availabilityHashName: buildAvailabilityModel(nil),
+		routingHashName:      buildRoutingModel(nil),
+		storageHashName:      buildStorageModel(nil),
+		hooksHashName:        buildHookPlans(),
+		crdsHashName:         map[string]customResourceDefinition{},
+		binaryHashName:       binary,
+		releaseHashName:      s.renderOptions().Release,
+		rbacHashName:         s.rbac,
+	}
+	if err := s.checkPerDocumentRules(base, options); err != nil {
+		return err
+	}
+
+	loadedCRDs, err := s.loadCRDs()
+	if err != nil {
+		return err
+	}
+
+	secrets := make([]string, 0)
+	for i := range spill.names {
+		input, err := spill.read(i)
+		if err != nil {
+			return &RenderError{Template: s.Template, Err: fmt.Errorf("formatting policy input failed: %w", err)}
+		}
+		secrets = append(secrets, secretValues(renderedObjects(input))...)
+		for _, template := range templateNames(input) {
+			for _, obj := range renderedObjects(map[string]interface{}{template: input[template]}) {
+				if objectKind(obj) == "CustomResourceDefinition" {
+					loadedCRDs[template] = append(loadedCRDs[template], obj)
+				}
+			}
+		}
+	}
+	crds := buildCRDModel(map[string]interface{}{}, loadedCRDs)
+
+	writer := s.Writer
+	redact := func(s string) string { return s }
+	if !s.ShowSecrets {
+		redactor := newRedactor(secrets)
+		writer = redactingWriter{writer: s.Writer, redactor: redactor}
+		redact = redactor.Replace
+	}
+
+	results := make([]RuleResult, 0)
+	violation := &ViolationError{Failed: make([]string, 0), Diffs: make(map[string][]string), Details: make(map[string]FailureDetail)}
+	findings := make([]secretFinding, 0)
+	conventionFindings := make([]conventionFinding, 0)
+	schemaFindings := make([]schemaFinding, 0)
+	expectationFindings := make([]expectationFinding, 0)
+	expectations := s.config.Profiles[s.activeProfile].Expect
+	expected := make([]bool, len(expectations))
+	for i := range spill.names {
+		input, err := spill.read(i)
+		if err != nil {
+			return &RenderError{Template: s.Template, Err: fmt.Errorf("formatting policy input failed: %w", err)}
+		}
+
+		if s.ScanSecrets {
+			findings = append(findings, scanForSecrets(renderedObjects(input), nil)...)
+		}
+
+		if conventions := s.config.Conventions; conventions.isSet() {
+			conventionFindings = append(conventionFindings, checkConventions(input, conventions)...)
+		}
+
+		if s.activeProfile != "" {
+			expectationFindings = append(expectationFindings, checkRenderedExpectations(input, expectations, expected, redact)...)
+		}
+		schemaFindings = append(schemaFindings, checkCustomResources(input, crds)...)
+
+		s.processInput(input)
+		for key, value := range base {
+			input[key] = value
+		}
+
+		templateResults, err := s.evalRun(writer, input, valuesConfig, options)
+		var templateViolation *ViolationError
+		switch {
+		case errors.Is(err, UnmatchedQuery):
+			continue
+		case errors.As(err, &templateViolation):
+			violation.Failed = append(violation.Failed, templateViolation.Failed...)
+			for rule, diffs := range templateViolation.Diffs {
+				violation.Diffs[rule] = diffs
+			}
+			for rule, detail := range templateViolation.Details {
+				violation.Details[rule] = detail
+			}
+		case err != nil:
+			return err
+		}
+		results = append(results, templateResults...)
+	}
+
+	if len(results) == 0 {
+		return UnmatchedQuery
+	}
+
+	checks := append([]RuleResult{}, s.lintChecks...)
+	checksErr := s.lintErr
+	if s.ScanSecrets {
+		allowed := make(map[string]bool, len(secrets))
+		for _, secret := range secrets {
+			allowed[secret] = true
+		}
+		findings = append(findings, scanValue(valuesHashName, valuesConfig, allowed)...)
+		sort.Slice(findings, func(i, j int) bool { return findings[i].Location < findings[j].Location })
+		secretChecks, secretsErr := secretResults(findings)
+		checks = append(checks, secretChecks...)
+		if checksErr == nil {
+			checksErr = secretsErr
+		}
+	}
+
+	conventionChecks, conventionsErr := conventionResults(conventionFindings)
+	checks = append(checks, conventionChecks...)
+	if checksErr == nil {
+		checksErr = conventionsErr
+	}
+
+	if s.activeProfile != "" {
+		expectationFindings = append(expectationFindings, unrenderedExpectations(expectations, expected)...)
+	}
+	expectationChecks, expectationsErr := expectationResults(s.activeProfile, expectationFindings)
+	checks = append(checks, expectationChecks...)
+	if checksErr == nil {
+		checksErr = expectationsErr
+	}
+
+	schemaChecks, crdsErr := schemaResults(schemaFindings)
+	checks = append(checks, schemaChecks...)
+	if checksErr == nil {
+		checksErr = crdsErr
+	}
+
+	sort.Slice(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+	err = nil
+	if len(violation.Failed) > 0 {
+		sort.Strings(violation.Failed)
+		err = violation
+	}
+
+	results, err = s.recordResults(results, checks, err, redact)
+	documents := make(map[string]string)
+	if errors.As(err, &violation) && s.ArtifactsDir != "" {
+		referenced := make([]string, 0)
+		for _, detail := range violation.Details {
+			referenced = append(referenced, detail.Documents...)
+		}
+
+		if documents, err = spill.documents(referenced); err != nil {
+			return err
+		}
+		err = violation
+	}
+	return s.concludeEvaluation(results, err, kubeVersion, documents, redact, checksErr)
+}
+
+// checkPerDocumentRules - fails with MemoryBudgetExceeded when any rule
+// (matching --run) is not per document, as it needs the whole chart
+func (s *EvalCommand) checkPerDocumentRules(input map[string]interface{}, options []func(*rego.Rego)) error {
+	loaded, err := loadPolicies(s.Policy, s.policyCacheDir())
+	if err != nil {
+		return &PolicyCompileError{Policies: s.Policy, Err: err}
+	}
+
+	parsedInput, err := toValue(input)
+	if err != nil {
+		return &EvaluationError{Query: fmt.Sprintf("data.%s", s.Namespace), Err: err}
+	}
+
+	options = append([]func(*rego.Rego){loaded.option(), new(assertionRecorder).builtin()}, options...)
+	metadata, err := loadMetadata(context.Background(), s.Policy, s.Namespace, parsedInput, options)
+	if err != nil {
+		return err
+	}
+
+	wholeChart := make([]string, 0)
+	for querySuffix := range ruleQueries(loaded.modules) {
+		rule := fmt.Sprintf("data.%s.%s", s.Namespace, querySuffix)
+		if isPerDocument(metadata[ruleKey(querySuffix)]) || (s.runFilter != nil && !s.runFilter.MatchString(rule)) {
+			continue
+		}
+		wholeChart = append(wholeChart, rule)
+	}
+	sort.Strings(wholeChart)
+
+	if len(wholeChart) > 0 {
+		return fmt.Errorf("%w: %s need the whole chart in memory, mark them per_document or raise the budget",
+			MemoryBudgetExceeded, strings.Join(wholeChart, ", "))
+	}
+	return nil
+}
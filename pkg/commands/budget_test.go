@@ -0,0 +1,204 @@
+package commands_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestEvalMemoryBudget(t *testing.T) {
+	resultLines := func(output string) []string {
+		lines := make([]string, 0)
+		for _, line := range strings.Split(output, "\n") {
+			if strings.Contains(line, "PASS: ") || strings.Contains(line, "FAIL: ") {
+				lines = append(lines, line)
+			}
+		}
+		return lines
+	}
+
+	for _, tt := range []struct {
+		name      string
+		policy    string
+		budget    string
+		failsWith error
+		spilled   bool
+	}{
+		{
+			name:      "charts within the budget are evaluated as a whole",
+			policy:    "testdata/policy/passing",
+			budget:    "1Gi",
+			failsWith: nil,
+		},
+		{
+			name:      "per document rules are evaluated one template at a time",
+			policy:    "testdata/policy/individuals/per_document.rego",
+			budget:    "1K",
+			failsWith: commands.PolicyFailure,
+			spilled:   true,
+		},
+		{
+			name:      "rules needing the whole chart exceed the budget",
+			policy:    "testdata/policy/passing",
+			budget:    "1K",
+			failsWith: commands.MemoryBudgetExceeded,
+			spilled:   true,
+		},
+		{
+			name:      "the budget must be a size",
+			policy:    "testdata/policy/passing",
+			budget:    "lots",
+			failsWith: commands.InvalidMemoryBudget,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			stdOut := new(bytes.Buffer)
+			evalCmd := &commands.EvalCommand{
+				Stdout:       stdOut,
+				Template:     "testdata/templates",
+				Values:       []string{"testdata/values.yml"},
+				Policy:       []string{tt.policy},
+				MemoryBudget: tt.budget,
+			}
+			err := evalCmd.Execute([]string{})
+			if tt.failsWith == nil && err != nil || !errors.Is(err, tt.failsWith) {
+				t.Fatalf("expected error:\n%v\ngot:\n%v", tt.failsWith, err)
+			}
+
+			if spilled := strings.Contains(stdOut.String(), "MEMORY BUDGET: "); spilled != tt.spilled {
+				t.Errorf("expected the chart spilled to disk: %v, got:\n%s", tt.spilled, stdOut.String())
+			}
+
+			if tt.failsWith != commands.PolicyFailure {
+				return
+			}
+
+			wholeOut := new(bytes.Buffer)
+			wholeCmd := &commands.EvalCommand{
+				Stdout:   wholeOut,
+				Template: "testdata/templates",
+				Values:   []string{"testdata/values.yml"},
+				Policy:   []string{tt.policy},
+			}
+			wholeCmd.Execute([]string{})
+			if budgeted, whole := resultLines(stdOut.String()), resultLines(wholeOut.String()); strings.Join(budgeted, "\n") != strings.Join(whole, "\n") {
+				t.Errorf("expected the same results as evaluating the whole chart:\n%s\ngot:\n%s", strings.Join(whole, "\n"), strings.Join(budgeted, "\n"))
+			}
+		})
+	}
+
+	t.Run("failure artifacts are written for rules failing on spilled templates", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "hcunit-budget-artifacts")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		evalCmd := &commands.EvalCommand{
+			Stdout:       ioutil.Discard,
+			Template:     "testdata/templates",
+			Values:       []string{"testdata/values.yml"},
+			Policy:       []string{"testdata/policy/individuals/per_document.rego"},
+			MemoryBudget: "1K",
+			ArtifactsDir: dir,
+		}
+		if err := evalCmd.Execute([]string{}); !errors.Is(err, commands.PolicyFailure) {
+			t.Fatalf("expected error:\n%v\ngot:\n%v", commands.PolicyFailure, err)
+		}
+
+		rules, err := filepath.Glob(filepath.Join(dir, "*", "rule.txt"))
+		if err != nil || len(rules) == 0 {
+			t.Fatalf("expected artifacts of the failed rules, got %v (%v)", rules, err)
+		}
+
+		content, err := ioutil.ReadFile(rules[0])
+		if err != nil || !strings.Contains(string(content), "cost-center") {
+			t.Errorf("expected the failed rule, got %q (%v)", content, err)
+		}
+	})
+
+	dir, err := ioutil.TempDir("", "hcunit-budget-checks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	conventionsPath := filepath.Join(dir, ".hcunit.yaml")
+	if err := ioutil.WriteFile(conventionsPath, []byte("conventions:\n  kinds: [Backup]\n  labels:\n    required: [app]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	retentionPath := filepath.Join(dir, "values.yaml")
+	if err := ioutil.WriteFile(retentionPath, []byte("retention: 0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name      string
+		template  string
+		values    []string
+		config    string
+		profiles  []string
+		failsWith error
+		expected  []string
+	}{
+		{
+			name:      "conventions are checked on spilled templates",
+			template:  "testdata/crdchart",
+			values:    []string{"testdata/crdchart/values.yaml"},
+			config:    conventionsPath,
+			failsWith: commands.ConventionViolation,
+			expected:  []string{"convention Backup/hcunit-name-backup in backup.yaml: missing label app"},
+		},
+		{
+			name:      "custom resources are checked against crds of other spilled templates",
+			template:  "testdata/crdchart",
+			values:    []string{"testdata/crdchart/values.yaml", retentionPath},
+			failsWith: commands.CRDViolation,
+			expected:  []string{"crd schema Backup/hcunit-name-backup in backup.yaml: spec.retention: 0 is less than the minimum 1"},
+		},
+		{
+			name:      "the expectations of the profile are checked on spilled templates",
+			template:  "testdata/profiles/templates",
+			config:    "testdata/profiles/expectations.yaml",
+			profiles:  []string{"understaffed"},
+			failsWith: commands.ProfileExpectationFailure,
+			expected: []string{
+				"profile understaffed expects Deployment/app-dev in deployment.yaml: spec.replicas is 1, expected at least 3",
+				"profile understaffed expects HorizontalPodAutoscaler: not rendered",
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			stdOut := new(bytes.Buffer)
+			evalCmd := &commands.EvalCommand{
+				Stdout:       stdOut,
+				Template:     tt.template,
+				Values:       tt.values,
+				Policy:       []string{"testdata/policy/individuals/named_per_document.rego"},
+				Config:       tt.config,
+				Profile:      tt.profiles,
+				MemoryBudget: "100",
+			}
+			if err := evalCmd.Execute([]string{}); !errors.Is(err, tt.failsWith) {
+				t.Fatalf("expected error:\n%v\ngot:\n%v\n%s", tt.failsWith, err, stdOut)
+			}
+
+			if !strings.Contains(stdOut.String(), "MEMORY BUDGET: ") {
+				t.Errorf("expected the chart spilled to disk, got:\n%s", stdOut)
+			}
+
+			for _, finding := range tt.expected {
+				if !strings.Contains(stdOut.String(), finding) {
+					t.Errorf("expected output to contain:\n%s\ngot:\n%s", finding, stdOut)
+				}
+			}
+		})
+	}
+}
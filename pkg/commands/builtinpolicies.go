@@ -0,0 +1,207 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// InvalidBuiltinPolicies - --builtin-policies names a pack hcunit doesn't
+// ship
+var InvalidBuiltinPolicies = errors.New("invalid --builtin-policies")
+
+// builtinPoliciesDir - where the built-in packs are written to be evaluated
+// like any other policy path, next to the fetched policy packs
+const builtinPoliciesDir = ".hcunit/builtin"
+
+// builtinPolicyPack - an optional policy pack shipped with hcunit. Its
+// rules are written in the package of --namespace, so they are evaluated
+// alongside the given policies, and its helpers in a package of their own
+// so they can't clash with the rules of the given policies
+type builtinPolicyPack struct {
+	Rules string
+	Lib   string
+}
+
+// builtinPolicyPacks - the packs --builtin-policies can enable, by name
+var builtinPolicyPacks = map[string]builtinPolicyPack{
+	"operators": {Rules: operatorsRules, Lib: operatorsLib},
+}
+
+// operatorsRules - cross references of the custom resources of widely used
+// operators, which only hold when what they reference is rendered too
+const operatorsRules = `package %[1]s
+
+import data.hcunit.builtin.operators
+
+deny ["ServiceMonitors select a Service of the chart"] {
+  m := operators.objects[_]
+  m.kind == "ServiceMonitor"
+  count(operators.selected_services(m)) == 0
+}
+
+deny ["ServiceMonitor endpoints name a port of the Services they select"] {
+  m := operators.objects[_]
+  m.kind == "ServiceMonitor"
+  services := operators.selected_services(m)
+  count(services) > 0
+  port := m.spec.endpoints[_].port
+  not operators.service_port(services, port)
+}
+
+deny ["Certificates reference an Issuer of the chart"] {
+  c := operators.objects[_]
+  c.kind == "Certificate"
+  operators.issuer_group(c) == "cert-manager.io"
+  operators.issuer_kind(c) == "Issuer"
+  not operators.rendered("Issuer", c.spec.issuerRef.name)
+}
+
+deny ["Certificates don't share a secretName"] {
+  a := operators.objects[_]
+  a.kind == "Certificate"
+  b := operators.objects[_]
+  b.kind == "Certificate"
+  a.metadata.name != b.metadata.name
+  a.spec.secretName == b.spec.secretName
+}
+
+deny ["ExternalSecrets reference a SecretStore of the chart"] {
+  e := operators.objects[_]
+  e.kind == "ExternalSecret"
+  name := e.spec.secretStoreRef.name
+  operators.store_kind(e) == "SecretStore"
+  not operators.rendered("SecretStore", name)
+}
+
+deny ["ExternalSecrets don't target a Secret the chart renders"] {
+  e := operators.objects[_]
+  e.kind == "ExternalSecret"
+  operators.rendered("Secret", operators.target_name(e))
+}
+`
+
+// operatorsLib - the helpers of the operators pack
+const operatorsLib = `package hcunit.builtin.operators
+
+manifest(name) {
+  re_match("\\.(ya?ml|json)$", name)
+}
+
+objects[obj] {
+  doc := input[name]
+  manifest(name)
+  is_object(doc)
+  obj := doc
+  is_string(obj.kind)
+}
+
+objects[obj] {
+  doc := input[name]
+  manifest(name)
+  is_array(doc)
+  obj := doc[_]
+  is_string(obj.kind)
+}
+
+rendered(kind, name) {
+  o := objects[_]
+  o.kind == kind
+  o.metadata.name == name
+}
+
+match_labels(m) = labels {
+  labels := m.spec.selector.matchLabels
+}
+
+match_labels(m) = {} {
+  not m.spec.selector.matchLabels
+}
+
+selected_services(m) = {s |
+  s := objects[_]
+  s.kind == "Service"
+  selector := match_labels(m)
+  count({k | s.metadata.labels[k] == selector[k]}) == count(selector)
+}
+
+service_port(services, name) {
+  s := services[_]
+  s.spec.ports[_].name == name
+}
+
+issuer_kind(c) = kind {
+  kind := c.spec.issuerRef.kind
+}
+
+issuer_kind(c) = "Issuer" {
+  not c.spec.issuerRef.kind
+}
+
+issuer_group(c) = group {
+  group := c.spec.issuerRef.group
+}
+
+issuer_group(c) = "cert-manager.io" {
+  not c.spec.issuerRef.group
+}
+
+store_kind(e) = kind {
+  kind := e.spec.secretStoreRef.kind
+}
+
+store_kind(e) = "SecretStore" {
+  not e.spec.secretStoreRef.kind
+}
+
+target_name(e) = name {
+  name := e.spec.target.name
+}
+
+target_name(e) = name {
+  not e.spec.target.name
+  name := e.metadata.name
+}
+`
+
+// writeBuiltinPolicies - writes the packs of --builtin-policies under
+// .hcunit/builtin and adds them to the evaluated policies. They are written
+// on every run, so upgrading hcunit upgrades them too
+func (s *EvalCommand) writeBuiltinPolicies() error {
+	for _, name := range s.BuiltinPolicies {
+		pack, ok := builtinPolicyPacks[name]
+		if !ok {
+			return fmt.Errorf("%w: %q is not one of %s", InvalidBuiltinPolicies, name, strings.Join(builtinPolicyPackNames(), ", "))
+		}
+
+		dir := filepath.Join(builtinPoliciesDir, name)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+
+		rules := fmt.Sprintf(pack.Rules, s.Namespace)
+		if err := ioutil.WriteFile(filepath.Join(dir, name+".rego"), []byte(rules), 0644); err != nil {
+			return err
+		}
+
+		if err := ioutil.WriteFile(filepath.Join(dir, name+"_lib.rego"), []byte(pack.Lib), 0644); err != nil {
+			return err
+		}
+		s.Policy = append(s.Policy, dir)
+	}
+	return nil
+}
+
+// builtinPolicyPackNames - the names of the built-in packs, sorted
+func builtinPolicyPackNames() []string {
+	names := make([]string, 0, len(builtinPolicyPacks))
+	for name := range builtinPolicyPacks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,102 @@
+package commands_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestEvalBuiltinPolicies(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hcunit-builtin-policies")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer os.RemoveAll(".hcunit")
+
+	for _, tt := range []struct {
+		name      string
+		values    string
+		packs     []string
+		failsWith error
+		failed    []string
+	}{
+		{
+			name:  "cross references resolving within the chart pass",
+			packs: []string{"operators"},
+		},
+		{
+			name:      "service monitors have to select a service",
+			values:    "monitorApp: api\n",
+			packs:     []string{"operators"},
+			failsWith: commands.PolicyFailure,
+			failed:    []string{`deny["ServiceMonitors select a Service of the chart"]`},
+		},
+		{
+			name:      "service monitor endpoints have to name a port of the service",
+			values:    "monitorPort: metrics\n",
+			packs:     []string{"operators"},
+			failsWith: commands.PolicyFailure,
+			failed:    []string{`deny["ServiceMonitor endpoints name a port of the Services they select"]`},
+		},
+		{
+			name:      "certificates have to reference an issuer of the chart and their own secret",
+			values:    "issuer: staging\napiSecret: web-tls\n",
+			packs:     []string{"operators"},
+			failsWith: commands.PolicyFailure,
+			failed:    []string{`deny["Certificates reference an Issuer of the chart"]`, `deny["Certificates don't share a secretName"]`},
+		},
+		{
+			name:      "external secrets have to reference a store of the chart and not overwrite its secrets",
+			values:    "store: aws\ntarget: web-config\n",
+			packs:     []string{"operators"},
+			failsWith: commands.PolicyFailure,
+			failed:    []string{`deny["ExternalSecrets reference a SecretStore of the chart"]`, `deny["ExternalSecrets don't target a Secret the chart renders"]`},
+		},
+		{
+			name:   "packs are only evaluated when enabled",
+			values: "monitorApp: api\n",
+		},
+		{
+			name:      "unknown packs are rejected",
+			packs:     []string{"operator"},
+			failsWith: commands.InvalidBuiltinPolicies,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			valuesPath := filepath.Join(dir, "values.yaml")
+			if err := ioutil.WriteFile(valuesPath, []byte(tt.values), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			stdOut := new(bytes.Buffer)
+			evalCmd := &commands.EvalCommand{
+				Stdout:          stdOut,
+				Template:        "testdata/operators",
+				Values:          []string{valuesPath},
+				Policy:          []string{"testdata/policy/passing/passing.rego"},
+				BuiltinPolicies: tt.packs,
+			}
+			err := evalCmd.Execute([]string{})
+			if tt.failsWith == nil && err != nil || !errors.Is(err, tt.failsWith) {
+				t.Fatalf("expected error:\n%v\ngot:\n%v\n%s", tt.failsWith, err, stdOut)
+			}
+
+			for _, rule := range tt.failed {
+				if !strings.Contains(stdOut.String(), "FAIL: \x1b[0mdata.main."+rule) {
+					t.Errorf("expected %s to fail, got:\n%s", rule, stdOut)
+				}
+			}
+
+			if strings.Count(stdOut.String(), "FAIL: ") != len(tt.failed) {
+				t.Errorf("expected %d failures, got:\n%s", len(tt.failed), stdOut)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+
+	yaml "gopkg.in/yaml.v3"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/engine"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+)
+
+// InvalidChartMode - --chart is combined with flags rendering templates
+// outside of their chart
+var InvalidChartMode = errors.New("invalid --chart")
+
+// checkChartFlags - --chart renders the whole chart, so it can't be
+// combined with a template path or rendering only named templates
+func (s *EvalCommand) checkChartFlags() error {
+	if s.Template != "" && s.Template != s.Chart {
+		return fmt.Errorf("%w: it can't be combined with --template", InvalidChartMode)
+	}
+
+	if len(s.Defines) > 0 {
+		return fmt.Errorf("%w: it can't be combined with --define, which renders named templates outside of the chart", InvalidChartMode)
+	}
+
+	if !fileExists(filepath.Join(s.Chart, chartutil.ChartfileName)) {
+		return fmt.Errorf("%w: no %s in %s", ChartNotFound, chartutil.ChartfileName, s.Chart)
+	}
+	return nil
+}
+
+// renderChart - renders the chart in chartDir the way helm template does:
+// loaded as a whole with its Chart.yaml, values.yaml, files and subcharts,
+// the values given overriding the chart's, the requirements' conditions,
+// tags and import-values processed, and every template rendered with the
+// chart's own .Chart and .Files. The outputs are keyed like those of
+// render, with the templates of subcharts keyed by the subchart
+func renderChart(chartDir string, values map[string]interface{}, options renderOptions) (map[string]string, error) {
+	c, err := chartutil.Load(chartDir)
+	if err != nil {
+		return nil, fmt.Errorf("loading chart %s failed: %w", chartDir, err)
+	}
+
+	if len(options.Fixtures) == 0 {
+		library, err := isLibraryChart(chartDir)
+		if err != nil {
+			return nil, err
+		}
+
+		if library {
+			return nil, LibraryChartWithoutFixtures
+		}
+	}
+
+	if c.Templates, err = filterChartTemplates(chartDir, c.Templates, options.Filter); err != nil {
+		return nil, err
+	}
+
+	for _, fixture := range options.Fixtures {
+		fixtureFiles, err := WalkTemplatePath(fixture)
+		if err != nil {
+			return nil, fmt.Errorf("fixture validation failed: %w", err)
+		}
+
+		for name, file := range fixtureFiles {
+			data, err := ioutil.ReadAll(file)
+			file.Close()
+			if err != nil {
+				return nil, fmt.Errorf("reading fixture %s failed: %w", name, err)
+			}
+			c.Templates = append(c.Templates, &chart.Template{Name: path.Join("templates", filepath.Base(name)), Data: data})
+		}
+	}
+
+	b, err := yaml.Marshal(values)
+	if err != nil {
+		return nil, fmt.Errorf("couldnt marshal values: %w", err)
+	}
+
+	config := &chart.Config{Raw: string(b)}
+	if err := chartutil.ProcessRequirementsEnabled(c, config); err != nil {
+		return nil, fmt.Errorf("processing the requirements of %s failed: %w", c.GetMetadata().GetName(), err)
+	}
+
+	if err := chartutil.ProcessRequirementsImportValues(c); err != nil {
+		return nil, fmt.Errorf("importing the values of the subcharts of %s failed: %w", c.GetMetadata().GetName(), err)
+	}
+
+	caps, err := renderCapabilities(options.KubeVersion, options.APIVersions)
+	if err != nil {
+		return nil, err
+	}
+
+	renderValues, err := chartutil.ToRenderValuesCaps(c, config, options.release().chartutil(), caps)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute the values of %s: %w", c.GetMetadata().GetName(), err)
+	}
+
+	if options.TplValues {
+		if err := tplValues(renderValues["Values"].(chartutil.Values), partialTemplates(c.Templates), renderValues); err != nil {
+			return nil, err
+		}
+	}
+
+	rendered, err := engine.New().Render(c, renderValues)
+	if err != nil {
+		return nil, err
+	}
+
+	rendered = renameSubchartOutputs(rendered, c.GetMetadata().GetName(), c.Templates)
+	if len(options.SecondPass) > 0 {
+		return secondPass(rendered, options.SecondPass, partialTemplates(c.Templates), renderValues)
+	}
+	return rendered, nil
+}
+
+// filterChartTemplates - the templates of a loaded chart passing the
+// include/exclude filter, matched the way the files of a template path are
+func filterChartTemplates(chartDir string, templates []*chart.Template, filter TemplateFilter) ([]*chart.Template, error) {
+	if filter.empty() {
+		return templates, nil
+	}
+
+	files := make(map[string]io.ReadCloser, len(templates))
+	for _, template := range templates {
+		file := filepath.Join(chartDir, template.Name)
+		files[file] = ioutil.NopCloser(bytes.NewReader(template.Data))
+	}
+
+	kept, err := filter.apply(chartDir, files)
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := make([]*chart.Template, 0, len(kept))
+	for _, template := range templates {
+		if _, ok := kept[filepath.Join(chartDir, template.Name)]; ok {
+			filtered = append(filtered, template)
+		}
+	}
+	return filtered, nil
+}
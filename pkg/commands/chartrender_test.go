@@ -0,0 +1,82 @@
+package commands_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestEvalChart(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		chart     string
+		template  string
+		values    []string
+		defines   []string
+		exclude   []string
+		failsWith error
+		contains  []string
+	}{
+		{
+			name:     "the chart renders with its metadata, files, values and subcharts",
+			chart:    "testdata/fullchart",
+			contains: []string{"[SUCCESS]"},
+		},
+		{
+			name:     "given values override the chart's and its subcharts'",
+			chart:    "testdata/fullchart",
+			values:   []string{"testdata/fullchart_values.yaml"},
+			contains: []string{"[SUCCESS]"},
+		},
+		{
+			name:      "templates filters apply to the chart's templates",
+			chart:     "testdata/fullchart",
+			exclude:   []string{"configmap.yaml"},
+			failsWith: commands.PolicyFailure,
+			contains:  []string{`FAIL: ` + "\x1b[0m" + `data.main.expect["the chart's files are available"]`, "REPRODUCE: \x1b[0mhcunit eval --chart testdata/fullchart"},
+		},
+		{
+			name:      "a template path can't be given too",
+			chart:     "testdata/fullchart",
+			template:  "testdata/fullchart/templates",
+			failsWith: commands.InvalidChartMode,
+		},
+		{
+			name:      "named templates can't be rendered on their own",
+			chart:     "testdata/fullchart",
+			defines:   []string{"fullchart.labels"},
+			failsWith: commands.InvalidChartMode,
+		},
+		{
+			name:      "directories without a Chart.yaml are rejected",
+			chart:     "testdata/templates",
+			failsWith: commands.ChartNotFound,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			stdOut := new(bytes.Buffer)
+			evalCmd := &commands.EvalCommand{
+				Stdout:           stdOut,
+				Chart:            tt.chart,
+				Template:         tt.template,
+				Values:           tt.values,
+				Defines:          tt.defines,
+				ExcludeTemplates: tt.exclude,
+				Policy:           []string{"testdata/policy/individuals/full_chart.rego"},
+			}
+			err := evalCmd.Execute([]string{})
+			if tt.failsWith == nil && err != nil || !errors.Is(err, tt.failsWith) {
+				t.Fatalf("expected error:\n%v\ngot:\n%v\n%s", tt.failsWith, err, stdOut)
+			}
+
+			for _, s := range tt.contains {
+				if !strings.Contains(stdOut.String(), s) {
+					t.Errorf("expected output to contain:\n%s\ngot:\n%s", s, stdOut)
+				}
+			}
+		})
+	}
+}
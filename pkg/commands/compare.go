@@ -0,0 +1,143 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/mitchellh/colorstring"
+)
+
+var NewViolations = errors.New("new policy violations compared to the previous results")
+var InvalidCompareArgs = errors.New("compare takes the old and the new results file")
+
+// ResultComparison - the rules of two runs, by how their outcome changed.
+// Rules which warn count as passing
+type ResultComparison struct {
+	NewlyFailing []string `json:"newlyFailing"`
+	NewlyPassing []string `json:"newlyPassing"`
+	StillFailing []string `json:"stillFailing"`
+	Unchanged    []string `json:"unchanged"`
+}
+
+type CompareCommand struct {
+	Writer io.Writer
+}
+
+// Execute - compares the results files given as arguments, old then new,
+// failing when the new results have violations the old ones don't
+func (s *CompareCommand) Execute(args []string) error {
+	s.setDefaults()
+	if len(args) != 2 {
+		return InvalidCompareArgs
+	}
+
+	old, err := loadRunReport(args[0])
+	if err != nil {
+		return err
+	}
+
+	current, err := loadRunReport(args[1])
+	if err != nil {
+		return err
+	}
+	return reportComparison(s.Writer, compareResults(old.Results, current.Results))
+}
+
+func (s *CompareCommand) setDefaults() {
+	if s.Writer == nil {
+		s.Writer = os.Stdout
+	}
+}
+
+// compareTo - compares this run to the results in --compare-to, so that only
+// violations the previous results don't have fail the run
+func (s *EvalCommand) compareTo(err error) error {
+	var violation *ViolationError
+	if err != nil && !errors.As(err, &violation) {
+		return err
+	}
+
+	old, loadErr := loadRunReport(s.CompareTo)
+	if loadErr != nil {
+		return loadErr
+	}
+	return reportComparison(s.Stdout, compareResults(old.Results, ruleReports(s.results)))
+}
+
+func compareResults(old, current []RuleReport) ResultComparison {
+	failedBefore := make(map[string]bool, len(old))
+	for _, report := range old {
+		failedBefore[comparisonKey(report)] = report.Result == "fail"
+	}
+
+	comparison := ResultComparison{}
+	for _, report := range current {
+		key := comparisonKey(report)
+		failing := report.Result == "fail"
+		switch {
+		case failing && failedBefore[key]:
+			comparison.StillFailing = append(comparison.StillFailing, key)
+		case failing:
+			comparison.NewlyFailing = append(comparison.NewlyFailing, key)
+		case failedBefore[key]:
+			comparison.NewlyPassing = append(comparison.NewlyPassing, key)
+		default:
+			comparison.Unchanged = append(comparison.Unchanged, key)
+		}
+	}
+
+	for _, rules := range [][]string{comparison.NewlyFailing, comparison.NewlyPassing, comparison.StillFailing, comparison.Unchanged} {
+		sort.Strings(rules)
+	}
+	return comparison
+}
+
+// comparisonKey - a rule along with the kubernetes version and values set
+// it was evaluated for, so matrix and batch runs compare per combination,
+// e.g. deny["no latest tags"] (kubernetes 1.20, values set prod)
+func comparisonKey(report RuleReport) string {
+	dimensions := make([]string, 0, 2)
+	if report.KubeVersion != "" {
+		dimensions = append(dimensions, "kubernetes "+report.KubeVersion)
+	}
+
+	if report.ValuesSet != "" {
+		dimensions = append(dimensions, "values set "+report.ValuesSet)
+	}
+
+	if len(dimensions) == 0 {
+		return report.Rule
+	}
+	return fmt.Sprintf("%s (%s)", report.Rule, strings.Join(dimensions, ", "))
+}
+
+// reportComparison - prints the rules whose outcome changed and the ones
+// still failing, returning NewViolations when any rule newly fails
+func reportComparison(writer io.Writer, comparison ResultComparison) error {
+	for _, rule := range comparison.NewlyFailing {
+		colorstring.Fprint(writer, "[red]NEW FAILURE: ")
+		fmt.Fprintln(writer, rule)
+	}
+
+	for _, rule := range comparison.NewlyPassing {
+		colorstring.Fprint(writer, "[green]FIXED: ")
+		fmt.Fprintln(writer, rule)
+	}
+
+	for _, rule := range comparison.StillFailing {
+		colorstring.Fprint(writer, "[yellow]STILL FAILING: ")
+		fmt.Fprintln(writer, rule)
+	}
+
+	fmt.Fprintf(writer, "%d newly failing, %d newly passing, %d still failing, %d unchanged\n",
+		len(comparison.NewlyFailing), len(comparison.NewlyPassing), len(comparison.StillFailing), len(comparison.Unchanged))
+
+	if len(comparison.NewlyFailing) > 0 {
+		return fmt.Errorf("%w: %s", NewViolations, strings.Join(comparison.NewlyFailing, ", "))
+	}
+	return nil
+}
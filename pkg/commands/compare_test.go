@@ -0,0 +1,169 @@
+package commands_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestCompareResults(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hcunit-compare")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	current := filepath.Join(dir, "current.json")
+	evalCmd := &commands.EvalCommand{
+		Stdout:      ioutil.Discard,
+		Template:    "testdata/templates",
+		Values:      []string{"testdata/values.yml"},
+		Policy:      []string{"testdata/policy/failing"},
+		ResultsFile: current,
+	}
+	if err := evalCmd.Execute([]string{}); !errors.Is(err, commands.PolicyFailure) {
+		t.Fatalf("expected error:\n%v\ngot:\n%v", commands.PolicyFailure, err)
+	}
+
+	previous := filepath.Join(dir, "previous.json")
+	content, err := ioutil.ReadFile(current)
+	if err != nil {
+		t.Fatalf("expected a results file: %v", err)
+	}
+
+	fixed := strings.Replace(string(content), `"rule": "data.main.expect[\"force failure\"]",
+      "result": "fail"`, `"rule": "data.main.expect[\"force failure\"]",
+      "result": "pass"`, 1)
+	if fixed == string(content) {
+		t.Fatalf("expected the results to contain the failing rule, got:\n%s", content)
+	}
+
+	if err := ioutil.WriteFile(previous, []byte(fixed), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("eval only fails on violations the previous results don't have", func(t *testing.T) {
+		stdOut := new(bytes.Buffer)
+		evalCmd := &commands.EvalCommand{
+			Stdout:    stdOut,
+			Template:  "testdata/templates",
+			Values:    []string{"testdata/values.yml"},
+			Policy:    []string{"testdata/policy/failing"},
+			CompareTo: current,
+		}
+		if err := evalCmd.Execute([]string{}); err != nil {
+			t.Errorf("expected known violations to pass, got: %v", err)
+		}
+
+		if !strings.Contains(stdOut.String(), "0 newly failing, 0 newly passing, 4 still failing, 4 unchanged") {
+			t.Errorf("expected a comparison summary, got:\n%s", stdOut.String())
+		}
+	})
+
+	for _, tt := range []struct {
+		name      string
+		args      []string
+		failsWith error
+		contains  string
+	}{
+		{
+			name:      "a rule failing only in the new results",
+			args:      []string{previous, current},
+			failsWith: commands.NewViolations,
+			contains:  `NEW FAILURE: ` + "\x1b[0m" + `data.main.expect["force failure"]`,
+		},
+		{
+			name:      "a rule failing only in the old results",
+			args:      []string{current, previous},
+			failsWith: nil,
+			contains:  `FIXED: ` + "\x1b[0m" + `data.main.expect["force failure"]`,
+		},
+		{
+			name:      "a single results file",
+			args:      []string{current},
+			failsWith: commands.InvalidCompareArgs,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			stdOut := new(bytes.Buffer)
+			compareCmd := &commands.CompareCommand{Writer: stdOut}
+			err := compareCmd.Execute(tt.args)
+			if !errors.Is(err, tt.failsWith) {
+				t.Fatalf("expected error:\n%v\ngot:\n%v", tt.failsWith, err)
+			}
+
+			if !strings.Contains(stdOut.String(), tt.contains) {
+				t.Errorf("expected output to contain %q, got:\n%s", tt.contains, stdOut.String())
+			}
+		})
+	}
+}
+
+func TestCompareResultsPerDimension(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hcunit-compare-dimensions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	evaluate := func(name string, evalCmd *commands.EvalCommand) string {
+		evalCmd.Stdout = ioutil.Discard
+		evalCmd.Values = []string{"testdata/values.yml"}
+		evalCmd.ResultsFile = filepath.Join(dir, name+".json")
+		if err := evalCmd.Execute([]string{}); !errors.Is(err, commands.PolicyFailure) {
+			t.Fatalf("expected error:\n%v\ngot:\n%v", commands.PolicyFailure, err)
+		}
+		return evalCmd.ResultsFile
+	}
+
+	t.Run("results of a kubernetes version matrix name their version", func(t *testing.T) {
+		content, err := ioutil.ReadFile(evaluate("matrix", &commands.EvalCommand{
+			Template:     "testdata/kubeversions",
+			Policy:       []string{"testdata/policy/individuals/kube_versions.rego"},
+			KubeVersions: []string{"1.16,1.29"},
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, version := range []string{`"kubeVersion": "1.16"`, `"kubeVersion": "1.29"`} {
+			if !strings.Contains(string(content), version) {
+				t.Errorf("expected %s in the results, got:\n%s", version, content)
+			}
+		}
+	})
+
+	t.Run("rules are compared per values set", func(t *testing.T) {
+		previous := evaluate("previous", &commands.EvalCommand{
+			Template:   "testdata/templates",
+			Policy:     []string{"testdata/policy/partial"},
+			ValuesSets: []string{"default=testdata/added_values.yml", "ingress="},
+		})
+		current := evaluate("current", &commands.EvalCommand{
+			Template:   "testdata/templates",
+			Policy:     []string{"testdata/policy/partial"},
+			ValuesSets: []string{"default=", "ingress=testdata/added_values.yml"},
+		})
+
+		stdOut := new(bytes.Buffer)
+		compareCmd := &commands.CompareCommand{Writer: stdOut}
+		if err := compareCmd.Execute([]string{previous, current}); !errors.Is(err, commands.NewViolations) {
+			t.Fatalf("expected error:\n%v\ngot:\n%v", commands.NewViolations, err)
+		}
+
+		for _, line := range []string{
+			`NEW FAILURE: ` + "\x1b[0m" + `data.main.deny["the ui ingress is enabled without tls"] (values set ingress)`,
+			`FIXED: ` + "\x1b[0m" + `data.main.deny["the ui ingress is enabled without tls"] (values set default)`,
+		} {
+			if !strings.Contains(stdOut.String(), line) {
+				t.Errorf("expected the output to contain %q, got:\n%s", line, stdOut.String())
+			}
+		}
+	})
+}
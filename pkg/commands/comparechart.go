@@ -0,0 +1,304 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mitchellh/colorstring"
+	yaml "gopkg.in/yaml.v3"
+	"k8s.io/helm/pkg/chartutil"
+)
+
+var InvalidChartComparison = errors.New("compare-chart takes a --base and a --head chart")
+
+// ResourceComparison - the rendered objects of two chart versions, by
+// Kind/namespace/name, and how they changed
+type ResourceComparison struct {
+	Added     []string          `json:"added"`
+	Removed   []string          `json:"removed"`
+	Changed   []string          `json:"changed"`
+	Unchanged []string          `json:"unchanged"`
+	Diffs     map[string]string `json:"diffs"`
+}
+
+type CompareChartCommand struct {
+	Writer      io.Writer
+	Base        string   `long:"base" description:"the reference chart: a chart directory, a .tgz archive, an https:// archive or an oci:// reference pulled with helm"`
+	Head        string   `long:"head" description:"the chart to compare with the reference, in any form --base takes"`
+	Values      []string `short:"c" long:"values" description:"path to values file(s) both charts are rendered with"`
+	Policy      []string `short:"p" long:"policy" description:"path to rego policies (a .rego file or a directory) to find the violations the head chart introduces with (repeatable)"`
+	Namespace   string   `short:"n" long:"namespace" description:"policy namespace to query for rules"`
+	ShowSecrets bool     `long:"show-secrets" description:"print the values of rendered Secrets in diffs instead of redacting them"`
+	Offline     bool     `long:"offline" description:"fail instead of fetching remote charts"`
+}
+
+// Execute - renders the base and head charts with the same values, prints
+// the objects added, removed and changed by the head chart, failing on
+// changes a helm upgrade can't apply in place and, given policies, when the
+// head chart violates rules the base chart doesn't
+func (s *CompareChartCommand) Execute(args []string) error {
+	s.setDefaults()
+	if s.Base == "" || s.Head == "" {
+		return InvalidChartComparison
+	}
+
+	dir, err := ioutil.TempDir("", "hcunit-compare-chart")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	fetch := defaultFetchOptions
+	charts := make([]string, 0, 2)
+	for _, ref := range []string{s.Base, s.Head} {
+		chartDir, err := s.fetchChart(ref, filepath.Join(dir, fmt.Sprint(len(charts))), fetch)
+		if err != nil {
+			return fmt.Errorf("fetching chart %s failed: %w", ref, err)
+		}
+		charts = append(charts, chartDir)
+	}
+
+	valuesConfig, err := mergeValues(s.Values)
+	if err != nil {
+		return fmt.Errorf("failed merging values files %w ", err)
+	}
+
+	objects := make([]map[string]map[string]interface{}, 0, 2)
+	for _, chartDir := range charts {
+		rendered, err := s.renderObjects(chartDir, valuesConfig)
+		if err != nil {
+			return err
+		}
+		objects = append(objects, rendered)
+	}
+
+	comparison, err := compareResources(objects[0], objects[1])
+	if err != nil {
+		return err
+	}
+	reportResourceComparison(s.Writer, comparison)
+	hazardsErr := reportUpgradeHazards(s.Writer, upgradeHazards(objects[0], objects[1], comparison))
+
+	if len(s.Policy) == 0 {
+		return hazardsErr
+	}
+
+	reports := make([][]RuleReport, 0, 2)
+	for _, chartDir := range charts {
+		results, err := s.evaluate(chartDir)
+		if err != nil {
+			return err
+		}
+		reports = append(reports, ruleReports(results))
+	}
+
+	if err := reportComparison(s.Writer, compareResults(reports[0], reports[1])); err != nil {
+		return err
+	}
+	return hazardsErr
+}
+
+func (s *CompareChartCommand) setDefaults() {
+	if s.Writer == nil {
+		s.Writer = os.Stdout
+	}
+}
+
+// fetchChart - the chart directory of a chart reference, unpacking archives
+// and fetching remote charts into dir
+func (s *CompareChartCommand) fetchChart(ref, dir string, fetch FetchOptions) (string, error) {
+	remote := strings.HasPrefix(ref, "oci://") || strings.HasPrefix(ref, "https://") || strings.HasPrefix(ref, "http://")
+	if remote && s.Offline {
+		return "", offlineError("fetching chart " + ref)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	switch {
+	case strings.HasPrefix(ref, "oci://"):
+		pulled := filepath.Join(dir, "pulled")
+		if err := fetch.run("helm", "pull", ref, "--destination", pulled); err != nil {
+			return "", err
+		}
+
+		archives, err := filepath.Glob(filepath.Join(pulled, "*.tgz"))
+		if err != nil || len(archives) != 1 {
+			return "", fmt.Errorf("%w: helm pull of %s did not yield a chart archive", ChartNotFound, ref)
+		}
+		ref = archives[0]
+
+	case remote:
+		b, err := fetch.httpGet(ref)
+		if err != nil {
+			return "", err
+		}
+
+		if err := untar(bytes.NewReader(b), dir); err != nil {
+			return "", err
+		}
+		return unpackedChart(dir)
+	}
+
+	info, err := os.Stat(ref)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", TemplatePathNotFound, ref)
+	}
+
+	if info.IsDir() {
+		return findChartRoot(ref)
+	}
+
+	archive, err := os.Open(ref)
+	if err != nil {
+		return "", err
+	}
+	defer archive.Close()
+
+	unpacked := filepath.Join(dir, "unpacked")
+	if err := untar(archive, unpacked); err != nil {
+		return "", err
+	}
+	return unpackedChart(unpacked)
+}
+
+// unpackedChart - the chart directory within an unpacked chart archive
+func unpackedChart(dir string) (string, error) {
+	charts, err := filepath.Glob(filepath.Join(dir, "*", chartutil.ChartfileName))
+	if err != nil || len(charts) != 1 {
+		return "", fmt.Errorf("%w: the archive holds %d charts", ChartNotFound, len(charts))
+	}
+	return filepath.Dir(charts[0]), nil
+}
+
+// renderObjects - the objects rendered by a chart by Kind/namespace/name
+func (s *CompareChartCommand) renderObjects(chartDir string, valuesConfig map[string]interface{}) (map[string]map[string]interface{}, error) {
+	rendered, err := validateAndRender(chartDir, valuesConfig, renderOptions{})
+	if err != nil {
+		return nil, &RenderError{Template: chartDir, Err: err}
+	}
+
+	if !s.ShowSecrets {
+		for name, content := range rendered {
+			rendered[name] = redactSecretDocuments(content)
+		}
+	}
+
+	input, err := UnmarshalYamlMap(rendered)
+	if err != nil {
+		return nil, &RenderError{Template: chartDir, Err: fmt.Errorf("formatting rendered templates failed: %w", err)}
+	}
+
+	objects := make(map[string]map[string]interface{})
+	for _, obj := range renderedObjects(input) {
+		objects[resourceKey(obj)] = obj
+	}
+	return objects, nil
+}
+
+func objectYAML(obj map[string]interface{}) (string, error) {
+	out := new(bytes.Buffer)
+	encoder := yaml.NewEncoder(out)
+	encoder.SetIndent(2)
+	err := encoder.Encode(obj)
+	return out.String(), err
+}
+
+// resourceKey - identifies an object across chart versions
+func resourceKey(obj map[string]interface{}) string {
+	if namespace := objectNamespace(obj); namespace != "" {
+		return fmt.Sprintf("%s/%s/%s", objectKind(obj), namespace, objectName(obj))
+	}
+	return objectRef(obj)
+}
+
+// evaluate - the results of the policies on a chart, violations included
+func (s *CompareChartCommand) evaluate(chartDir string) ([]RuleResult, error) {
+	evalCmd := &EvalCommand{
+		Writer:    ioutil.Discard,
+		Stdout:    ioutil.Discard,
+		Template:  chartDir,
+		Values:    s.Values,
+		Policy:    s.Policy,
+		Namespace: s.Namespace,
+		Offline:   s.Offline,
+	}
+
+	var violation *ViolationError
+	if err := evalCmd.Execute(nil); err != nil && !errors.As(err, &violation) {
+		return nil, err
+	}
+	return evalCmd.results, nil
+}
+
+func compareResources(base, head map[string]map[string]interface{}) (ResourceComparison, error) {
+	comparison := ResourceComparison{Diffs: make(map[string]string)}
+	for key, obj := range head {
+		baseObj, ok := base[key]
+		if !ok {
+			comparison.Added = append(comparison.Added, key)
+			continue
+		}
+
+		before, err := objectYAML(baseObj)
+		if err != nil {
+			return comparison, err
+		}
+
+		after, err := objectYAML(obj)
+		if err != nil {
+			return comparison, err
+		}
+
+		if before != after {
+			comparison.Changed = append(comparison.Changed, key)
+			comparison.Diffs[key] = lineDiff(before, after)
+			continue
+		}
+		comparison.Unchanged = append(comparison.Unchanged, key)
+	}
+
+	for key := range base {
+		if _, ok := head[key]; !ok {
+			comparison.Removed = append(comparison.Removed, key)
+		}
+	}
+
+	for _, keys := range [][]string{comparison.Added, comparison.Removed, comparison.Changed, comparison.Unchanged} {
+		sort.Strings(keys)
+	}
+	return comparison, nil
+}
+
+// reportResourceComparison - prints the objects added, removed and changed,
+// the latter with a diff of their rendered yaml
+func reportResourceComparison(writer io.Writer, comparison ResourceComparison) {
+	for _, key := range comparison.Added {
+		colorstring.Fprint(writer, "[green]ADDED: ")
+		fmt.Fprintln(writer, key)
+	}
+
+	for _, key := range comparison.Removed {
+		colorstring.Fprint(writer, "[red]REMOVED: ")
+		fmt.Fprintln(writer, key)
+	}
+
+	for _, key := range comparison.Changed {
+		colorstring.Fprint(writer, "[yellow]CHANGED: ")
+		fmt.Fprintln(writer, key)
+		for _, line := range strings.Split(strings.TrimSuffix(comparison.Diffs[key], "\n"), "\n") {
+			fmt.Fprintf(writer, "      %s\n", line)
+		}
+	}
+
+	fmt.Fprintf(writer, "%d added, %d removed, %d changed, %d unchanged\n",
+		len(comparison.Added), len(comparison.Removed), len(comparison.Changed), len(comparison.Unchanged))
+}
@@ -0,0 +1,94 @@
+package commands_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+	"k8s.io/helm/pkg/chartutil"
+)
+
+func TestCompareChart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hcunit-compare-chart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	base, err := chartutil.Load("testdata/comparechart/base")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	archive, err := chartutil.Save(base, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name      string
+		cmd       *commands.CompareChartCommand
+		failsWith error
+		expected  []string
+	}{
+		{
+			name: "objects added, removed and changed by the head chart are reported",
+			cmd:  &commands.CompareChartCommand{Base: "testdata/comparechart/base", Head: "testdata/comparechart/head"},
+			expected: []string{
+				"ADDED: \x1b[0mService/web/hcunit-name-app\n",
+				"REMOVED: \x1b[0mConfigMap/hcunit-name-app\n",
+				"CHANGED: \x1b[0mDeployment/hcunit-name-app\n",
+				"      -       - image: app:1.2.0\n      +       - image: app:1.3.0\n",
+				"1 added, 1 removed, 1 changed, 0 unchanged\n",
+			},
+		},
+		{
+			name:     "a packaged base chart is unpacked",
+			cmd:      &commands.CompareChartCommand{Base: archive, Head: "testdata/comparechart/base"},
+			expected: []string{"0 added, 0 removed, 0 changed, 2 unchanged\n"},
+		},
+		{
+			name: "violations introduced by the head chart fail the comparison",
+			cmd: &commands.CompareChartCommand{
+				Base:   archive,
+				Head:   "testdata/comparechart/head",
+				Values: []string{"testdata/comparechart/values.yaml"},
+				Policy: []string{"testdata/policy/comparechart"},
+			},
+			failsWith: commands.NewViolations,
+			expected: []string{
+				"NEW FAILURE: \x1b[0mdata.main.expect[\"services are not exposed on node ports\"]\n",
+				"STILL FAILING: \x1b[0mdata.main.expect[\"images are pinned by digest\"]\n",
+			},
+		},
+		{
+			name:      "remote charts are not fetched offline",
+			cmd:       &commands.CompareChartCommand{Base: "oci://registry.example.com/charts/app:1.2.0", Head: "testdata/comparechart/head", Offline: true},
+			failsWith: commands.OfflineViolation,
+		},
+		{
+			name:      "both charts are required",
+			cmd:       &commands.CompareChartCommand{Head: "testdata/comparechart/head"},
+			failsWith: commands.InvalidChartComparison,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			stdOut := new(bytes.Buffer)
+			tt.cmd.Writer = stdOut
+			err := tt.cmd.Execute([]string{})
+			if tt.failsWith == nil && err != nil || !errors.Is(err, tt.failsWith) {
+				t.Fatalf("expected error:\n%v\ngot:\n%v", tt.failsWith, err)
+			}
+
+			for _, expected := range tt.expected {
+				if !strings.Contains(stdOut.String(), expected) {
+					t.Errorf("expected output to contain %q, got:\n%s", expected, stdOut.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/Masterminds/semver"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// policyManifestName - the manifest a policy bundle declares its
+// requirements on hcunit in, next to its rego files
+const policyManifestName = ".hcunit-policy.yaml"
+
+// inputSchemaVersion - the version of the input hcunit hands to policies
+// (rendered templates by file name, values, tests, defines, kube_version,
+// network, rbac, podspecs, autoscaling, availability, routing, storage,
+// hooks, crds, binary and release), bumped whenever policies written
+// against it could silently misbehave on an older one
+const inputSchemaVersion = 10
+
+var IncompatiblePolicy = errors.New("policy is not compatible with this hcunit")
+
+// PolicyManifest - what a policy bundle requires of the hcunit evaluating it
+type PolicyManifest struct {
+	MinHcunitVersion   string `yaml:"min_hcunit_version"`
+	InputSchemaVersion int    `yaml:"input_schema_version"`
+}
+
+// policyManifestPath - the manifest of a policy path: in the directory
+// itself, or next to a single .rego file
+func policyManifestPath(policy string) string {
+	if info, err := os.Stat(policy); err == nil && !info.IsDir() {
+		return filepath.Join(filepath.Dir(policy), policyManifestName)
+	}
+	return filepath.Join(policy, policyManifestName)
+}
+
+// checkPolicyCompatibility - fails when a policy path's manifest requires a
+// newer hcunit or input schema than this one. Development builds, which
+// have no released version, are only checked against the input schema
+func checkPolicyCompatibility(policies []string, version string) error {
+	for _, policy := range policies {
+		b, err := ioutil.ReadFile(policyManifestPath(policy))
+		if os.IsNotExist(err) {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		manifest := PolicyManifest{}
+		if err := yaml.Unmarshal(b, &manifest); err != nil {
+			return fmt.Errorf("failed to parse %s of %s: %w", policyManifestName, policy, err)
+		}
+
+		if manifest.InputSchemaVersion > inputSchemaVersion {
+			return fmt.Errorf("%w: %s is written for input schema version %d, this hcunit provides version %d; upgrade hcunit (hcunit self-update)",
+				IncompatiblePolicy, policy, manifest.InputSchemaVersion, inputSchemaVersion)
+		}
+
+		if manifest.MinHcunitVersion == "" {
+			continue
+		}
+
+		required, err := semver.NewVersion(manifest.MinHcunitVersion)
+		if err != nil {
+			return fmt.Errorf("invalid min_hcunit_version %q in %s of %s: %w", manifest.MinHcunitVersion, policyManifestName, policy, err)
+		}
+
+		current, err := semver.NewVersion(version)
+		if err != nil || current.Prerelease() == "localdev" {
+			continue
+		}
+
+		if current.LessThan(required) {
+			return fmt.Errorf("%w: %s requires hcunit %s or newer, this is %s; upgrade hcunit (hcunit self-update)",
+				IncompatiblePolicy, policy, required, current)
+		}
+	}
+	return nil
+}
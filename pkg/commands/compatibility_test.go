@@ -0,0 +1,106 @@
+package commands_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestPolicyCompatibility(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hcunit-compatibility")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	rego, err := ioutil.ReadFile("testdata/policy/compatible/compatible.rego")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bundle := func(name, manifest string) string {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(path, 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(filepath.Join(path, "policy.rego"), rego, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(filepath.Join(path, ".hcunit-policy.yaml"), []byte(manifest), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	for _, tt := range []struct {
+		name      string
+		policy    string
+		version   string
+		failsWith error
+		message   string
+	}{
+		{
+			name:    "a release satisfying the manifest evaluates the policy",
+			policy:  "testdata/policy/compatible",
+			version: "0.9.1",
+		},
+		{
+			name:    "single rego files are checked against the manifest next to them",
+			policy:  "testdata/policy/compatible/compatible.rego",
+			version: "1.0.0",
+		},
+		{
+			name:      "an older release is told to upgrade",
+			policy:    "testdata/policy/compatible",
+			version:   "0.8.0",
+			failsWith: commands.IncompatiblePolicy,
+			message:   "requires hcunit 0.9.0 or newer, this is 0.8.0; upgrade hcunit",
+		},
+		{
+			name:    "development builds skip the version check",
+			policy:  "testdata/policy/compatible",
+			version: "0.0.0-localdev",
+		},
+		{
+			name:      "a newer input schema is incompatible with any release",
+			policy:    bundle("schema", "input_schema_version: 11\n"),
+			version:   "0.0.0-localdev",
+			failsWith: commands.IncompatiblePolicy,
+			message:   "written for input schema version 11, this hcunit provides version 10",
+		},
+		{
+			name:    "policies without a manifest are always compatible",
+			policy:  "testdata/policy/passing",
+			version: "0.1.0",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			evalCmd := &commands.EvalCommand{
+				Stdout:   ioutil.Discard,
+				Version:  tt.version,
+				Template: "testdata/templates",
+				Values:   []string{"testdata/values.yml"},
+				Policy:   []string{tt.policy},
+			}
+			err := evalCmd.Execute([]string{})
+			if tt.failsWith == nil && err != nil {
+				t.Fatalf("expected a compatible policy to pass, got: %v", err)
+			}
+
+			if tt.failsWith != nil && !errors.Is(err, tt.failsWith) {
+				t.Fatalf("expected error:\n%v\ngot:\n%v", tt.failsWith, err)
+			}
+
+			if tt.message != "" && !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("expected the error to contain %q, got: %v", tt.message, err)
+			}
+		})
+	}
+}
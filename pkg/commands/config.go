@@ -0,0 +1,215 @@
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+const (
+	defaultConfigPath = ".hcunit.yaml"
+	userConfigName    = "config.yaml"
+)
+
+// Config - the repo level hcunit configuration read from .hcunit.yaml, or
+// the user level one read from $XDG_CONFIG_HOME/hcunit/config.yaml
+type Config struct {
+	Policies      []PolicySource     `yaml:"policies"`
+	Fetch         FetchOptions       `yaml:"fetch"`
+	Notifications []Notification     `yaml:"notifications"`
+	Input         InputConfig        `yaml:"input"`
+	Enforcement   EnforcementConfig  `yaml:"enforcement"`
+	Conventions   ConventionsConfig  `yaml:"conventions"`
+	Adapters      []Adapter          `yaml:"adapters"`
+	Defaults      Defaults           `yaml:"defaults"`
+	Profiles      map[string]Profile `yaml:"profiles"`
+
+	path string
+}
+
+// Defaults - what eval does when a flag isn't given. Flags (and their
+// HCUNIT_* environment variables) take precedence over the defaults of
+// .hcunit.yaml, which take precedence over the defaults of the user config
+type Defaults struct {
+	// Output - how results are printed, text, json or plain-verbose
+	Output string `yaml:"output"`
+
+	// Color - whether the output is colored, it is when unset
+	Color *bool `yaml:"color"`
+
+	// ASCII - whether hcunit's own symbols are limited to ascii
+	ASCII bool `yaml:"ascii"`
+
+	// Policies - the policy paths evaluated when no -p is given, relative
+	// to the config declaring them
+	Policies []string `yaml:"policies"`
+}
+
+// PolicySource - a remote policy pack. The url scheme picks how it is
+// fetched: http(s)://, git+https:// (or any url ending in .git) and oci://
+type PolicySource struct {
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"`
+	Ref  string `yaml:"ref,omitempty"`
+
+	// SHA256 - optional pin of the downloaded artifact (http) or of the
+	// fetched tree's digest (git, oci)
+	SHA256 string `yaml:"sha256,omitempty"`
+}
+
+// LoadConfig - reads the hcunit config file at the given path
+func LoadConfig(path string) (*Config, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config %s failed: %w", path, err)
+	}
+
+	config := new(Config)
+	if err := yaml.Unmarshal(b, config); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	config.path = path
+	for i, policy := range config.Defaults.Policies {
+		config.Defaults.Policies[i] = config.resolve(policy)
+	}
+
+	for name, profile := range config.Profiles {
+		for i, values := range profile.Values {
+			profile.Values[i] = config.resolve(values)
+		}
+
+		for i, policy := range profile.Policies {
+			profile.Policies[i] = config.resolve(policy)
+		}
+		config.Profiles[name] = profile
+	}
+	return config, nil
+}
+
+// DiscoverConfig - the user config merged with the repo config at path, or
+// with .hcunit.yaml when path is empty. Settings of the repo config take
+// precedence, notifications of both are sent. Either config may not exist,
+// unless its path is given
+func DiscoverConfig(path string) (*Config, error) {
+	config := new(Config)
+	if dir := userConfigDir(); dir != "" && fileExists(filepath.Join(dir, userConfigName)) {
+		user, err := LoadConfig(filepath.Join(dir, userConfigName))
+		if err != nil {
+			return nil, err
+		}
+		config = user
+	}
+
+	if path == "" {
+		if !fileExists(defaultConfigPath) {
+			return config.merge(new(Config)), nil
+		}
+		path = defaultConfigPath
+	}
+
+	repo, err := LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return config.merge(repo), nil
+}
+
+// merge - the settings of repo over the ones of s, each setting repo leaves
+// unset taken from s. Notifications of both are kept, and the fetch options
+// are merged option by option. Policy sources are the ones of repo only, as
+// hcunit policy update locks them in the .hcunit.lock of the repo
+func (s *Config) merge(repo *Config) *Config {
+	merged := *repo
+	merged.Notifications = append(append([]Notification{}, s.Notifications...), repo.Notifications...)
+	if merged.Fetch.Retries == 0 {
+		merged.Fetch.Retries = s.Fetch.Retries
+	}
+
+	if merged.Fetch.Timeout == 0 {
+		merged.Fetch.Timeout = s.Fetch.Timeout
+	}
+
+	if merged.Fetch.Backoff == 0 {
+		merged.Fetch.Backoff = s.Fetch.Backoff
+	}
+
+	if len(merged.Input.Processors) == 0 {
+		merged.Input = s.Input
+	}
+
+	if len(merged.Enforcement.Subcharts) == 0 {
+		merged.Enforcement = s.Enforcement
+	}
+
+	if !merged.Conventions.isSet() {
+		merged.Conventions = s.Conventions
+	}
+
+	if len(merged.Adapters) == 0 {
+		merged.Adapters = s.Adapters
+	}
+
+	if merged.Defaults.Output == "" {
+		merged.Defaults.Output = s.Defaults.Output
+	}
+
+	if merged.Defaults.Color == nil {
+		merged.Defaults.Color = s.Defaults.Color
+	}
+
+	if !merged.Defaults.ASCII {
+		merged.Defaults.ASCII = s.Defaults.ASCII
+	}
+
+	if len(merged.Defaults.Policies) == 0 {
+		merged.Defaults.Policies = s.Defaults.Policies
+	}
+
+	if len(merged.Profiles) == 0 {
+		merged.Profiles = s.Profiles
+	}
+	return &merged
+}
+
+// resolve - returns a path relative to the directory holding the config file
+func (s *Config) resolve(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(filepath.Dir(s.path), path)
+}
+
+// userConfigDir - where the user config is read from:
+// $XDG_CONFIG_HOME/hcunit, or the platform's user config directory
+func userConfigDir() string {
+	return xdgDir("XDG_CONFIG_HOME", os.UserConfigDir)
+}
+
+// userCacheDir - where caches are kept between runs: $XDG_CACHE_HOME/hcunit,
+// or the platform's user cache directory
+func userCacheDir() string {
+	return xdgDir("XDG_CACHE_HOME", os.UserCacheDir)
+}
+
+// xdgDir - the hcunit directory under the base directory set in env, or
+// under the fallback when it isn't set. Empty when neither is known
+func xdgDir(env string, fallback func() (string, error)) string {
+	dir := os.Getenv(env)
+	if !filepath.IsAbs(dir) {
+		// relative paths are invalid in the xdg base directory spec
+		var err error
+		if dir, err = fallback(); err != nil {
+			return ""
+		}
+	}
+	return filepath.Join(dir, "hcunit")
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,134 @@
+package commands_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestDiscoverConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hcunit-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	xdgConfig := filepath.Join(dir, "xdg")
+	if err := os.MkdirAll(filepath.Join(xdgConfig, "hcunit"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Setenv("XDG_CONFIG_HOME", os.Getenv("XDG_CONFIG_HOME"))
+	os.Setenv("XDG_CONFIG_HOME", xdgConfig)
+
+	yes, no := true, false
+	for _, tt := range []struct {
+		name          string
+		user          string
+		repo          string
+		expected      commands.Defaults
+		notifications int
+		processors    []string
+		fetch         commands.FetchOptions
+	}{
+		{
+			name:     "without any config nothing is defaulted",
+			expected: commands.Defaults{},
+		},
+		{
+			name: "the user config sets defaults on its own",
+			user: "defaults:\n  output: json\n  color: false\n  policies: [/opt/policies]\n",
+			expected: commands.Defaults{
+				Output:   "json",
+				Color:    &no,
+				Policies: []string{"/opt/policies"},
+			},
+		},
+		{
+			name: "the repo config takes precedence over the user config",
+			user: "defaults:\n  output: json\n  color: false\n  policies: [/opt/policies]\ninput:\n  processors: [split-docs]\n",
+			repo: "defaults:\n  color: true\n  policies: [policy]\ninput:\n  processors: [index-by-kind]\n",
+			expected: commands.Defaults{
+				Output:   "json",
+				Color:    &yes,
+				Policies: []string{filepath.Join(dir, "policy")},
+			},
+			processors: []string{"index-by-kind"},
+		},
+		{
+			name:          "notifications of both configs are sent",
+			user:          "notifications:\n  - type: slack\n    url: https://example.com/user\n",
+			repo:          "notifications:\n  - type: slack\n    url: https://example.com/repo\ninput:\n  processors: []\n",
+			notifications: 2,
+		},
+		{
+			name:  "fetch options the repo config leaves unset are the ones of the user config",
+			user:  "fetch:\n  retries: 5\n  timeout: 10s\n",
+			repo:  "fetch:\n  timeout: 30s\ninput:\n  processors: []\n",
+			fetch: commands.FetchOptions{Retries: 5, Timeout: 30 * time.Second},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			userPath := filepath.Join(xdgConfig, "hcunit", "config.yaml")
+			os.Remove(userPath)
+			if tt.user != "" {
+				if err := ioutil.WriteFile(userPath, []byte(tt.user), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			repoPath := filepath.Join(dir, ".hcunit.yaml")
+			if err := ioutil.WriteFile(repoPath, []byte(tt.repo), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			config, err := commands.DiscoverConfig(repoPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(config.Defaults, tt.expected) {
+				t.Errorf("expected defaults:\n%+v\ngot:\n%+v", tt.expected, config.Defaults)
+			}
+
+			if len(config.Notifications) != tt.notifications {
+				t.Errorf("expected %d notifications, got: %+v", tt.notifications, config.Notifications)
+			}
+
+			if config.Fetch != tt.fetch {
+				t.Errorf("expected fetch options %+v, got: %+v", tt.fetch, config.Fetch)
+			}
+
+			if len(config.Input.Processors) != 0 && !reflect.DeepEqual(config.Input.Processors, tt.processors) {
+				t.Errorf("expected processors %v, got: %v", tt.processors, config.Input.Processors)
+			}
+		})
+	}
+
+	t.Run("policy sources of the user config are ignored", func(t *testing.T) {
+		userPath := filepath.Join(xdgConfig, "hcunit", "config.yaml")
+		if err := ioutil.WriteFile(userPath, []byte("policies:\n  - name: user\n    url: https://example.com/user.rego\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(userPath)
+
+		config, err := commands.DiscoverConfig("")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(config.Policies) != 0 {
+			t.Errorf("expected no policy sources without a repo config, got: %+v", config.Policies)
+		}
+	})
+
+	t.Run("a config given explicitly has to exist", func(t *testing.T) {
+		if _, err := commands.DiscoverConfig(filepath.Join(dir, "missing.yaml")); err == nil {
+			t.Error("expected the missing config to fail")
+		}
+	})
+}
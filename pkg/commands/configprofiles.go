@@ -0,0 +1,187 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mitchellh/colorstring"
+)
+
+// InvalidProfile - --profile names no profile of the config, or
+// --all-profiles is given without any
+var InvalidProfile = errors.New("invalid --profile")
+
+// Profile - a named environment of the config the chart is evaluated for,
+// e.g. dev, staging or prod, bundling what would otherwise be given as
+// flags. Flags given along with a profile take precedence over it, values
+// files given with -c are merged over the ones of the profile
+type Profile struct {
+	// Values - values files, relative to the config declaring them
+	Values []string `yaml:"values"`
+
+	// ReleaseName, ReleaseNamespace, IsUpgrade and APIVersions - the
+	// release options of --release-name, --release-namespace, --is-upgrade
+	// and --api-versions
+	ReleaseName      string   `yaml:"release_name"`
+	ReleaseNamespace string   `yaml:"release_namespace"`
+	IsUpgrade        bool     `yaml:"is_upgrade"`
+	APIVersions      []string `yaml:"api_versions"`
+
+	// KubeVersion - the kubernetes version the chart is rendered against
+	KubeVersion string `yaml:"kube_version"`
+
+	// Policies - the policy paths evaluated when no -p is given, relative
+	// to the config declaring them, instead of the default policies
+	Policies []string `yaml:"policies"`
+
+	// Namespace - the policy namespace queried for rules
+	Namespace string `yaml:"namespace"`
+
+	// Expect - what the objects rendered for the profile are expected to
+	// hold, checked after rendering, see ProfileExpectation
+	Expect []ProfileExpectation `yaml:"expect"`
+}
+
+// profileNames - the profiles of the config the run evaluates, all of them
+// ordered by name with --all-profiles
+func (s *EvalCommand) profileNames() ([]string, error) {
+	profiles := make(map[string]Profile)
+	if s.config != nil {
+		profiles = s.config.Profiles
+	}
+
+	available := make([]string, 0, len(profiles))
+	for name := range profiles {
+		available = append(available, name)
+	}
+	sort.Strings(available)
+
+	if s.AllProfiles {
+		if len(s.Profile) > 0 {
+			return nil, fmt.Errorf("%w: it can't be combined with --all-profiles", InvalidProfile)
+		}
+
+		if len(available) == 0 {
+			return nil, fmt.Errorf("%w: --all-profiles needs profiles in the config", InvalidProfile)
+		}
+		return available, validateProfiles(profiles, available)
+	}
+
+	for _, name := range s.Profile {
+		if _, ok := profiles[name]; !ok {
+			return nil, fmt.Errorf("%w: %q is not one of the profiles of the config (%s)", InvalidProfile, name, strings.Join(available, ", "))
+		}
+	}
+	return s.Profile, validateProfiles(profiles, s.Profile)
+}
+
+// validateProfiles - the expectations of the named profiles have to be
+// checkable
+func validateProfiles(profiles map[string]Profile, names []string) error {
+	for _, name := range names {
+		for _, expectation := range profiles[name].Expect {
+			if err := expectation.validate(); err != nil {
+				return fmt.Errorf("%w: profile %s: %v", InvalidProfile, name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// applyProfile - sets the flags which weren't given to the settings of the
+// named profile, whose expectations the run checks
+func (s *EvalCommand) applyProfile(name string) {
+	profile := s.config.Profiles[name]
+	s.activeProfile = name
+	s.Values = append(append([]string{}, profile.Values...), s.Values...)
+	s.APIVersions = append(append([]string{}, profile.APIVersions...), s.APIVersions...)
+	s.IsUpgrade = s.IsUpgrade || profile.IsUpgrade
+	if s.ReleaseName == "" {
+		s.ReleaseName = profile.ReleaseName
+	}
+
+	if s.ReleaseNamespace == "" {
+		s.ReleaseNamespace = profile.ReleaseNamespace
+	}
+
+	if len(s.KubeVersions) == 0 && s.KubeVersion == "" && profile.KubeVersion != "" {
+		s.KubeVersions = []string{profile.KubeVersion}
+	}
+
+	if len(s.Policy) == 0 {
+		s.Policy = profile.Policies
+	}
+
+	if s.Namespace == "" {
+		s.Namespace = profile.Namespace
+	}
+}
+
+// executeProfiles - executes the run for the profiles it is given, if any.
+// Several profiles are evaluated one after the other, each in a section of
+// its own and starting over from the flags, then it prints which passed
+func (s *EvalCommand) executeProfiles() error {
+	names, err := s.profileNames()
+	if err != nil {
+		return err
+	}
+
+	if len(names) <= 1 {
+		if len(names) == 1 {
+			s.applyProfile(names[0])
+		}
+		s.applyDefaults()
+		return s.execute()
+	}
+
+	base := *s
+	errs := make(map[string]error, len(names))
+	for _, name := range names {
+		results, profile := s.results, s.profile
+		*s = base
+		s.results, s.profile = results, profile
+		s.applyProfile(name)
+		s.applyDefaults()
+
+		colorstring.Fprintln(s.Stdout, fmt.Sprintf("[bold]== profile %s ==", name))
+		errs[name] = s.execute()
+	}
+
+	results, profile := s.results, s.profile
+	*s = base
+	s.results, s.profile = results, profile
+	s.applyDefaults()
+
+	colorstring.Fprintln(s.Stdout, "[bold]== profiles ==")
+	failed := make([]string, 0)
+	for _, name := range names {
+		if errs[name] == nil {
+			colorstring.Fprint(s.Stdout, "[green]PASS: ")
+			fmt.Fprintf(s.Stdout, "profile %s\n", name)
+			continue
+		}
+
+		failed = append(failed, name)
+		colorstring.Fprint(s.Stdout, "[red]FAIL: ")
+		fmt.Fprintf(s.Stdout, "profile %s: %v\n", name, errs[name])
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed with profiles %s: %w", strings.Join(failed, ", "), errs[failed[0]])
+	}
+	return nil
+}
+
+// applyDefaults - evaluates the default policies of the config, and queries
+// the main namespace, when neither the flags nor the profile name any
+func (s *EvalCommand) applyDefaults() {
+	if len(s.Policy) == 0 && s.config != nil {
+		s.Policy = s.config.Defaults.Policies
+	}
+
+	if s.Namespace == "" {
+		s.Namespace = "main"
+	}
+}
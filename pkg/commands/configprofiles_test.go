@@ -0,0 +1,119 @@
+package commands_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestEvalProfiles(t *testing.T) {
+	for _, tt := range []struct {
+		name        string
+		profiles    []string
+		allProfiles bool
+		namespace   string
+		policies    []string
+		values      []string
+		failsWith   error
+		contains    []string
+	}{
+		{
+			name:     "a profile sets the values files and release of the run",
+			profiles: []string{"dev"},
+			contains: []string{`PASS: ` + "\x1b[0m" + `data.main.expect["development releases go to the dev namespace"]`},
+		},
+		{
+			name:     "a profile sets the kubernetes version and policy namespace",
+			profiles: []string{"prod"},
+			contains: []string{
+				"== kubernetes 1.29 ==",
+				`data.prod.expect["production upgrades run highly available on a pinned image"]`,
+			},
+		},
+		{
+			name:      "flags take precedence over the profile",
+			profiles:  []string{"prod"},
+			policies:  []string{"testdata/profiles/policy/dev"},
+			namespace: "main",
+			failsWith: commands.PolicyFailure,
+			contains:  []string{"-c testdata/profiles/values/common.yaml -c testdata/profiles/values/prod.yaml -p testdata/profiles/policy/dev -n main --release-name app --release-namespace prod --is-upgrade --kube-versions 1.29 --run"},
+		},
+		{
+			name:      "values files of the flags are merged over the ones of the profile",
+			profiles:  []string{"prod"},
+			values:    []string{"testdata/profiles/values/common.yaml"},
+			failsWith: commands.PolicyFailure,
+		},
+		{
+			name:        "every profile is evaluated in a section of its own",
+			allProfiles: true,
+			contains: []string{
+				"== profile dev ==",
+				"== profile prod ==",
+				"PASS: \x1b[0mprofile dev\n",
+				"PASS: \x1b[0mprofile prod\n",
+			},
+		},
+		{
+			name:        "the flags apply to every profile",
+			allProfiles: true,
+			policies:    []string{"testdata/profiles/policy/dev"},
+			failsWith:   commands.PolicyFailure,
+			contains: []string{
+				"PASS: \x1b[0mprofile dev\n",
+				"FAIL: \x1b[0mprofile prod: ",
+			},
+		},
+		{
+			name:      "profiles have to be declared",
+			profiles:  []string{"qa"},
+			failsWith: commands.InvalidProfile,
+		},
+		{
+			name:        "profiles can't be given along with all profiles",
+			profiles:    []string{"dev"},
+			allProfiles: true,
+			failsWith:   commands.InvalidProfile,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			stdOut := new(bytes.Buffer)
+			evalCmd := &commands.EvalCommand{
+				Stdout:      stdOut,
+				Template:    "testdata/profiles/templates",
+				Policy:      tt.policies,
+				Config:      "testdata/profiles/hcunit.yaml",
+				Namespace:   tt.namespace,
+				Values:      tt.values,
+				Profile:     tt.profiles,
+				AllProfiles: tt.allProfiles,
+			}
+			err := evalCmd.Execute([]string{})
+			if tt.failsWith == nil && err != nil || !errors.Is(err, tt.failsWith) {
+				t.Fatalf("expected error:\n%v\ngot:\n%v\n%s", tt.failsWith, err, stdOut)
+			}
+
+			for _, s := range tt.contains {
+				if !strings.Contains(stdOut.String(), s) {
+					t.Errorf("expected %q in:\n%s", s, stdOut)
+				}
+			}
+		})
+	}
+}
+
+func TestEvalAllProfilesNeedsProfiles(t *testing.T) {
+	evalCmd := &commands.EvalCommand{
+		Stdout:      new(bytes.Buffer),
+		Template:    "testdata/profiles/templates",
+		Policy:      []string{"testdata/profiles/policy/dev"},
+		Config:      "testdata/profiles/values/common.yaml",
+		AllProfiles: true,
+	}
+	if err := evalCmd.Execute([]string{}); !errors.Is(err, commands.InvalidProfile) {
+		t.Errorf("expected %v, got: %v", commands.InvalidProfile, err)
+	}
+}
@@ -0,0 +1,128 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+var ConventionViolation = errors.New("objects break the label and annotation conventions")
+var InvalidConvention = errors.New("invalid convention in config")
+
+// ConventionsConfig - the labels and annotations every rendered object (of
+// the given kinds, or all) must carry, checked without writing any rego
+type ConventionsConfig struct {
+	Kinds       []string           `yaml:"kinds"`
+	Labels      MetadataConvention `yaml:"labels"`
+	Annotations MetadataConvention `yaml:"annotations"`
+}
+
+// MetadataConvention - the keys an object's labels or annotations must set,
+// and regular expressions the values of keys must match when set
+type MetadataConvention struct {
+	Required []string          `yaml:"required"`
+	Match    map[string]string `yaml:"match"`
+}
+
+// conventionFinding - an object breaking a convention
+type conventionFinding struct {
+	Location string
+	Reason   string
+}
+
+// isSet - true when there is any convention to check
+func (s ConventionsConfig) isSet() bool {
+	return len(s.Labels.Required)+len(s.Labels.Match)+len(s.Annotations.Required)+len(s.Annotations.Match) > 0
+}
+
+// validate - the expressions of the conventions have to compile
+func (s ConventionsConfig) validate() error {
+	for field, convention := range map[string]MetadataConvention{"labels": s.Labels, "annotations": s.Annotations} {
+		if _, err := convention.patterns(); err != nil {
+			return fmt.Errorf("%w: %s: %v", InvalidConvention, field, err)
+		}
+	}
+	return nil
+}
+
+func (s MetadataConvention) patterns() (map[string]*regexp.Regexp, error) {
+	patterns := make(map[string]*regexp.Regexp, len(s.Match))
+	for key, expression := range s.Match {
+		pattern, err := regexp.Compile(expression)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", key, err)
+		}
+		patterns[key] = pattern
+	}
+	return patterns, nil
+}
+
+// check - the ways the labels or annotations of an object break the
+// convention, e.g. missing label team
+func (s MetadataConvention) check(field, singular string, obj map[string]interface{}) []string {
+	values, _ := getField(obj, "metadata", field).(map[string]interface{})
+	reasons := make([]string, 0)
+	for _, key := range s.Required {
+		if _, ok := values[key]; !ok {
+			reasons = append(reasons, fmt.Sprintf("missing %s %s", singular, key))
+		}
+	}
+
+	patterns, _ := s.patterns()
+	keys := make([]string, 0, len(patterns))
+	for key := range patterns {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value, ok := values[key]
+		if ok && !patterns[key].MatchString(fmt.Sprint(value)) {
+			reasons = append(reasons, fmt.Sprintf("%s %s %q does not match %s", singular, key, fmt.Sprint(value), patterns[key]))
+		}
+	}
+	return reasons
+}
+
+// checkConventions - the objects of the rendered templates in the policy
+// input breaking the conventions
+func checkConventions(input map[string]interface{}, conventions ConventionsConfig) []conventionFinding {
+	findings := make([]conventionFinding, 0)
+	for _, template := range templateNames(input) {
+		for _, obj := range renderedObjects(map[string]interface{}{template: input[template]}) {
+			if kinds := conventions.Kinds; len(kinds) > 0 && !containsFold(stringValues(kinds), objectKind(obj)) {
+				continue
+			}
+
+			location := fmt.Sprintf("%s in %s", objectRef(obj), template)
+			reasons := append(conventions.Labels.check("labels", "label", obj), conventions.Annotations.check("annotations", "annotation", obj)...)
+			for _, reason := range reasons {
+				findings = append(findings, conventionFinding{Location: location, Reason: reason})
+			}
+		}
+	}
+	return findings
+}
+
+func stringValues(values []string) []interface{} {
+	out := make([]interface{}, len(values))
+	for i, value := range values {
+		out[i] = value
+	}
+	return out
+}
+
+// conventionResults - the findings as failed results, reported and counted
+// along with our policy results
+func conventionResults(findings []conventionFinding) ([]RuleResult, error) {
+	results := make([]RuleResult, 0, len(findings))
+	for _, finding := range findings {
+		results = append(results, checkResult("convention", finding.Location, finding.Reason))
+	}
+
+	if len(results) > 0 {
+		return results, ConventionViolation
+	}
+	return results, nil
+}
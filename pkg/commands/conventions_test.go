@@ -0,0 +1,148 @@
+package commands_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestConventions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hcunit-conventions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, tt := range []struct {
+		name      string
+		config    string
+		failsWith error
+		expected  []string
+	}{
+		{
+			name:   "without conventions nothing is checked",
+			config: "defaults:\n  output: text\n",
+		},
+		{
+			name:   "objects of the given kinds carrying the required labels pass",
+			config: "conventions:\n  kinds: [deployment]\n  labels:\n    required: [app]\n",
+		},
+		{
+			name:      "objects missing a required label fail",
+			config:    "conventions:\n  labels:\n    required: [app]\n",
+			failsWith: commands.ConventionViolation,
+			expected:  []string{"convention Service/hcunit-name-web in service.yml: missing label app"},
+		},
+		{
+			name:      "labels have to match their expressions when set",
+			config:    "conventions:\n  labels:\n    match:\n      app: ^api$\n      team: ^[a-z]+$\n",
+			failsWith: commands.ConventionViolation,
+			expected:  []string{`convention Deployment/hcunit-name-web in deployment.yml: label app "web" does not match ^api$`},
+		},
+		{
+			name:      "required annotations are checked too",
+			config:    "conventions:\n  kinds: [Deployment]\n  annotations:\n    required: [owner]\n",
+			failsWith: commands.ConventionViolation,
+			expected:  []string{"convention Deployment/hcunit-name-web in deployment.yml: missing annotation owner"},
+		},
+		{
+			name:      "invalid expressions are rejected",
+			config:    "conventions:\n  annotations:\n    match:\n      owner: \"[\"\n",
+			failsWith: commands.InvalidConvention,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(dir, ".hcunit.yaml")
+			if err := ioutil.WriteFile(configPath, []byte(tt.config), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			stdOut := new(bytes.Buffer)
+			evalCmd := &commands.EvalCommand{
+				Stdout:   stdOut,
+				Template: "testdata/workloads",
+				Values:   []string{"testdata/values.yml"},
+				Policy:   []string{"testdata/policy/individuals/network_in_input.rego"},
+				Config:   configPath,
+			}
+			err := evalCmd.Execute([]string{})
+			if tt.failsWith == nil && err != nil || !errors.Is(err, tt.failsWith) {
+				t.Fatalf("expected error:\n%v\ngot:\n%v", tt.failsWith, err)
+			}
+
+			for _, finding := range tt.expected {
+				if !strings.Contains(stdOut.String(), finding) {
+					t.Errorf("expected the finding %q, got:\n%s", finding, stdOut.String())
+				}
+			}
+		})
+	}
+}
+
+func TestConventionFindingsAreReported(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hcunit-conventions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	configPath := filepath.Join(dir, ".hcunit.yaml")
+	if err := ioutil.WriteFile(configPath, []byte("conventions:\n  labels:\n    required: [app]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	finding := "convention Service/hcunit-name-web in service.yml: missing label app"
+	evalCmd := func(output string, stdOut *bytes.Buffer) *commands.EvalCommand {
+		return &commands.EvalCommand{
+			Stdout:   stdOut,
+			Template: "testdata/workloads",
+			Values:   []string{"testdata/values.yml"},
+			Policy:   []string{"testdata/policy/individuals/network_in_input.rego"},
+			Config:   configPath,
+			Output:   output,
+		}
+	}
+
+	t.Run("findings are counted as failed results of the json output", func(t *testing.T) {
+		stdOut := new(bytes.Buffer)
+		if err := evalCmd("json", stdOut).Execute([]string{}); !errors.Is(err, commands.ConventionViolation) {
+			t.Fatalf("expected error:\n%v\ngot:\n%v", commands.ConventionViolation, err)
+		}
+
+		var results commands.Results
+		if err := json.Unmarshal(stdOut.Bytes(), &results); err != nil {
+			t.Fatalf("expected json results, got %v:\n%s", err, stdOut.String())
+		}
+
+		if results.Summary.Failed == 0 {
+			t.Errorf("expected the findings to be counted as failed, got %+v", results.Summary)
+		}
+
+		reported := false
+		for _, result := range results.Results {
+			reported = reported || result.Rule == finding && result.Result == "fail"
+		}
+
+		if !reported {
+			t.Errorf("expected the failed result %q, got:\n%s", finding, stdOut.String())
+		}
+	})
+
+	t.Run("runs with findings don't succeed in the text output", func(t *testing.T) {
+		stdOut := new(bytes.Buffer)
+		if err := evalCmd("", stdOut).Execute([]string{}); !errors.Is(err, commands.ConventionViolation) {
+			t.Fatalf("expected error:\n%v\ngot:\n%v", commands.ConventionViolation, err)
+		}
+
+		if strings.Contains(stdOut.String(), "[SUCCESS]") {
+			t.Errorf("expected no success summary, got:\n%s", stdOut.String())
+		}
+	})
+}
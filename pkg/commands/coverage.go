@@ -0,0 +1,150 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/mitchellh/colorstring"
+)
+
+// conditionalBranch - a value tested by an if (or else if) condition of a
+// template, and whether any scenario made it truthy or falsy
+type conditionalBranch struct {
+	Template string
+	Line     int
+	Value    string
+	True     bool
+	False    bool
+}
+
+// branchCoverage - finds the values tested by if conditions across the
+// templates and records which of their branches the scenarios exercised
+func branchCoverage(templatePath string, scenarios []vetScenario) ([]*conditionalBranch, error) {
+	templates, err := WalkTemplatePath(templatePath)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(templates))
+	for name := range templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	branches := make([]*conditionalBranch, 0)
+	for _, name := range names {
+		content, err := ioutil.ReadAll(templates[name])
+		templates[name].Close()
+		if err != nil {
+			return nil, fmt.Errorf("reading template %s failed: %w", name, err)
+		}
+
+		if strings.HasPrefix(path.Base(name), ".") {
+			continue
+		}
+
+		for _, branch := range conditionalValues(relativeTemplateName(templatePath, name), string(content)) {
+			for _, scenario := range scenarios {
+				if truthy(getField(scenario.Values, strings.Split(branch.Value, ".")...)) {
+					branch.True = true
+				} else {
+					branch.False = true
+				}
+			}
+			branches = append(branches, branch)
+		}
+	}
+	return branches, nil
+}
+
+// conditionalValues - every .Values reference in the if and else if
+// conditions of a template
+func conditionalValues(template, content string) []*conditionalBranch {
+	branches := make([]*conditionalBranch, 0)
+	seen := make(map[string]bool)
+	for _, loc := range templateAction.FindAllStringSubmatchIndex(content, -1) {
+		tokens := actionTokens(content[loc[2]:loc[3]])
+		if len(tokens) > 1 && tokens[0] == "else" {
+			tokens = tokens[1:]
+		}
+
+		if len(tokens) == 0 || tokens[0] != "if" {
+			continue
+		}
+
+		line := strings.Count(content[:loc[0]], "\n") + 1
+		for _, token := range tokens[1:] {
+			match := valuesRef.FindStringSubmatch(token)
+			if match == nil {
+				continue
+			}
+
+			value := strings.TrimPrefix(match[1], ".")
+			key := fmt.Sprintf("%d:%s", line, value)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			branches = append(branches, &conditionalBranch{Template: template, Line: line, Value: value})
+		}
+	}
+	return branches
+}
+
+// truthy - go template truthiness: false, 0, nil and empty strings and
+// collections are false
+func truthy(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case int:
+		return v != 0
+	case int64:
+		return v != 0
+	case uint64:
+		return v != 0
+	case float64:
+		return v != 0
+	case string:
+		return v != ""
+	case map[string]interface{}:
+		return len(v) > 0
+	case []interface{}:
+		return len(v) > 0
+	}
+	return true
+}
+
+// reportBranchCoverage - warns about every branch no scenario exercised,
+// with the scenario which would, and prints the overall coverage
+func reportBranchCoverage(writer io.Writer, branches []*conditionalBranch) {
+	covered := 0
+	for _, branch := range branches {
+		for _, exercised := range []struct {
+			ok    bool
+			state string
+			hint  string
+		}{
+			{branch.True, "true", "add a scenario setting " + branch.Value},
+			{branch.False, "false", "add a scenario leaving " + branch.Value + " unset or false"},
+		} {
+			if exercised.ok {
+				covered++
+				continue
+			}
+			colorstring.Fprint(writer, "[yellow]WARN: ")
+			fmt.Fprintf(writer, "branch coverage %s:%d .Values.%s is never %s, %s\n", branch.Template, branch.Line, branch.Value, exercised.state, exercised.hint)
+		}
+	}
+
+	if total := len(branches) * 2; total > 0 {
+		colorstring.Fprint(writer, "[blue]INFO: ")
+		fmt.Fprintf(writer, "branch coverage %d/%d branches exercised (%.0f%%)\n", covered, total, float64(covered)*100/float64(total))
+	}
+}
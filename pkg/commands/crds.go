@@ -0,0 +1,441 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const crdsHashName = "crds"
+
+var CRDViolation = errors.New("custom resources don't match the schemas of their CRDs")
+var InvalidCRD = errors.New("invalid --crd")
+
+// customResourceDefinition - a CRD known to the run, from the rendered
+// templates, the crds/ directory of the chart or --crd, keyed in
+// input.crds by group/Kind. Versions holds the openAPIV3Schema of each
+// version, nil for versions without a schema
+type customResourceDefinition struct {
+	Name     string                 `json:"name"`
+	Group    string                 `json:"group"`
+	Kind     string                 `json:"kind"`
+	Plural   string                 `json:"plural"`
+	Scope    string                 `json:"scope"`
+	Source   string                 `json:"source"`
+	Versions map[string]interface{} `json:"versions"`
+}
+
+// schemaFinding - a custom resource not matching the schema of its CRD
+type schemaFinding struct {
+	Location string
+	Reason   string
+}
+
+// loadCRDs - the CRDs of the chart's crds/ directory, the way helm 3
+// installs them before the templates, and of the --crd files and
+// directories, by the file they were read from
+func (s *EvalCommand) loadCRDs() (map[string][]map[string]interface{}, error) {
+	crds := make(map[string][]map[string]interface{})
+	if chartDir, err := findChartRoot(s.Template); err == nil && s.manifestsPath() == "" {
+		if _, err := os.Stat(filepath.Join(chartDir, "crds")); err == nil {
+			if err := readCRDs(crds, filepath.Join(chartDir, "crds"), "crds"); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, path := range s.CRDs {
+		if err := readCRDs(crds, path, path); err != nil {
+			return nil, err
+		}
+	}
+	return crds, nil
+}
+
+// readCRDs - adds the objects of the manifests under path to crds, by
+// their file name under source
+func readCRDs(crds map[string][]map[string]interface{}, path, source string) error {
+	manifests, err := readManifests(path, TemplateFilter{})
+	if err != nil {
+		return fmt.Errorf("%w: %v", InvalidCRD, err)
+	}
+
+	input, err := UnmarshalYamlMap(manifests)
+	if err != nil {
+		return fmt.Errorf("%w: %v", InvalidCRD, err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("%w: %v", InvalidCRD, err)
+	}
+
+	for _, name := range templateNames(input) {
+		key := filepath.ToSlash(source)
+		if info.IsDir() {
+			key = filepath.ToSlash(filepath.Join(source, name))
+		}
+		crds[key] = append(crds[key], renderedObjects(map[string]interface{}{name: input[name]})...)
+	}
+	return nil
+}
+
+// buildCRDModel - the CRDs among the rendered templates of the input and
+// the loaded ones, keyed by group/Kind. Later definitions of the same
+// resource replace earlier ones, so --crd can stand in for the chart's
+func buildCRDModel(input map[string]interface{}, loaded map[string][]map[string]interface{}) map[string]customResourceDefinition {
+	sources := make(map[string][]map[string]interface{})
+	names := make([]string, 0)
+	for _, template := range templateNames(input) {
+		sources[template] = renderedObjects(map[string]interface{}{template: input[template]})
+		names = append(names, template)
+	}
+
+	loadedNames := make([]string, 0, len(loaded))
+	for name := range loaded {
+		loadedNames = append(loadedNames, name)
+	}
+	sort.Strings(loadedNames)
+	for _, name := range loadedNames {
+		sources[name] = loaded[name]
+		names = append(names, name)
+	}
+
+	crds := make(map[string]customResourceDefinition)
+	for _, name := range names {
+		for _, obj := range sources[name] {
+			if objectKind(obj) != "CustomResourceDefinition" {
+				continue
+			}
+
+			crd := customResourceDefinition{
+				Name:     objectName(obj),
+				Group:    getString(obj, "spec", "group"),
+				Kind:     getString(obj, "spec", "names", "kind"),
+				Plural:   getString(obj, "spec", "names", "plural"),
+				Scope:    getString(obj, "spec", "scope"),
+				Source:   name,
+				Versions: make(map[string]interface{}),
+			}
+
+			// apiextensions.k8s.io/v1beta1 declares one schema for every version
+			shared := getMap(obj, "spec", "validation", "openAPIV3Schema")
+			if version := getString(obj, "spec", "version"); version != "" {
+				crd.Versions[version] = schemaOrNil(shared)
+			}
+
+			for _, v := range getSlice(obj, "spec", "versions") {
+				version, _ := v.(map[string]interface{})
+				schema := getMap(version, "schema", "openAPIV3Schema")
+				if schema == nil {
+					schema = shared
+				}
+				crd.Versions[getString(version, "name")] = schemaOrNil(schema)
+			}
+			crds[crd.Group+"/"+crd.Kind] = crd
+		}
+	}
+	return crds
+}
+
+// schemaOrNil - keeps a missing schema an untyped nil in the input
+func schemaOrNil(schema map[string]interface{}) interface{} {
+	if schema == nil {
+		return nil
+	}
+	return schema
+}
+
+// checkCustomResources - the custom resources of the rendered templates in
+// the policy input not matching the schema of their CRD's version
+func checkCustomResources(input map[string]interface{}, crds map[string]customResourceDefinition) []schemaFinding {
+	findings := make([]schemaFinding, 0)
+	for _, template := range templateNames(input) {
+		for _, obj := range renderedObjects(map[string]interface{}{template: input[template]}) {
+			group, version := splitAPIVersion(getString(obj, "apiVersion"))
+			crd, ok := crds[group+"/"+objectKind(obj)]
+			if !ok {
+				continue
+			}
+
+			location := fmt.Sprintf("%s in %s", objectRef(obj), template)
+			schema, served := crd.Versions[version]
+			if !served {
+				findings = append(findings, schemaFinding{Location: location, Reason: fmt.Sprintf("version %s is not served by %s", version, crd.Name)})
+				continue
+			}
+
+			if schema, ok := schema.(map[string]interface{}); ok {
+				for _, reason := range validateSchema(obj, schema, "", true) {
+					findings = append(findings, schemaFinding{Location: location, Reason: reason})
+				}
+			}
+		}
+	}
+	return findings
+}
+
+func splitAPIVersion(apiVersion string) (string, string) {
+	if i := strings.LastIndex(apiVersion, "/"); i >= 0 {
+		return apiVersion[:i], apiVersion[i+1:]
+	}
+	return "", apiVersion
+}
+
+// validateSchema - the ways a value breaks a structural openAPIV3Schema:
+// types, required and unknown properties, enums, bounds, lengths and
+// patterns, items and the allOf/anyOf/oneOf combinations. The apiVersion,
+// kind and metadata of the root object are validated by kubernetes itself
+func validateSchema(value interface{}, schema map[string]interface{}, path string, root bool) []string {
+	if value == nil && schema["nullable"] == true {
+		return nil
+	}
+
+	reasons := make([]string, 0)
+	fail := func(format string, args ...interface{}) {
+		reasons = append(reasons, fmt.Sprintf("%s: %s", fieldPath(path), fmt.Sprintf(format, args...)))
+	}
+
+	if schema["x-kubernetes-int-or-string"] == true {
+		if _, isString := value.(string); !isString && !isInteger(value) {
+			fail("expected an integer or string, got %s", schemaType(value))
+		}
+	} else if expected, ok := schema["type"].(string); ok && !hasSchemaType(value, expected) {
+		fail("expected %s, got %s", expected, schemaType(value))
+		return reasons
+	}
+
+	if enum := getSliceValue(schema["enum"]); len(enum) > 0 && !containsValue(enum, value) {
+		fail("%s is not one of %s", jsonString(value), jsonString(enum))
+	}
+
+	switch v := value.(type) {
+	case string:
+		if min, ok := schemaNumber(schema["minLength"]); ok && float64(len([]rune(v))) < min {
+			fail("shorter than %v characters", min)
+		}
+		if max, ok := schemaNumber(schema["maxLength"]); ok && float64(len([]rune(v))) > max {
+			fail("longer than %v characters", max)
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(v) {
+				fail("%q does not match %s", v, pattern)
+			}
+		}
+	case []interface{}:
+		if min, ok := schemaNumber(schema["minItems"]); ok && float64(len(v)) < min {
+			fail("fewer than %v items", min)
+		}
+		if max, ok := schemaNumber(schema["maxItems"]); ok && float64(len(v)) > max {
+			fail("more than %v items", max)
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				reasons = append(reasons, validateSchema(item, items, fmt.Sprintf("%s[%d]", path, i), false)...)
+			}
+		}
+	case map[string]interface{}:
+		reasons = append(reasons, validateProperties(v, schema, path, root)...)
+	default:
+		if n, ok := schemaNumber(value); ok {
+			if min, ok := schemaNumber(schema["minimum"]); ok && (n < min || n == min && schema["exclusiveMinimum"] == true) {
+				fail("%v is less than the minimum %v", n, min)
+			}
+			if max, ok := schemaNumber(schema["maximum"]); ok && (n > max || n == max && schema["exclusiveMaximum"] == true) {
+				fail("%v is more than the maximum %v", n, max)
+			}
+		}
+	}
+
+	for _, s := range getSliceValue(schema["allOf"]) {
+		if s, ok := s.(map[string]interface{}); ok {
+			reasons = append(reasons, validateSchema(value, s, path, root)...)
+		}
+	}
+
+	for combination, matches := range map[string]func(int, int) bool{
+		"anyOf": func(valid, total int) bool { return valid > 0 },
+		"oneOf": func(valid, total int) bool { return valid == 1 },
+	} {
+		alternatives := getSliceValue(schema[combination])
+		if len(alternatives) == 0 {
+			continue
+		}
+
+		valid := 0
+		for _, s := range alternatives {
+			if s, ok := s.(map[string]interface{}); ok && len(validateSchema(value, s, path, root)) == 0 {
+				valid++
+			}
+		}
+
+		if !matches(valid, len(alternatives)) {
+			fail("matches %d of the %d schemas of %s", valid, len(alternatives), combination)
+		}
+	}
+	return reasons
+}
+
+// validateProperties - the required, declared and unknown properties of an
+// object. Unknown properties are allowed by additionalProperties and
+// x-kubernetes-preserve-unknown-fields, or when no properties are declared
+func validateProperties(obj map[string]interface{}, schema map[string]interface{}, path string, root bool) []string {
+	reasons := make([]string, 0)
+	for _, required := range getSliceValue(schema["required"]) {
+		if name, ok := required.(string); ok {
+			if _, set := obj[name]; !set {
+				reasons = append(reasons, fmt.Sprintf("%s: required", fieldPath(joinFieldPath(path, name))))
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	additional := schema["additionalProperties"]
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if root && (key == "apiVersion" || key == "kind" || key == "metadata") {
+			continue
+		}
+
+		if property, ok := properties[key].(map[string]interface{}); ok {
+			reasons = append(reasons, validateSchema(obj[key], property, joinFieldPath(path, key), false)...)
+			continue
+		}
+
+		switch additional := additional.(type) {
+		case map[string]interface{}:
+			reasons = append(reasons, validateSchema(obj[key], additional, joinFieldPath(path, key), false)...)
+		case bool:
+			if !additional {
+				reasons = append(reasons, fmt.Sprintf("%s: unknown field", fieldPath(joinFieldPath(path, key))))
+			}
+		default:
+			if properties != nil && schema["x-kubernetes-preserve-unknown-fields"] != true {
+				reasons = append(reasons, fmt.Sprintf("%s: unknown field", fieldPath(joinFieldPath(path, key))))
+			}
+		}
+	}
+	return reasons
+}
+
+func joinFieldPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func fieldPath(path string) string {
+	if path == "" {
+		return "<root>"
+	}
+	return path
+}
+
+// hasSchemaType - whether a value decoded from yaml or json is of an
+// openAPI type
+func hasSchemaType(value interface{}, expected string) bool {
+	switch expected {
+	case "integer":
+		return isInteger(value)
+	case "number":
+		_, ok := schemaNumber(value)
+		return ok
+	}
+	return schemaType(value) == expected
+}
+
+// schemaType - the openAPI type of a value decoded from yaml or json
+func schemaType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+
+	if isInteger(value) {
+		return "integer"
+	}
+	return "number"
+}
+
+func isInteger(value interface{}) bool {
+	n, ok := schemaNumber(value)
+	return ok && n == math.Trunc(n)
+}
+
+func schemaNumber(value interface{}) (float64, bool) {
+	switch n := value.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func getSliceValue(value interface{}) []interface{} {
+	s, _ := value.([]interface{})
+	return s
+}
+
+// containsValue - whether values holds value, comparing their json
+func containsValue(values []interface{}, value interface{}) bool {
+	for _, v := range values {
+		if jsonString(v) == jsonString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func jsonString(value interface{}) string {
+	if n, ok := schemaNumber(value); ok {
+		value = n
+	}
+
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(b)
+}
+
+// schemaResults - the findings as failed results, reported and counted
+// along with our policy results
+func schemaResults(findings []schemaFinding) ([]RuleResult, error) {
+	results := make([]RuleResult, 0, len(findings))
+	for _, finding := range findings {
+		results = append(results, checkResult("crd schema", finding.Location, finding.Reason))
+	}
+
+	if len(results) > 0 {
+		return results, CRDViolation
+	}
+	return results, nil
+}
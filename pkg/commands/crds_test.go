@@ -0,0 +1,94 @@
+package commands_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestEvalCRDs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hcunit-crds")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, tt := range []struct {
+		name      string
+		values    string
+		crds      []string
+		failsWith error
+		expected  []string
+	}{
+		{
+			name: "custom resources matching their schemas pass",
+			crds: []string{"testdata/crds/certificates.yaml"},
+		},
+		{
+			name:      "values breaking the schema of a crd rendered by the chart fail",
+			values:    "retention: 0\n",
+			failsWith: commands.CRDViolation,
+			expected:  []string{"crd schema Backup/hcunit-name-backup in backup.yaml: spec.retention: 0 is less than the minimum 1"},
+		},
+		{
+			name:      "crds of the crds directory are validated against",
+			values:    "alertFor: ten minutes\n",
+			failsWith: commands.CRDViolation,
+			expected:  []string{`crd schema PrometheusRule/hcunit-name-rules in rules.yaml: spec.groups[0].rules[0].for: "ten minutes" does not match ^[0-9]+(ms|s|m|h)$`},
+		},
+		{
+			name:      "versions the crd doesn't serve fail",
+			values:    "rulesVersion: v2\n",
+			failsWith: commands.CRDViolation,
+			expected:  []string{"crd schema PrometheusRule/hcunit-name-rules in rules.yaml: version v2 is not served by prometheusrules.monitoring.coreos.com"},
+		},
+		{
+			name:   "custom resources without a known crd aren't validated",
+			values: "issuerKind: Vault\n",
+		},
+		{
+			name:      "supplied crds are validated against",
+			values:    "issuerKind: Vault\n",
+			crds:      []string{"testdata/crds"},
+			failsWith: commands.CRDViolation,
+			expected:  []string{`crd schema Certificate/hcunit-name-tls in certificate.yaml: spec.issuerRef.kind: "Vault" is not one of ["Issuer","ClusterIssuer"]`},
+		},
+		{
+			name:      "missing supplied crds are rejected",
+			crds:      []string{"testdata/crds/missing.yaml"},
+			failsWith: commands.InvalidCRD,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			valuesPath := filepath.Join(dir, "values.yaml")
+			if err := ioutil.WriteFile(valuesPath, []byte(tt.values), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			stdOut := new(bytes.Buffer)
+			evalCmd := &commands.EvalCommand{
+				Stdout:   stdOut,
+				Template: "testdata/crdchart",
+				Values:   []string{"testdata/crdchart/values.yaml", valuesPath},
+				Policy:   []string{"testdata/policy/passing/passing.rego"},
+				CRDs:     tt.crds,
+			}
+			err := evalCmd.Execute([]string{})
+			if tt.failsWith == nil && err != nil || !errors.Is(err, tt.failsWith) {
+				t.Fatalf("expected error:\n%v\ngot:\n%v", tt.failsWith, err)
+			}
+
+			for _, finding := range tt.expected {
+				if !strings.Contains(stdOut.String(), finding) {
+					t.Errorf("expected output to contain:\n%s\ngot:\n%s", finding, stdOut.String())
+				}
+			}
+		})
+	}
+}
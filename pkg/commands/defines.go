@@ -0,0 +1,102 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const definesHashName = "defines"
+const defineTemplatePrefix = "hcunit-define/"
+const goldenExt = ".golden"
+
+var GoldenMismatch = errors.New("rendered output does not match the golden file")
+
+// defineTemplates - one template per named template, rendering it the way a
+// chart would with `include "name" .`
+func defineTemplates(defines []string) map[string]io.ReadCloser {
+	templates := make(map[string]io.ReadCloser)
+	for _, define := range defines {
+		content := fmt.Sprintf("{{ include %q . }}", define)
+		templates[defineTemplatePrefix+define] = ioutil.NopCloser(strings.NewReader(content))
+	}
+	return templates
+}
+
+// onlyPartials - drops every template which isn't a partial, so unit testing
+// a named template doesn't depend on the rest of the chart rendering
+func onlyPartials(templates map[string]io.ReadCloser) map[string]io.ReadCloser {
+	partials := make(map[string]io.ReadCloser)
+	for name, reader := range templates {
+		if strings.HasPrefix(path.Base(filepath.ToSlash(name)), "_") {
+			partials[name] = reader
+		} else {
+			reader.Close()
+		}
+	}
+	return partials
+}
+
+// splitDefines - separates the output of the rendered named templates, keyed
+// by their define name, from the rest of the rendered templates
+func splitDefines(rendered map[string]string) (map[string]string, map[string]string) {
+	templates := make(map[string]string)
+	defines := make(map[string]string)
+	for name, content := range rendered {
+		if i := strings.Index(name, defineTemplatePrefix); i >= 0 {
+			defines[name[i+len(defineTemplatePrefix):]] = content
+		} else {
+			templates[name] = content
+		}
+	}
+	return templates, defines
+}
+
+// checkGolden - compares each output with <dir>/<name>.golden, or rewrites
+// the golden files when update is set
+func checkGolden(dir string, outputs map[string]string, update bool) error {
+	names := make([]string, 0, len(outputs))
+	for name := range outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if update {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("creating golden dir failed: %w", err)
+		}
+	}
+
+	mismatches := new(bytes.Buffer)
+	for _, name := range names {
+		goldenPath := filepath.Join(dir, name+goldenExt)
+		if update {
+			if err := ioutil.WriteFile(goldenPath, []byte(outputs[name]), 0644); err != nil {
+				return fmt.Errorf("writing golden file failed: %w", err)
+			}
+			continue
+		}
+
+		expected, err := ioutil.ReadFile(goldenPath)
+		if err != nil {
+			fmt.Fprintf(mismatches, "\n%s: missing golden file, run with --update-golden to create it", goldenPath)
+			continue
+		}
+
+		if string(expected) != outputs[name] {
+			fmt.Fprintf(mismatches, "\n%s:\n%s", goldenPath, lineDiff(string(expected), outputs[name]))
+		}
+	}
+
+	if mismatches.Len() > 0 {
+		return fmt.Errorf("%w%s", GoldenMismatch, mismatches.String())
+	}
+	return nil
+}
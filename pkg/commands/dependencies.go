@@ -0,0 +1,138 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	yaml "gopkg.in/yaml.v3"
+	"k8s.io/helm/pkg/chartutil"
+)
+
+var DependencyLockMissing = errors.New("chart declares dependencies but has no Chart.lock or requirements.lock")
+var DependencyMismatch = errors.New("chart dependencies do not match the lock file")
+
+type chartDependencies struct {
+	Dependencies []chartDependency `yaml:"dependencies"`
+}
+
+type chartDependency struct {
+	Name       string `yaml:"name"`
+	Version    string `yaml:"version"`
+	Repository string `yaml:"repository"`
+}
+
+// updateDependencies - rebuilds the charts/ directory from the lock file
+// exactly like `helm dependency build` would
+func updateDependencies(chartDir string) error {
+	return defaultFetchOptions.run("helm", "dependency", "build", chartDir)
+}
+
+// verifyDependencies - ensures the dependencies declared by a chart are
+// locked, and that the subcharts vendored in charts/ are the locked versions
+func verifyDependencies(chartDir string) error {
+	declared, err := readDependencies(chartDir, "requirements.yaml", chartutil.ChartfileName)
+	if err != nil {
+		return err
+	}
+
+	if declared == nil || len(declared.Dependencies) == 0 {
+		return nil
+	}
+
+	locked, err := readDependencies(chartDir, "Chart.lock", "requirements.lock")
+	if err != nil {
+		return err
+	}
+
+	if locked == nil {
+		return DependencyLockMissing
+	}
+
+	chart, err := chartutil.Load(chartDir)
+	if err != nil {
+		return fmt.Errorf("loading chart %s failed: %w", chartDir, err)
+	}
+
+	vendored := map[string]string{}
+	for _, sub := range chart.GetDependencies() {
+		vendored[sub.GetMetadata().GetName()] = sub.GetMetadata().GetVersion()
+	}
+
+	lockedVersions := map[string]string{}
+	for _, dep := range locked.Dependencies {
+		lockedVersions[dep.Name] = dep.Version
+	}
+
+	problems := make([]string, 0)
+	for _, dep := range declared.Dependencies {
+		version, ok := lockedVersions[dep.Name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s is not in the lock file", dep.Name))
+			continue
+		}
+
+		if !versionSatisfies(version, dep.Version) {
+			problems = append(problems, fmt.Sprintf("%s is locked at %s which does not satisfy %s", dep.Name, version, dep.Version))
+		}
+
+		installed, ok := vendored[dep.Name]
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("%s %s is missing from charts/", dep.Name, version))
+		case installed != version:
+			problems = append(problems, fmt.Sprintf("%s is %s in charts/ but locked at %s", dep.Name, installed, version))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", DependencyMismatch, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// readDependencies - reads the dependencies list from the first of the
+// given chart files which exists, or nil when none do
+func readDependencies(chartDir string, names ...string) (*chartDependencies, error) {
+	for _, name := range names {
+		path := filepath.Join(chartDir, name)
+		if !fileExists(path) {
+			continue
+		}
+
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		deps := new(chartDependencies)
+		if err := yaml.Unmarshal(b, deps); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+
+		if len(deps.Dependencies) > 0 {
+			return deps, nil
+		}
+	}
+	return nil, nil
+}
+
+func versionSatisfies(version, constraint string) bool {
+	if constraint == "" {
+		return true
+	}
+
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return version == constraint
+	}
+
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return false
+	}
+	return c.Check(v)
+}
@@ -0,0 +1,118 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/mitchellh/colorstring"
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+)
+
+// removeAfterLayout - the date format of remove_after metadata
+const removeAfterLayout = "2006-01-02"
+
+// ruleDeprecation - whether a rule is deprecated and the date after which
+// it is to be removed, if any. A remove_after date implies deprecated
+func ruleDeprecation(metadata map[string]interface{}) (deprecated bool, removeAfter time.Time) {
+	if date, err := time.Parse(removeAfterLayout, metadataString(metadata, metadataRemoveAfter)); err == nil {
+		return true, date
+	}
+	return metadataBool(metadata, metadataDeprecated), time.Time{}
+}
+
+// sunsetPassed - true when a rule is past its remove_after date
+func sunsetPassed(metadata map[string]interface{}, now time.Time) bool {
+	_, removeAfter := ruleDeprecation(metadata)
+	return !removeAfter.IsZero() && now.After(removeAfter.AddDate(0, 0, 1))
+}
+
+// deprecationNotice - a note on a deprecated rule for its consumers (and
+// maintainers once its sunset passed), or empty when it isn't deprecated
+func deprecationNotice(metadata map[string]interface{}, now time.Time, messages messageCatalog) string {
+	deprecated, removeAfter := ruleDeprecation(metadata)
+	switch {
+	case !deprecated:
+		return ""
+	case removeAfter.IsZero():
+		return messages.text("deprecated")
+	case sunsetPassed(metadata, now):
+		return messages.text("deprecated_since", removeAfter.Format(removeAfterLayout))
+	}
+	return messages.text("deprecated_until", removeAfter.Format(removeAfterLayout))
+}
+
+// validateRemoveAfter - fails metadata whose remove_after is not a date
+func validateRemoveAfter(rule string, metadata map[string]interface{}) error {
+	value, ok := metadata[metadataRemoveAfter]
+	if !ok {
+		return nil
+	}
+
+	date, _ := value.(string)
+	if _, err := time.Parse(removeAfterLayout, date); err != nil {
+		return fmt.Errorf("%s[%q].%s must be a date like 2006-01-02, got %v", metadataRuleName, rule, metadataRemoveAfter, value)
+	}
+	return nil
+}
+
+// PolicyDeprecatedCommand - lists the deprecated rules of policies, for
+// maintainers to see what is due for removal
+type PolicyDeprecatedCommand struct {
+	Writer    io.Writer
+	Policy    []string `short:"p" long:"policy" description:"path to rego policies (a .rego file or a directory) to list the deprecated rules of (repeatable)"`
+	Namespace string   `short:"n" long:"namespace" description:"policy namespace to query for rules"`
+}
+
+func (s *PolicyDeprecatedCommand) Execute(args []string) error {
+	s.setDefaults()
+	if err := validatePolicyPaths(s.Policy); err != nil {
+		return err
+	}
+
+	loaded, err := loadPolicies(s.Policy, "")
+	if err != nil {
+		return &PolicyCompileError{Policies: s.Policy, Err: err}
+	}
+
+	options := []func(*rego.Rego){loaded.option()}
+	metadata, err := loadMetadata(context.Background(), s.Policy, s.Namespace, ast.NewObject(), options)
+	if err != nil {
+		return err
+	}
+
+	rules := make([]string, 0)
+	for querySuffix := range ruleQueries(loaded.modules) {
+		if deprecated, _ := ruleDeprecation(metadata[ruleKey(querySuffix)]); deprecated {
+			rules = append(rules, querySuffix)
+		}
+	}
+	sort.Strings(rules)
+
+	now := time.Now()
+	for _, querySuffix := range rules {
+		ruleMetadata := metadata[ruleKey(querySuffix)]
+		if sunsetPassed(ruleMetadata, now) {
+			colorstring.Fprint(s.Writer, "[red]SUNSET: ")
+		} else {
+			colorstring.Fprint(s.Writer, "[yellow]DEPRECATED: ")
+		}
+		fmt.Fprintf(s.Writer, "data.%s.%s %s\n", s.Namespace, querySuffix, deprecationNotice(ruleMetadata, now, defaultMessages))
+	}
+	fmt.Fprintf(s.Writer, "%d deprecated rules\n", len(rules))
+	return nil
+}
+
+func (s *PolicyDeprecatedCommand) setDefaults() {
+	if s.Writer == nil {
+		s.Writer = os.Stdout
+	}
+
+	if s.Namespace == "" {
+		s.Namespace = "main"
+	}
+}
@@ -0,0 +1,94 @@
+package commands_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestEvalDeprecatedRules(t *testing.T) {
+	stdOut := new(bytes.Buffer)
+	evalCmd := &commands.EvalCommand{
+		Stdout:   stdOut,
+		Template: "testdata/templates",
+		Values:   []string{"testdata/values.yml"},
+		Policy:   []string{"testdata/policy/individuals/deprecated.rego"},
+	}
+	if err := evalCmd.Execute([]string{}); err != nil {
+		t.Fatalf("expected deprecated rules not to fail the run, got: %v", err)
+	}
+
+	for _, expected := range []string{
+		"WARN: \x1b[0mdata.main.warn[\"ingress without tls\"]\n      deprecated, to be removed after 2999-12-31",
+		"PASS: \x1b[0mdata.main.expect[\"something is an ingress\"]\n      deprecated, due for removal since 2020-01-01",
+		"PASS: \x1b[0mdata.main.expect[\"something has a kind\"]\n",
+		"[SUNSET] 1 evaluated rules are past their remove_after date",
+	} {
+		if !strings.Contains(stdOut.String(), expected) {
+			t.Errorf("expected the output to contain %q, got:\n%s", expected, stdOut.String())
+		}
+	}
+}
+
+func TestPolicyDeprecatedCommand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hcunit-deprecated")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	invalid := filepath.Join(dir, "invalid.rego")
+	policy := "package main\n\nmetadata := {\"rule\": {\"remove_after\": \"next year\"}}\n\nexpect [\"rule\"] { true }\n"
+	if err := ioutil.WriteFile(invalid, []byte(policy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name     string
+		policy   string
+		expected []string
+		fails    bool
+	}{
+		{
+			name:   "deprecated rules are listed with their sunset",
+			policy: "testdata/policy/individuals/deprecated.rego",
+			expected: []string{
+				"SUNSET: \x1b[0mdata.main.expect[\"something is an ingress\"] deprecated, due for removal since 2020-01-01\n",
+				"DEPRECATED: \x1b[0mdata.main.warn[\"ingress without tls\"] deprecated, to be removed after 2999-12-31\n",
+				"2 deprecated rules\n",
+			},
+		},
+		{
+			name:     "policies without deprecated rules list none",
+			policy:   "testdata/policy/passing",
+			expected: []string{"0 deprecated rules\n"},
+		},
+		{
+			name:   "remove_after must be a date",
+			policy: invalid,
+			fails:  true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			stdOut := new(bytes.Buffer)
+			cmd := &commands.PolicyDeprecatedCommand{Writer: stdOut, Policy: []string{tt.policy}}
+			err := cmd.Execute([]string{})
+			var evalErr *commands.EvaluationError
+			if tt.fails != errors.As(err, &evalErr) {
+				t.Fatalf("expected an evaluation error: %v, got: %v", tt.fails, err)
+			}
+
+			for _, expected := range tt.expected {
+				if !strings.Contains(stdOut.String(), expected) {
+					t.Errorf("expected the output to contain %q, got:\n%s", expected, stdOut.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// lsp diagnostic severities
+const (
+	diagnosticError   = 1
+	diagnosticWarning = 2
+)
+
+// templateErrorPosition - the position of a go template parse or execution
+// error, in a template named after the chart we render it in
+var templateErrorPosition = regexp.MustCompile(`template: [^/\s]+/(\S+?):(\d+)(?::(\d+))?: (.*)`)
+
+// regoErrorPosition - the position of a rego parse, compile or type error
+var regoErrorPosition = regexp.MustCompile(`(\S+\.rego):(\d+): (rego_\w+: .*)`)
+
+// yamlErrorLine - the line of a yaml parse error
+var yamlErrorLine = regexp.MustCompile(`yaml: line (\d+): `)
+
+type DiagnosticsCommand struct {
+	EvalCommand
+	Format string `long:"format" default:"lsp-json" choice:"lsp-json" description:"output format of the diagnostics"`
+}
+
+// Diagnostics - the diagnostics of a single file, as the params of an lsp
+// textDocument/publishDiagnostics notification
+type Diagnostics struct {
+	URI         string       `json:"uri"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+type Diagnostic struct {
+	Range    DiagnosticRange `json:"range"`
+	Severity int             `json:"severity"`
+	Source   string          `json:"source"`
+	Message  string          `json:"message"`
+}
+
+type DiagnosticRange struct {
+	Start DiagnosticPosition `json:"start"`
+	End   DiagnosticPosition `json:"end"`
+}
+
+// DiagnosticPosition - a zero based line and character offset
+type DiagnosticPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// Execute - evaluates the chart like eval, but instead of reporting results
+// prints the policy failures, render errors and values errors as diagnostics
+// on the rego, template and values files they come from. Failures are part
+// of the output, so only usage errors fail the command
+func (s *DiagnosticsCommand) Execute(args []string) error {
+	out := s.Stdout
+	if out == nil {
+		out = os.Stdout
+	}
+
+	eval := s.EvalCommand
+	eval.Writer, eval.Stdout = nil, ioutil.Discard
+	eval.Verbose, eval.Interactive = false, false
+	evalErr := eval.Execute(args)
+	if ExitCode(evalErr) == ExitUsage && !errors.Is(evalErr, ValuesMergeFailure) {
+		return evalErr
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(eval.diagnostics(evalErr)); err != nil {
+		return fmt.Errorf("couldnt marshal diagnostics: %w", err)
+	}
+	return nil
+}
+
+// diagnostics - maps the error of an evaluation back to file positions
+func (s *EvalCommand) diagnostics(err error) []Diagnostics {
+	files := make(map[string][]Diagnostic)
+	add := func(file string, line int, character int, severity int, message string) {
+		files[file] = append(files[file], Diagnostic{
+			Range: DiagnosticRange{
+				Start: DiagnosticPosition{Line: line, Character: character},
+				End:   DiagnosticPosition{Line: line + 1, Character: 0},
+			},
+			Severity: severity,
+			Source:   "hcunit",
+			Message:  message,
+		})
+	}
+
+	var violation *ViolationError
+	var renderErr *RenderError
+	var compileErr *PolicyCompileError
+	var valuesErrs ValuesErrors
+	switch {
+	case errors.As(err, &violation):
+		templates := templateFilesByName(s.Template)
+		for _, rule := range violation.Failed {
+			detail := violation.Details[rule]
+			message := "rule failed: " + rule
+			if diffs := violation.Diffs[rule]; len(diffs) > 0 {
+				message += "\n" + strings.Join(diffs, "\n")
+			}
+
+			if detail.File != "" {
+				add(detail.File, detail.Line-1, 0, diagnosticError, message)
+			}
+
+			for _, document := range detail.Documents {
+				if template, ok := templates[document]; ok {
+					add(template, 0, 0, diagnosticWarning, fmt.Sprintf("policy rule %s failed on this template", rule))
+				}
+			}
+		}
+	case errors.As(err, &renderErr):
+		match := templateErrorPosition.FindStringSubmatch(renderErr.Error())
+		if match == nil {
+			add(s.Template, 0, 0, diagnosticError, renderErr.Error())
+			break
+		}
+
+		line, _ := strconv.Atoi(match[2])
+		character, _ := strconv.Atoi(match[3])
+		add(renderedTemplateFile(match[1]), line-1, positive(character-1), diagnosticError, match[4])
+	case errors.As(err, &compileErr):
+		matches := regoErrorPosition.FindAllStringSubmatch(compileErr.Error(), -1)
+		for _, match := range matches {
+			line, _ := strconv.Atoi(match[2])
+			add(match[1], line-1, 0, diagnosticError, match[3])
+		}
+
+		if len(matches) == 0 && len(compileErr.Policies) > 0 {
+			add(compileErr.Policies[0], 0, 0, diagnosticError, compileErr.Err.Error())
+		}
+	case errors.As(err, &valuesErrs):
+		for _, problem := range valuesErrs {
+			file := ""
+			for _, values := range s.Values {
+				if strings.Contains(problem.Error(), values) {
+					file = values
+				}
+			}
+
+			if file == "" {
+				continue
+			}
+
+			line := 0
+			if match := yamlErrorLine.FindStringSubmatch(problem.Error()); match != nil {
+				line, _ = strconv.Atoi(match[1])
+				line--
+			}
+			add(file, line, 0, diagnosticError, problem.Error())
+		}
+	}
+
+	diagnostics := make([]Diagnostics, 0, len(files))
+	for file, fileDiagnostics := range files {
+		diagnostics = append(diagnostics, Diagnostics{URI: fileURI(file), Diagnostics: fileDiagnostics})
+	}
+	sort.Slice(diagnostics, func(i, j int) bool { return diagnostics[i].URI < diagnostics[j].URI })
+	return diagnostics
+}
+
+// templateFilesByName - the template files under the template path, by the
+// file name they are keyed with in the policy input
+func templateFilesByName(templatePath string) map[string]string {
+	files := make(map[string]string)
+	filepath.Walk(templatePath, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			files[filepath.Base(path)] = path
+		}
+		return nil
+	})
+	return files
+}
+
+// renderedTemplateFile - the file of a template named in a render error.
+// Absolute paths lose their leading slash once joined to the chart name
+func renderedTemplateFile(name string) string {
+	if _, err := os.Stat(name); err != nil {
+		if _, err := os.Stat("/" + name); err == nil {
+			return "/" + name
+		}
+	}
+	return filepath.FromSlash(name)
+}
+
+func fileURI(file string) string {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		abs = file
+	}
+
+	path := filepath.ToSlash(abs)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return "file://" + path
+}
+
+func positive(i int) int {
+	if i < 0 {
+		return 0
+	}
+	return i
+}
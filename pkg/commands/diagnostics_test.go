@@ -0,0 +1,128 @@
+package commands_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestDiagnosticsCommand(t *testing.T) {
+	type expectedDiagnostic struct {
+		file     string
+		line     int
+		severity int
+		message  string
+	}
+
+	for _, tt := range []struct {
+		name        string
+		template    string
+		values      []string
+		policy      string
+		failsWith   error
+		diagnostics []expectedDiagnostic
+	}{
+		{
+			name:     "policy failures are reported on the rule and the templates it referenced",
+			template: "testdata/templates",
+			values:   []string{"testdata/values.yml"},
+			policy:   "testdata/policy/individuals/params_missing_row.rego",
+			diagnostics: []expectedDiagnostic{
+				{"testdata/policy/individuals/params_missing_row.rego", 4, 1, `rule failed: data.main.expect["required label"] with input.param as "cost-center"`},
+				{"testdata/templates/something.yml", 0, 2, `policy rule data.main.expect["required label"] with input.param as "cost-center" failed on this template`},
+			},
+		},
+		{
+			name:     "render errors are reported on the template position",
+			template: "testdata/mychart/templates",
+			values:   []string{"testdata/values.yml"},
+			policy:   "testdata/policy/passing",
+			diagnostics: []expectedDiagnostic{
+				{"testdata/mychart/templates/deployment.yaml", 18, 1, "nil pointer evaluating interface {}.repository"},
+			},
+		},
+		{
+			name:     "policy compile errors are reported on the rego position",
+			template: "testdata/templates",
+			values:   []string{"testdata/values.yml"},
+			policy:   "testdata/policy/broken/undefined_function.rego",
+			diagnostics: []expectedDiagnostic{
+				{"testdata/policy/broken/undefined_function.rego", 3, 1, "rego_type_error: undefined function not_a_builtin"},
+			},
+		},
+		{
+			name:     "values parse errors are reported on the values file",
+			template: "testdata/templates",
+			values:   []string{"testdata/broken_values.yml"},
+			policy:   "testdata/policy/passing",
+			diagnostics: []expectedDiagnostic{
+				{"testdata/broken_values.yml", 1, 1, "did not find expected node content"},
+			},
+		},
+		{
+			name:     "passing policies have no diagnostics",
+			template: "testdata/templates",
+			values:   []string{"testdata/values.yml"},
+			policy:   "testdata/policy/passing",
+		},
+		{
+			name:      "usage errors fail the command",
+			template:  "testdata/does-not-exist",
+			policy:    "testdata/policy/passing",
+			failsWith: commands.TemplatePathNotFound,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			stdOut := new(bytes.Buffer)
+			diagnosticsCmd := &commands.DiagnosticsCommand{
+				EvalCommand: commands.EvalCommand{
+					Stdout:   stdOut,
+					Template: tt.template,
+					Values:   tt.values,
+					Policy:   []string{tt.policy},
+				},
+			}
+			err := diagnosticsCmd.Execute([]string{})
+			if !errors.Is(err, tt.failsWith) {
+				t.Fatalf("expected error:\n%v\ngot:\n%v", tt.failsWith, err)
+			}
+
+			if tt.failsWith != nil {
+				return
+			}
+
+			diagnostics := make([]commands.Diagnostics, 0)
+			if err := json.Unmarshal(stdOut.Bytes(), &diagnostics); err != nil {
+				t.Fatalf("expected lsp json, got %v:\n%s", err, stdOut.String())
+			}
+
+			found := 0
+			for _, file := range diagnostics {
+				for _, diagnostic := range file.Diagnostics {
+					found++
+					matched := false
+					for _, expected := range tt.diagnostics {
+						if strings.HasSuffix(file.URI, "/"+expected.file) &&
+							diagnostic.Range.Start.Line == expected.line &&
+							diagnostic.Severity == expected.severity &&
+							strings.Contains(diagnostic.Message, expected.message) {
+							matched = true
+						}
+					}
+
+					if !matched {
+						t.Errorf("unexpected diagnostic %+v on %s", diagnostic, file.URI)
+					}
+				}
+			}
+
+			if found != len(tt.diagnostics) {
+				t.Errorf("expected %d diagnostics, got %d:\n%s", len(tt.diagnostics), found, stdOut.String())
+			}
+		})
+	}
+}
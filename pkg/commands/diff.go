@@ -0,0 +1,35 @@
+package commands
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// lineDiff - a line based diff of expected vs actual, with removed lines
+// prefixed by "-" and added lines by "+"
+func lineDiff(expected, actual string) string {
+	dmp := diffmatchpatch.New()
+	a, b, lines := dmp.DiffLinesToChars(expected, actual)
+	diffs := dmp.DiffCharsToLines(dmp.DiffMain(a, b, false), lines)
+
+	out := new(bytes.Buffer)
+	for _, diff := range diffs {
+		prefix := "  "
+		switch diff.Type {
+		case diffmatchpatch.DiffDelete:
+			prefix = "- "
+		case diffmatchpatch.DiffInsert:
+			prefix = "+ "
+		}
+
+		for _, line := range strings.SplitAfter(diff.Text, "\n") {
+			if line == "" {
+				continue
+			}
+			out.WriteString(prefix + strings.TrimSuffix(line, "\n") + "\n")
+		}
+	}
+	return out.String()
+}
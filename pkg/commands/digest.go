@@ -0,0 +1,199 @@
+package commands
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+)
+
+var NothingToDigest = errors.New("give a chart with --template or policies with --policy to digest")
+
+// digestPath - computes a stable sha256 digest of a file or directory tree.
+// Directory digests cover each file's slash separated relative path and
+// contents in lexical order, so they don't depend on mtimes or walk order
+func digestPath(root string) (string, error) {
+	files := make([]string, 0)
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.IsDir() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("digesting %s failed: %w", root, err)
+	}
+	sort.Strings(files)
+
+	hash := sha256.New()
+	for _, path := range files {
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return "", err
+		}
+
+		fmt.Fprintf(hash, "%s\x00", filepath.ToSlash(rel))
+		f, err := os.Open(path)
+		if err != nil {
+			return "", fmt.Errorf("digesting %s failed: %w", path, err)
+		}
+
+		_, err = io.Copy(hash, f)
+		f.Close()
+		if err != nil {
+			return "", fmt.Errorf("digesting %s failed: %w", path, err)
+		}
+		hash.Write([]byte{0})
+	}
+	return fmt.Sprintf("sha256:%x", hash.Sum(nil)), nil
+}
+
+// digestPaths - a digest over the digests of several files or directories,
+// in the order given
+func digestPaths(paths []string) (string, error) {
+	hash := sha256.New()
+	for _, path := range paths {
+		digest, err := digestPath(path)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(hash, "%s\x00", digest)
+	}
+	return fmt.Sprintf("sha256:%x", hash.Sum(nil)), nil
+}
+
+// ChartDigest - a stable sha256 digest of the content of a chart directory
+// or packaged chart archive. It covers the chart as helm loads it (skipping
+// what .helmignore excludes), with Chart.yaml by its parsed metadata, so a
+// chart directory and the archive helm package makes of it share a digest
+func ChartDigest(path string) (string, error) {
+	c, err := chartutil.Load(path)
+	if err != nil {
+		return "", fmt.Errorf("digesting chart %s failed: %w", path, err)
+	}
+
+	hash := sha256.New()
+	if err := writeChartContent(hash, "", c); err != nil {
+		return "", fmt.Errorf("digesting chart %s failed: %w", path, err)
+	}
+	return fmt.Sprintf("sha256:%x", hash.Sum(nil)), nil
+}
+
+// writeChartContent - writes the files of a chart and its subcharts, by
+// their path within the chart in lexical order, the way digestPath does
+func writeChartContent(w io.Writer, prefix string, c *chart.Chart) error {
+	metadata, err := json.Marshal(c.GetMetadata())
+	if err != nil {
+		return err
+	}
+
+	files := map[string][]byte{
+		chartutil.ChartfileName:  metadata,
+		chartutil.ValuesfileName: []byte(c.GetValues().GetRaw()),
+	}
+	for _, template := range c.GetTemplates() {
+		files[template.GetName()] = template.GetData()
+	}
+	for _, file := range c.GetFiles() {
+		files[file.GetTypeUrl()] = file.GetValue()
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(w, "%s%s\x00", prefix, name)
+		w.Write(files[name])
+		w.Write([]byte{0})
+	}
+
+	dependencies := append([]*chart.Chart{}, c.GetDependencies()...)
+	sort.Slice(dependencies, func(i, j int) bool {
+		return dependencies[i].GetMetadata().GetName() < dependencies[j].GetMetadata().GetName()
+	})
+	for _, dependency := range dependencies {
+		if err := writeChartContent(w, prefix+"charts/"+dependency.GetMetadata().GetName()+"/", dependency); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PolicySetDigest - a stable sha256 digest of a set of policy files and
+// directories, which doesn't depend on the order they are given in
+func PolicySetDigest(paths []string) (string, error) {
+	sorted := append([]string{}, paths...)
+	sort.Strings(sorted)
+	return digestPaths(sorted)
+}
+
+// isChartArchive - true for packaged charts, e.g. mychart-0.1.0.tgz
+func isChartArchive(path string) bool {
+	return strings.HasSuffix(path, ".tgz") || strings.HasSuffix(path, ".tar.gz")
+}
+
+// DigestCommand - prints the digests hcunit identifies charts and policy
+// sets by in provenance, audit logs and attestations
+type DigestCommand struct {
+	Writer   io.Writer
+	Template string   `short:"t" long:"template" description:"path to the chart (its directory, a path inside of it or a packaged .tgz) to digest"`
+	Policy   []string `short:"p" long:"policy" description:"path to rego policies (a .rego file or a directory) to digest as one policy set (repeatable)"`
+}
+
+func (s *DigestCommand) Execute(args []string) error {
+	s.setDefaults()
+	if s.Template == "" && len(s.Policy) == 0 {
+		return NothingToDigest
+	}
+
+	if s.Template != "" {
+		chartPath := s.Template
+		if !isChartArchive(chartPath) {
+			root, err := findChartRoot(s.Template)
+			if err != nil {
+				return err
+			}
+			chartPath = root
+		}
+
+		digest, err := ChartDigest(chartPath)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(s.Writer, "chart: %s\n", digest)
+	}
+
+	if len(s.Policy) > 0 {
+		if err := validatePolicyPaths(s.Policy); err != nil {
+			return err
+		}
+
+		digest, err := PolicySetDigest(s.Policy)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(s.Writer, "policies: %s\n", digest)
+	}
+	return nil
+}
+
+func (s *DigestCommand) setDefaults() {
+	if s.Writer == nil {
+		s.Writer = os.Stdout
+	}
+}
@@ -0,0 +1,118 @@
+package commands_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+	"k8s.io/helm/pkg/chartutil"
+)
+
+func TestDigest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hcunit-digest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, chartDir := range []string{"testdata/mychart", "testdata/umbrella"} {
+		t.Run("a chart and its packaged archive share a digest: "+chartDir, func(t *testing.T) {
+			c, err := chartutil.Load(chartDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			archive, err := chartutil.Save(c, dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			dirDigest, err := commands.ChartDigest(chartDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			archiveDigest, err := commands.ChartDigest(archive)
+			if err != nil || dirDigest != archiveDigest || !strings.HasPrefix(dirDigest, "sha256:") {
+				t.Errorf("expected the archive digest %s to match the directory's %s (%v)", archiveDigest, dirDigest, err)
+			}
+		})
+	}
+
+	t.Run("different charts have different digests", func(t *testing.T) {
+		a, _ := commands.ChartDigest("testdata/mychart")
+		b, _ := commands.ChartDigest("testdata/umbrella")
+		if a == b {
+			t.Errorf("expected different digests, got %s for both", a)
+		}
+	})
+
+	t.Run("policy set digests don't depend on the order of the policies", func(t *testing.T) {
+		a, err := commands.PolicySetDigest([]string{"testdata/policy/passing", "testdata/policy/failing"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b, _ := commands.PolicySetDigest([]string{"testdata/policy/failing", "testdata/policy/passing"})
+		c, _ := commands.PolicySetDigest([]string{"testdata/policy/passing"})
+		if a != b || a == c {
+			t.Errorf("expected the same digest for the same policies only, got %s, %s and %s", a, b, c)
+		}
+	})
+
+	for _, tt := range []struct {
+		name      string
+		cmd       *commands.DigestCommand
+		expected  []string
+		failsWith error
+	}{
+		{
+			name:     "the chart owning the template path is digested",
+			cmd:      &commands.DigestCommand{Template: "testdata/mychart/templates"},
+			expected: []string{"chart: sha256:"},
+		},
+		{
+			name:     "charts and policies are digested together",
+			cmd:      &commands.DigestCommand{Template: "testdata/mychart", Policy: []string{"testdata/policy/passing"}},
+			expected: []string{"chart: sha256:", "policies: sha256:"},
+		},
+		{
+			name:      "template paths outside of a chart have no chart digest",
+			cmd:       &commands.DigestCommand{Template: "testdata/templates"},
+			failsWith: commands.ChartNotFound,
+		},
+		{
+			name:      "something needs to be digested",
+			cmd:       &commands.DigestCommand{},
+			failsWith: commands.NothingToDigest,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			stdOut := new(bytes.Buffer)
+			tt.cmd.Writer = stdOut
+			err := tt.cmd.Execute([]string{})
+			if tt.failsWith == nil && err != nil || !errors.Is(err, tt.failsWith) {
+				t.Fatalf("expected error:\n%v\ngot:\n%v", tt.failsWith, err)
+			}
+
+			lines := strings.Split(strings.TrimSpace(stdOut.String()), "\n")
+			if tt.failsWith != nil {
+				return
+			}
+
+			if len(lines) != len(tt.expected) {
+				t.Fatalf("expected %d digests, got:\n%s", len(tt.expected), stdOut.String())
+			}
+
+			for i, prefix := range tt.expected {
+				if !strings.HasPrefix(lines[i], prefix) || len(lines[i]) != len(prefix)+64 {
+					t.Errorf("expected a digest line starting with %q, got %q", prefix, lines[i])
+				}
+			}
+		})
+	}
+}
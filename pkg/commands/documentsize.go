@@ -0,0 +1,211 @@
+package commands
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// InvalidMaxDocumentSize - --max-document-size is not a size, or
+// --oversized-documents is not one of its strategies
+var InvalidMaxDocumentSize = errors.New("invalid --max-document-size")
+
+// DocumentTooLarge - a rendered document exceeds --max-document-size with
+// --oversized-documents error
+var DocumentTooLarge = errors.New("rendered document exceeds --max-document-size")
+
+// oversizedKey - the key of the documents replacing oversized documents,
+// holding their size, hash and strategy
+const oversizedKey = "hcunitOversized"
+
+const (
+	oversizedError    = "error"
+	oversizedTruncate = "truncate"
+	oversizedHash     = "hash"
+)
+
+var oversizedStrategies = []string{oversizedError, oversizedTruncate, oversizedHash}
+
+// parseDocumentLimit - the size of --max-document-size, checking the
+// strategy of --oversized-documents
+func (s *EvalCommand) parseDocumentLimit() (int64, error) {
+	if s.MaxDocumentSize == "" {
+		if s.OversizedDocuments != "" {
+			return 0, fmt.Errorf("%w: --oversized-documents needs --max-document-size", InvalidMaxDocumentSize)
+		}
+		return 0, nil
+	}
+
+	switch s.OversizedDocuments {
+	case "", oversizedError, oversizedTruncate, oversizedHash:
+	default:
+		return 0, fmt.Errorf("%w: --oversized-documents %q is not one of %s", InvalidMaxDocumentSize, s.OversizedDocuments, strings.Join(oversizedStrategies, ", "))
+	}
+	return parseSize(s.MaxDocumentSize, InvalidMaxDocumentSize)
+}
+
+// limitDocuments - replaces the yaml and json documents of the rendered
+// templates over --max-document-size before they are parsed, so a
+// ConfigMap embedding a binary can't exhaust the memory of the yaml parser
+// and of the policy evaluation. Depending on --oversized-documents the run
+// fails, or the document is replaced by its apiVersion, kind and metadata
+// and a hcunitOversized object with its size and sha256, plus its first
+// --max-document-size bytes with truncate
+func (s *EvalCommand) limitDocuments(rendered map[string]string) (map[string]string, error) {
+	if s.documentLimit <= 0 {
+		return rendered, nil
+	}
+
+	names := make([]string, 0, len(rendered))
+	for name := range rendered {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	strategy := s.OversizedDocuments
+	if strategy == "" {
+		strategy = oversizedError
+	}
+
+	limited := make(map[string]string, len(rendered))
+	for _, name := range names {
+		content := rendered[name]
+		limited[name] = content
+		if int64(len(content)) <= s.documentLimit {
+			continue
+		}
+
+		var documents []string
+		separator := ""
+		switch strings.ToLower(filepath.Ext(name)) {
+		case ".yml", ".yaml":
+			documents, separator = splitDocuments(content), "\n---\n"
+		case ".json":
+			documents = []string{content}
+		case ".jsonl":
+			documents, separator = strings.Split(strings.Replace(content, "\r\n", "\n", -1), "\n"), "\n"
+		default:
+			continue
+		}
+
+		replaced := false
+		for i, doc := range documents {
+			if int64(len(doc)) <= s.documentLimit {
+				continue
+			}
+
+			if strategy == oversizedError {
+				return nil, &RenderError{Template: s.Template, Err: fmt.Errorf("%w: document %d of %s is %d bytes, over %s", DocumentTooLarge, i, documentName(name), len(doc), s.MaxDocumentSize)}
+			}
+
+			replacement, err := json.Marshal(oversizedDocument(doc, s.documentLimit, strategy))
+			if err != nil {
+				return nil, err
+			}
+			fmt.Fprintf(s.Stdout, "oversized document %d of %s (%d bytes) replaced by its %s\n", i, documentName(name), len(doc), strategy)
+			documents[i], replaced = string(replacement), true
+		}
+
+		if replaced {
+			limited[name] = strings.Join(documents, separator)
+		}
+	}
+	return limited, nil
+}
+
+// oversizedDocument - what is left of an oversized document for the
+// policies: what identifies it and the hcunitOversized object
+func oversizedDocument(doc string, limit int64, strategy string) map[string]interface{} {
+	sum := sha256.Sum256([]byte(doc))
+	oversized := map[string]interface{}{
+		"size":     len(doc),
+		"sha256":   hex.EncodeToString(sum[:]),
+		"strategy": strategy,
+	}
+
+	if strategy == oversizedTruncate {
+		oversized["content"] = truncateDocument(doc, limit)
+	}
+
+	replacement := documentHeader(doc)
+	replacement[oversizedKey] = oversized
+	return replacement
+}
+
+// truncateDocument - the first limit bytes of a document, cut at a rune
+// boundary, followed by a marker of how much was cut
+func truncateDocument(doc string, limit int64) string {
+	end := int(limit)
+	for end > 0 && !utf8.RuneStart(doc[end]) {
+		end--
+	}
+	return fmt.Sprintf("%s... [truncated by hcunit, %d of %d bytes kept]", doc[:end], end, len(doc))
+}
+
+// documentHeader - the apiVersion, kind and metadata of a document, read
+// without parsing the rest of it: from the top level keys of a yaml
+// document, or skipping the other fields of a json document
+func documentHeader(doc string) map[string]interface{} {
+	header := make(map[string]interface{})
+	if strings.HasPrefix(strings.TrimSpace(doc), "{") {
+		var fields struct {
+			APIVersion interface{} `json:"apiVersion"`
+			Kind       interface{} `json:"kind"`
+			Metadata   interface{} `json:"metadata"`
+		}
+		if json.Unmarshal([]byte(doc), &fields) == nil {
+			for key, value := range map[string]interface{}{"apiVersion": fields.APIVersion, "kind": fields.Kind, "metadata": fields.Metadata} {
+				if value != nil {
+					header[key] = value
+				}
+			}
+		}
+		return header
+	}
+
+	var kept strings.Builder
+	keep := false
+	for rest := doc; rest != ""; {
+		line := rest
+		if i := strings.IndexByte(rest, '\n'); i >= 0 {
+			line, rest = rest[:i+1], rest[i+1:]
+		} else {
+			rest = ""
+		}
+
+		if topLevelLine(line) {
+			keep = strings.HasPrefix(line, "apiVersion:") || strings.HasPrefix(line, "kind:") || strings.HasPrefix(line, "metadata:")
+		}
+
+		if keep {
+			kept.WriteString(line)
+		}
+	}
+
+	if yaml.Unmarshal([]byte(kept.String()), &header) != nil {
+		return make(map[string]interface{})
+	}
+	return header
+}
+
+// topLevelLine - whether a yaml line starts a top level key, rather than
+// continuing the value of the previous one
+func topLevelLine(line string) bool {
+	if line == "" || strings.TrimSpace(line) == "" {
+		return false
+	}
+
+	switch line[0] {
+	case ' ', '\t', '#', '-':
+		return false
+	}
+	return strings.Contains(line, ":")
+}
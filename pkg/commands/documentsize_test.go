@@ -0,0 +1,101 @@
+package commands_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestEvalOversizedDocuments(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		maxSize   string
+		strategy  string
+		policy    string
+		failsWith error
+		printed   []string
+	}{
+		{
+			name:      "oversized documents fail the run by default",
+			maxSize:   "4Ki",
+			policy:    "testdata/policy/passing/passing.rego",
+			failsWith: commands.DocumentTooLarge,
+		},
+		{
+			name:     "hashed documents keep their apiVersion, kind and metadata",
+			maxSize:  "4Ki",
+			strategy: "hash",
+			policy:   "testdata/policy/individuals/oversized_documents.rego",
+			printed: []string{
+				"oversized document 1 of firmware.yaml",
+				"oversized document 0 of manifest.json",
+			},
+		},
+		{
+			name:     "truncated documents keep their first bytes",
+			maxSize:  "4Ki",
+			strategy: "truncate",
+			policy:   "testdata/policy/individuals/oversized_truncated.rego",
+		},
+		{
+			name:    "documents within the limit are parsed as usual",
+			maxSize: "1Mi",
+			policy:  "testdata/policy/passing/passing.rego",
+		},
+		{
+			name:      "sizes have to be sizes",
+			maxSize:   "a lot",
+			policy:    "testdata/policy/passing/passing.rego",
+			failsWith: commands.InvalidMaxDocumentSize,
+		},
+		{
+			name:      "strategies have to be known",
+			maxSize:   "4Ki",
+			strategy:  "drop",
+			policy:    "testdata/policy/passing/passing.rego",
+			failsWith: commands.InvalidMaxDocumentSize,
+		},
+		{
+			name:      "strategies need a size",
+			strategy:  "hash",
+			policy:    "testdata/policy/passing/passing.rego",
+			failsWith: commands.InvalidMaxDocumentSize,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			stdOut := new(bytes.Buffer)
+			evalCmd := &commands.EvalCommand{
+				Stdout:             stdOut,
+				Template:           "testdata/oversized",
+				Policy:             []string{tt.policy},
+				MaxDocumentSize:    tt.maxSize,
+				OversizedDocuments: tt.strategy,
+			}
+			err := evalCmd.Execute([]string{})
+			if tt.failsWith == nil && err != nil || !errors.Is(err, tt.failsWith) {
+				t.Fatalf("expected error:\n%v\ngot:\n%v\n%s", tt.failsWith, err, stdOut)
+			}
+
+			for _, line := range tt.printed {
+				if !strings.Contains(stdOut.String(), line) {
+					t.Errorf("expected %q in:\n%s", line, stdOut)
+				}
+			}
+		})
+	}
+}
+
+func TestOversizedDocumentsExitCode(t *testing.T) {
+	evalCmd := &commands.EvalCommand{
+		Stdout:          new(bytes.Buffer),
+		Template:        "testdata/oversized",
+		Policy:          []string{"testdata/policy/passing/passing.rego"},
+		MaxDocumentSize: "4Ki",
+	}
+	if code := commands.ExitCode(evalCmd.Execute([]string{})); code != commands.ExitRender {
+		t.Errorf("expected exit code %d, got %d", commands.ExitRender, code)
+	}
+}
@@ -0,0 +1,118 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+)
+
+const (
+	enforcementDeny = "deny"
+	enforcementWarn = "warn"
+)
+
+var InvalidEnforcement = errors.New("invalid enforcement in config")
+
+// EnforcementConfig - how failures are enforced by where the documents the
+// failed rules referenced come from, e.g. to only warn about violations in
+// vendored subcharts while still reporting them
+type EnforcementConfig struct {
+	Subcharts []SubchartEnforcement `yaml:"subcharts"`
+}
+
+// SubchartEnforcement - the enforcement level (deny, warn or dryrun) of the
+// subcharts matching a glob, like vendored-* or backend/db
+type SubchartEnforcement struct {
+	Match string `yaml:"match"`
+	Level string `yaml:"level"`
+}
+
+// validate - every subchart enforcement needs a valid glob and level
+func (s EnforcementConfig) validate() error {
+	for _, enforcement := range s.Subcharts {
+		if _, err := path.Match(enforcement.Match, ""); err != nil || enforcement.Match == "" {
+			return fmt.Errorf("%w: %q is not a glob of subchart names", InvalidEnforcement, enforcement.Match)
+		}
+
+		switch enforcement.Level {
+		case enforcementDeny, enforcementWarn, enforcementDryRun:
+		default:
+			return fmt.Errorf("%w: level %q of %s is not one of deny, warn, dryrun", InvalidEnforcement, enforcement.Level, enforcement.Match)
+		}
+	}
+	return nil
+}
+
+// level - the enforcement of a failure referencing the given documents: the
+// level of the first subchart enforcement matching the subchart of each
+// document, with the strictest level winning. Documents of the chart itself,
+// and failures not referencing any template, are denied
+func (s EnforcementConfig) level(documents []string) string {
+	levels := map[string]int{enforcementDryRun: 0, enforcementWarn: 1, enforcementDeny: 2}
+	level := ""
+	for _, document := range documents {
+		if filepath.Ext(document) == "" {
+			continue
+		}
+
+		documentLevel := enforcementDeny
+		if subchart := subchartOf(document); subchart != "" {
+			for _, enforcement := range s.Subcharts {
+				if matched, _ := path.Match(enforcement.Match, subchart); matched {
+					documentLevel = enforcement.Level
+					break
+				}
+			}
+		}
+
+		if level == "" || levels[documentLevel] > levels[level] {
+			level = documentLevel
+		}
+	}
+
+	if level == "" {
+		return enforcementDeny
+	}
+	return level
+}
+
+// enforceSubcharts - lowers the failures of rules referencing only documents
+// of subcharts enforced with warn or dryrun in the config to warnings or dry
+// runs, so they are reported without failing the run
+func (s *EvalCommand) enforceSubcharts(results []RuleResult, err error) ([]RuleResult, error) {
+	var violation *ViolationError
+	if s.config == nil || len(s.config.Enforcement.Subcharts) == 0 || !errors.As(err, &violation) {
+		return results, err
+	}
+
+	lowered := make(map[string]bool)
+	for i := range results {
+		if results[i].Passed {
+			continue
+		}
+
+		switch s.config.Enforcement.level(results[i].Documents) {
+		case enforcementWarn:
+			results[i].Passed, results[i].Warning = true, true
+		case enforcementDryRun:
+			results[i].Passed, results[i].DryRun = true, true
+		default:
+			continue
+		}
+		lowered[results[i].Name] = true
+	}
+
+	failed := make([]string, 0, len(violation.Failed))
+	for _, name := range violation.Failed {
+		if !lowered[name] {
+			failed = append(failed, name)
+		}
+	}
+
+	if len(failed) == 0 {
+		return results, nil
+	}
+	violation.Failed = failed
+	return results, violation
+}
@@ -0,0 +1,103 @@
+package commands_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestEvalSubchartEnforcement(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hcunit-enforcement")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	outcomes := func(results []commands.RuleResult) map[string]string {
+		out := make(map[string]string)
+		for _, result := range results {
+			switch {
+			case result.Warning:
+				out[result.Name] = "warn"
+			case result.DryRun:
+				out[result.Name] = "dryrun"
+			case result.Passed:
+				out[result.Name] = "pass"
+			default:
+				out[result.Name] = "fail"
+			}
+		}
+		return out
+	}
+
+	const (
+		pinned          = `data.main.deny["frontend images must be pinned"]`
+		ownedByBackend  = `data.main.expect["configmaps are owned by the umbrella"] on ConfigMap/hcunit-name-backend in backend/configmap.yaml`
+		ownedByUmbrella = `data.main.expect["configmaps are owned by the umbrella"] on ConfigMap/hcunit-name-umbrella in configmap.yaml`
+	)
+
+	for _, tt := range []struct {
+		name      string
+		config    string
+		failsWith error
+		expected  map[string]string
+	}{
+		{
+			name:      "failures in subcharts are denied by default",
+			failsWith: commands.PolicyFailure,
+			expected:  map[string]string{pinned: "fail", ownedByBackend: "fail", ownedByUmbrella: "pass"},
+		},
+		{
+			name:     "subcharts enforced with warn only warn",
+			config:   "enforcement:\n  subcharts:\n    - match: \"*\"\n      level: warn\n",
+			expected: map[string]string{pinned: "warn", ownedByBackend: "warn", ownedByUmbrella: "pass"},
+		},
+		{
+			name:      "the first matching subchart enforcement applies",
+			config:    "enforcement:\n  subcharts:\n    - match: frontend\n      level: deny\n    - match: \"*\"\n      level: dryrun\n",
+			failsWith: commands.PolicyFailure,
+			expected:  map[string]string{pinned: "fail", ownedByBackend: "dryrun", ownedByUmbrella: "pass"},
+		},
+		{
+			name:      "unknown levels are rejected",
+			config:    "enforcement:\n  subcharts:\n    - match: \"*\"\n      level: ignore\n",
+			failsWith: commands.InvalidEnforcement,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(dir, ".hcunit.yaml")
+			if err := ioutil.WriteFile(configPath, []byte(tt.config), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			evalCmd := &commands.EvalCommand{
+				Stdout:   new(bytes.Buffer),
+				Template: "testdata/umbrellachart",
+				Values:   []string{"testdata/umbrellachart/values.yaml"},
+				Policy:   []string{"testdata/policy/subcharts/enforcement.rego"},
+				Config:   configPath,
+			}
+			results, err := evalCmd.EvaluateBatch([]commands.ValuesSet{{Name: "umbrella"}})
+			if tt.failsWith == nil && err != nil || !errors.Is(err, tt.failsWith) {
+				t.Fatalf("expected error:\n%v\ngot:\n%v", tt.failsWith, err)
+			}
+
+			if tt.expected == nil {
+				return
+			}
+
+			got := outcomes(results[0].Results)
+			delete(got, `data.main.expect["configmaps are owned by the umbrella"] on Service/hcunit-name-db in backend/db/service.yaml`)
+			delete(got, `data.main.expect["configmaps are owned by the umbrella"] on Deployment/hcunit-name-frontend in frontend/deployment.yaml`)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected outcomes:\n%v\ngot:\n%v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// exit codes returned by the hcunit cli, by failure category
+const (
+	ExitOK      = 0
+	ExitFailure = 1 // policy violations and failed checks (lint, secret scan, golden files, locks)
+	ExitUsage   = 2 // invalid flags, paths or values
+	ExitRender  = 3 // the templates failed to render
+	ExitPolicy  = 4 // the policies failed to compile or evaluate, or require a newer hcunit
+)
+
+// RenderError - rendering the templates (or parsing the rendered output) failed
+type RenderError struct {
+	Template string
+	Err      error
+}
+
+func (e *RenderError) Error() string {
+	return fmt.Sprintf("error while rendering %s: %v", e.Template, e.Err)
+}
+
+func (e *RenderError) Unwrap() error { return e.Err }
+
+// PolicyCompileError - the policies failed to parse or compile
+type PolicyCompileError struct {
+	Policies []string
+	Err      error
+}
+
+func (e *PolicyCompileError) Error() string {
+	return fmt.Sprintf("failed preparing for eval on policies %s: %v", strings.Join(e.Policies, ", "), e.Err)
+}
+
+func (e *PolicyCompileError) Unwrap() error { return e.Err }
+
+// EvaluationError - a compiled query failed while being evaluated
+type EvaluationError struct {
+	Query string
+	Err   error
+}
+
+func (e *EvaluationError) Error() string {
+	return fmt.Sprintf("failed eval of %s: %v", e.Query, e.Err)
+}
+
+func (e *EvaluationError) Unwrap() error { return e.Err }
+
+// ViolationError - the policies evaluated but some of their rules failed.
+// Diffs holds the expected vs actual diffs recorded by failed
+// hcunit.assert_equal calls, by rule, and Details what is needed to debug
+// each failed rule. It matches PolicyFailure with errors.Is
+type ViolationError struct {
+	Failed  []string
+	Diffs   map[string][]string
+	Details map[string]FailureDetail
+}
+
+// FailureDetail - the source and location of a failed rule, the trace of
+// its evaluation, the input documents (template file names) it referenced
+// and, for expect/assert rules, the expression it failed at
+type FailureDetail struct {
+	Source      string
+	File        string
+	Line        int
+	Trace       string
+	Documents   []string
+	Explanation *FailureExplanation
+}
+
+func (e *ViolationError) Error() string {
+	return fmt.Sprintf("%v: %s", PolicyFailure, strings.Join(e.Failed, ", "))
+}
+
+func (e *ViolationError) Is(target error) bool { return target == PolicyFailure }
+
+// ExitCode - maps an error returned by a command to the cli's exit code
+func ExitCode(err error) int {
+	var renderErr *RenderError
+	var compileErr *PolicyCompileError
+	var evalErr *EvaluationError
+
+	switch {
+	case err == nil:
+		return ExitOK
+	case isAny(err, InvalidPolicyPath, TemplatePathNotFound, NoTemplatesFound, FilepathValueEmpty, ValuesMergeFailure, LibraryChartWithoutFixtures, ChartNotFound, InvalidKubeVersion, OutputFileExists, InvalidRunFilter, InvalidReportHeader, InvalidCompareArgs, InvalidNotification, InvalidMemoryBudget, InvalidInputProcessor, InvalidValuesSet, NothingToDigest, InvalidOutput, SubchartNotFound, InvalidEnforcement, InvalidConvention, InvalidChartComparison, InvalidTimestampFormat, InvalidLanguage, InvalidManifests, InvalidAdapter, InvalidCRD, InvalidBuiltinPolicies, InvalidProfileOut, InvalidChartMode, InvalidPprofAddr, InvalidMaxDocumentSize, InvalidSetValue, InvalidProfile, InvalidPolicySourceName):
+		return ExitUsage
+	case errors.As(err, &renderErr):
+		return ExitRender
+	case errors.As(err, &compileErr), errors.As(err, &evalErr), isAny(err, DuplicatePolicyFailure, UnmatchedQuery, IncompatiblePolicy):
+		return ExitPolicy
+	}
+	return ExitFailure
+}
+
+func isAny(err error, targets ...error) bool {
+	for _, target := range targets {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,165 @@
+package commands_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestErrors(t *testing.T) {
+	t.Run("exit codes follow the failure category through wrapping", func(t *testing.T) {
+		for _, tt := range []struct {
+			err      error
+			exitCode int
+		}{
+			{nil, commands.ExitOK},
+			{fmt.Errorf("wrapped: %w", &commands.ViolationError{Failed: []string{"rule"}}), commands.ExitFailure},
+			{commands.LintFailure, commands.ExitFailure},
+			{fmt.Errorf("wrapped: %w", &commands.RenderError{Err: errors.New("boom")}), commands.ExitRender},
+			{&commands.EvaluationError{Query: "data.main.expect", Err: errors.New("boom")}, commands.ExitPolicy},
+			{fmt.Errorf("%w: requires hcunit 9.0.0", commands.IncompatiblePolicy), commands.ExitPolicy},
+			{commands.ValuesErrors{errors.New("missing")}, commands.ExitUsage},
+		} {
+			if code := commands.ExitCode(tt.err); code != tt.exitCode {
+				t.Errorf("expected exit code %d for %v, got %d", tt.exitCode, tt.err, code)
+			}
+		}
+	})
+
+	t.Run("failures carry their category", func(t *testing.T) {
+		for _, tt := range []struct {
+			name     string
+			eval     *commands.EvalCommand
+			exitCode int
+			check    func(error) error
+		}{
+			{
+				name: "violations",
+				eval: &commands.EvalCommand{
+					Template: "testdata/templates",
+					Values:   []string{"testdata/values.yml"},
+					Policy:   []string{"testdata/policy/individuals/assert_fail.rego"},
+				},
+				exitCode: commands.ExitFailure,
+				check: func(err error) error {
+					var violation *commands.ViolationError
+					if !errors.As(err, &violation) || len(violation.Failed) == 0 {
+						return fmt.Errorf("expected a ViolationError listing the failed rules, got %#v", err)
+					}
+
+					if !errors.Is(err, commands.PolicyFailure) {
+						return fmt.Errorf("a ViolationError should match PolicyFailure")
+					}
+					return nil
+				},
+			},
+			{
+				name: "assertion diffs",
+				eval: &commands.EvalCommand{
+					Template: "testdata/templates",
+					Values:   []string{"testdata/values.yml"},
+					Policy:   []string{"testdata/policy/individuals/assert_equal.rego"},
+				},
+				exitCode: commands.ExitFailure,
+				check: func(err error) error {
+					var violation *commands.ViolationError
+					if !errors.As(err, &violation) {
+						return fmt.Errorf("expected a ViolationError, got %#v", err)
+					}
+
+					diffs := violation.Diffs[`data.main.expect["assert_equal reports a diff on different values"]`]
+					if len(diffs) != 1 || !strings.Contains(diffs[0], "- host: other.com\n+ host: hcunit.com\n") {
+						return fmt.Errorf("expected a diff of the host, got %q", diffs)
+					}
+
+					if len(violation.Failed) != 1 {
+						return fmt.Errorf("expected only the differing assertion to fail, got %v", violation.Failed)
+					}
+					return nil
+				},
+			},
+			{
+				name: "parametrized violations",
+				eval: &commands.EvalCommand{
+					Template: "testdata/templates",
+					Values:   []string{"testdata/values.yml"},
+					Policy:   []string{"testdata/policy/individuals/params_missing_row.rego"},
+				},
+				exitCode: commands.ExitFailure,
+				check: func(err error) error {
+					var violation *commands.ViolationError
+					if !errors.As(err, &violation) {
+						return fmt.Errorf("expected a ViolationError, got %#v", err)
+					}
+
+					failed := []string{`data.main.expect["required label"] with input.param as "cost-center"`}
+					if fmt.Sprint(violation.Failed) != fmt.Sprint(failed) {
+						return fmt.Errorf("expected only %v to fail, got %v", failed, violation.Failed)
+					}
+					return nil
+				},
+			},
+			{
+				name: "render failures",
+				eval: &commands.EvalCommand{
+					Template: "testdata/mychart/templates",
+					Values:   []string{"testdata/values.yml"},
+					Policy:   []string{"testdata/policy/passing"},
+				},
+				exitCode: commands.ExitRender,
+				check: func(err error) error {
+					var renderErr *commands.RenderError
+					if !errors.As(err, &renderErr) || renderErr.Template != "testdata/mychart/templates" {
+						return fmt.Errorf("expected a RenderError for the template path, got %#v", err)
+					}
+					return nil
+				},
+			},
+			{
+				name: "policy compile failures",
+				eval: &commands.EvalCommand{
+					Template: "testdata/templates",
+					Values:   []string{"testdata/values.yml"},
+					Policy:   []string{"testdata/policy/broken/undefined_function.rego"},
+				},
+				exitCode: commands.ExitPolicy,
+				check: func(err error) error {
+					var compileErr *commands.PolicyCompileError
+					if !errors.As(err, &compileErr) {
+						return fmt.Errorf("expected a PolicyCompileError, got %#v", err)
+					}
+					return nil
+				},
+			},
+			{
+				name: "usage errors",
+				eval: &commands.EvalCommand{
+					Template: "testdata/does-not-exist",
+					Values:   []string{"testdata/values.yml"},
+					Policy:   []string{"testdata/policy/passing"},
+				},
+				exitCode: commands.ExitUsage,
+				check: func(err error) error {
+					if !errors.Is(err, commands.TemplatePathNotFound) {
+						return fmt.Errorf("expected TemplatePathNotFound, got %v", err)
+					}
+					return nil
+				},
+			},
+		} {
+			t.Run(tt.name, func(t *testing.T) {
+				err := tt.eval.Execute([]string{})
+				if err := tt.check(err); err != nil {
+					t.Error(err)
+				}
+
+				if commands.ExitCode(err) != tt.exitCode {
+					t.Errorf("expected exit code %d, got %d for %v", tt.exitCode, commands.ExitCode(err), err)
+				}
+			})
+		}
+	})
+}
@@ -1,52 +1,657 @@
 package commands
 
 import (
+	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/mitchellh/colorstring"
+	"github.com/open-policy-agent/opa/rego"
 )
 
 const valuesHashName = "values"
 
 type EvalCommand struct {
 	Writer    io.Writer
+	Stdin     io.Reader
+	Stdout    io.Writer
+	Version   string
+	Hooks     *Hooks
+	Reporter  Reporter
+	Manifests string   `long:"manifests" description:"directory of plain kubernetes manifests (yaml or json) to evaluate instead of rendering a chart"`
+	Adapter   string   `long:"adapter" description:"run this manifest adapter (cdk8s, jsonnet or one declared under adapters in the config) and evaluate the manifests it produces instead of rendering a chart"`
+	CRDs      []string `long:"crd" description:"CRD manifest file or directory to validate rendered custom resources against, besides the CRDs of the chart (repeatable)"`
 	Template  string   `short:"t" long:"template" description:"path to yaml template you would like to render"`
+	Chart     string   `long:"chart" description:"chart directory to render as a whole the way helm template does, with its Chart.yaml, values.yaml, files and subcharts, instead of a template path"`
 	Values    []string `short:"c" long:"values" description:"path to values file(s) you would like to use for rendering"`
-	Policy    string   `short:"p" long:"policy" description:"path to rego policies to evaluate against rendered templates"`
+	Set       []string `long:"set" redact:"true" description:"set values over the values files, helm style, e.g. image.tag=1.2.3,replicas=3 (repeatable)"`
+	SetString []string `long:"set-string" redact:"true" description:"set string values over the values files, helm style (repeatable)"`
+	SetFile   []string `long:"set-file" redact:"true" description:"set values over the values files to the content of a file, helm style, e.g. config=./app.conf (repeatable)"`
+	Policy    []string `short:"p" long:"policy" description:"path to rego policies (a .rego file or a directory) to evaluate against rendered templates (repeatable)"`
 	Namespace string   `short:"n" long:"namespace" description:"policy namespace to query for rules"`
 	Verbose   bool     `short:"v" long:"verbose" description:"prints tracing output to stdout"`
+	Lint      bool     `long:"lint" description:"run helm lint over the chart owning the template path and include its findings"`
+
+	IncludeTemplates   []string `long:"include-template" description:"only render templates matching this glob (repeatable)"`
+	ExcludeTemplates   []string `long:"exclude-template" description:"skip templates matching this glob (repeatable)"`
+	Fixtures           []string `long:"fixture" description:"path to caller templates rendered with the chart's named templates, e.g. to evaluate a library chart (repeatable)"`
+	Defines            []string `long:"define" description:"render only this named template (from a define block) with the given values instead of the chart (repeatable)"`
+	TplValues          bool     `long:"tpl-values" description:"render go template expressions in string values with the release and chart context first, the way helmfile does"`
+	SecondPass         []string `long:"second-pass" description:"render the output of templates matching this glob again with the same values and context, for output templated once more at install time, e.g. by tpl (repeatable)"`
+	DependencyUpdate   bool     `long:"dependency-update" description:"run helm dependency build on the chart owning the template path before evaluating"`
+	DependencyVerify   bool     `long:"dependency-verify" description:"fail if the subcharts in charts/ do not match the chart's lock file"`
+	Offline            bool     `long:"offline" description:"fail on any attempt to reach the network (dependency updates, http.send in policies)"`
+	IncludeTests       bool     `long:"include-tests" description:"evaluate helm test hooks (templates/tests/*) alongside the chart instead of only under input.tests"`
+	ScanSecrets        bool     `long:"scan-secrets" description:"fail on credentials (known key formats, high entropy strings) found in rendered objects other than Secrets or in values"`
+	BuiltinPolicies    []string `long:"builtin-policies" description:"also evaluate this policy pack shipped with hcunit, e.g. operators for the cross references of ServiceMonitors, Certificates and ExternalSecrets (repeatable)"`
+	ShowSecrets        bool     `long:"show-secrets" description:"do not redact the values of rendered Secrets from trace output"`
+	KubeVersions       []string `long:"kube-versions" description:"comma separated kubernetes versions to render and evaluate the chart against, one run per version (repeatable)"`
+	KubeVersion        string   `long:"kube-version" description:"kubernetes version to render and evaluate the chart against in a single run, e.g. 1.29"`
+	Interactive        bool     `long:"interactive" description:"browse the results after evaluating: view the source, manifests and trace of failed rules and re-run them"`
+	Run                string   `long:"run" description:"only evaluate the rules (and parameter rows) whose name matches this regular expression"`
+	ArtifactsDir       string   `long:"artifacts-dir" description:"write the rule source, referenced documents, trace and a reproduction command of every failed rule into this directory"`
+	TimestampFormat    string   `long:"timestamp-format" description:"encode the UTC times of results, audit records and attestations as rfc3339, rfc3339nano, unix or unix-ms (default: rfc3339)"`
+	AuditLog           string   `long:"audit-log" description:"append a json record of the run (chart, policy and values digests, results, exit code) to this file"`
+	Attestation        string   `long:"attestation" description:"write an in-toto attestation of the results for the chart to this file, for signing with cosign"`
+	AttestationSubject string   `long:"attestation-subject" description:"packaged chart (.tgz) the attestation is about (default: the chart directory by its digest)"`
+	ReportURL          string   `long:"report-url" description:"post the json results of the run to this url (a bearer token can be given in HCUNIT_REPORT_TOKEN)"`
+	ReportHeaders      []string `long:"report-header" redact:"true" env-delim:";" description:"header sent with --report-url submissions, as 'Name: value' (repeatable)"`
+	ResultsFile        string   `long:"results-file" description:"write the json results of the run to this file"`
+	CompareTo          string   `long:"compare-to" description:"results file of a previous run: only rules which newly fail compared to it fail the run"`
+	Notify             bool     `long:"notify" description:"send the results to the slack or teams webhooks declared under notifications in the config"`
+	Config             string   `long:"config" description:"path to the hcunit config declaring notifications, input processors and defaults, merged over the user config in $XDG_CONFIG_HOME/hcunit/config.yaml (default: .hcunit.yaml)"`
+	ByTeam             bool     `long:"by-team" description:"print the failed rules grouped by the team owning them (the team of their metadata)"`
+	TeamReports        string   `long:"team-reports" description:"write a json report per owning team (<team>.json) of the rules it owns into this directory"`
+	History            string   `long:"history" optional:"yes" optional-value:".hcunit/history.jsonl" description:"store the results of the run, keyed by chart and commit, in this results history for hcunit trends"`
+	MaxFailures        *int     `long:"max-failures" description:"tolerate up to this many failed rules instead of failing on any"`
+	MaxWarnings        *int     `long:"max-warnings" description:"fail when more than this many warn rules fire"`
+	MinScore           float64  `long:"min-score" description:"fail when less than this percentage of rules pass, e.g. 90"`
+	DryRun             bool     `long:"dryrun" description:"report and count the violations of every rule of the policy namespace without failing the run, e.g. to roll out new policies"`
+	PolicyCache        string   `long:"policy-cache" description:"cache parsed policy modules in this directory, keyed by their content, to skip parsing them again (default: $XDG_CACHE_HOME/hcunit/policies)"`
+	NoPolicyCache      bool     `long:"no-policy-cache" description:"parse every policy module from source instead of reading or writing the policy cache"`
+	MemoryBudget       string   `long:"memory-budget" description:"evaluate charts rendering to more than this size (e.g. 512Mi) one template at a time from disk, with per document rules only"`
+	ProfileOut         []string `long:"profile-out" description:"write a profile of the policy evaluation as pprof (for go tool pprof) or folded (for flame graphs), optionally followed by :<path> (default: .hcunit/profile.pb.gz or .hcunit/profile.folded) (repeatable)"`
+	PprofAddr          string   `long:"pprof-addr" description:"serve net/http/pprof on this address (e.g. localhost:6060) for as long as the run lasts"`
+	CPUProfile         string   `long:"cpuprofile" description:"write a cpu profile of hcunit to this file"`
+	MemProfile         string   `long:"memprofile" description:"write a heap profile of hcunit to this file after the run"`
+	MaxDocumentSize    string   `long:"max-document-size" description:"handle rendered documents larger than this size (e.g. 1Mi) with --oversized-documents before parsing them"`
+	OversizedDocuments string   `long:"oversized-documents" description:"fail the run on documents over --max-document-size (error), or replace them by their apiVersion, kind and metadata with their size and sha256 (hash) and their first bytes (truncate) (default: error)"`
+	ReleaseName        string   `long:"release-name" description:"name of the release the templates are rendered for, as .Release.Name (default: hcunit-name)"`
+	ReleaseNamespace   string   `long:"release-namespace" description:"namespace of the release the templates are rendered for, as .Release.Namespace (default: hcunit-namespace)"`
+	IsUpgrade          bool     `long:"is-upgrade" description:"render the templates for an upgrade of the release instead of an install (.Release.IsUpgrade)"`
+	APIVersions        []string `long:"api-versions" description:"api version the cluster serves besides v1, as .Capabilities.APIVersions, e.g. monitoring.coreos.com/v1 (repeatable)"`
+	PartialEval        bool     `long:"partial-eval" description:"partially evaluate the rules against the values once, and evaluate only what is left of them in every run of --kube-versions or --memory-budget"`
+	OnlySubcharts      []string `long:"only-subchart" description:"only evaluate the templates rendered by this subchart of an umbrella chart, and the subcharts nested in it (repeatable)"`
+	Output             string   `long:"output" description:"print the results as text, as plain-verbose text for screen readers (no colors, the result spelled out in a fixed width column), or only the results of the run as json (the results of --results-file) or tap (default: text, also called pretty, or the output of the config)"`
+	NoColor            bool     `long:"no-color" description:"print the results without colors"`
+	ASCII              bool     `long:"ascii" description:"only print ascii symbols, e.g. | and ... instead of the box drawing and ellipsis of --interactive"`
+	Lang               string   `long:"lang" description:"language of hcunit's own result labels, summaries and hints: en, de, es, or a yaml message catalog (default: en)"`
+	ValuesSets         []string `long:"values-set" env-delim:";" description:"evaluate the chart once per named set of values files merged over --values, given as name=a.yml,b.yml, with the policies compiled once for every set (repeatable)"`
+	Profile            []string `long:"profile" description:"evaluate the chart for this profile of the config, with its values files, release options, kubernetes version, policies and policy namespace (repeatable)"`
+	AllProfiles        bool     `long:"all-profiles" description:"evaluate the chart once for every profile of the config, with a section of results per profile"`
+
+	config        *Config
+	adapterOutput string
+	runFilter     *regexp.Regexp
+	started       time.Time
+	memoryBudget  int64
+	documentLimit int64
+	activeProfile string
+	lintChecks    []RuleResult
+	lintErr       error
+	specialized   *loadedPolicies
+	prepared      *preparedPolicies
+	profile       *policyProfile
+	rbac          rbacModel
+	processors    []func(map[string]interface{})
+	messages      messageCatalog
+	valuesSets    []ValuesSet
+	batch         []BatchResult
+	stdinScanner  *bufio.Scanner
+	results       []RuleResult
 }
 
 func (s *EvalCommand) Execute(args []string) error {
+	s.started = time.Now()
 	s.setDefaults()
+	if err := s.loadConfig(); err != nil {
+		return err
+	}
+
+	stdout := s.Stdout
+	defer func() { s.Stdout = stdout }()
+	s.Stdout = s.outputWriter(stdout)
+
+	stopProfiling, err := s.startRuntimeProfiling()
+	if err != nil {
+		return err
+	}
+
+	err = s.executeProfiles()
+	if s.CompareTo != "" {
+		err = s.compareTo(err)
+	}
+
+	if s.ByTeam {
+		reportFailuresByTeam(s.Stdout, s.results)
+	}
+
+	if s.TeamReports != "" {
+		if teamErr := s.writeTeamReports(); teamErr != nil && err == nil {
+			err = teamErr
+		}
+	}
+
+	if s.AuditLog != "" {
+		if auditErr := s.appendAuditRecord(err); auditErr != nil && err == nil {
+			err = auditErr
+		}
+	}
+
+	if s.Attestation != "" {
+		if attestationErr := s.writeAttestation(err); attestationErr != nil && err == nil {
+			err = attestationErr
+		}
+	}
+
+	if s.ReportURL != "" {
+		if reportErr := s.submitReport(err); reportErr != nil && err == nil {
+			err = reportErr
+		}
+	}
+
+	if s.History != "" {
+		if historyErr := s.recordHistory(err); historyErr != nil && err == nil {
+			err = historyErr
+		}
+	}
 
-	if s.Policy == "" {
-		return InvalidPolicyPath
+	if s.Notify {
+		if notifyErr := s.notify(err); notifyErr != nil && err == nil {
+			err = notifyErr
+		}
 	}
 
-	fileFile, err := os.Open(s.Policy)
+	if s.profile != nil {
+		if profileErr := s.writeProfiles(); profileErr != nil && err == nil {
+			err = profileErr
+		}
+	}
+
+	if profilingErr := stopProfiling(); profilingErr != nil && err == nil {
+		err = profilingErr
+	}
+
+	if s.ResultsFile != "" {
+		if resultsErr := s.writeResults(err); resultsErr != nil && err == nil {
+			err = resultsErr
+		}
+	}
+
+	if reporter := s.reporter(); reporter != nil {
+		if printErr := reporter.Report(stdout, s.runReport(err)); printErr != nil && err == nil {
+			err = printErr
+		}
+	}
+	return err
+}
+
+func (s *EvalCommand) execute() error {
+	if err := s.writeBuiltinPolicies(); err != nil {
+		return err
+	}
+
+	if err := validatePolicyPaths(s.Policy); err != nil {
+		return err
+	}
+
+	if err := checkPolicyCompatibility(s.Policy, s.Version); err != nil {
+		return err
+	}
+
+	if len(s.ProfileOut) > 0 {
+		if _, err := parseProfileOut(s.ProfileOut); err != nil {
+			return err
+		}
+		if s.profile == nil {
+			s.profile = newPolicyProfile()
+		}
+	}
+
+	if s.MemoryBudget != "" {
+		budget, err := parseSize(s.MemoryBudget, InvalidMemoryBudget)
+		if err != nil {
+			return err
+		}
+
+		if s.Interactive {
+			return fmt.Errorf("%w: it can't be combined with --interactive", InvalidMemoryBudget)
+		}
+		s.memoryBudget = budget
+	}
+
+	limit, err := s.parseDocumentLimit()
 	if err != nil {
-		return InvalidPolicyPath
+		return err
+	}
+	s.documentLimit = limit
+
+	if s.Run != "" {
+		filter, err := regexp.Compile(s.Run)
+		if err != nil {
+			return fmt.Errorf("%w: %v", InvalidRunFilter, err)
+		}
+		s.runFilter = filter
 	}
-	fileFile.Close()
+
+	sets, err := s.batchSets()
+	if err != nil {
+		return err
+	}
+
+	if s.Adapter != "" {
+		if s.adapterOutput, err = s.runAdapter(); err != nil {
+			return err
+		}
+	}
+
+	if manifests := s.manifestsPath(); manifests != "" {
+		if err := s.checkManifestsFlags(); err != nil {
+			return err
+		}
+		s.Template = manifests
+	} else if s.Chart != "" {
+		if err := s.checkChartFlags(); err != nil {
+			return err
+		}
+		s.Template = s.Chart
+	} else {
+		templatePath, err := resolveTemplatePath(s.Template)
+		if err != nil {
+			return err
+		}
+		s.Template = templatePath
+	}
+
+	options := append([]func(*rego.Rego){s.rbac.allowsBuiltin()}, payloadBuiltins()...)
+	if s.Offline {
+		if err := checkOfflinePolicy(s.Policy, s.policyCacheDir()); err != nil {
+			return err
+		}
+		options = append(options, offlineBuiltins())
+	}
+
 	valuesConfig, err := mergeValues(s.Values)
 	if err != nil {
 		return fmt.Errorf("failed merging values files %w ", err)
 	}
 
-	renderedOutput, err := validateAndRender(s.Template, valuesConfig)
+	if err := s.setValues(valuesConfig); err != nil {
+		return err
+	}
+
+	if s.DependencyUpdate || s.DependencyVerify {
+		chartDir, err := findChartRoot(s.Template)
+		if err != nil {
+			return err
+		}
+
+		if s.DependencyUpdate {
+			if s.Offline {
+				return offlineError("helm dependency build of " + chartDir)
+			}
+
+			if err := updateDependencies(chartDir); err != nil {
+				return fmt.Errorf("updating chart dependencies failed: %w", err)
+			}
+		}
+
+		if err := verifyDependencies(chartDir); err != nil {
+			return err
+		}
+	}
+
+	if s.Lint {
+		s.lintChecks, s.lintErr = lintResults(s.Template, valuesConfig, s.renderOptions().Release, false)
+		if s.lintErr != nil && !errors.Is(s.lintErr, LintFailure) {
+			return fmt.Errorf("linting chart failed: %w", s.lintErr)
+		}
+	}
+
+	if err := s.checkKubeVersion(); err != nil {
+		return err
+	}
+
+	switch kubeVersions := s.kubeVersions(); {
+	case len(sets) > 0:
+		err = s.evaluateBatch(sets, options, kubeVersions)
+	case len(kubeVersions) > 0:
+		err = s.evaluateMatrix(valuesConfig, options, kubeVersions)
+	default:
+		err = s.evaluate(valuesConfig, options, s.KubeVersion)
+	}
+	return err
+}
+
+// render - renders the chart, or reads the --manifests as they are
+func (s *EvalCommand) render(valuesConfig map[string]interface{}, options renderOptions) (map[string]string, error) {
+	if manifests := s.manifestsPath(); manifests != "" {
+		return readManifests(manifests, options.Filter)
+	}
+
+	rendered, err := validateAndRender(s.Template, valuesConfig, options)
 	if err != nil {
-		return fmt.Errorf("error while rendering: %w", err)
+		return nil, &RenderError{Template: s.Template, Err: err}
 	}
+	return rendered, nil
+}
 
-	policyInput, err := UnmarshalYamlMap(renderedOutput)
+// evaluate - renders the chart for the given kubernetes version (helm's
+// default when empty) and evaluates the policies against it
+func (s *EvalCommand) evaluate(valuesConfig map[string]interface{}, options []func(*rego.Rego), kubeVersion string) error {
+	renderOpts := s.renderOptions()
+	renderOpts.KubeVersion = kubeVersion
+	if err := s.Hooks.beforeRender(s.Template, kubeVersion); err != nil {
+		return err
+	}
+
+	renderedOutput, err := s.render(valuesConfig, renderOpts)
 	if err != nil {
-		return fmt.Errorf("formatting policy input failed: %w", err)
+		return err
+	}
+
+	if err := s.Hooks.afterRender(s.Template, kubeVersion, renderedOutput); err != nil {
+		return err
+	}
+
+	renderedOutput, defines := splitDefines(renderedOutput)
+	renderedOutput, binary := excludeBinaryOutput(s.Stdout, renderedOutput)
+	if renderedOutput, err = s.limitDocuments(renderedOutput); err != nil {
+		return err
 	}
 
+	chartOutput, testOutput := splitHelmTests(s.Template, renderedOutput)
+	if s.IncludeTests {
+		chartOutput = renderedOutput
+	}
+
+	if s.manifestsPath() != "" {
+		chartOutput, testOutput = renderedOutput, map[string]string{}
+	}
+
+	if len(s.OnlySubcharts) > 0 {
+		if chartOutput, testOutput, err = s.onlySubcharts(chartOutput, testOutput); err != nil {
+			return err
+		}
+	}
+
+	if s.memoryBudget > 0 && renderedSize(chartOutput) > s.memoryBudget {
+		return s.evaluateWithinBudget(chartOutput, defines, binary, valuesConfig, options, kubeVersion)
+	}
+
+	policyInput, err := UnmarshalYamlMap(chartOutput)
+	if err != nil {
+		return &RenderError{Template: s.Template, Err: fmt.Errorf("formatting policy input failed: %w", err)}
+	}
+
+	testsInput, err := UnmarshalYamlMap(testOutput)
+	if err != nil {
+		return &RenderError{Template: s.Template, Err: fmt.Errorf("formatting helm test input failed: %w", err)}
+	}
+
+	policyInput[testsHashName] = testsInput
+	policyInput[definesHashName] = defines
+	objects := renderedObjects(policyInput)
+	writer := s.Writer
+	redact := func(s string) string { return s }
+	if !s.ShowSecrets {
+		redactor := newRedactor(secretValues(append(objects, renderedObjects(testsInput)...)))
+		writer = redactingWriter{writer: s.Writer, redactor: redactor}
+		redact = redactor.Replace
+	}
+
+	checks := append([]RuleResult{}, s.lintChecks...)
+	checksErr := s.lintErr
+	if s.ScanSecrets {
+		secretChecks, secretsErr := secretResults(scanForSecrets(objects, valuesConfig))
+		checks = append(checks, secretChecks...)
+		if checksErr == nil {
+			checksErr = secretsErr
+		}
+	}
+
+	if conventions := s.config.Conventions; conventions.isSet() {
+		conventionChecks, conventionsErr := conventionResults(checkConventions(policyInput, conventions))
+		checks = append(checks, conventionChecks...)
+		if checksErr == nil {
+			checksErr = conventionsErr
+		}
+	}
+
+	if s.activeProfile != "" {
+		findings := checkProfileExpectations(policyInput, s.config.Profiles[s.activeProfile].Expect, redact)
+		expectationChecks, expectationsErr := expectationResults(s.activeProfile, findings)
+		checks = append(checks, expectationChecks...)
+		if checksErr == nil {
+			checksErr = expectationsErr
+		}
+	}
+
+	loadedCRDs, err := s.loadCRDs()
+	if err != nil {
+		return err
+	}
+
+	crds := buildCRDModel(policyInput, loadedCRDs)
+	schemaChecks, crdsErr := schemaResults(checkCustomResources(policyInput, crds))
+	checks = append(checks, schemaChecks...)
+	if checksErr == nil {
+		checksErr = crdsErr
+	}
+
+	if subcharts := subchartDocuments(policyInput); len(subcharts) > 0 {
+		policyInput[subchartsHashName] = subcharts
+	}
+	s.processInput(policyInput)
+
 	policyInput[valuesHashName] = valuesConfig
-	return evalPolicyOnInput(s.Writer, s.Policy, s.Namespace, policyInput)
+	policyInput[kubeVersionHashName] = kubeGitVersion(kubeVersion)
+	policyInput[networkHashName] = buildNetworkModel(objects)
+	policyInput[podspecsHashName] = buildPodSpecs(objects)
+	policyInput[autoscalingHashName] = buildAutoscalingModel(objects)
+	policyInput[availabilityHashName] = buildAvailabilityModel(objects)
+	policyInput[routingHashName] = buildRoutingModel(objects)
+	policyInput[storageHashName] = buildStorageModel(objects)
+	policyInput[hooksHashName] = buildHookPlans(policyInput, testsInput)
+	policyInput[crdsHashName] = crds
+	policyInput[binaryHashName] = binary
+	policyInput[releaseHashName] = renderOpts.Release
+	s.rbac = buildRBACModel(objects)
+	policyInput[rbacHashName] = s.rbac
+	results, err := s.evalRun(writer, policyInput, valuesConfig, options)
+	results, err = s.recordResults(results, checks, err, redact)
+	var violation *ViolationError
+	if s.Interactive && (err == nil || errors.As(err, &violation)) {
+		err = s.browse(results, violation, chartOutput, redact, func(rule string) (RuleResult, FailureDetail, error) {
+			filter := regexp.MustCompile("^" + regexp.QuoteMeta(rule) + "$")
+			rerun, err := evalPolicyOnInput(writer, s.Policy, s.Namespace, filterHooks(filter), policyInput, s.policyCacheDir(), options...)
+			var rerunViolation *ViolationError
+			if errors.As(err, &rerunViolation) {
+				return RuleResult{Name: rule}, rerunViolation.Details[rule], nil
+			}
+
+			if err != nil {
+				return RuleResult{}, FailureDetail{}, err
+			}
+			return rerun[0], FailureDetail{}, nil
+		})
+	}
+	return s.concludeEvaluation(results, err, kubeVersion, chartOutput, redact, checksErr)
+}
+
+// evalRun - evaluates the policies against the input of one run. The
+// policies are compiled and their queries prepared on the first run, and
+// shared by every later one (--kube-versions, --values-set, --memory-budget).
+// With --partial-eval they are specialized to the values first, and every
+// run evaluates what is left of them
+func (s *EvalCommand) evalRun(writer io.Writer, input, valuesConfig map[string]interface{}, options []func(*rego.Rego)) ([]RuleResult, error) {
+	if s.prepared == nil {
+		if s.PartialEval {
+			s.specialized = s.specialize(valuesConfig, options)
+		}
+
+		var err error
+		if s.specialized == nil {
+			s.prepared, err = loadPreparedPolicies(s.Policy, s.Namespace, s.policyCacheDir(), options...)
+		} else {
+			s.prepared, err = preparePolicies(s.specialized, s.Policy, s.Namespace, options...)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+	s.prepared.profile = s.profile
+	return s.prepared.eval(writer, s.ruleHooks(), input)
+}
+
+// ruleHooks - the hooks called before each rule: --run, then the embedder's
+func (s *EvalCommand) ruleHooks() *Hooks {
+	return filterHooks(s.runFilter).then(s.Hooks)
+}
+
+// specialize - the policies specialized to the values for --partial-eval,
+// or nil when they can't be, leaving any error to the evaluation to report
+func (s *EvalCommand) specialize(valuesConfig map[string]interface{}, options []func(*rego.Rego)) *loadedPolicies {
+	loaded, err := loadPolicies(s.Policy, s.policyCacheDir())
+	if err != nil {
+		return nil
+	}
+
+	specialized, count, err := specializePolicies(loaded, s.Namespace, valuesConfig, options)
+	if err != nil {
+		return nil
+	}
+
+	colorstring.Fprint(s.Stdout, "[yellow]"+s.messages.text("partial_eval")+": ")
+	fmt.Fprintln(s.Stdout, s.messages.text("partial_eval_summary", count, len(ruleQueries(loaded.modules))))
+	return specialized
+}
+
+// recordResults - applies the subchart enforcement of the config and
+// --dryrun to the results of a run, then records and prints them after the
+// failed results of the checks run besides the policies, e.g. --scan-secrets,
+// with the assertion diffs and the values explaining failures passed through
+// redact. The results of the checks aren't returned, their failures are
+// applied after the thresholds
+func (s *EvalCommand) recordResults(results, checks []RuleResult, err error, redact func(string) string) ([]RuleResult, error) {
+	results, err = s.enforceSubcharts(results, err)
+	if s.DryRun {
+		results, err = dryRunResults(results, err)
+	}
+	s.results = append(append(s.results, checks...), results...)
+	var violation *ViolationError
+	if err == nil || errors.As(err, &violation) {
+		diffs := map[string][]string{}
+		explain := map[string]*FailureExplanation{}
+		if violation != nil {
+			for rule, ruleDiffs := range violation.Diffs {
+				diffs[rule] = redactDiffs(ruleDiffs, redact)
+			}
+			for rule, detail := range violation.Details {
+				explain[rule] = detail.Explanation.redact(redact)
+			}
+		}
+
+		reporter := newResultReporter(s.Stdout, diffs, s.messages)
+		reporter.plain = s.output() == outputPlainVerbose
+		reporter.explain = explain
+		if hookErr := reporter.hooks().then(s.Hooks).afterRun(append(append([]RuleResult{}, checks...), results...), violation); hookErr != nil {
+			return results, hookErr
+		}
+		reporter.conclude()
+	}
+	return results, err
+}
+
+// checkResult - a finding of a check run besides the policies as a failed
+// result, named the way our policy results read, e.g.
+// convention Service/web in service.yml: missing label app
+func checkResult(check, location, reason string) RuleResult {
+	return RuleResult{Name: fmt.Sprintf("%s %s: %s", check, location, reason)}
+}
+
+// concludeEvaluation - prints how to reproduce the violations of a run,
+// writes their artifacts and applies the thresholds
+func (s *EvalCommand) concludeEvaluation(results []RuleResult, err error, kubeVersion string, rendered map[string]string, redact func(string) string, checksErr error) error {
+	var violation *ViolationError
+	if errors.As(err, &violation) {
+		repro := func(rule string) string { return s.reproCommand(kubeVersion, rule) }
+		printReproCommands(s.Stdout, violation, repro, s.messages)
+		if s.ArtifactsDir != "" {
+			if artifactsErr := s.writeArtifacts(kubeVersion, violation, rendered, repro, redact); artifactsErr != nil {
+				return artifactsErr
+			}
+		}
+	}
+
+	err = s.applyThresholds(s.Stdout, results, err)
+	if err == nil {
+		err = checksErr
+	}
+	return err
+}
+
+// browse - hands the results of a run to the interactive results browser
+// and returns the violations left once the user is done
+func (s *EvalCommand) browse(results []RuleResult, violation *ViolationError, rendered map[string]string, redact func(string) string, rerun func(string) (RuleResult, FailureDetail, error)) error {
+	browser := &resultsBrowser{
+		in:        s.stdinScanner,
+		out:       s.Stdout,
+		results:   results,
+		details:   make(map[string]FailureDetail),
+		diffs:     make(map[string][]string),
+		documents: make(map[string]string),
+		redact:    redact,
+		rerun:     rerun,
+		ascii:     s.ascii(),
+	}
+
+	if violation != nil {
+		browser.details, browser.diffs = violation.Details, violation.Diffs
+	}
+
+	for name, content := range rendered {
+		browser.documents[documentName(name)] = content
+	}
+
+	browser.browse()
+	return browser.violation()
+}
+
+// writeArtifacts - writes the failure artifacts of a run, under a directory
+// per version when evaluating a kubernetes version matrix
+func (s *EvalCommand) writeArtifacts(kubeVersion string, violation *ViolationError, rendered map[string]string, repro, redact func(string) string) error {
+	dir := s.ArtifactsDir
+	if kubeVersion != "" {
+		dir = filepath.Join(dir, "kubernetes-"+kubeVersion)
+	}
+
+	documents := make(map[string]string)
+	for name, content := range rendered {
+		documents[documentName(name)] = content
+	}
+
+	if err := writeFailureArtifacts(dir, violation, documents, repro, redact); err != nil {
+		return err
+	}
+	return writeProvenance(s.ArtifactsDir, s.provenance())
 }
 
 func (s *EvalCommand) setDefaults() {
@@ -54,11 +659,77 @@ func (s *EvalCommand) setDefaults() {
 		s.Writer = os.Stdout
 	}
 
+	if s.Stdin == nil {
+		s.Stdin = os.Stdin
+	}
+
+	if s.Stdout == nil {
+		s.Stdout = os.Stdout
+	}
+	s.stdinScanner = bufio.NewScanner(s.Stdin)
+	s.results = nil
+
 	if !s.Verbose {
 		s.Writer = new(bytes.Buffer)
 	}
+}
+
+// loadConfig - discovers the user and repo config of the run, see
+// DiscoverConfig. Its default policies are evaluated when neither -p nor
+// the profile of the run give any, see applyDefaults
+func (s *EvalCommand) loadConfig() error {
+	config, err := DiscoverConfig(s.Config)
+	if err != nil {
+		return err
+	}
+	s.config = config
+
+	if err := s.checkOutput(); err != nil {
+		return err
+	}
+
+	if err := validateTimestampFormat(s.TimestampFormat); err != nil {
+		return err
+	}
+
+	if s.messages, err = loadMessages(s.Lang); err != nil {
+		return err
+	}
+
+	if err := config.Enforcement.validate(); err != nil {
+		return err
+	}
+
+	if err := config.Conventions.validate(); err != nil {
+		return err
+	}
+
+	s.processors, err = config.Input.processors()
+	return err
+}
+
+// policyCacheDir - where parsed policy modules are cached, or empty when
+// caching is disabled. Profiling disables it, as cached modules don't keep
+// the source locations of their rules and expressions
+func (s *EvalCommand) policyCacheDir() string {
+	switch {
+	case s.NoPolicyCache, s.profile != nil:
+		return ""
+	case s.PolicyCache != "":
+		return s.PolicyCache
+	}
+	return defaultPolicyCacheDir()
+}
 
-	if s.Namespace == "" {
-		s.Namespace = "main"
+func (s *EvalCommand) renderOptions() renderOptions {
+	return renderOptions{
+		Filter:      TemplateFilter{Include: s.IncludeTemplates, Exclude: s.ExcludeTemplates},
+		Fixtures:    s.Fixtures,
+		Defines:     s.Defines,
+		TplValues:   s.TplValues,
+		SecondPass:  s.SecondPass,
+		Chart:       s.Chart != "",
+		APIVersions: s.APIVersions,
+		Release:     newReleaseOptions(s.ReleaseName, s.ReleaseNamespace, s.IsUpgrade),
 	}
 }
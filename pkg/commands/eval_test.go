@@ -2,8 +2,14 @@ package commands_test
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/xchapter7x/hcunit/pkg/commands"
@@ -19,6 +25,19 @@ func TestEvalCommand(t *testing.T) {
 			failsWith error
 			skip      bool
 			verbose   bool
+			lint      bool
+			depVerify bool
+			offline   bool
+			tests     bool
+			fixtures  []string
+			defines   []string
+			scan      bool
+			policies  []string
+			kubeVers  []string
+			kubeVer   string
+			run       string
+			dryRun    bool
+			crds      []string
 		}{
 			{
 				name:      "invalid policy path given",
@@ -131,6 +150,389 @@ func TestEvalCommand(t *testing.T) {
 				policy:    "testdata/policy/individuals/no_passing_valid.rego",
 				failsWith: commands.PolicyFailure,
 			},
+			{
+				name:      "network model available in input",
+				template:  "testdata/workloads",
+				values:    []string{"testdata/values.yml"},
+				policy:    "testdata/policy/individuals/network_in_input.rego",
+				failsWith: nil,
+			},
+			{
+				name:      "pod templates of every workload kind available in input",
+				template:  "testdata/podspecs",
+				values:    []string{"testdata/values.yml"},
+				policy:    "testdata/policy/individuals/podspecs_in_input.rego",
+				failsWith: nil,
+			},
+			{
+				name:      "autoscalers joined with their workloads available in input",
+				template:  "testdata/autoscaling",
+				values:    []string{"testdata/values.yml"},
+				policy:    "testdata/policy/individuals/autoscaling_in_input.rego",
+				failsWith: nil,
+			},
+			{
+				name:      "disruption budgets joined with their workloads available in input",
+				template:  "testdata/availability",
+				values:    []string{"testdata/values.yml"},
+				policy:    "testdata/policy/individuals/availability_in_input.rego",
+				failsWith: nil,
+			},
+			{
+				name:      "hosts, paths and tls of every routing api available in input",
+				template:  "testdata/routing",
+				values:    []string{"testdata/values.yml"},
+				policy:    "testdata/policy/individuals/routing_in_input.rego",
+				failsWith: nil,
+			},
+			{
+				name:      "claims, sizes and storage classes available in input",
+				template:  "testdata/storage",
+				values:    []string{"testdata/values.yml"},
+				policy:    "testdata/policy/individuals/storage_in_input.rego",
+				failsWith: nil,
+			},
+			{
+				name:      "hook plans of every release operation available in input",
+				template:  "testdata/hooks",
+				values:    []string{"testdata/values.yml"},
+				policy:    "testdata/policy/individuals/hooks_in_input.rego",
+				failsWith: nil,
+			},
+			{
+				name:      "crds and their schemas available in input",
+				template:  "testdata/crdchart",
+				values:    []string{"testdata/crdchart/values.yaml"},
+				policy:    "testdata/policy/individuals/crds_in_input.rego",
+				crds:      []string{"testdata/crds/certificates.yaml"},
+				failsWith: nil,
+			},
+			{
+				name:      "rbac model and rbac.allows available to policies",
+				template:  "testdata/workloads",
+				values:    []string{"testdata/values.yml"},
+				policy:    "testdata/policy/individuals/rbac_in_input.rego",
+				failsWith: nil,
+			},
+			{
+				name:      "rbac model keeps namespaces, resource names and api groups apart",
+				template:  "testdata/rbacscoped",
+				values:    []string{"testdata/values.yml"},
+				policy:    "testdata/policy/individuals/rbac_scoped.rego",
+				failsWith: nil,
+			},
+			{
+				name:      "lint on a valid chart with passing policies",
+				template:  "testdata/mychart/templates",
+				values:    []string{"testdata/mychart/values.yaml"},
+				policy:    "testdata/policy/passing",
+				failsWith: nil,
+				lint:      true,
+			},
+			{
+				name:      "lint errors fail an otherwise passing evaluation",
+				template:  "testdata/brokenchart/templates",
+				values:    []string{"testdata/values.yml"},
+				policy:    "testdata/policy/passing",
+				failsWith: commands.LintFailure,
+				lint:      true,
+			},
+			{
+				name:      "dependency verify on subcharts matching the lock",
+				template:  "testdata/umbrella/templates",
+				values:    []string{"testdata/umbrella/values.yaml"},
+				policy:    "testdata/policy/passing",
+				failsWith: nil,
+				depVerify: true,
+			},
+			{
+				name:      "dependency verify on subcharts that drifted from the lock",
+				template:  "testdata/driftchart/templates",
+				values:    []string{"testdata/values.yml"},
+				policy:    "testdata/policy/passing",
+				failsWith: commands.DependencyMismatch,
+				depVerify: true,
+			},
+			{
+				name:      "offline mode rejects policies calling http.send",
+				template:  "testdata/templates",
+				values:    []string{"testdata/values.yml"},
+				policy:    "testdata/policy/individuals/http_send.rego",
+				failsWith: commands.OfflineViolation,
+				offline:   true,
+			},
+			{
+				name:      "offline mode allows policies without network access",
+				template:  "testdata/templates",
+				values:    []string{"testdata/values.yml"},
+				policy:    "testdata/policy/passing",
+				failsWith: nil,
+				offline:   true,
+			},
+			{
+				name:      "helm test hooks exposed under input.tests by default",
+				template:  "testdata/mychart/templates",
+				values:    []string{"testdata/mychart/values.yaml"},
+				policy:    "testdata/policy/individuals/tests_in_input.rego",
+				failsWith: nil,
+			},
+			{
+				name:      "helm test hooks evaluated with the chart when included",
+				template:  "testdata/mychart/templates",
+				values:    []string{"testdata/mychart/values.yaml"},
+				policy:    "testdata/policy/individuals/tests_included.rego",
+				failsWith: nil,
+				tests:     true,
+			},
+			{
+				name:      "library chart partials from charts/ are available to templates",
+				template:  "testdata/appchart/templates",
+				values:    []string{"testdata/values.yml"},
+				policy:    "testdata/policy/individuals/library_in_input.rego",
+				failsWith: nil,
+			},
+			{
+				name:      "library chart evaluated against caller fixtures",
+				template:  "testdata/libchart/templates",
+				values:    []string{"testdata/values.yml"},
+				policy:    "testdata/policy/individuals/library_in_input.rego",
+				failsWith: nil,
+				fixtures:  []string{"testdata/libfixtures"},
+			},
+			{
+				name:      "library chart without fixtures",
+				template:  "testdata/libchart/templates",
+				values:    []string{"testdata/values.yml"},
+				policy:    "testdata/policy/passing",
+				failsWith: commands.LibraryChartWithoutFixtures,
+			},
+			{
+				name:      "named templates rendered into input.defines",
+				template:  "testdata/mychart/templates",
+				values:    []string{"testdata/mychart/values.yaml"},
+				policy:    "testdata/policy/individuals/defines_in_input.rego",
+				failsWith: nil,
+				defines:   []string{"mychart.labels", "mychart.fullname"},
+			},
+			{
+				name:      "embedded config payloads parsed by builtins",
+				template:  "testdata/payloads",
+				values:    []string{"testdata/values.yml"},
+				policy:    "testdata/policy/individuals/payloads_in_input.rego",
+				failsWith: nil,
+			},
+			{
+				name:      "secret scan flags credentials outside of Secrets",
+				template:  "testdata/leaky",
+				values:    []string{"testdata/leaky_values.yaml"},
+				policy:    "testdata/policy/passing",
+				failsWith: commands.SecretsFound,
+				scan:      true,
+			},
+			{
+				name:      "secret scan ignores credentials rendered into Secrets",
+				template:  "testdata/leaky",
+				values:    []string{"testdata/clean_values.yaml"},
+				policy:    "testdata/policy/passing",
+				failsWith: nil,
+				scan:      true,
+			},
+			{
+				name:      "chart root given as the template path resolves to templates/",
+				template:  "testdata/mychart",
+				values:    []string{"testdata/mychart/values.yaml"},
+				policy:    "testdata/policy/individuals/tests_in_input.rego",
+				failsWith: nil,
+			},
+			{
+				name:      "template path which does not exist",
+				template:  "testdata/does-not-exist",
+				values:    []string{"testdata/values.yml"},
+				policy:    "testdata/policy/passing",
+				failsWith: commands.TemplatePathNotFound,
+			},
+			{
+				name:      "template dir without any templates",
+				template:  "testdata/emptytemplates",
+				values:    []string{"testdata/values.yml"},
+				policy:    "testdata/policy/passing",
+				failsWith: commands.NoTemplatesFound,
+			},
+			{
+				name:      "multiple policy paths evaluated together",
+				template:  "testdata/templates",
+				values:    []string{"testdata/values.yml"},
+				policy:    "testdata/policy/passing",
+				policies:  []string{"testdata/policy/individuals/values_in_input.rego"},
+				failsWith: nil,
+			},
+			{
+				name:      "policy path with an unexpected extension",
+				template:  "testdata/templates",
+				values:    []string{"testdata/values.yml"},
+				policy:    "testdata/values.yml",
+				failsWith: commands.InvalidPolicyPath,
+			},
+			{
+				name:      "policy dir without any rego files",
+				template:  "testdata/templates",
+				values:    []string{"testdata/values.yml"},
+				policy:    "testdata/templates",
+				failsWith: commands.InvalidPolicyPath,
+			},
+			{
+				name:      "missing policy among several",
+				template:  "testdata/templates",
+				values:    []string{"testdata/values.yml"},
+				policy:    "testdata/policy/passing",
+				policies:  []string{"testdata/policy/missing.rego"},
+				failsWith: commands.InvalidPolicyPath,
+			},
+			{
+				name:      "crlf line endings in templates and values",
+				template:  "testdata/crlf",
+				values:    []string{"testdata/crlf_values.yml"},
+				policy:    "testdata/policy/individuals/crlf_documents.rego",
+				failsWith: nil,
+			},
+			{
+				name:      "hcunit.assert_equal fails rules with different values",
+				template:  "testdata/templates",
+				values:    []string{"testdata/values.yml"},
+				policy:    "testdata/policy/individuals/assert_equal.rego",
+				failsWith: commands.PolicyFailure,
+			},
+			{
+				name:      "parametrized rules run once per row",
+				template:  "testdata/templates",
+				values:    []string{"testdata/values.yml"},
+				policy:    "testdata/policy/individuals/params.rego",
+				failsWith: nil,
+			},
+			{
+				name:      "parametrized rules fail per row",
+				template:  "testdata/templates",
+				values:    []string{"testdata/values.yml"},
+				policy:    "testdata/policy/individuals/params_missing_row.rego",
+				failsWith: commands.PolicyFailure,
+			},
+			{
+				name:      "deny rules fail when their body holds",
+				template:  "testdata/templates",
+				values:    []string{"testdata/values.yml"},
+				policy:    "testdata/policy/individuals/deny_ingress.rego",
+				failsWith: commands.PolicyFailure,
+			},
+			{
+				name:      "warn rules never fail",
+				template:  "testdata/templates",
+				values:    []string{"testdata/values.yml"},
+				policy:    "testdata/policy/individuals/warn_ingress.rego",
+				failsWith: nil,
+			},
+			{
+				name:      "per document rules fail per object",
+				template:  "testdata/templates",
+				values:    []string{"testdata/values.yml"},
+				policy:    "testdata/policy/individuals/per_document.rego",
+				failsWith: commands.PolicyFailure,
+			},
+			{
+				name:      "dry run rules report violations without failing",
+				template:  "testdata/templates",
+				values:    []string{"testdata/values.yml"},
+				policy:    "testdata/policy/individuals/dryrun.rego",
+				failsWith: nil,
+			},
+			{
+				name:      "dryrun reports the violations of every rule without failing",
+				template:  "testdata/templates",
+				values:    []string{"testdata/values.yml"},
+				policy:    "testdata/policy/individuals/deny_ingress.rego",
+				dryRun:    true,
+				failsWith: nil,
+			},
+			{
+				name:      "kube versions should each pass when the chart supports them",
+				template:  "testdata/kubeversions",
+				values:    []string{"testdata/values.yml"},
+				policy:    "testdata/policy/individuals/kube_versions.rego",
+				kubeVers:  []string{"1.27,1.29", "1.31"},
+				failsWith: nil,
+			},
+			{
+				name:      "kube versions should fail when any version breaks",
+				template:  "testdata/kubeversions",
+				values:    []string{"testdata/values.yml"},
+				policy:    "testdata/policy/individuals/kube_versions.rego",
+				kubeVers:  []string{"1.16,1.29"},
+				failsWith: commands.PolicyFailure,
+			},
+			{
+				name:      "kube versions should be validated",
+				template:  "testdata/kubeversions",
+				values:    []string{"testdata/values.yml"},
+				policy:    "testdata/policy/individuals/kube_versions.rego",
+				kubeVers:  []string{"latest"},
+				failsWith: commands.InvalidKubeVersion,
+			},
+			{
+				name:      "kube version should be in input",
+				template:  "testdata/kubeversions",
+				values:    []string{"testdata/values.yml"},
+				policy:    "testdata/policy/individuals/kube_version_in_input.rego",
+				kubeVers:  []string{"1.29"},
+				failsWith: nil,
+			},
+			{
+				name:      "a single kube version should be in input",
+				template:  "testdata/kubeversions",
+				values:    []string{"testdata/values.yml"},
+				policy:    "testdata/policy/individuals/kube_version_in_input.rego",
+				kubeVer:   "1.29",
+				failsWith: nil,
+			},
+			{
+				name:      "a single kube version can't be combined with kube versions",
+				template:  "testdata/kubeversions",
+				values:    []string{"testdata/values.yml"},
+				policy:    "testdata/policy/individuals/kube_version_in_input.rego",
+				kubeVer:   "1.29",
+				kubeVers:  []string{"1.27"},
+				failsWith: commands.InvalidKubeVersion,
+			},
+			{
+				name:      "run should only evaluate the matching rules",
+				template:  "testdata/templates",
+				values:    []string{"testdata/values.yml"},
+				policy:    "testdata/policy/individuals/params_missing_row.rego",
+				run:       `as "release"$`,
+				failsWith: nil,
+			},
+			{
+				name:      "run should reproduce a failing row on its own",
+				template:  "testdata/templates",
+				values:    []string{"testdata/values.yml"},
+				policy:    "testdata/policy/individuals/params_missing_row.rego",
+				run:       `^data\.main\.expect\["required label"\] with input\.param as "cost-center"$`,
+				failsWith: commands.PolicyFailure,
+			},
+			{
+				name:      "run matching no rules",
+				template:  "testdata/templates",
+				values:    []string{"testdata/values.yml"},
+				policy:    "testdata/policy/individuals/params_missing_row.rego",
+				run:       "no such rule",
+				failsWith: commands.UnmatchedQuery,
+			},
+			{
+				name:      "run should be a valid regular expression",
+				template:  "testdata/templates",
+				values:    []string{"testdata/values.yml"},
+				policy:    "testdata/policy/individuals/params_missing_row.rego",
+				run:       "(",
+				failsWith: commands.InvalidRunFilter,
+			},
 			{
 				name:      "verbosity on success should print trace information",
 				template:  "testdata/templates",
@@ -157,9 +559,22 @@ func TestEvalCommand(t *testing.T) {
 				evalCmd := &commands.EvalCommand{
 					Writer:   stdOut,
 					Template: tt.template,
-					Policy:   tt.policy,
+					Policy:   append([]string{tt.policy}, tt.policies...),
 					Values:   tt.values,
 					Verbose:  tt.verbose,
+					Lint:     tt.lint,
+
+					DependencyVerify: tt.depVerify,
+					Offline:          tt.offline,
+					IncludeTests:     tt.tests,
+					Fixtures:         tt.fixtures,
+					Defines:          tt.defines,
+					ScanSecrets:      tt.scan,
+					KubeVersions:     tt.kubeVers,
+					KubeVersion:      tt.kubeVer,
+					Run:              tt.run,
+					DryRun:           tt.dryRun,
+					CRDs:             tt.crds,
 				}
 				err := evalCmd.Execute([]string{})
 				if err != nil && !errors.Is(err, tt.failsWith) {
@@ -175,9 +590,268 @@ func TestEvalCommand(t *testing.T) {
 				}
 
 				if err == nil && tt.failsWith != nil {
-					t.Errorf("expected a failing policy %w but no failures found", tt.failsWith)
+					t.Errorf("expected a failing policy %v but no failures found", tt.failsWith)
 				}
 			})
 		}
 	})
+
+	t.Run("secret values should be redacted from traces", func(t *testing.T) {
+		for _, tt := range []struct {
+			name        string
+			template    string
+			secrets     []string
+			showSecrets bool
+			leaked      bool
+		}{
+			{"redacted by default", "secrets", []string{"tok-0123456789abcdef"}, false, false},
+			{"shown with show-secrets", "secrets", []string{"tok-0123456789abcdef"}, true, true},
+			{"multi-line values redacted by default", "pemsecrets", []string{"key-0123456789abcdef", "crt-0123456789abcdef"}, false, false},
+			{"multi-line values shown with show-secrets", "pemsecrets", []string{"key-0123456789abcdef", "crt-0123456789abcdef"}, true, true},
+		} {
+			t.Run(tt.name, func(t *testing.T) {
+				stdOut := new(bytes.Buffer)
+				evalCmd := &commands.EvalCommand{
+					Writer:      stdOut,
+					Template:    "testdata/" + tt.template,
+					Policy:      []string{"testdata/policy/individuals/" + tt.template + "_in_input.rego"},
+					Values:      []string{"testdata/" + tt.template + "_values.yaml"},
+					Verbose:     true,
+					ShowSecrets: tt.showSecrets,
+				}
+				if err := evalCmd.Execute([]string{}); err != nil {
+					t.Fatalf("policies should see the real secret values: %v", err)
+				}
+
+				for _, secret := range tt.secrets {
+					if leaked := strings.Contains(stdOut.String(), secret); leaked != tt.leaked {
+						t.Errorf("expected %s in the trace to be %v, got %v:\n%s", secret, tt.leaked, leaked, stdOut.String())
+					}
+				}
+			})
+		}
+	})
+
+	t.Run("secret values should be redacted from assertion diffs", func(t *testing.T) {
+		for _, tt := range []struct {
+			name        string
+			showSecrets bool
+			leaked      bool
+		}{
+			{"redacted by default", false, false},
+			{"shown with show-secrets", true, true},
+		} {
+			t.Run(tt.name, func(t *testing.T) {
+				dir, err := ioutil.TempDir("", "hcunit-artifacts")
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer os.RemoveAll(dir)
+
+				stdOut := new(bytes.Buffer)
+				evalCmd := &commands.EvalCommand{
+					Stdout:       stdOut,
+					Template:     "testdata/secrets",
+					Policy:       []string{"testdata/policy/individuals/assert_equal_secret.rego"},
+					Values:       []string{"testdata/secrets_values.yaml"},
+					ShowSecrets:  tt.showSecrets,
+					ArtifactsDir: dir,
+				}
+				if err := evalCmd.Execute([]string{}); !errors.Is(err, commands.PolicyFailure) {
+					t.Fatalf("expected error:\n%v\ngot:\n%v", commands.PolicyFailure, err)
+				}
+
+				if !strings.Contains(stdOut.String(), "tok-expected") {
+					t.Errorf("expected the diff of the assertion, got:\n%s", stdOut)
+				}
+
+				if leaked := strings.Contains(stdOut.String(), "tok-0123456789abcdef"); leaked != tt.leaked {
+					t.Errorf("expected the secret in the diff to be %v, got %v:\n%s", tt.leaked, leaked, stdOut)
+				}
+
+				diff, err := ioutil.ReadFile(filepath.Join(evalCmd.ArtifactsDir, "01-main-expect-assert-equal-diffs-of-secrets-are-redacted", "diff.txt"))
+				if err != nil {
+					t.Fatalf("expected the diff artifact: %v", err)
+				}
+
+				if leaked := strings.Contains(string(diff), "tok-0123456789abcdef"); leaked != tt.leaked {
+					t.Errorf("expected the secret in diff.txt to be %v, got %v:\n%s", tt.leaked, leaked, diff)
+				}
+			})
+		}
+	})
+
+	t.Run("failure artifacts should be written per failed rule", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "hcunit-artifacts")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		evalCmd := &commands.EvalCommand{
+			Template:     "testdata/templates",
+			Policy:       []string{"testdata/policy/individuals/params_missing_row.rego"},
+			Values:       []string{"testdata/values.yml"},
+			ArtifactsDir: dir,
+		}
+		if err := evalCmd.Execute([]string{}); !errors.Is(err, commands.PolicyFailure) {
+			t.Fatalf("expected error:\n%v\ngot:\n%v", commands.PolicyFailure, err)
+		}
+
+		ruleDir := filepath.Join(dir, "01-main-expect-required-label-with-input-param-as-cost-center")
+		for filename, expected := range map[string]string{
+			"rule.txt":       `data.main.expect["required label"] with input.param as "cost-center"`,
+			"rule.rego":      `expect ["required label"] {`,
+			"documents.yaml": "#something.yml",
+			"trace.txt":      `Enter data.main.expect["required label"]`,
+			"repro.sh":       `hcunit eval -t testdata/templates -c testdata/values.yml -p testdata/policy/individuals/params_missing_row.rego -n main --run '^data\.main\.expect\["required label"\] with input\.param as "cost-center"$' -v`,
+		} {
+			content, err := ioutil.ReadFile(filepath.Join(ruleDir, filename))
+			if err != nil {
+				t.Errorf("expected artifact %s: %v", filename, err)
+				continue
+			}
+
+			if !strings.Contains(string(content), expected) {
+				t.Errorf("expected %s to contain %q, got:\n%s", filename, expected, content)
+			}
+		}
+
+		entries, err := ioutil.ReadDir(dir)
+		if err != nil || len(entries) != 2 {
+			t.Errorf("expected artifacts for the failed rule and their provenance only, got %v (%v)", len(entries), err)
+		}
+
+		content, err := ioutil.ReadFile(filepath.Join(dir, "provenance.json"))
+		if err != nil {
+			t.Fatalf("expected the provenance of the artifacts: %v", err)
+		}
+
+		provenance := commands.Provenance{}
+		if err := json.Unmarshal(content, &provenance); err != nil {
+			t.Fatalf("expected json provenance, got %q: %v", content, err)
+		}
+
+		if len(provenance.Policies) != 1 || provenance.Policies[0].Path != evalCmd.Policy[0] || provenance.Chart.Digest == "" || provenance.ValuesDigest == "" {
+			t.Errorf("expected the digests of the evaluated inputs, got: %+v", provenance)
+		}
+
+		if strings.Join(provenance.Flags, " ") != "--template=testdata/templates --values=testdata/values.yml --policy=testdata/policy/individuals/params_missing_row.rego --namespace=main --artifacts-dir="+dir {
+			t.Errorf("expected the flags of the run, got: %v", provenance.Flags)
+		}
+	})
+
+	t.Run("dry run violations should be reported and counted", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "hcunit-dryrun")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		stdOut := new(bytes.Buffer)
+		evalCmd := &commands.EvalCommand{
+			Template:    "testdata/templates",
+			Policy:      []string{"testdata/policy/individuals/dryrun.rego"},
+			Values:      []string{"testdata/values.yml"},
+			Stdout:      stdOut,
+			ResultsFile: filepath.Join(dir, "results.json"),
+		}
+		if err := evalCmd.Execute([]string{}); err != nil {
+			t.Fatalf("expected dry run violations not to fail the run, got: %v", err)
+		}
+
+		for _, expected := range []string{
+			"DRYRUN: \x1b[0mdata.main.deny[\"ingress without tls\"]\n      ingresses should terminate tls",
+			"[DRYRUN] 1 policy violations found by rules which are not enforced yet",
+			"[SUCCESS]",
+		} {
+			if !strings.Contains(stdOut.String(), expected) {
+				t.Errorf("expected the output to contain %q, got:\n%s", expected, stdOut.String())
+			}
+		}
+
+		content, err := ioutil.ReadFile(evalCmd.ResultsFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		report := commands.RunReport{}
+		if err := json.Unmarshal(content, &report); err != nil {
+			t.Fatalf("expected json results, got %q: %v", content, err)
+		}
+
+		if report.Summary.DryRun != 1 || report.Summary.Failed != 0 || report.ExitCode != 0 {
+			t.Errorf("expected the dry run violation to be counted apart from failures, got: %+v", report.Summary)
+		}
+
+		for _, result := range report.Results {
+			if result.Rule == `data.main.deny["ingress without tls"]` && result.Result != "dryrun" {
+				t.Errorf("expected a dryrun result, got: %+v", result)
+			}
+		}
+	})
+
+	t.Run("interactive mode should browse failures and re-run rules", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "hcunit-interactive")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		policyPath := filepath.Join(dir, "params.rego")
+		failing, err := ioutil.ReadFile("testdata/policy/individuals/params_missing_row.rego")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(policyPath, failing, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		fixed := strings.Replace(string(failing), "labels[input.param]", "labels", 1)
+		stdOut := new(bytes.Buffer)
+		evalCmd := &commands.EvalCommand{
+			Template:    "testdata/templates",
+			Policy:      []string{policyPath},
+			Values:      []string{"testdata/values.yml"},
+			Interactive: true,
+			Stdout:      stdOut,
+			Stdin: &editingReader{
+				reader: strings.NewReader("1\nr 1\nq\n"),
+				edit:   func() error { return ioutil.WriteFile(policyPath, []byte(fixed), 0644) },
+			},
+		}
+		if err := evalCmd.Execute([]string{}); err != nil {
+			t.Errorf("expected the re-run rule to pass after fixing the policy, got: %v", err)
+		}
+
+		for _, expected := range []string{
+			"FAIL\x1b[0m  data.main.expect[\"required label\"] with input.param as \"cost-center\"",
+			`labels[input.param]        │ kind: Ingress`,
+			"trace:",
+			"PASS\x1b[0m  data.main.expect[\"required label\"] with input.param as \"cost-center\"",
+		} {
+			if !strings.Contains(stdOut.String(), expected) {
+				t.Errorf("expected the browser output to contain %q, got:\n%s", expected, stdOut.String())
+			}
+		}
+	})
+}
+
+// editingReader - edits a file once its input has been read, i.e. while the
+// user is browsing the results
+type editingReader struct {
+	reader io.Reader
+	edit   func() error
+}
+
+func (s *editingReader) Read(p []byte) (int, error) {
+	n, err := s.reader.Read(p)
+	if s.edit != nil {
+		if editErr := s.edit(); editErr != nil {
+			return n, editErr
+		}
+		s.edit = nil
+	}
+	return n, err
 }
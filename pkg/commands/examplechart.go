@@ -0,0 +1,205 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// exampleChart - a tiny chart and a policy per rule style hcunit supports,
+// by file path. Every policy passes against the chart with its values
+var exampleChart = map[string]string{
+	"Chart.yaml": `apiVersion: v1
+name: hcunit-example
+version: 0.1.0
+description: a tiny chart demonstrating every rule style hcunit supports
+`,
+	"values.yaml": `replicaCount: 2
+image:
+  repository: nginx
+  tag: "1.17"
+service:
+  port: 80
+debug: true
+`,
+	"README.md": `# hcunit-example
+
+A tiny chart with a policy per rule style hcunit supports. Evaluate it with:
+
+    hcunit eval -t . -c values.yaml -p policy
+
+- policy/expect.rego: expect rules must hold
+- policy/assert.rego: assert rules must hold, hcunit.assert_equal prints a diff when they don't
+- policy/deny.rego: deny rules must not hold
+- policy/warn.rego: warn rules are reported when they hold, without failing the run
+- policy/params.rego: a rule evaluated once per row of its parameter table
+- policy/per_document.rego: a rule evaluated once per rendered object
+- policy/metadata.rego: descriptions and per document evaluation, by rule name
+`,
+	"templates/_helpers.tpl": `{{- define "example.labels" -}}
+app.kubernetes.io/name: {{ .Chart.Name }}
+app.kubernetes.io/instance: {{ .Release.Name }}
+{{- end -}}
+`,
+	"templates/deployment.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: {{ .Release.Name }}-{{ .Chart.Name }}
+  labels:
+{{ include "example.labels" . | indent 4 }}
+spec:
+  replicas: {{ .Values.replicaCount }}
+  selector:
+    matchLabels:
+{{ include "example.labels" . | indent 6 }}
+  template:
+    metadata:
+      labels:
+{{ include "example.labels" . | indent 8 }}
+    spec:
+      containers:
+        - name: {{ .Chart.Name }}
+          image: "{{ .Values.image.repository }}:{{ .Values.image.tag }}"
+          envFrom:
+            - configMapRef:
+                name: {{ .Release.Name }}-{{ .Chart.Name }}
+          ports:
+            - containerPort: {{ .Values.service.port }}
+`,
+	"templates/service.yaml": `apiVersion: v1
+kind: Service
+metadata:
+  name: {{ .Release.Name }}-{{ .Chart.Name }}
+  labels:
+{{ include "example.labels" . | indent 4 }}
+spec:
+  selector:
+{{ include "example.labels" . | indent 4 }}
+  ports:
+    - port: {{ .Values.service.port }}
+`,
+	"templates/configmap.yaml": `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: {{ .Release.Name }}-{{ .Chart.Name }}
+  labels:
+{{ include "example.labels" . | indent 4 }}
+data:
+  LOG_LEVEL: {{ if .Values.debug }}debug{{ else }}info{{ end }}
+`,
+	"policy/expect.rego": `package main
+
+# expect rules must hold: each passes when its body is true
+expect ["deployment runs the configured number of replicas"] {
+  input["deployment.yaml"].spec.replicas == input.values.replicaCount
+}
+`,
+	"policy/assert.rego": `package main
+
+# assert rules behave like expect rules. hcunit.assert_equal fails the rule
+# with a diff of the two values when they differ
+assert ["service selects the deployment's pods"] {
+  hcunit.assert_equal(input["service.yaml"].spec.selector, input["deployment.yaml"].spec.template.metadata.labels)
+}
+`,
+	"policy/deny.rego": `package main
+
+# deny rules must not hold: each fails when its body is true. input.podspecs
+# holds the init, regular and ephemeral containers of every workload
+deny ["containers run as root"] {
+  container := input.podspecs[_].containers[_]
+  container.securityContext.runAsUser == 0
+}
+`,
+	"policy/warn.rego": `package main
+
+# warn rules are reported when their body is true, without failing the run
+warn ["debug logging is enabled"] {
+  input["configmap.yaml"].data.LOG_LEVEL == "debug"
+}
+`,
+	"policy/params.rego": `package main
+
+# params maps rule names to rows: the rule is evaluated (and reported) once
+# per row, with the row available as input.param
+params := {"deployment label": ["app.kubernetes.io/name", "app.kubernetes.io/instance"]}
+
+expect ["deployment label"] {
+  input["deployment.yaml"].metadata.labels[input.param]
+}
+`,
+	"policy/per_document.rego": `package main
+
+# per_document rules (see metadata.rego) are evaluated (and reported) once
+# per rendered object, with the object available as input.document
+expect ["objects carry a name label"] {
+  input.document.metadata.labels["app.kubernetes.io/name"]
+}
+`,
+	"policy/metadata.rego": `package main
+
+# metadata maps rule names to their metadata: a description printed under
+# the rule when it fails or warns, and per_document evaluation
+metadata := {
+  "objects carry a name label": {
+    "description": "every object should be selectable by its app.kubernetes.io/name label",
+    "per_document": true,
+  },
+  "debug logging is enabled": {
+    "description": "debug logging is verbose and may log request payloads, set debug: false in production",
+  },
+}
+`,
+}
+
+type ExampleChartCommand struct {
+	Writer io.Writer
+	Output string `short:"o" long:"output" default:"hcunit-example" description:"directory to write the example chart and policies to"`
+	Force  bool   `long:"force" description:"overwrite the example files if the directory already has them"`
+}
+
+func (s *ExampleChartCommand) Execute(args []string) error {
+	s.setDefaults()
+	files := make([]string, 0, len(exampleChart))
+	for file := range exampleChart {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	if !s.Force {
+		for _, file := range files {
+			target := filepath.Join(s.Output, filepath.FromSlash(file))
+			if _, err := os.Stat(target); err == nil {
+				return fmt.Errorf("%w: %s", OutputFileExists, target)
+			}
+		}
+	}
+
+	for _, file := range files {
+		target := filepath.Join(s.Output, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("creating %s failed: %w", filepath.Dir(target), err)
+		}
+
+		if err := writeOutput(s.Writer, target, exampleChart[file], true); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(s.Writer, "wrote an example chart and policies to %s, evaluate them with:\n", s.Output)
+	fmt.Fprintf(s.Writer, "  hcunit eval -t %s -c %s -p %s\n",
+		s.Output, filepath.Join(s.Output, "values.yaml"), filepath.Join(s.Output, "policy"))
+	return nil
+}
+
+func (s *ExampleChartCommand) setDefaults() {
+	if s.Writer == nil {
+		s.Writer = os.Stdout
+	}
+
+	if s.Output == "" {
+		s.Output = "hcunit-example"
+	}
+}
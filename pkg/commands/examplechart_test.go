@@ -0,0 +1,67 @@
+package commands_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestExampleChartCommand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hcunit-example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	output := filepath.Join(dir, "example")
+	exampleCmd := &commands.ExampleChartCommand{Writer: new(bytes.Buffer), Output: output}
+	if err := exampleCmd.Execute([]string{}); err != nil {
+		t.Fatalf("writing the example chart failed: %v", err)
+	}
+
+	t.Run("the example policies pass against the example chart", func(t *testing.T) {
+		stdOut := new(bytes.Buffer)
+		evalCmd := &commands.EvalCommand{
+			Stdout:   stdOut,
+			Template: output,
+			Values:   []string{filepath.Join(output, "values.yaml")},
+			Policy:   []string{filepath.Join(output, "policy")},
+		}
+		if err := evalCmd.Execute([]string{}); err != nil {
+			t.Fatalf("expected the example policies to pass, got: %v\n%s", err, stdOut.String())
+		}
+
+		for _, expected := range []string{
+			"PASS: \x1b[0mdata.main.expect[\"deployment runs the configured number of replicas\"]",
+			"PASS: \x1b[0mdata.main.assert[\"service selects the deployment's pods\"]",
+			"PASS: \x1b[0mdata.main.deny[\"containers run as root\"]",
+			"WARN: \x1b[0mdata.main.warn[\"debug logging is enabled\"]",
+			"set debug: false in production",
+			"PASS: \x1b[0mdata.main.expect[\"deployment label\"]",
+			"PASS: \x1b[0mdata.main.expect[\"objects carry a name label\"] on Deployment/hcunit-name-hcunit in deployment.yaml",
+			"PASS: \x1b[0mdata.main.expect[\"objects carry a name label\"] on ConfigMap/hcunit-name-hcunit in configmap.yaml",
+		} {
+			if !strings.Contains(stdOut.String(), expected) {
+				t.Errorf("expected output to contain %q, got:\n%s", expected, stdOut.String())
+			}
+		}
+	})
+
+	t.Run("existing example files are only replaced with force", func(t *testing.T) {
+		err := exampleCmd.Execute([]string{})
+		if !errors.Is(err, commands.OutputFileExists) {
+			t.Errorf("expected error:\n%v\ngot:\n%v", commands.OutputFileExists, err)
+		}
+
+		exampleCmd.Force = true
+		if err := exampleCmd.Execute([]string{}); err != nil {
+			t.Errorf("expected --force to overwrite the example, got: %v", err)
+		}
+	})
+}
@@ -0,0 +1,168 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+)
+
+// explainVar - the variable the operands of a failed expression are bound
+// to, to read their values back
+const explainVar = "__hcunit_explain__"
+
+// FailureExplanation - why an expect/assert rule failed: the first
+// expression of its body which can't be satisfied, where it is in its
+// policy, and the values of its operands, bound as far as the body got
+type FailureExplanation struct {
+	Expression string
+	File       string
+	Line       int
+	Values     []ExplainedValue
+}
+
+// ExplainedValue - an operand of a failed expression and its value, empty
+// with Undefined when the operand has none, e.g. a missing key
+type ExplainedValue struct {
+	Term      string
+	Value     string
+	Undefined bool
+}
+
+// explain - re-evaluates the body of a failed rule one expression at a
+// time against the input of the failed run, the way opa test --explain
+// fails narrows down a failure, and explains the first expression after
+// which the body has no solution left. Rules defined more than once, or
+// failures which can't be narrowed down, have no explanation
+func (s *preparedPolicies) explain(ctx context.Context, querySuffix string, input ast.Value) *FailureExplanation {
+	rule := s.compiledRule(querySuffix)
+	if rule == nil {
+		return nil
+	}
+
+	options := append(append([]func(*rego.Rego){}, s.options...), rego.Compiler(s.explained), rego.ParsedInput(input))
+	eval := func(body ast.Body) (rego.ResultSet, error) {
+		return rego.New(append([]func(*rego.Rego){rego.ParsedQuery(body)}, options...)...).Eval(ctx)
+	}
+
+	for i, expr := range rule.Body {
+		results, err := eval(rule.Body[:i+1])
+		if err != nil || len(results) > 0 {
+			if err != nil {
+				return nil
+			}
+			continue
+		}
+
+		explanation := &FailureExplanation{Expression: expressionText(expr)}
+		if expr.Location != nil {
+			explanation.File, explanation.Line = expr.Location.File, expr.Location.Row
+		}
+
+		for _, operand := range explainedOperands(expr) {
+			value := ExplainedValue{Term: sourceText(operand.Location, operand.String()), Undefined: true}
+			bound := append(rule.Body[:i:i], ast.Equality.Expr(ast.VarTerm(explainVar), operand))
+			if results, err := eval(bound); err == nil && len(results) > 0 {
+				if v, ok := results[0].Bindings[explainVar]; ok {
+					if term, err := ast.InterfaceToValue(v); err == nil {
+						value.Value, value.Undefined = term.String(), false
+					}
+				}
+			}
+			explanation.Values = append(explanation.Values, value)
+		}
+		return explanation
+	}
+	return nil
+}
+
+// compiledRule - the only rule of the namespace a query suffix evaluates,
+// compiled from the source of the policies for their locations
+func (s *preparedPolicies) compiledRule(querySuffix string) *ast.Rule {
+	if s.explained == nil {
+		s.explained = compilePolicies(s.loaded.sourceModules(s.policies))
+	}
+
+	if s.explained.Failed() {
+		return nil
+	}
+
+	var found *ast.Rule
+	for _, mod := range s.explained.Modules {
+		if mod.Package.Path.String() != "data."+s.namespace {
+			continue
+		}
+
+		for _, rule := range mod.Rules {
+			if fmt.Sprintf("%s[%s]", rule.Head.Name, rule.Head.Key) != querySuffix {
+				continue
+			}
+
+			if found != nil {
+				return nil
+			}
+			found = rule
+		}
+	}
+	return found
+}
+
+// explainedOperands - the operands of an expression worth showing the
+// value of: every operand of a call, e.g. both sides of ==, or the term
+// itself, leaving out constants
+func explainedOperands(expr *ast.Expr) []*ast.Term {
+	var terms []*ast.Term
+	switch t := expr.Terms.(type) {
+	case *ast.Term:
+		terms = []*ast.Term{t}
+	case []*ast.Term:
+		if expr.IsCall() {
+			terms = expr.Operands()
+		}
+	}
+
+	operands := make([]*ast.Term, 0, len(terms))
+	for _, term := range terms {
+		if !ast.IsConstant(term.Value) {
+			operands = append(operands, term)
+		}
+	}
+	return operands
+}
+
+// expressionText - the policy source of an expression, along with its
+// negation: the location of a negated expression starts after the not
+func expressionText(expr *ast.Expr) string {
+	text := sourceText(expr.Location, expr.String())
+	if expr.Negated && !strings.HasPrefix(text, "not ") {
+		return "not " + text
+	}
+	return text
+}
+
+// sourceText - the policy source of a node, the first line of it for
+// multiline nodes, or the given fallback without a location
+func sourceText(location *ast.Location, fallback string) string {
+	if location == nil || len(location.Text) == 0 {
+		return fallback
+	}
+	return strings.SplitN(string(location.Text), "\n", 2)[0]
+}
+
+// redact - the explanation with the values passed through redact, e.g. to
+// hide the values of Secrets
+func (e *FailureExplanation) redact(redact func(string) string) *FailureExplanation {
+	if e == nil {
+		return nil
+	}
+
+	redacted := *e
+	redacted.Values = make([]ExplainedValue, len(e.Values))
+	for i, value := range e.Values {
+		value.Value = redact(value.Value)
+		redacted.Values[i] = value
+	}
+	return &redacted
+}
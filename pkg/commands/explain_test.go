@@ -0,0 +1,122 @@
+package commands_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestEvalExplainsFailures(t *testing.T) {
+	for _, tt := range []struct {
+		name        string
+		run         string
+		lang        string
+		showSecrets bool
+		contains    []string
+		excludes    []string
+	}{
+		{
+			name: "the first failing expression and the values it compared",
+			run:  "explains the comparison which failed",
+			contains: []string{
+				"failed at testdata/policy/individuals/explain.rego:7: settings.replicas == \"3\"\n",
+				"  settings.replicas = \"1\"\n",
+			},
+			excludes: []string{"explain.rego:6"},
+		},
+		{
+			name: "references without a value are undefined",
+			run:  "explains references which are undefined",
+			contains: []string{
+				"failed at testdata/policy/individuals/explain.rego:12: settings.missing == \"value\"\n",
+				"  settings.missing = undefined\n",
+			},
+		},
+		{
+			name:     "secret values are redacted",
+			run:      "explains values of secrets redacted",
+			contains: []string{"  settings.password = \"<redacted>\"\n"},
+			excludes: []string{"hunter2"},
+		},
+		{
+			name:        "secret values are shown with --show-secrets",
+			run:         "explains values of secrets redacted",
+			showSecrets: true,
+			contains:    []string{"  settings.password = \"hunter2\"\n"},
+		},
+		{
+			name: "negated expressions keep their negation",
+			run:  "explains negated expressions",
+			contains: []string{
+				"failed at testdata/policy/individuals/explain.rego:24: not settings.replicas == \"1\"\n",
+				"  settings.replicas = \"1\"\n",
+			},
+		},
+		{
+			name:     "deny rules are not explained",
+			run:      "deny rules are not explained",
+			excludes: []string{"failed at"},
+		},
+		{
+			name:     "explanations are translated",
+			run:      "explains references which are undefined",
+			lang:     "de",
+			contains: []string{"fehlgeschlagen bei testdata/policy/individuals/explain.rego:12", "settings.missing = undefiniert"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			stdOut := new(bytes.Buffer)
+			evalCmd := &commands.EvalCommand{
+				Stdout:      stdOut,
+				Template:    "testdata/explain",
+				Values:      []string{"testdata/values.yml"},
+				Policy:      []string{"testdata/policy/individuals/explain.rego"},
+				Run:         tt.run,
+				Lang:        tt.lang,
+				ShowSecrets: tt.showSecrets,
+			}
+			err := evalCmd.Execute([]string{})
+			if !errors.Is(err, commands.PolicyFailure) {
+				t.Fatalf("expected error:\n%v\ngot:\n%v\n%s", commands.PolicyFailure, err, stdOut)
+			}
+
+			for _, s := range tt.contains {
+				if !strings.Contains(stdOut.String(), s) {
+					t.Errorf("expected %q in:\n%s", s, stdOut)
+				}
+			}
+
+			for _, s := range tt.excludes {
+				if strings.Contains(stdOut.String(), s) {
+					t.Errorf("expected no %q in:\n%s", s, stdOut)
+				}
+			}
+		})
+	}
+}
+
+func TestFailureDetailExplanation(t *testing.T) {
+	var violation *commands.ViolationError
+	err := (&commands.EvalCommand{
+		Stdout:   new(bytes.Buffer),
+		Template: "testdata/explain",
+		Values:   []string{"testdata/values.yml"},
+		Policy:   []string{"testdata/policy/individuals/explain.rego"},
+		Run:      "explains the comparison which failed",
+	}).Execute([]string{})
+	if !errors.As(err, &violation) {
+		t.Fatalf("expected a violation, got: %v", err)
+	}
+
+	explanation := violation.Details[`data.main.expect["explains the comparison which failed"]`].Explanation
+	if explanation == nil {
+		t.Fatalf("expected an explanation in %+v", violation.Details)
+	}
+
+	if explanation.Line != 7 || len(explanation.Values) != 1 || explanation.Values[0].Value != `"1"` {
+		t.Errorf("unexpected explanation: %+v", explanation)
+	}
+}
@@ -0,0 +1,167 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+var ChecksumMismatch = errors.New("downloaded content does not match the pinned sha256")
+
+// FetchOptions - retry and timeout settings applied to every remote
+// operation. Proxies are taken from HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+type FetchOptions struct {
+	Retries int           `yaml:"retries"`
+	Timeout time.Duration `yaml:"timeout"`
+	Backoff time.Duration `yaml:"backoff"`
+}
+
+var defaultFetchOptions = FetchOptions{
+	Retries: 3,
+	Timeout: 60 * time.Second,
+	Backoff: time.Second,
+}
+
+// withDefaults - fills any unset option from defaultFetchOptions
+func (s FetchOptions) withDefaults() FetchOptions {
+	if s.Retries == 0 {
+		s.Retries = defaultFetchOptions.Retries
+	}
+
+	if s.Timeout == 0 {
+		s.Timeout = defaultFetchOptions.Timeout
+	}
+
+	if s.Backoff == 0 {
+		s.Backoff = defaultFetchOptions.Backoff
+	}
+	return s
+}
+
+// permanentError - a failure retrying won't fix, like a 404
+type permanentError struct {
+	err error
+}
+
+func (s permanentError) Error() string { return s.err.Error() }
+func (s permanentError) Unwrap() error { return s.err }
+
+// withRetries - calls f until it succeeds, returns a permanentError or runs
+// out of retries, doubling the backoff between each attempt
+func (s FetchOptions) withRetries(f func(ctx context.Context) error) error {
+	var err error
+	backoff := s.Backoff
+	for attempt := 0; attempt <= s.Retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), s.Timeout)
+		err = f(ctx)
+		cancel()
+
+		var permanent permanentError
+		if err == nil || errors.As(err, &permanent) {
+			return err
+		}
+	}
+	return fmt.Errorf("giving up after %d attempts: %w", s.Retries+1, err)
+}
+
+// httpGet - downloads url, retrying on network errors, 429s and 5xxs
+func (s FetchOptions) httpGet(url string) ([]byte, error) {
+	client := &http.Client{
+		Transport: &http.Transport{Proxy: http.ProxyFromEnvironment},
+	}
+
+	var body []byte
+	err := s.withRetries(func(ctx context.Context) error {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		if err != nil {
+			return permanentError{err}
+		}
+
+		resp, err := client.Do(req.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		switch {
+		case resp.StatusCode == http.StatusOK:
+			body, err = ioutil.ReadAll(resp.Body)
+			return err
+		case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+			return fmt.Errorf("GET %s: unexpected status %s", url, resp.Status)
+		}
+		return permanentError{fmt.Errorf("GET %s: unexpected status %s", url, resp.Status)}
+	})
+	return body, err
+}
+
+// httpPost - posts body to url with the given headers, retrying on network
+// errors, 429s and 5xxs
+func (s FetchOptions) httpPost(url string, body []byte, headers map[string]string) error {
+	client := &http.Client{
+		Transport: &http.Transport{Proxy: http.ProxyFromEnvironment},
+	}
+
+	return s.withRetries(func(ctx context.Context) error {
+		req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return permanentError{err}
+		}
+
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+
+		resp, err := client.Do(req.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		switch {
+		case resp.StatusCode >= 200 && resp.StatusCode < 300:
+			return nil
+		case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+			return fmt.Errorf("POST %s: unexpected status %s", url, resp.Status)
+		}
+		return permanentError{fmt.Errorf("POST %s: unexpected status %s", url, resp.Status)}
+	})
+}
+
+// run - executes an external fetcher (git, oras, helm) with retries and a
+// timeout per attempt. Proxy variables are inherited from our environment
+func (s FetchOptions) run(name string, args ...string) error {
+	return s.withRetries(func(ctx context.Context) error {
+		out, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("%s %s failed: %w: %s", name, strings.Join(args, " "), err, out)
+		}
+		return nil
+	})
+}
+
+// verifyChecksum - compares content against a pinned sha256, given either
+// as bare hex or prefixed with "sha256:". An empty pin always passes
+func verifyChecksum(content []byte, pinned string) error {
+	if pinned == "" {
+		return nil
+	}
+
+	actual := fmt.Sprintf("%x", sha256.Sum256(content))
+	if strings.TrimPrefix(pinned, "sha256:") != actual {
+		return fmt.Errorf("%w: expected %s got sha256:%s", ChecksumMismatch, pinned, actual)
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+type GeneratePolicyCommand struct {
+	Writer    io.Writer
+	Template  string   `short:"t" long:"template" description:"path to yaml template you would like to render"`
+	Values    []string `short:"c" long:"values" description:"path to values file(s) you would like to use for rendering"`
+	Namespace string   `short:"n" long:"namespace" description:"package name of the generated policy"`
+	Output    string   `short:"o" long:"output" description:"write the generated policy to this file instead of stdout"`
+	Force     bool     `long:"force" description:"overwrite the --output file if it already exists"`
+}
+
+func (s *GeneratePolicyCommand) Execute(args []string) error {
+	s.setDefaults()
+	templatePath, err := resolveTemplatePath(s.Template)
+	if err != nil {
+		return err
+	}
+	s.Template = templatePath
+
+	valuesConfig, err := mergeValues(s.Values)
+	if err != nil {
+		return fmt.Errorf("failed merging values files %w ", err)
+	}
+
+	renderedOutput, err := validateAndRender(s.Template, valuesConfig, renderOptions{})
+	if err != nil {
+		return &RenderError{Template: s.Template, Err: err}
+	}
+
+	chartOutput, _ := splitHelmTests(s.Template, renderedOutput)
+	documents, err := UnmarshalYamlMap(chartOutput)
+	if err != nil {
+		return &RenderError{Template: s.Template, Err: fmt.Errorf("formatting policy input failed: %w", err)}
+	}
+
+	policy := generatePolicy(s.Namespace, s.Template, documents)
+	return writeOutput(s.Writer, s.Output, policy, s.Force)
+}
+
+func (s *GeneratePolicyCommand) setDefaults() {
+	if s.Writer == nil {
+		s.Writer = os.Stdout
+	}
+
+	if s.Namespace == "" {
+		s.Namespace = "main"
+	}
+}
+
+// generatePolicy - a starting policy asserting what the chart renders today:
+// the objects each template renders, replica counts, image registries and
+// resource limits. It is meant to be refined, not kept as is
+func generatePolicy(namespace, templatePath string, documents map[string]interface{}) string {
+	names := make([]string, 0, len(documents))
+	for name := range documents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	out := new(bytes.Buffer)
+	fmt.Fprintf(out, "package %s\n\n", namespace)
+	fmt.Fprintf(out, "# generated by `hcunit generate-policy -t %s`\n", templatePath)
+	fmt.Fprintf(out, "# these rules capture what the chart renders today, refine or delete them\n")
+
+	seen := make(map[string]bool)
+	rule := func(name string, body ...string) {
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+		fmt.Fprintf(out, "\nexpect [%q] {\n  %s\n}\n", name, strings.Join(body, "\n  "))
+	}
+
+	for _, name := range names {
+		refs := make(map[string]map[string]interface{})
+		order := make([]string, 0)
+		switch doc := documents[name].(type) {
+		case map[string]interface{}:
+			if isObject(doc) {
+				ref := fmt.Sprintf("input[%q]", name)
+				refs[ref] = doc
+				order = append(order, ref)
+			}
+		case []interface{}:
+			for i, d := range doc {
+				if obj, ok := d.(map[string]interface{}); ok && isObject(obj) {
+					ref := fmt.Sprintf("input[%q][%d]", name, i)
+					refs[ref] = obj
+					order = append(order, ref)
+				}
+			}
+		}
+
+		for _, ref := range order {
+			generateObjectRules(rule, name, ref, refs[ref])
+		}
+	}
+	return out.String()
+}
+
+func generateObjectRules(rule func(string, ...string), template, ref string, obj map[string]interface{}) {
+	id := objectRef(obj)
+	rule(
+		fmt.Sprintf("%s renders %s", template, id),
+		fmt.Sprintf("%s.kind == %s", ref, regoLiteral(objectKind(obj))),
+		fmt.Sprintf("%s.metadata.name == %s", ref, regoLiteral(objectName(obj))),
+	)
+
+	if replicas := getField(obj, "spec", "replicas"); replicas != nil {
+		rule(
+			fmt.Sprintf("%s has %v replicas", id, replicas),
+			fmt.Sprintf("%s.spec.replicas == %s", ref, regoLiteral(replicas)),
+		)
+	}
+
+	path, ok := podTemplatePath(objectKind(obj))
+	if !ok {
+		return
+	}
+
+	podSpec := ref + strings.Join(append(append([]string{""}, path...), "spec"), ".")
+	for _, containerType := range containerTypes {
+		field := containerType.Field
+		for _, c := range getSlice(obj, append(path, "spec", field)...) {
+			container, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			containerName := getString(container, "name")
+			selectContainer := []string{
+				fmt.Sprintf("container := %s.%s[_]", podSpec, field),
+				fmt.Sprintf("container.name == %s", regoLiteral(containerName)),
+			}
+			subject := fmt.Sprintf("%s %s %s", id, strings.TrimSuffix(field, "s"), containerName)
+
+			if image := getString(container, "image"); image != "" {
+				registry, explicit := imageRegistry(image)
+				checks := []string{fmt.Sprintf("startswith(container.image, %s)", regoLiteral(registry+"/"))}
+				switch {
+				case !explicit && !strings.Contains(image, "/"):
+					checks = []string{`not contains(container.image, "/")`}
+				case !explicit:
+					checks = []string{`parts := split(container.image, "/")`, `not contains(parts[0], ".")`}
+				}
+				rule(fmt.Sprintf("%s image comes from %s", subject, registry), append(selectContainer, checks...)...)
+			}
+
+			limits := getMap(container, "resources", "limits")
+			resources := make([]string, 0, len(limits))
+			for resource := range limits {
+				resources = append(resources, resource)
+			}
+			sort.Strings(resources)
+			for _, resource := range resources {
+				rule(
+					fmt.Sprintf("%s limits %s to %v", subject, resource, limits[resource]),
+					append(selectContainer, fmt.Sprintf("container.resources.limits[%q] == %s", resource, regoLiteral(limits[resource])))...,
+				)
+			}
+		}
+	}
+}
+
+// imageRegistry - the registry an image is pulled from, and whether it is
+// named in the image or implied (docker hub)
+func imageRegistry(image string) (string, bool) {
+	parts := strings.SplitN(image, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		return parts[0], true
+	}
+	return "docker.io", false
+}
+
+func regoLiteral(value interface{}) string {
+	literal, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%q", fmt.Sprint(value))
+	}
+	return string(literal)
+}
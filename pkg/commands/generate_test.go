@@ -0,0 +1,104 @@
+package commands_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestGeneratePolicyCommand(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		template  string
+		values    []string
+		failsWith error
+		contains  []string
+	}{
+		{
+			name:     "generates assertions for objects, replicas, registries and limits",
+			template: "testdata/generate",
+			contains: []string{
+				"package main",
+				`expect ["app.yml renders Deployment/hcunit-name-app"]`,
+				`input["app.yml"][0].spec.replicas == 3`,
+				`expect ["Deployment/hcunit-name-app container app image comes from quay.io"]`,
+				`expect ["Deployment/hcunit-name-app initContainer migrate image comes from docker.io"]`,
+				`container.resources.limits["memory"] == "256Mi"`,
+				`expect ["app.yml renders Service/hcunit-name-app"]`,
+			},
+		},
+		{
+			name:     "generates assertions for ephemeral containers",
+			template: "testdata/podspecs",
+			contains: []string{
+				`expect ["Pod/hcunit-name-debug ephemeralContainer debugger image comes from docker.io"]`,
+				`container := input["pod.yml"].spec.ephemeralContainers[_]`,
+			},
+		},
+		{
+			name:     "a chart with values",
+			template: "testdata/mychart",
+			values:   []string{"testdata/mychart/values.yaml"},
+			contains: []string{`expect ["deployment.yaml renders Deployment/hcunit-name-hcunit"]`},
+		},
+		{
+			name:      "a template path which doesnt exist",
+			template:  "testdata/doesnotexist",
+			failsWith: commands.TemplatePathNotFound,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			stdOut := new(bytes.Buffer)
+			generateCmd := &commands.GeneratePolicyCommand{
+				Writer:   stdOut,
+				Template: tt.template,
+				Values:   tt.values,
+			}
+			err := generateCmd.Execute([]string{})
+			if !errors.Is(err, tt.failsWith) {
+				t.Fatalf("expected error:\n%v\ngot:\n%v", tt.failsWith, err)
+			}
+
+			for _, expected := range tt.contains {
+				if !strings.Contains(stdOut.String(), expected) {
+					t.Errorf("expected policy to contain %q, got:\n%s", expected, stdOut.String())
+				}
+			}
+		})
+	}
+
+	t.Run("the generated policy passes against the chart it was generated from", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "hcunit-generate")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		policyPath := filepath.Join(dir, "generated.rego")
+		generateCmd := &commands.GeneratePolicyCommand{Template: "testdata/generate", Output: policyPath}
+		if err := generateCmd.Execute([]string{}); err != nil {
+			t.Fatalf("generating policy failed: %v", err)
+		}
+
+		evalCmd := &commands.EvalCommand{Template: "testdata/generate", Policy: []string{policyPath}}
+		if err := evalCmd.Execute([]string{}); err != nil {
+			t.Errorf("expected the generated policy to pass, got: %v", err)
+		}
+
+		err = generateCmd.Execute([]string{})
+		if !errors.Is(err, commands.OutputFileExists) {
+			t.Errorf("expected error:\n%v\ngot:\n%v", commands.OutputFileExists, err)
+		}
+
+		generateCmd.Force = true
+		if err := generateCmd.Execute([]string{}); err != nil {
+			t.Errorf("expected --force to overwrite the policy, got: %v", err)
+		}
+	})
+}
@@ -0,0 +1,164 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const hooksHashName = "hooks"
+
+// hookOperations - the phases helm runs for each release operation, in
+// order. release stands for the chart's manifests being applied
+var hookOperations = []struct {
+	Operation string
+	Phases    []string
+}{
+	{"install", []string{"crd-install", "pre-install", "release", "post-install"}},
+	{"upgrade", []string{"pre-upgrade", "release", "post-upgrade"}},
+	{"rollback", []string{"pre-rollback", "release", "post-rollback"}},
+	{"delete", []string{"pre-delete", "release", "post-delete"}},
+	{"test", []string{"test", "test-success", "test-failure"}},
+}
+
+// hookStep - a step of a release operation: a hook run in one of its
+// phases, or the release manifests being applied
+type hookStep struct {
+	Order          int      `json:"order"`
+	Phase          string   `json:"phase"`
+	Ref            string   `json:"ref"`
+	Kind           string   `json:"kind"`
+	Name           string   `json:"name"`
+	Template       string   `json:"template"`
+	Weight         int      `json:"weight"`
+	DeletePolicies []string `json:"deletePolicies"`
+}
+
+// buildHookPlans - simulates the order helm runs the hooks of the rendered
+// templates in, per release operation: by phase, then weight, then name
+func buildHookPlans(inputs ...map[string]interface{}) map[string][]hookStep {
+	byPhase := make(map[string][]hookStep)
+	for _, input := range inputs {
+		for _, template := range templateNames(input) {
+			for _, obj := range renderedObjects(map[string]interface{}{template: input[template]}) {
+				annotations := getMap(obj, "metadata", "annotations")
+				phases, ok := annotations["helm.sh/hook"].(string)
+				if !ok {
+					continue
+				}
+
+				weight, _ := strconv.Atoi(strings.TrimSpace(fmt.Sprint(annotations["helm.sh/hook-weight"])))
+				deletePolicies := make([]string, 0)
+				if policies, ok := annotations["helm.sh/hook-delete-policy"].(string); ok {
+					deletePolicies = splitList(policies)
+				}
+
+				for _, phase := range splitList(phases) {
+					byPhase[phase] = append(byPhase[phase], hookStep{
+						Phase:          phase,
+						Ref:            objectRef(obj),
+						Kind:           objectKind(obj),
+						Name:           objectName(obj),
+						Template:       template,
+						Weight:         weight,
+						DeletePolicies: deletePolicies,
+					})
+				}
+			}
+		}
+	}
+
+	plans := make(map[string][]hookStep, len(hookOperations))
+	for _, operation := range hookOperations {
+		plan := make([]hookStep, 0)
+		for _, phase := range operation.Phases {
+			if phase == "release" {
+				plan = append(plan, hookStep{Phase: phase, Ref: "release", DeletePolicies: make([]string, 0)})
+				continue
+			}
+
+			steps := append([]hookStep{}, byPhase[phase]...)
+			sort.SliceStable(steps, func(i, j int) bool {
+				if steps[i].Weight == steps[j].Weight {
+					return steps[i].Name < steps[j].Name
+				}
+				return steps[i].Weight < steps[j].Weight
+			})
+			plan = append(plan, steps...)
+		}
+
+		for i := range plan {
+			plan[i].Order = i
+		}
+		plans[operation.Operation] = plan
+	}
+	return plans
+}
+
+func splitList(list string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+type HooksCommand struct {
+	Writer   io.Writer
+	Template string   `short:"t" long:"template" description:"path to the chart whose hooks you would like to see the order of"`
+	Values   []string `short:"c" long:"values" description:"path to values file(s) you would like to use for rendering"`
+}
+
+// Execute - renders the chart and prints the order helm runs its hooks in
+// for every release operation
+func (s *HooksCommand) Execute(args []string) error {
+	s.setDefaults()
+	templatePath, err := resolveTemplatePath(s.Template)
+	if err != nil {
+		return err
+	}
+
+	valuesConfig, err := mergeValues(s.Values)
+	if err != nil {
+		return fmt.Errorf("failed merging values files %w ", err)
+	}
+
+	rendered, err := validateAndRender(templatePath, valuesConfig, renderOptions{})
+	if err != nil {
+		return &RenderError{Template: templatePath, Err: err}
+	}
+
+	input, err := UnmarshalYamlMap(rendered)
+	if err != nil {
+		return &RenderError{Template: templatePath, Err: fmt.Errorf("formatting policy input failed: %w", err)}
+	}
+
+	plans := buildHookPlans(input)
+	for _, operation := range hookOperations {
+		plan := plans[operation.Operation]
+		if len(plan) == 1 && plan[0].Ref == "release" || len(plan) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(s.Writer, "%s:\n", operation.Operation)
+		for _, step := range plan {
+			if step.Ref == "release" {
+				fmt.Fprintf(s.Writer, "  %d. %-14s the release manifests are applied\n", step.Order+1, step.Phase)
+				continue
+			}
+			fmt.Fprintf(s.Writer, "  %d. %-14s weight %-4d %s (%s)\n", step.Order+1, step.Phase, step.Weight, step.Ref, step.Template)
+		}
+	}
+	return nil
+}
+
+func (s *HooksCommand) setDefaults() {
+	if s.Writer == nil {
+		s.Writer = os.Stdout
+	}
+}
@@ -0,0 +1,57 @@
+package commands_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestHooksCommand(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		template  string
+		failsWith error
+		expected  string
+	}{
+		{
+			name:     "hooks are printed in the order helm runs them per release operation",
+			template: "testdata/hooks",
+			expected: "install:\n" +
+				"  1. pre-install    weight -5   Job/hcunit-name-migrate (jobs.yaml)\n" +
+				"  2. release        the release manifests are applied\n" +
+				"  3. post-install   weight 0    Job/hcunit-name-cache-warm (post.yaml)\n" +
+				"  4. post-install   weight 0    Job/hcunit-name-seed (post.yaml)\n" +
+				"upgrade:\n" +
+				"  1. pre-upgrade    weight -5   Job/hcunit-name-migrate (jobs.yaml)\n" +
+				"  2. pre-upgrade    weight 5    Job/hcunit-name-deploy (jobs.yaml)\n" +
+				"  3. release        the release manifests are applied\n" +
+				"test:\n" +
+				"  1. test-success   weight 0    Pod/hcunit-name-test-connection (test-connection.yaml)\n",
+		},
+		{
+			name:     "operations without hooks are left out",
+			template: "testdata/workloads",
+			expected: "",
+		},
+		{
+			name:      "a template path which doesnt exist",
+			template:  "testdata/doesnotexist",
+			failsWith: commands.TemplatePathNotFound,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			stdOut := new(bytes.Buffer)
+			cmd := &commands.HooksCommand{Writer: stdOut, Template: tt.template}
+			err := cmd.Execute([]string{})
+			if !errors.Is(err, tt.failsWith) {
+				t.Fatalf("expected error:\n%v\ngot:\n%v", tt.failsWith, err)
+			}
+
+			if stdOut.String() != tt.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", tt.expected, stdOut.String())
+			}
+		})
+	}
+}
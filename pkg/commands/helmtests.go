@@ -0,0 +1,40 @@
+package commands
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const testsHashName = "tests"
+
+var helmTestHook = regexp.MustCompile(`helm\.sh/hook["']?\s*:\s*["']?test(-success|-failure)?\b`)
+
+// splitHelmTests - separates the rendered helm test hooks (anything under a
+// tests/ directory or annotated as a test hook) from the rest of the chart
+func splitHelmTests(templatePath string, rendered map[string]string) (map[string]string, map[string]string) {
+	chart := make(map[string]string)
+	tests := make(map[string]string)
+	for name, content := range rendered {
+		if isHelmTest(templatePath, name, content) {
+			tests[name] = content
+		} else {
+			chart[name] = content
+		}
+	}
+	return chart, tests
+}
+
+func isHelmTest(templatePath string, name string, content string) bool {
+	rel, err := filepath.Rel(templatePath, name)
+	if err != nil || rel == "." {
+		rel = name
+	}
+
+	for _, dir := range strings.Split(filepath.ToSlash(filepath.Dir(rel)), "/") {
+		if dir == "tests" {
+			return true
+		}
+	}
+	return helmTestHook.MatchString(content)
+}
@@ -0,0 +1,249 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const defaultHistoryPath = ".hcunit/history.jsonl"
+
+// commitEnvs - environment variables CI systems put the built commit in
+var commitEnvs = []string{"GITHUB_SHA", "CI_COMMIT_SHA", "CIRCLE_SHA1", "GIT_COMMIT"}
+
+// HistoryRecord - a run stored in the results history, keyed by its chart
+// and commit
+type HistoryRecord struct {
+	Chart  string `json:"chart"`
+	Commit string `json:"commit,omitempty"`
+	RunReport
+}
+
+// recordHistory - stores the results of this run in --history, replacing
+// an earlier run of the same chart and commit so re-runs don't skew trends
+func (s *EvalCommand) recordHistory(runErr error) error {
+	records, err := loadHistory(s.History)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	report := s.runReport(runErr)
+	record := HistoryRecord{
+		Chart:     historyChartName(report.Provenance, s.Template),
+		Commit:    currentCommit(s.Template),
+		RunReport: report,
+	}
+
+	kept := make([]HistoryRecord, 0, len(records)+1)
+	for _, r := range records {
+		if record.Commit == "" || r.Chart != record.Chart || r.Commit != record.Commit {
+			kept = append(kept, r)
+		}
+	}
+	kept = append(kept, record)
+
+	out := new(bytes.Buffer)
+	for _, r := range kept {
+		b, err := json.Marshal(r)
+		if err != nil {
+			return fmt.Errorf("couldnt marshal history record: %w", err)
+		}
+		out.Write(append(b, '\n'))
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.History), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(s.History, out.Bytes(), 0644)
+}
+
+// loadHistory - reads the records of a results history, oldest first
+func loadHistory(path string) ([]HistoryRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	records := make([]HistoryRecord, 0)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		record := HistoryRecord{}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("failed to parse %s line %d: %w", path, line, err)
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+func historyChartName(provenance Provenance, template string) string {
+	if provenance.Chart.Name != "" {
+		return provenance.Chart.Name
+	}
+	return filepath.Base(template)
+}
+
+// currentCommit - the commit being built according to the CI environment,
+// or else the HEAD of the git repository holding the template path
+func currentCommit(template string) string {
+	for _, env := range commitEnvs {
+		if commit := os.Getenv(env); commit != "" {
+			return commit
+		}
+	}
+
+	cmd := exec.Command("git", "rev-parse", "HEAD")
+	cmd.Dir = template
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// TrendsCommand - shows how often each rule passed over the stored history
+type TrendsCommand struct {
+	Writer  io.Writer
+	History string `long:"history" description:"path to the results history written by eval --history (default: .hcunit/history.jsonl)"`
+	Chart   string `long:"chart" description:"only show the trends of this chart"`
+	Last    int    `long:"last" default:"20" description:"number of most recent runs the outcome timeline shows"`
+}
+
+type ruleTrend struct {
+	rule     string
+	runs     int
+	passes   int
+	timeline string
+}
+
+func (s *TrendsCommand) Execute(args []string) error {
+	s.setDefaults()
+	records, err := loadHistory(s.History)
+	if err != nil {
+		return fmt.Errorf("reading results history failed: %w", err)
+	}
+
+	charts := make(map[string][]HistoryRecord)
+	for _, record := range records {
+		if s.Chart == "" || record.Chart == s.Chart {
+			charts[record.Chart] = append(charts[record.Chart], record)
+		}
+	}
+
+	names := make([]string, 0, len(charts))
+	for name := range charts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		runs := charts[name]
+		fmt.Fprintf(s.Writer, "== %s: %d runs ==\n", name, len(runs))
+		for _, trend := range ruleTrends(runs, s.Last) {
+			fmt.Fprintf(s.Writer, "%6.1f%%  %3d/%-3d  %-*s  %s\n",
+				float64(trend.passes)*100/float64(trend.runs), trend.passes, trend.runs, s.Last, trend.timeline, trend.rule)
+		}
+	}
+	return nil
+}
+
+func (s *TrendsCommand) setDefaults() {
+	if s.Writer == nil {
+		s.Writer = os.Stdout
+	}
+
+	if s.History == "" {
+		s.History = defaultHistoryPath
+	}
+
+	if s.Last <= 0 {
+		s.Last = 20
+	}
+}
+
+// ruleTrends - the pass rate of every rule over the given runs, lowest
+// first so chronically violated rules lead. The timeline shows the last
+// runs' outcomes, oldest first: "." passed, "F" failed, "W" warned, "D"
+// violated a dry run rule and " " not evaluated. Dry run violations count
+// against the pass rate, to see how a rule would fare once enforced
+func ruleTrends(runs []HistoryRecord, last int) []ruleTrend {
+	start := len(runs) - last
+	if start < 0 {
+		start = 0
+	}
+
+	trends := make(map[string]*ruleTrend)
+	for i, run := range runs {
+		outcomes := make(map[string]string, len(run.Results))
+		for _, result := range run.Results {
+			outcomes[result.Rule] = result.Result
+		}
+
+		for rule, outcome := range outcomes {
+			trend, ok := trends[rule]
+			if !ok {
+				trend = &ruleTrend{rule: rule}
+				if i > start {
+					trend.timeline = strings.Repeat(" ", i-start)
+				}
+				trends[rule] = trend
+			}
+
+			trend.runs++
+			if outcome != "fail" && outcome != "dryrun" {
+				trend.passes++
+			}
+		}
+
+		if i < start {
+			continue
+		}
+
+		for rule, trend := range trends {
+			trend.timeline += timelineMark(outcomes[rule])
+		}
+	}
+
+	sorted := make([]ruleTrend, 0, len(trends))
+	for _, trend := range trends {
+		sorted = append(sorted, *trend)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		rateI := float64(sorted[i].passes) / float64(sorted[i].runs)
+		rateJ := float64(sorted[j].passes) / float64(sorted[j].runs)
+		if rateI != rateJ {
+			return rateI < rateJ
+		}
+		return sorted[i].rule < sorted[j].rule
+	})
+	return sorted
+}
+
+func timelineMark(outcome string) string {
+	switch outcome {
+	case "pass":
+		return "."
+	case "fail":
+		return "F"
+	case "warn":
+		return "W"
+	case "dryrun":
+		return "D"
+	}
+	return " "
+}
@@ -0,0 +1,72 @@
+package commands_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestHistoryAndTrends(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hcunit-history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer os.Unsetenv("GITHUB_SHA")
+
+	history := filepath.Join(dir, ".hcunit", "history.jsonl")
+	for _, run := range []struct {
+		commit string
+		policy string
+	}{
+		{"aaaaaaa", "testdata/policy/failing"},
+		{"aaaaaaa", "testdata/policy/failing"},
+		{"bbbbbbb", "testdata/policy/failing"},
+		{"ccccccc", "testdata/policy/passing"},
+	} {
+		os.Setenv("GITHUB_SHA", run.commit)
+		evalCmd := &commands.EvalCommand{
+			Stdout:   ioutil.Discard,
+			Template: "testdata/templates",
+			Values:   []string{"testdata/values.yml"},
+			Policy:   []string{run.policy},
+			History:  history,
+		}
+		evalCmd.Execute([]string{})
+	}
+
+	content, err := ioutil.ReadFile(history)
+	if err != nil {
+		t.Fatalf("expected a results history: %v", err)
+	}
+
+	if lines := strings.Count(string(content), "\n"); lines != 3 {
+		t.Errorf("expected a record per chart and commit, got %d:\n%s", lines, content)
+	}
+
+	stdOut := new(bytes.Buffer)
+	trendsCmd := &commands.TrendsCommand{Writer: stdOut, History: history, Last: 3}
+	if err := trendsCmd.Execute([]string{}); err != nil {
+		t.Fatalf("expected trends, got: %v", err)
+	}
+
+	for _, expected := range []string{
+		"== templates: 3 runs ==\n",
+		`  0.0%    0/2    FF   data.main.expect["force failure"]`,
+		`100.0%    2/2    ..   data.main.expect["some things pass"]`,
+		`100.0%    1/1      .  data.main.expect["force passing"]`,
+	} {
+		if !strings.Contains(stdOut.String(), expected) {
+			t.Errorf("expected trends to contain %q, got:\n%s", expected, stdOut.String())
+		}
+	}
+
+	if !strings.HasPrefix(strings.TrimSpace(strings.SplitN(stdOut.String(), "\n", 3)[1]), "0.0%") {
+		t.Errorf("expected the most violated rules first, got:\n%s", stdOut.String())
+	}
+}
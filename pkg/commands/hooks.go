@@ -0,0 +1,139 @@
+package commands
+
+import (
+	"errors"
+	"regexp"
+)
+
+// SkipRule - returned by a BeforeRule hook to leave the rule out of the run
+var SkipRule = errors.New("rule skipped by hook")
+
+// Hooks - callbacks around an evaluation, for programs embedding hcunit to
+// add logging, metrics or to stop a run early without reimplementing it.
+// Any of them may be nil. An error returned by a hook ends the evaluation
+// with it, except for SkipRule from BeforeRule
+type Hooks struct {
+	// BeforeRender - called with the template path and kubernetes version
+	// (empty for helm's default) before the chart is rendered
+	BeforeRender func(template, kubeVersion string) error
+
+	// AfterRender - called with the rendered templates, keyed by path
+	AfterRender func(template, kubeVersion string, rendered map[string]string) error
+
+	// BeforeRule - called with the name of every rule (and parameter row or
+	// document of it) before it is evaluated
+	BeforeRule func(rule string) error
+
+	// AfterRule - called with the result of every evaluated rule, ordered by
+	// name, once the run is over
+	AfterRule func(result RuleResult) error
+
+	// OnFailure - called with every failed rule after its AfterRule hook
+	OnFailure func(result RuleResult, detail FailureDetail)
+}
+
+// then - hooks calling these hooks, then the next ones
+func (s *Hooks) then(next *Hooks) *Hooks {
+	if s == nil {
+		return next
+	}
+
+	if next == nil {
+		return s
+	}
+
+	return &Hooks{
+		BeforeRender: func(template, kubeVersion string) error {
+			if err := s.beforeRender(template, kubeVersion); err != nil {
+				return err
+			}
+			return next.beforeRender(template, kubeVersion)
+		},
+		AfterRender: func(template, kubeVersion string, rendered map[string]string) error {
+			if err := s.afterRender(template, kubeVersion, rendered); err != nil {
+				return err
+			}
+			return next.afterRender(template, kubeVersion, rendered)
+		},
+		BeforeRule: func(rule string) error {
+			if err := s.beforeRule(rule); err != nil {
+				return err
+			}
+			return next.beforeRule(rule)
+		},
+		AfterRule: func(result RuleResult) error {
+			if err := s.afterRule(result); err != nil {
+				return err
+			}
+			return next.afterRule(result)
+		},
+		OnFailure: func(result RuleResult, detail FailureDetail) {
+			s.onFailure(result, detail)
+			next.onFailure(result, detail)
+		},
+	}
+}
+
+func (s *Hooks) beforeRender(template, kubeVersion string) error {
+	if s == nil || s.BeforeRender == nil {
+		return nil
+	}
+	return s.BeforeRender(template, kubeVersion)
+}
+
+func (s *Hooks) afterRender(template, kubeVersion string, rendered map[string]string) error {
+	if s == nil || s.AfterRender == nil {
+		return nil
+	}
+	return s.AfterRender(template, kubeVersion, rendered)
+}
+
+func (s *Hooks) beforeRule(rule string) error {
+	if s == nil || s.BeforeRule == nil {
+		return nil
+	}
+	return s.BeforeRule(rule)
+}
+
+func (s *Hooks) afterRule(result RuleResult) error {
+	if s == nil || s.AfterRule == nil {
+		return nil
+	}
+	return s.AfterRule(result)
+}
+
+func (s *Hooks) onFailure(result RuleResult, detail FailureDetail) {
+	if s != nil && s.OnFailure != nil {
+		s.OnFailure(result, detail)
+	}
+}
+
+// filterHooks - hooks skipping the rules whose name doesn't match the
+// filter, e.g. for --run
+func filterHooks(filter *regexp.Regexp) *Hooks {
+	if filter == nil {
+		return nil
+	}
+
+	return &Hooks{BeforeRule: func(rule string) error {
+		if !filter.MatchString(rule) {
+			return SkipRule
+		}
+		return nil
+	}}
+}
+
+// afterRun - calls the AfterRule and OnFailure hooks with the results of a
+// run and the details of its violation, if any
+func (s *Hooks) afterRun(results []RuleResult, violation *ViolationError) error {
+	for _, result := range results {
+		if err := s.afterRule(result); err != nil {
+			return err
+		}
+
+		if !result.Passed && violation != nil {
+			s.onFailure(result, violation.Details[result.Name])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package commands_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestEvalHooks(t *testing.T) {
+	t.Run("hooks are called around rendering and every rule", func(t *testing.T) {
+		calls := make([]string, 0)
+		failures := make([]string, 0)
+		evalCmd := &commands.EvalCommand{
+			Stdout:   ioutil.Discard,
+			Template: "testdata/templates",
+			Values:   []string{"testdata/values.yml"},
+			Policy:   []string{"testdata/policy/failing/failing.rego"},
+			Hooks: &commands.Hooks{
+				BeforeRender: func(template, kubeVersion string) error {
+					calls = append(calls, "before render")
+					return nil
+				},
+				AfterRender: func(template, kubeVersion string, rendered map[string]string) error {
+					for name := range rendered {
+						if strings.HasSuffix(name, "something.yml") {
+							calls = append(calls, "after render")
+						}
+					}
+					return nil
+				},
+				BeforeRule: func(rule string) error {
+					calls = append(calls, "before rule")
+					return nil
+				},
+				AfterRule: func(result commands.RuleResult) error {
+					calls = append(calls, "after rule")
+					return nil
+				},
+				OnFailure: func(result commands.RuleResult, detail commands.FailureDetail) {
+					if detail.Line > 0 {
+						failures = append(failures, result.Name)
+					}
+				},
+			},
+		}
+		if err := evalCmd.Execute([]string{}); !errors.Is(err, commands.PolicyFailure) {
+			t.Fatalf("expected error:\n%v\ngot:\n%v", commands.PolicyFailure, err)
+		}
+
+		expected := "before render, after render, before rule, before rule, before rule, before rule, after rule, after rule, after rule, after rule"
+		if strings.Join(calls, ", ") != expected {
+			t.Errorf("expected the hooks to be called:\n%s\ngot:\n%s", expected, strings.Join(calls, ", "))
+		}
+
+		sort.Strings(failures)
+		expected = `data.main.expect["another force failure"], data.main.expect["force failure"]`
+		if strings.Join(failures, ", ") != expected {
+			t.Errorf("expected failures:\n%s\ngot:\n%s", expected, strings.Join(failures, ", "))
+		}
+	})
+
+	t.Run("rules skipped by a hook are left out of the run", func(t *testing.T) {
+		stdOut := new(bytes.Buffer)
+		evalCmd := &commands.EvalCommand{
+			Stdout:   stdOut,
+			Template: "testdata/templates",
+			Values:   []string{"testdata/values.yml"},
+			Policy:   []string{"testdata/policy/failing/failing.rego"},
+			Hooks: &commands.Hooks{
+				BeforeRule: func(rule string) error {
+					if strings.Contains(rule, "failure") {
+						return commands.SkipRule
+					}
+					return nil
+				},
+			},
+		}
+		if err := evalCmd.Execute([]string{}); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+
+		if strings.Contains(stdOut.String(), "FAIL: ") || !strings.Contains(stdOut.String(), `PASS: `+"\x1b[0m"+`data.main.expect["some things pass"]`) {
+			t.Errorf("expected only the passing rules to be reported, got:\n%s", stdOut.String())
+		}
+	})
+
+	hookErr := errors.New("stopped by hook")
+	for _, tt := range []struct {
+		name  string
+		hooks *commands.Hooks
+	}{
+		{
+			name:  "a before render hook stops the evaluation",
+			hooks: &commands.Hooks{BeforeRender: func(string, string) error { return hookErr }},
+		},
+		{
+			name:  "an after render hook stops the evaluation",
+			hooks: &commands.Hooks{AfterRender: func(string, string, map[string]string) error { return hookErr }},
+		},
+		{
+			name:  "a before rule hook stops the evaluation",
+			hooks: &commands.Hooks{BeforeRule: func(string) error { return hookErr }},
+		},
+		{
+			name:  "an after rule hook stops the evaluation",
+			hooks: &commands.Hooks{AfterRule: func(commands.RuleResult) error { return hookErr }},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			evalCmd := &commands.EvalCommand{
+				Stdout:   ioutil.Discard,
+				Template: "testdata/templates",
+				Values:   []string{"testdata/values.yml"},
+				Policy:   []string{"testdata/policy/passing"},
+				Hooks:    tt.hooks,
+			}
+			if err := evalCmd.Execute([]string{}); !errors.Is(err, hookErr) {
+				t.Fatalf("expected error:\n%v\ngot:\n%v", hookErr, err)
+			}
+		})
+	}
+}
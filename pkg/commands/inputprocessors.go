@@ -0,0 +1,185 @@
+package commands
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var InvalidInputProcessor = errors.New("unknown input processor in config")
+
+const (
+	byKindHashName  = "byKind"
+	summaryHashName = "summary"
+	parsedDataField = "parsedData"
+)
+
+// InputConfig - how the rendered templates are shaped before policies see
+// them, as the input processors run in the order they are listed
+type InputConfig struct {
+	Processors []string `yaml:"processors"`
+}
+
+// inputProcessors - the named steps the policy input can be run through.
+// Each one changes the templates of the input in place
+var inputProcessors = map[string]func(input map[string]interface{}){
+	"split-docs":             splitDocumentLists,
+	"index-by-kind":          indexByKind,
+	"parse-embedded-configs": parseEmbeddedConfigs,
+	"redact-secrets":         redactSecrets,
+	"aggregate-summary":      aggregateSummary,
+}
+
+// processors - the input processors the config lists, in order
+func (s InputConfig) processors() ([]func(map[string]interface{}), error) {
+	processors := make([]func(map[string]interface{}), 0, len(s.Processors))
+	for _, name := range s.Processors {
+		processor, ok := inputProcessors[name]
+		if !ok {
+			known := make([]string, 0, len(inputProcessors))
+			for name := range inputProcessors {
+				known = append(known, name)
+			}
+			sort.Strings(known)
+			return nil, fmt.Errorf("%w: %q is not one of %s", InvalidInputProcessor, name, strings.Join(known, ", "))
+		}
+		processors = append(processors, processor)
+	}
+	return processors, nil
+}
+
+// processInput - runs the templates of the input through the configured
+// input processors
+func (s *EvalCommand) processInput(input map[string]interface{}) {
+	for _, process := range s.processors {
+		process(input)
+	}
+}
+
+// templateNames - the keys of the input holding rendered templates, sorted
+func templateNames(input map[string]interface{}) []string {
+	names := make([]string, 0, len(input))
+	for name := range input {
+		if path.Ext(name) != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// templateObjects - the objects rendered by the templates of the input,
+// leaving out the values and anything added next to the templates
+func templateObjects(input map[string]interface{}) []map[string]interface{} {
+	templates := make(map[string]interface{})
+	for _, name := range templateNames(input) {
+		templates[name] = input[name]
+	}
+	return renderedObjects(templates)
+}
+
+// splitDocumentLists - every template as the list of its documents, even when it
+// renders a single one, so policies index them the same way
+func splitDocumentLists(input map[string]interface{}) {
+	for _, name := range templateNames(input) {
+		if doc, ok := input[name].(map[string]interface{}); ok {
+			input[name] = []interface{}{doc}
+		}
+	}
+}
+
+// indexByKind - the rendered objects listed by their kind under input.byKind
+func indexByKind(input map[string]interface{}) {
+	byKind := make(map[string]interface{})
+	for _, obj := range templateObjects(input) {
+		kind := objectKind(obj)
+		objects, _ := byKind[kind].([]interface{})
+		byKind[kind] = append(objects, obj)
+	}
+	input[byKindHashName] = byKind
+}
+
+// parseEmbeddedConfigs - parses the data of ConfigMaps and Secrets whose key
+// has the extension of a known config format (decoding the data of Secrets
+// first) into the object's parsedData. Entries failing to parse are left out
+func parseEmbeddedConfigs(input map[string]interface{}) {
+	for _, obj := range templateObjects(input) {
+		kind := objectKind(obj)
+		if kind != "ConfigMap" && kind != "Secret" {
+			continue
+		}
+
+		parsed := make(map[string]interface{})
+		for _, field := range []string{"data", "stringData"} {
+			for key, v := range getMap(obj, field) {
+				content, ok := v.(string)
+				format, known := payloadFormats[strings.ToLower(filepath.Ext(key))]
+				if !ok || !known {
+					continue
+				}
+
+				if kind == "Secret" && field == "data" {
+					decoded, err := base64.StdEncoding.DecodeString(content)
+					if err != nil {
+						continue
+					}
+					content = string(decoded)
+				}
+
+				if config, err := payloadParsers[format](content); err == nil {
+					parsed[key] = config
+				}
+			}
+		}
+
+		if len(parsed) > 0 {
+			obj[parsedDataField] = parsed
+		}
+	}
+}
+
+// redactSecrets - replaces the data, stringData and parsedData values of
+// rendered Secrets, for policies which have no business reading them
+func redactSecrets(input map[string]interface{}) {
+	for _, obj := range templateObjects(input) {
+		if objectKind(obj) != "Secret" {
+			continue
+		}
+
+		for _, field := range []string{"data", "stringData", parsedDataField} {
+			data := getMap(obj, field)
+			for key := range data {
+				data[key] = redactedValue
+			}
+		}
+	}
+}
+
+// aggregateSummary - counts of the rendered templates, objects, and objects
+// per kind and namespace under input.summary
+func aggregateSummary(input map[string]interface{}) {
+	objects := templateObjects(input)
+	kinds := make(map[string]interface{})
+	namespaces := make(map[string]interface{})
+	for _, obj := range objects {
+		kind := objectKind(obj)
+		count, _ := kinds[kind].(int)
+		kinds[kind] = count + 1
+
+		if namespace := getString(obj, "metadata", "namespace"); namespace != "" {
+			count, _ := namespaces[namespace].(int)
+			namespaces[namespace] = count + 1
+		}
+	}
+
+	input[summaryHashName] = map[string]interface{}{
+		"templates":  len(templateNames(input)),
+		"objects":    len(objects),
+		"kinds":      kinds,
+		"namespaces": namespaces,
+	}
+}
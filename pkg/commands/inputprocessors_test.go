@@ -0,0 +1,112 @@
+package commands_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestEvalInputProcessors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hcunit-input-processors")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, tt := range []struct {
+		name      string
+		config    string
+		template  string
+		values    string
+		policy    string
+		failsWith error
+	}{
+		{
+			name:      "split-docs lists the documents of every template",
+			config:    "input:\n  processors: [split-docs]\n",
+			template:  "testdata/templates",
+			values:    "testdata/values.yml",
+			policy:    "testdata/policy/processors/split_docs.rego",
+			failsWith: nil,
+		},
+		{
+			name:      "index-by-kind lists the objects under input.byKind",
+			config:    "input:\n  processors: [index-by-kind]\n",
+			template:  "testdata/templates",
+			values:    "testdata/values.yml",
+			policy:    "testdata/policy/processors/by_kind.rego",
+			failsWith: nil,
+		},
+		{
+			name:      "aggregate-summary counts the objects under input.summary",
+			config:    "input:\n  processors: [aggregate-summary]\n",
+			template:  "testdata/templates",
+			values:    "testdata/values.yml",
+			policy:    "testdata/policy/processors/summary.rego",
+			failsWith: nil,
+		},
+		{
+			name:      "parse-embedded-configs parses configmap data into parsedData",
+			config:    "input:\n  processors: [parse-embedded-configs]\n",
+			template:  "testdata/payloads",
+			values:    "testdata/values.yml",
+			policy:    "testdata/policy/processors/embedded_configs.rego",
+			failsWith: nil,
+		},
+		{
+			name:      "redact-secrets replaces the values of secrets",
+			config:    "input:\n  processors: [split-docs, redact-secrets]\n",
+			template:  "testdata/secrets",
+			values:    "testdata/secrets_values.yaml",
+			policy:    "testdata/policy/processors/redacted_secrets.rego",
+			failsWith: nil,
+		},
+		{
+			name:      "processors run in the order they are listed",
+			config:    "input:\n  processors: [redact-secrets, split-docs]\n",
+			template:  "testdata/secrets",
+			values:    "testdata/secrets_values.yaml",
+			policy:    "testdata/policy/processors/redacted_secrets.rego",
+			failsWith: nil,
+		},
+		{
+			name:      "policies see the rendered input without processors",
+			config:    "input:\n  processors: []\n",
+			template:  "testdata/templates",
+			values:    "testdata/values.yml",
+			policy:    "testdata/policy/processors/split_docs.rego",
+			failsWith: commands.PolicyFailure,
+		},
+		{
+			name:      "unknown processors are rejected",
+			config:    "input:\n  processors: [split-docs, sort-keys]\n",
+			template:  "testdata/templates",
+			values:    "testdata/values.yml",
+			policy:    "testdata/policy/processors/split_docs.rego",
+			failsWith: commands.InvalidInputProcessor,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(dir, ".hcunit.yaml")
+			if err := ioutil.WriteFile(configPath, []byte(tt.config), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			evalCmd := &commands.EvalCommand{
+				Stdout:   ioutil.Discard,
+				Template: tt.template,
+				Values:   []string{tt.values},
+				Policy:   []string{tt.policy},
+				Config:   configPath,
+			}
+			err := evalCmd.Execute([]string{})
+			if tt.failsWith == nil && err != nil || !errors.Is(err, tt.failsWith) {
+				t.Fatalf("expected error:\n%v\ngot:\n%v", tt.failsWith, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/mitchellh/colorstring"
+)
+
+// browserColumnWidth - the width of the rule source column when showing a
+// failure's source and manifests side by side
+const browserColumnWidth = 60
+
+// resultsBrowser - a prompt driven browser over the results of an
+// evaluation, for drilling into failures and re-running rules while
+// editing the policies
+type resultsBrowser struct {
+	in        *bufio.Scanner
+	out       io.Writer
+	results   []RuleResult
+	details   map[string]FailureDetail
+	diffs     map[string][]string
+	documents map[string]string
+	redact    func(string) string
+	rerun     func(rule string) (RuleResult, FailureDetail, error)
+	ascii     bool
+}
+
+// browse - lists the results and handles commands until the user quits or
+// the input ends
+func (b *resultsBrowser) browse() {
+	for {
+		b.list()
+		fmt.Fprint(b.out, "\nselect a rule to view, r <number> to re-run it, q to quit: ")
+		if !b.in.Scan() {
+			fmt.Fprintln(b.out)
+			return
+		}
+
+		fields := strings.Fields(b.in.Text())
+		switch {
+		case len(fields) == 0:
+			continue
+		case fields[0] == "q":
+			return
+		case fields[0] == "r" && len(fields) == 2:
+			if i, ok := b.index(fields[1]); ok {
+				b.rerunRule(i)
+			}
+		default:
+			if i, ok := b.index(fields[0]); ok {
+				b.show(i)
+			}
+		}
+	}
+}
+
+func (b *resultsBrowser) index(selection string) (int, bool) {
+	i, err := strconv.Atoi(selection)
+	if err != nil || i < 1 || i > len(b.results) {
+		fmt.Fprintf(b.out, "no rule %q, pick a number between 1 and %d\n", selection, len(b.results))
+		return 0, false
+	}
+	return i - 1, true
+}
+
+func (b *resultsBrowser) list() {
+	fmt.Fprintln(b.out)
+	for i, result := range b.results {
+		status := "[green]PASS"
+		switch {
+		case result.Warning:
+			status = "[yellow]WARN"
+		case result.DryRun:
+			status = "[yellow]DRYRUN"
+		case !result.Passed:
+			status = "[red]FAIL"
+		}
+		fmt.Fprintf(b.out, "%3d  %s  %s\n", i+1, colorstring.Color(status), result.Name)
+	}
+}
+
+// show - the rule source next to the manifests it referenced, followed by
+// any assertion diffs and the trace of its evaluation
+func (b *resultsBrowser) show(i int) {
+	result := b.results[i]
+	if result.Passed {
+		fmt.Fprintf(b.out, "%s passed, there is no failure to show\n", result.Name)
+		return
+	}
+
+	detail := b.details[result.Name]
+	manifests := new(bytes.Buffer)
+	for _, document := range detail.Documents {
+		if content, ok := b.documents[document]; ok {
+			fmt.Fprintf(manifests, "#%s\n%s\n", document, strings.TrimSpace(b.redact(redactSecretDocuments(content))))
+		}
+	}
+
+	fmt.Fprintln(b.out, colorstring.Color("\n[bold]"+result.Name))
+	fmt.Fprint(b.out, sideBySide(detail.Source, manifests.String(), browserColumnWidth, b.ascii))
+	for _, diff := range b.diffs[result.Name] {
+		fmt.Fprint(b.out, colorDiff(b.redact(diff)))
+	}
+	fmt.Fprintln(b.out, colorstring.Color("\n[bold]trace:"))
+	fmt.Fprint(b.out, b.redact(detail.Trace))
+}
+
+// rerunRule - evaluates a single rule again, picking up any edits made to
+// the policies since
+func (b *resultsBrowser) rerunRule(i int) {
+	name := b.results[i].Name
+	result, detail, err := b.rerun(name)
+	if err != nil {
+		fmt.Fprintf(b.out, "re-running %s failed: %v\n", name, err)
+		return
+	}
+
+	b.results[i] = result
+	b.details[name] = detail
+	delete(b.diffs, name)
+}
+
+// violation - the rules still failing after browsing, as returned by eval
+func (b *resultsBrowser) violation() error {
+	violation := &ViolationError{Failed: make([]string, 0), Diffs: make(map[string][]string), Details: make(map[string]FailureDetail)}
+	for _, result := range b.results {
+		if result.Passed {
+			continue
+		}
+
+		violation.Failed = append(violation.Failed, result.Name)
+		violation.Details[result.Name] = b.details[result.Name]
+		if diffs := b.diffs[result.Name]; len(diffs) > 0 {
+			violation.Diffs[result.Name] = diffs
+		}
+	}
+
+	if len(violation.Failed) > 0 {
+		return violation
+	}
+	return nil
+}
+
+// sideBySide - lays out two blocks of text as columns, the left one padded
+// or truncated to width
+func sideBySide(left, right string, width int, ascii bool) string {
+	separator, ellipsis := "│", "…"
+	if ascii {
+		separator, ellipsis = "|", "..."
+	}
+
+	leftLines := strings.Split(strings.TrimRight(left, "\n"), "\n")
+	rightLines := strings.Split(strings.TrimRight(right, "\n"), "\n")
+	rows := len(leftLines)
+	if len(rightLines) > rows {
+		rows = len(rightLines)
+	}
+
+	out := new(bytes.Buffer)
+	for i := 0; i < rows; i++ {
+		l, r := "", ""
+		if i < len(leftLines) {
+			l = strings.Replace(leftLines[i], "\t", "  ", -1)
+		}
+		if i < len(rightLines) {
+			r = rightLines[i]
+		}
+
+		if len(l) > width {
+			l = l[:width-len([]rune(ellipsis))] + ellipsis
+		}
+		fmt.Fprintf(out, "%-*s %s %s\n", width, l, separator, r)
+	}
+	return out.String()
+}
@@ -0,0 +1,95 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"github.com/mitchellh/colorstring"
+	"github.com/open-policy-agent/opa/rego"
+	"k8s.io/helm/pkg/chartutil"
+)
+
+const kubeVersionHashName = "kubeVersion"
+
+var InvalidKubeVersion = errors.New("invalid kubernetes version")
+
+// kubeVersions - the --kube-versions given, split on commas
+func (s *EvalCommand) kubeVersions() []string {
+	versions := make([]string, 0)
+	for _, flag := range s.KubeVersions {
+		for _, version := range strings.Split(flag, ",") {
+			if version = strings.TrimSpace(version); version != "" {
+				versions = append(versions, version)
+			}
+		}
+	}
+	return versions
+}
+
+// checkKubeVersion - --kube-version is a single version, which can't be
+// combined with the matrix of --kube-versions
+func (s *EvalCommand) checkKubeVersion() error {
+	if s.KubeVersion == "" {
+		return nil
+	}
+
+	if len(s.kubeVersions()) > 0 {
+		return fmt.Errorf("%w: --kube-version can't be combined with --kube-versions", InvalidKubeVersion)
+	}
+
+	if _, err := semver.NewVersion(s.KubeVersion); err != nil {
+		return fmt.Errorf("%w %q: %v", InvalidKubeVersion, s.KubeVersion, err)
+	}
+	return nil
+}
+
+// evaluateMatrix - renders and evaluates the chart once per kubernetes
+// version, then prints which versions passed
+func (s *EvalCommand) evaluateMatrix(valuesConfig map[string]interface{}, options []func(*rego.Rego), versions []string) error {
+	for _, version := range versions {
+		if _, err := semver.NewVersion(version); err != nil {
+			return fmt.Errorf("%w %q: %v", InvalidKubeVersion, version, err)
+		}
+	}
+
+	results := make(map[string]error, len(versions))
+	for _, version := range versions {
+		colorstring.Fprintln(s.Stdout, fmt.Sprintf("[bold]== kubernetes %s ==", version))
+		recorded := len(s.results)
+		results[version] = s.evaluate(valuesConfig, options, version)
+		for i := range s.results[recorded:] {
+			s.results[recorded+i].KubeVersion = version
+		}
+	}
+
+	colorstring.Fprintln(s.Stdout, "[bold]== kubernetes version matrix ==")
+	failed := make([]string, 0)
+	for _, version := range versions {
+		if results[version] == nil {
+			colorstring.Fprint(s.Stdout, "[green]PASS: ")
+			fmt.Fprintf(s.Stdout, "kubernetes %s\n", version)
+			continue
+		}
+
+		failed = append(failed, version)
+		colorstring.Fprint(s.Stdout, "[red]FAIL: ")
+		fmt.Fprintf(s.Stdout, "kubernetes %s: %v\n", version, results[version])
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed on kubernetes %s: %w", strings.Join(failed, ", "), results[failed[0]])
+	}
+	return nil
+}
+
+// kubeGitVersion - the .Capabilities.KubeVersion.GitVersion the chart was
+// rendered with
+func kubeGitVersion(version string) string {
+	v, err := semver.NewVersion(version)
+	if version == "" || err != nil {
+		return chartutil.DefaultKubeVersion.GitVersion
+	}
+	return fmt.Sprintf("v%d.%d.0", v.Major(), v.Minor())
+}
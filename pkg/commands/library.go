@@ -0,0 +1,73 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+)
+
+const libraryChartType = "library"
+
+var LibraryChartWithoutFixtures = errors.New("library charts only define named templates; give caller templates with --fixture to evaluate them")
+
+// isLibraryChart - true when the Chart.yaml in chartDir declares `type: library`
+func isLibraryChart(chartDir string) (bool, error) {
+	b, err := ioutil.ReadFile(filepath.Join(chartDir, chartutil.ChartfileName))
+	if err != nil {
+		return false, err
+	}
+
+	metadata := struct {
+		Type string `yaml:"type"`
+	}{}
+	if err := yaml.Unmarshal(b, &metadata); err != nil {
+		return false, fmt.Errorf("failed to parse %s: %w", chartDir, err)
+	}
+	return metadata.Type == libraryChartType, nil
+}
+
+// dependencyPartials - loads the named template definitions (the `_*.tpl`
+// style partials) of every subchart vendored under the chart owning
+// templatePath, so library charts resolve like they do in helm. Templates
+// outside of a chart have no dependencies and yield nothing
+func dependencyPartials(templatePath string) (map[string]io.ReadCloser, error) {
+	partials := make(map[string]io.ReadCloser)
+	chartDir, err := findChartRoot(templatePath)
+	if err != nil {
+		return partials, nil
+	}
+
+	if _, err := os.Stat(filepath.Join(chartDir, "charts")); err != nil {
+		return partials, nil
+	}
+
+	c, err := chartutil.Load(chartDir)
+	if err != nil {
+		return nil, fmt.Errorf("loading chart %s failed: %w", chartDir, err)
+	}
+
+	collectPartials(partials, "charts", c.GetDependencies())
+	return partials, nil
+}
+
+func collectPartials(partials map[string]io.ReadCloser, prefix string, dependencies []*chart.Chart) {
+	for _, dependency := range dependencies {
+		dir := path.Join(prefix, dependency.GetMetadata().GetName())
+		for _, template := range dependency.GetTemplates() {
+			if strings.HasPrefix(path.Base(template.GetName()), "_") {
+				partials[path.Join(dir, template.GetName())] = ioutil.NopCloser(bytes.NewReader(template.GetData()))
+			}
+		}
+		collectPartials(partials, path.Join(dir, "charts"), dependency.GetDependencies())
+	}
+}
@@ -0,0 +1,132 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/mitchellh/colorstring"
+	yaml "gopkg.in/yaml.v3"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/lint"
+	"k8s.io/helm/pkg/lint/support"
+)
+
+var ChartNotFound = errors.New("no Chart.yaml found in the template path or any of its parents")
+var LintFailure = errors.New("helm lint found errors in your chart")
+
+type LintCommand struct {
+	Writer   io.Writer
+	Template string   `short:"t" long:"template" description:"path to the chart, or a template inside of it, you would like to lint"`
+	Values   []string `short:"c" long:"values" description:"path to values file(s) you would like to use for linting"`
+	Strict   bool     `long:"strict" description:"fail on lint warnings as well as errors"`
+}
+
+func (s *LintCommand) Execute(args []string) error {
+	s.setDefaults()
+	valuesConfig, err := mergeValues(s.Values)
+	if err != nil {
+		return fmt.Errorf("failed merging values files %w ", err)
+	}
+
+	return lintChart(s.Writer, s.Template, valuesConfig, newReleaseOptions("", "", false), s.Strict)
+}
+
+func (s *LintCommand) setDefaults() {
+	if s.Writer == nil {
+		s.Writer = os.Stdout
+	}
+}
+
+// lintChart - runs helm's linter over the chart owning the given template
+// path, for the namespace of the release, and prints its findings in the
+// same format as our policy results
+func lintChart(writer io.Writer, templatePath string, valuesMap map[string]interface{}, release releaseOptions, strict bool) error {
+	linter, err := runLinter(templatePath, valuesMap, release, strict)
+	if err != nil {
+		return err
+	}
+
+	for _, msg := range linter.Messages {
+		switch msg.Severity {
+		case support.ErrorSev:
+			colorstring.Fprint(writer, "[red]FAIL: ")
+		case support.WarningSev:
+			colorstring.Fprint(writer, "[yellow]WARN: ")
+		default:
+			colorstring.Fprint(writer, "[blue]INFO: ")
+		}
+		fmt.Fprintf(writer, "lint %s: %v\n", msg.Path, msg.Err)
+	}
+
+	if lintFails(linter.HighestSeverity, strict) {
+		return LintFailure
+	}
+	return nil
+}
+
+// lintResults - the findings of helm's linter over the chart owning the
+// given template path which fail it, as failed results reported and counted
+// along with our policy results
+func lintResults(templatePath string, valuesMap map[string]interface{}, release releaseOptions, strict bool) ([]RuleResult, error) {
+	linter, err := runLinter(templatePath, valuesMap, release, strict)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]RuleResult, 0)
+	for _, msg := range linter.Messages {
+		if lintFails(msg.Severity, strict) {
+			results = append(results, checkResult("lint", msg.Path, msg.Err.Error()))
+		}
+	}
+
+	if len(results) > 0 {
+		return results, LintFailure
+	}
+	return results, nil
+}
+
+func runLinter(templatePath string, valuesMap map[string]interface{}, release releaseOptions, strict bool) (support.Linter, error) {
+	chartDir, err := findChartRoot(templatePath)
+	if err != nil {
+		return support.Linter{}, err
+	}
+
+	values, err := yaml.Marshal(valuesMap)
+	if err != nil {
+		return support.Linter{}, fmt.Errorf("couldnt marshal values: %w", err)
+	}
+	return lint.All(chartDir, values, release.Namespace, strict), nil
+}
+
+func lintFails(severity int, strict bool) bool {
+	return severity >= support.ErrorSev || (strict && severity >= support.WarningSev)
+}
+
+// findChartRoot - walks up from the given template path to the nearest
+// directory containing a Chart.yaml
+func findChartRoot(templatePath string) (string, error) {
+	if templatePath == "" {
+		return "", FilepathValueEmpty
+	}
+
+	dir, err := filepath.Abs(templatePath)
+	if err != nil {
+		return "", fmt.Errorf("resolving template path failed: %w", err)
+	}
+
+	for {
+		if _, err := os.Stat(filepath.Join(dir, chartutil.ChartfileName)); err == nil {
+			return dir, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", ChartNotFound
+		}
+		dir = parent
+	}
+}
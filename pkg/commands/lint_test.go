@@ -0,0 +1,134 @@
+package commands_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestLintCommand(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		template  string
+		values    []string
+		strict    bool
+		failsWith error
+		contains  string
+	}{
+		{
+			name:      "a valid chart passes lint",
+			template:  "testdata/mychart",
+			values:    []string{"testdata/mychart/values.yaml"},
+			failsWith: nil,
+		},
+		{
+			name:      "a template path inside a chart lints the owning chart",
+			template:  "testdata/mychart/templates/deployment.yaml",
+			values:    []string{"testdata/mychart/values.yaml"},
+			failsWith: nil,
+		},
+		{
+			name:      "a chart with lint errors fails",
+			template:  "testdata/brokenchart/templates",
+			failsWith: commands.LintFailure,
+			contains:  "lint Chart.yaml: version is required",
+		},
+		{
+			name:      "a template path outside of any chart",
+			template:  "/",
+			failsWith: commands.ChartNotFound,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			stdOut := new(bytes.Buffer)
+			lintCmd := &commands.LintCommand{
+				Writer:   stdOut,
+				Template: tt.template,
+				Values:   tt.values,
+				Strict:   tt.strict,
+			}
+			err := lintCmd.Execute([]string{})
+			if !errors.Is(err, tt.failsWith) {
+				t.Errorf("expected error:\n%v\ngot:\n%v", tt.failsWith, err)
+			}
+
+			if !strings.Contains(stdOut.String(), tt.contains) {
+				t.Errorf("expected output to contain %q, got:\n%s", tt.contains, stdOut.String())
+			}
+		})
+	}
+}
+
+func TestEvalLintsForTheReleaseNamespace(t *testing.T) {
+	stdOut := new(bytes.Buffer)
+	evalCmd := &commands.EvalCommand{
+		Stdout:           stdOut,
+		Template:         "testdata/namespacedchart/templates",
+		Values:           []string{"testdata/namespacedchart/values.yaml"},
+		Policy:           []string{"testdata/policy/individuals/named_per_document.rego"},
+		ReleaseNamespace: "payments",
+		Lint:             true,
+	}
+	if err := evalCmd.Execute([]string{}); err != nil {
+		t.Fatalf("expected the chart to lint for the release namespace, got: %v\n%s", err, stdOut)
+	}
+
+	if strings.Contains(stdOut.String(), "FAIL: ") {
+		t.Errorf("expected no lint findings, got:\n%s", stdOut)
+	}
+}
+
+func TestEvalLintResults(t *testing.T) {
+	t.Run("lint errors are counted as failed results", func(t *testing.T) {
+		stdOut := new(bytes.Buffer)
+		evalCmd := &commands.EvalCommand{
+			Stdout:   stdOut,
+			Template: "testdata/brokenchart/templates",
+			Values:   []string{"testdata/values.yml"},
+			Policy:   []string{"testdata/policy/passing"},
+			Lint:     true,
+			Output:   "json",
+		}
+		if err := evalCmd.Execute([]string{}); !errors.Is(err, commands.LintFailure) {
+			t.Fatalf("expected error:\n%v\ngot:\n%v\n%s", commands.LintFailure, err, stdOut)
+		}
+
+		var results commands.Results
+		if err := json.Unmarshal(stdOut.Bytes(), &results); err != nil {
+			t.Fatalf("expected json results, got %v:\n%s", err, stdOut)
+		}
+
+		if results.Summary.Failed == 0 || results.ExitCode != 1 {
+			t.Errorf("expected the lint errors to fail the run, got %+v exiting %d", results.Summary, results.ExitCode)
+		}
+
+		for _, result := range results.Results {
+			if strings.HasPrefix(result.Rule, "lint ") && result.Result == "fail" {
+				return
+			}
+		}
+		t.Errorf("expected a failed lint result in:\n%s", stdOut)
+	})
+
+	t.Run("lint errors are not reported as a success", func(t *testing.T) {
+		stdOut := new(bytes.Buffer)
+		evalCmd := &commands.EvalCommand{
+			Stdout:   stdOut,
+			Template: "testdata/brokenchart/templates",
+			Values:   []string{"testdata/values.yml"},
+			Policy:   []string{"testdata/policy/passing"},
+			Lint:     true,
+		}
+		if err := evalCmd.Execute([]string{}); !errors.Is(err, commands.LintFailure) {
+			t.Fatalf("expected error:\n%v\ngot:\n%v\n%s", commands.LintFailure, err, stdOut)
+		}
+
+		if strings.Contains(stdOut.String(), "SUCCESS") || !strings.Contains(stdOut.String(), "FAIL: ") {
+			t.Errorf("expected the lint errors to be reported as failures, got:\n%s", stdOut)
+		}
+	})
+}
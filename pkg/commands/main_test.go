@@ -0,0 +1,24 @@
+package commands_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestMain - keeps the tests off the caches and the config of the machine
+// running them, e.g. the parsed policy modules hcunit caches under
+// $XDG_CACHE_HOME and the user config under $XDG_CONFIG_HOME
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "hcunit-xdg")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("XDG_CACHE_HOME", filepath.Join(dir, "cache"))
+	os.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "config"))
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
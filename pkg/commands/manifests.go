@@ -0,0 +1,120 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// InvalidManifests - --manifests holds no manifests, or it or --adapter is
+// combined with flags which only apply to rendering a chart
+var InvalidManifests = errors.New("invalid --manifests")
+
+// manifestExtensions - the files of a manifests directory which are read
+// as kubernetes manifests
+var manifestExtensions = map[string]bool{".yaml": true, ".yml": true, ".json": true}
+
+// manifestsPath - the manifests evaluated instead of rendering a chart:
+// --manifests, or the output of the --adapter. Empty when rendering a chart
+func (s *EvalCommand) manifestsPath() string {
+	if s.Manifests != "" {
+		return s.Manifests
+	}
+	return s.adapterOutput
+}
+
+// checkManifestsFlags - refuses the flags which only apply to rendering a
+// chart when evaluating plain manifests
+func (s *EvalCommand) checkManifestsFlags() error {
+	for _, flag := range []struct {
+		name string
+		set  bool
+	}{
+		{"--template", s.Template != "" && s.Template != s.manifestsPath()},
+		{"--chart", s.Chart != ""},
+		{"--lint", s.Lint},
+		{"--dependency-update", s.DependencyUpdate},
+		{"--dependency-verify", s.DependencyVerify},
+		{"--define", len(s.Defines) > 0},
+		{"--fixture", len(s.Fixtures) > 0},
+		{"--tpl-values", s.TplValues},
+		{"--second-pass", len(s.SecondPass) > 0},
+		{"--only-subchart", len(s.OnlySubcharts) > 0},
+	} {
+		if flag.set {
+			return fmt.Errorf("%w: %s renders a chart, it can't be combined with --manifests or --adapter", InvalidManifests, flag.name)
+		}
+	}
+	return nil
+}
+
+// readManifests - the yaml and json manifests under a directory (or a
+// single manifest file), keyed by their file name the way rendered
+// templates are. Hidden directories like .git and .github are skipped,
+// and yaml files sharing a name are joined as the documents of one file
+func readManifests(root string, filter TemplateFilter) (map[string]string, error) {
+	files, err := WalkTemplatePath(root)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", InvalidManifests, err)
+	}
+
+	if files, err = filter.apply(root, files); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(files))
+	for name, file := range files {
+		if hiddenPath(root, name) || !manifestExtensions[strings.ToLower(path.Ext(name))] {
+			file.Close()
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	manifests := make(map[string]string)
+	for _, name := range names {
+		b, err := ioutil.ReadAll(files[name])
+		files[name].Close()
+		if err != nil {
+			return nil, fmt.Errorf("reading manifest %s failed: %w", name, err)
+		}
+
+		key := path.Base(name)
+		previous, ok := manifests[key]
+		switch {
+		case !ok:
+			manifests[key] = string(b)
+		case strings.ToLower(path.Ext(key)) == ".json":
+			return nil, fmt.Errorf("%w: more than one json manifest is named %s", InvalidManifests, key)
+		default:
+			manifests[key] = strings.TrimRight(previous, "\n") + "\n---\n" + string(b)
+		}
+	}
+
+	if len(manifests) == 0 {
+		return nil, fmt.Errorf("%w: no yaml or json manifests found in %s", InvalidManifests, root)
+	}
+	return manifests, nil
+}
+
+// hiddenPath - whether a directory of the path below root starts with a
+// dot. A manifest file given as the root itself is never hidden
+func hiddenPath(root, name string) bool {
+	root = filepath.ToSlash(filepath.Clean(root))
+	if name == root {
+		return false
+	}
+
+	rel := strings.TrimPrefix(name, root+"/")
+	for _, dir := range strings.Split(path.Dir(rel), "/") {
+		if strings.HasPrefix(dir, ".") && dir != "." {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package commands_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestEvalManifests(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		cmd       *commands.EvalCommand
+		failsWith error
+		contains  []string
+	}{
+		{
+			name: "plain manifests are evaluated without rendering",
+			cmd: &commands.EvalCommand{
+				Manifests: "testdata/manifests",
+				Policy:    []string{"testdata/policy/individuals/manifests_in_input.rego"},
+			},
+			contains: []string{"[SUCCESS]"},
+		},
+		{
+			name: "violations in manifests are reproduced with --manifests",
+			cmd: &commands.EvalCommand{
+				Manifests: "testdata/manifests",
+				Policy:    []string{"testdata/policy/individuals/manifests_pinned_images.rego"},
+			},
+			failsWith: commands.PolicyFailure,
+			contains:  []string{`FAIL: `, "REPRODUCE: \x1b[0mhcunit eval --manifests testdata/manifests"},
+		},
+		{
+			name: "template filters apply to manifests",
+			cmd: &commands.EvalCommand{
+				Manifests:        "testdata/manifests",
+				Policy:           []string{"testdata/policy/individuals/manifests_pinned_images.rego"},
+				ExcludeTemplates: []string{"worker/"},
+			},
+			contains: []string{"[SUCCESS]"},
+		},
+		{
+			name: "a single manifest file can be evaluated",
+			cmd: &commands.EvalCommand{
+				Manifests: "testdata/manifests/worker/deployment.yaml",
+				Policy:    []string{"testdata/policy/individuals/manifests_pinned_images.rego"},
+			},
+			failsWith: commands.PolicyFailure,
+		},
+		{
+			name: "directories without manifests are refused",
+			cmd: &commands.EvalCommand{
+				Manifests: "testdata/nomanifests",
+				Policy:    []string{"testdata/policy/passing"},
+			},
+			failsWith: commands.InvalidManifests,
+		},
+		{
+			name: "chart flags are refused with --manifests",
+			cmd: &commands.EvalCommand{
+				Manifests: "testdata/manifests",
+				Template:  "testdata/templates",
+				Policy:    []string{"testdata/policy/passing"},
+			},
+			failsWith: commands.InvalidManifests,
+		},
+		{
+			name: "rendering flags are refused with --manifests",
+			cmd: &commands.EvalCommand{
+				Manifests: "testdata/manifests",
+				Policy:    []string{"testdata/policy/passing"},
+				TplValues: true,
+			},
+			failsWith: commands.InvalidManifests,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			stdOut := new(bytes.Buffer)
+			tt.cmd.Stdout = stdOut
+			err := tt.cmd.Execute([]string{})
+			if tt.failsWith == nil && err != nil || !errors.Is(err, tt.failsWith) {
+				t.Fatalf("expected error:\n%v\ngot:\n%v\n%s", tt.failsWith, err, stdOut.String())
+			}
+
+			for _, expected := range tt.contains {
+				if !strings.Contains(stdOut.String(), expected) {
+					t.Errorf("expected the output to contain %q, got:\n%s", expected, stdOut.String())
+				}
+			}
+		})
+	}
+}
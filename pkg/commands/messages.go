@@ -0,0 +1,187 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// InvalidLanguage - --lang is neither a built-in language nor a readable
+// message catalog
+var InvalidLanguage = errors.New("unknown language, give one of the built-in languages or a yaml message catalog")
+
+// messageCatalog - hcunit's own user facing strings (result labels,
+// summary lines and hints) by key. Rule names, descriptions and violation
+// messages come from the policies and are printed as they are
+type messageCatalog map[string]string
+
+// defaultMessages - the english catalog, which every other catalog falls
+// back to for the keys it doesn't translate
+var defaultMessages = messageCatalog{
+	"pass":                  "PASS",
+	"fail":                  "FAIL",
+	"warn":                  "WARN",
+	"dryrun":                "DRYRUN",
+	"reproduce":             "REPRODUCE",
+	"score":                 "SCORE",
+	"threshold":             "THRESHOLD",
+	"partial_eval":          "PARTIAL EVAL",
+	"expected":              "expected",
+	"actual":                "actual",
+	"owned_by":              "owned by %s",
+	"from_subchart":         "from subchart %s",
+	"failed_at":             "failed at %s:%d: %s",
+	"undefined":             "undefined",
+	"deprecated":            "deprecated",
+	"deprecated_since":      "deprecated, due for removal since %s",
+	"deprecated_until":      "deprecated, to be removed after %s",
+	"sunset_summary":        "[SUNSET] %d evaluated rules are past their remove_after date and due for removal from the policies",
+	"dryrun_summary":        "[DRYRUN] %d policy violations found by rules which are not enforced yet",
+	"failure_summary":       "[FAILURE] Policy violations found on the Helm Chart!",
+	"success_summary":       "[SUCCESS] Your Helm Chart complies with all policies!",
+	"tolerated_summary":     "[TOLERATED] Policy violations are within the configured thresholds",
+	"score_summary":         "%.1f%% compliant (%d passed, %d failed, %d warned)",
+	"partial_eval_summary":  "%d of %d rules specialized to the values",
+	"max_failures_exceeded": "%d failures exceed --max-failures %d",
+	"failures_untolerated":  "%d failures, give --max-failures or --min-score to tolerate them",
+	"max_warnings_exceeded": "%d warnings exceed --max-warnings %d",
+	"min_score_missed":      "score %.1f%% is below --min-score %.1f%%",
+}
+
+// builtinMessages - the catalogs shipped with hcunit, by language tag
+var builtinMessages = map[string]messageCatalog{
+	"en": defaultMessages,
+	"de": {
+		"pass":                  "BESTANDEN",
+		"fail":                  "FEHLER",
+		"warn":                  "WARNUNG",
+		"dryrun":                "PROBELAUF",
+		"reproduce":             "NACHSTELLEN",
+		"score":                 "ERGEBNIS",
+		"threshold":             "SCHWELLENWERT",
+		"partial_eval":          "TEILAUSWERTUNG",
+		"expected":              "erwartet",
+		"actual":                "tatsächlich",
+		"owned_by":              "verantwortlich: %s",
+		"from_subchart":         "aus Subchart %s",
+		"failed_at":             "fehlgeschlagen bei %s:%d: %s",
+		"undefined":             "undefiniert",
+		"deprecated":            "veraltet",
+		"deprecated_since":      "veraltet, seit %s zur Entfernung fällig",
+		"deprecated_until":      "veraltet, wird nach %s entfernt",
+		"sunset_summary":        "[AUSLAUFEND] %d ausgewertete Regeln haben ihr remove_after-Datum überschritten und sollten aus den Policies entfernt werden",
+		"dryrun_summary":        "[PROBELAUF] %d Policy-Verstöße durch Regeln gefunden, die noch nicht durchgesetzt werden",
+		"failure_summary":       "[FEHLGESCHLAGEN] Policy-Verstöße im Helm Chart gefunden!",
+		"success_summary":       "[ERFOLGREICH] Dein Helm Chart erfüllt alle Policies!",
+		"tolerated_summary":     "[TOLERIERT] Die Policy-Verstöße liegen innerhalb der konfigurierten Schwellenwerte",
+		"score_summary":         "%.1f%% konform (%d bestanden, %d fehlgeschlagen, %d gewarnt)",
+		"partial_eval_summary":  "%d von %d Regeln auf die Values spezialisiert",
+		"max_failures_exceeded": "%d Fehler überschreiten --max-failures %d",
+		"failures_untolerated":  "%d Fehler, mit --max-failures oder --min-score werden sie toleriert",
+		"max_warnings_exceeded": "%d Warnungen überschreiten --max-warnings %d",
+		"min_score_missed":      "Ergebnis %.1f%% liegt unter --min-score %.1f%%",
+	},
+	"es": {
+		"pass":                  "CORRECTO",
+		"fail":                  "ERROR",
+		"warn":                  "AVISO",
+		"dryrun":                "SIMULACIÓN",
+		"reproduce":             "REPRODUCIR",
+		"score":                 "PUNTUACIÓN",
+		"threshold":             "UMBRAL",
+		"partial_eval":          "EVALUACIÓN PARCIAL",
+		"expected":              "esperado",
+		"actual":                "obtenido",
+		"owned_by":              "responsable: %s",
+		"from_subchart":         "del subchart %s",
+		"failed_at":             "falló en %s:%d: %s",
+		"undefined":             "indefinido",
+		"deprecated":            "obsoleta",
+		"deprecated_since":      "obsoleta, pendiente de eliminar desde %s",
+		"deprecated_until":      "obsoleta, se eliminará después del %s",
+		"sunset_summary":        "[RETIRADA] %d reglas evaluadas han superado su fecha remove_after y deben eliminarse de las políticas",
+		"dryrun_summary":        "[SIMULACIÓN] %d infracciones encontradas por reglas que todavía no se aplican",
+		"failure_summary":       "[FALLO] ¡Se encontraron infracciones de las políticas en el Helm Chart!",
+		"success_summary":       "[ÉXITO] ¡Tu Helm Chart cumple todas las políticas!",
+		"tolerated_summary":     "[TOLERADO] Las infracciones están dentro de los umbrales configurados",
+		"score_summary":         "%.1f%% de cumplimiento (%d correctas, %d fallidas, %d avisos)",
+		"partial_eval_summary":  "%d de %d reglas especializadas para los values",
+		"max_failures_exceeded": "%d fallos superan --max-failures %d",
+		"failures_untolerated":  "%d fallos, usa --max-failures o --min-score para tolerarlos",
+		"max_warnings_exceeded": "%d avisos superan --max-warnings %d",
+		"min_score_missed":      "la puntuación %.1f%% está por debajo de --min-score %.1f%%",
+	},
+}
+
+// loadMessages - the catalog for a language tag, falling back from a
+// regional tag (de-AT, de_AT.UTF-8) to its language, or read from a yaml
+// file of key: message pairs. The empty tag is english
+func loadMessages(lang string) (messageCatalog, error) {
+	if lang == "" {
+		return defaultMessages, nil
+	}
+
+	if ext := filepath.Ext(lang); ext == ".yaml" || ext == ".yml" {
+		return readMessages(lang)
+	}
+
+	tag := strings.ToLower(strings.Replace(strings.SplitN(lang, ".", 2)[0], "_", "-", -1))
+	if catalog, ok := builtinMessages[tag]; ok {
+		return catalog, nil
+	}
+
+	if catalog, ok := builtinMessages[strings.SplitN(tag, "-", 2)[0]]; ok {
+		return catalog, nil
+	}
+	return nil, fmt.Errorf("%w: %q is not one of %s", InvalidLanguage, lang, strings.Join(languages(), ", "))
+}
+
+// readMessages - reads a message catalog file, refusing keys hcunit
+// doesn't know so typos don't silently fall back to english
+func readMessages(path string) (messageCatalog, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", InvalidLanguage, err)
+	}
+
+	catalog := messageCatalog{}
+	if err := yaml.Unmarshal(b, &catalog); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", InvalidLanguage, path, err)
+	}
+
+	for key := range catalog {
+		if _, ok := defaultMessages[key]; !ok {
+			return nil, fmt.Errorf("%w: %s: unknown message %q", InvalidLanguage, path, key)
+		}
+	}
+	return catalog, nil
+}
+
+// languages - the built-in language tags, sorted
+func languages() []string {
+	tags := make([]string, 0, len(builtinMessages))
+	for tag := range builtinMessages {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// text - the message of a key formatted with its args, in english when
+// the catalog doesn't translate it
+func (c messageCatalog) text(key string, args ...interface{}) string {
+	message, ok := c[key]
+	if !ok {
+		message = defaultMessages[key]
+	}
+
+	if len(args) == 0 {
+		return message
+	}
+	return fmt.Sprintf(message, args...)
+}
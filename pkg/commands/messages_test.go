@@ -0,0 +1,89 @@
+package commands_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestEvalLang(t *testing.T) {
+	intPtr := func(i int) *int { return &i }
+	for _, tt := range []struct {
+		name        string
+		lang        string
+		policy      string
+		maxFailures *int
+		failsWith   error
+		contains    []string
+	}{
+		{
+			name:     "results are in english by default",
+			policy:   "testdata/policy/passing",
+			contains: []string{"PASS: \x1b[0m", "[SUCCESS] Your Helm Chart complies with all policies!"},
+		},
+		{
+			name:      "labels and summaries are localized",
+			lang:      "de",
+			policy:    "testdata/policy/failing",
+			failsWith: commands.PolicyFailure,
+			contains:  []string{"BESTANDEN: \x1b[0m", "FEHLER: \x1b[0m", "[FEHLGESCHLAGEN] Policy-Verstöße im Helm Chart gefunden!"},
+		},
+		{
+			name:     "regional tags fall back to their language",
+			lang:     "es_ES.UTF-8",
+			policy:   "testdata/policy/passing",
+			contains: []string{"CORRECTO: \x1b[0m", "[ÉXITO] ¡Tu Helm Chart cumple todas las políticas!"},
+		},
+		{
+			name:        "threshold hints are localized",
+			lang:        "de-AT",
+			policy:      "testdata/policy/failing",
+			maxFailures: intPtr(3),
+			failsWith:   commands.ThresholdExceeded,
+			contains:    []string{"ERGEBNIS: \x1b[0m50.0% konform", "4 Fehler überschreiten --max-failures 3"},
+		},
+		{
+			name:     "catalog files fall back to english for the messages they don't translate",
+			lang:     "testdata/messages/pt.yaml",
+			policy:   "testdata/policy/passing",
+			contains: []string{"APROVADO: \x1b[0m", "[SUCESSO] O seu Helm Chart cumpre todas as políticas!"},
+		},
+		{
+			name:      "unknown languages are refused",
+			lang:      "xx",
+			policy:    "testdata/policy/passing",
+			failsWith: commands.InvalidLanguage,
+		},
+		{
+			name:      "catalog files with unknown messages are refused",
+			lang:      "testdata/messages/typo.yaml",
+			policy:    "testdata/policy/passing",
+			failsWith: commands.InvalidLanguage,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			stdOut := new(bytes.Buffer)
+			evalCmd := &commands.EvalCommand{
+				Stdout:      stdOut,
+				Template:    "testdata/templates",
+				Values:      []string{"testdata/values.yml"},
+				Policy:      []string{tt.policy},
+				Lang:        tt.lang,
+				MaxFailures: tt.maxFailures,
+			}
+			err := evalCmd.Execute([]string{})
+			if !errors.Is(err, tt.failsWith) {
+				t.Fatalf("expected error:\n%v\ngot:\n%v", tt.failsWith, err)
+			}
+
+			for _, expected := range tt.contains {
+				if !strings.Contains(stdOut.String(), expected) {
+					t.Errorf("expected output to contain %q, got:\n%s", expected, stdOut.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package commands
+
+const networkHashName = "network"
+
+type networkModel struct {
+	Policies  []networkPolicy   `json:"policies"`
+	Services  []networkService  `json:"services"`
+	Workloads []networkWorkload `json:"workloads"`
+}
+
+type networkPolicy struct {
+	Name             string   `json:"name"`
+	Namespace        string   `json:"namespace"`
+	PolicyTypes      []string `json:"policyTypes"`
+	AllowsAllIngress bool     `json:"allowsAllIngress"`
+	AllowsAllEgress  bool     `json:"allowsAllEgress"`
+	IngressCIDRs     []string `json:"ingressCIDRs"`
+	EgressCIDRs      []string `json:"egressCIDRs"`
+	Workloads        []string `json:"workloads"`
+}
+
+type networkService struct {
+	Name      string   `json:"name"`
+	Namespace string   `json:"namespace"`
+	Workloads []string `json:"workloads"`
+}
+
+type networkWorkload struct {
+	Ref       string                 `json:"ref"`
+	Kind      string                 `json:"kind"`
+	Name      string                 `json:"name"`
+	Namespace string                 `json:"namespace"`
+	Labels    map[string]interface{} `json:"labels"`
+	Policies  []string               `json:"policies"`
+	Services  []string               `json:"services"`
+}
+
+// buildNetworkModel - joins the rendered NetworkPolicies and Services with the
+// pod templates they select so policies don't need to match selectors in rego
+func buildNetworkModel(objects []map[string]interface{}) networkModel {
+	model := networkModel{
+		Policies:  make([]networkPolicy, 0),
+		Services:  make([]networkService, 0),
+		Workloads: make([]networkWorkload, 0),
+	}
+
+	for _, obj := range objects {
+		template := podTemplate(obj)
+		if template == nil {
+			continue
+		}
+
+		labels := getMap(template, "metadata", "labels")
+		if labels == nil {
+			labels = map[string]interface{}{}
+		}
+
+		model.Workloads = append(model.Workloads, networkWorkload{
+			Ref:       objectRef(obj),
+			Kind:      objectKind(obj),
+			Name:      objectName(obj),
+			Namespace: objectNamespace(obj),
+			Labels:    labels,
+			Policies:  make([]string, 0),
+			Services:  make([]string, 0),
+		})
+	}
+
+	for _, obj := range objects {
+		switch objectKind(obj) {
+		case "NetworkPolicy":
+			policy := newNetworkPolicy(obj)
+			for i, workload := range model.Workloads {
+				if workload.Namespace == policy.Namespace &&
+					labelSelectorMatches(getMap(obj, "spec", "podSelector"), workload.Labels) {
+					policy.Workloads = append(policy.Workloads, workload.Ref)
+					model.Workloads[i].Policies = append(model.Workloads[i].Policies, policy.Name)
+				}
+			}
+			model.Policies = append(model.Policies, policy)
+
+		case "Service":
+			service := networkService{
+				Name:      objectName(obj),
+				Namespace: objectNamespace(obj),
+				Workloads: make([]string, 0),
+			}
+			selector := getMap(obj, "spec", "selector")
+			for i, workload := range model.Workloads {
+				if len(selector) > 0 &&
+					workload.Namespace == service.Namespace &&
+					mapSelectorMatches(selector, workload.Labels) {
+					service.Workloads = append(service.Workloads, workload.Ref)
+					model.Workloads[i].Services = append(model.Workloads[i].Services, service.Name)
+				}
+			}
+			model.Services = append(model.Services, service)
+		}
+	}
+	return model
+}
+
+func newNetworkPolicy(obj map[string]interface{}) networkPolicy {
+	policy := networkPolicy{
+		Name:         objectName(obj),
+		Namespace:    objectNamespace(obj),
+		PolicyTypes:  make([]string, 0),
+		IngressCIDRs: make([]string, 0),
+		EgressCIDRs:  make([]string, 0),
+		Workloads:    make([]string, 0),
+	}
+
+	for _, t := range getSlice(obj, "spec", "policyTypes") {
+		if s, ok := t.(string); ok {
+			policy.PolicyTypes = append(policy.PolicyTypes, s)
+		}
+	}
+
+	// the api server defaults policyTypes to Ingress, plus Egress when
+	// egress rules are present
+	if len(policy.PolicyTypes) == 0 {
+		policy.PolicyTypes = append(policy.PolicyTypes, "Ingress")
+		if getField(obj, "spec", "egress") != nil {
+			policy.PolicyTypes = append(policy.PolicyTypes, "Egress")
+		}
+	}
+
+	for _, r := range getSlice(obj, "spec", "ingress") {
+		rule, _ := r.(map[string]interface{})
+		peers := getSlice(rule, "from")
+		if len(peers) == 0 {
+			policy.AllowsAllIngress = true
+		}
+		policy.IngressCIDRs = append(policy.IngressCIDRs, peerCIDRs(peers)...)
+	}
+
+	for _, r := range getSlice(obj, "spec", "egress") {
+		rule, _ := r.(map[string]interface{})
+		peers := getSlice(rule, "to")
+		if len(peers) == 0 {
+			policy.AllowsAllEgress = true
+		}
+		policy.EgressCIDRs = append(policy.EgressCIDRs, peerCIDRs(peers)...)
+	}
+	return policy
+}
+
+func peerCIDRs(peers []interface{}) []string {
+	cidrs := make([]string, 0)
+	for _, p := range peers {
+		peer, _ := p.(map[string]interface{})
+		if cidr := getString(peer, "ipBlock", "cidr"); cidr != "" {
+			cidrs = append(cidrs, cidr)
+		}
+	}
+	return cidrs
+}
@@ -0,0 +1,164 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"text/template"
+)
+
+// notification types and when they are sent
+const (
+	notifySlack   = "slack"
+	notifyTeams   = "teams"
+	notifyFailure = "failure"
+	notifyAlways  = "always"
+)
+
+// defaultNotificationTemplate - a summary line and the top failures
+const defaultNotificationTemplate = `hcunit: {{ .Chart }}{{ if .Commit }} at {{ .Commit }}{{ end }} {{ if .Passed }}passed{{ else }}failed{{ end }}: {{ .Summary.Passed }} passed, {{ .Summary.Failed }} failed, {{ .Summary.Warned }} warned
+{{- range .TopFailures }}
+• {{ .Rule }}{{ if .Team }} (team {{ .Team }}){{ end }}
+{{- end }}
+{{- if gt .MoreFailures 0 }}
+…and {{ .MoreFailures }} more
+{{- end }}
+{{- if .Error }}
+{{ .Error }}
+{{- end }}`
+
+var InvalidNotification = errors.New("invalid notification in config")
+
+// Notification - a chat webhook notified of eval --notify runs, e.g. from
+// scheduled compliance scans
+type Notification struct {
+	// Type - slack or teams
+	Type string `yaml:"type"`
+	// URL - the incoming webhook url, or URLEnv the environment variable
+	// holding it, to keep it out of the repo
+	URL    string `yaml:"url,omitempty"`
+	URLEnv string `yaml:"url_env,omitempty"`
+	// On - failure (the default) or always
+	On string `yaml:"on,omitempty"`
+	// Template - a go text/template of the message, given NotificationData
+	Template string `yaml:"template,omitempty"`
+	// Top - the number of failed rules listed (default: 5)
+	Top int `yaml:"top,omitempty"`
+}
+
+// NotificationData - what notification templates are rendered with
+type NotificationData struct {
+	Chart        string
+	Commit       string
+	Passed       bool
+	Summary      AuditSummary
+	TopFailures  []RuleReport
+	MoreFailures int
+	Error        string
+}
+
+// notify - sends the results of this run to the notifications declared in
+// the config
+func (s *EvalCommand) notify(runErr error) error {
+	report := s.runReport(runErr)
+	for i, notification := range s.config.Notifications {
+		if runErr == nil && notification.On != notifyAlways {
+			continue
+		}
+
+		url := notification.URL
+		if notification.URLEnv != "" {
+			url = os.Getenv(notification.URLEnv)
+		}
+
+		if url == "" {
+			return fmt.Errorf("%w: notification %d has no url (or %s is unset)", InvalidNotification, i+1, notification.URLEnv)
+		}
+
+		if s.Offline {
+			return offlineError(notification.Type + " notification")
+		}
+
+		message, err := notificationMessage(notification, report, s.Template, runErr)
+		if err != nil {
+			return err
+		}
+
+		payload, err := notificationPayload(notification.Type, message)
+		if err != nil {
+			return fmt.Errorf("%w: notification %d: %v", InvalidNotification, i+1, err)
+		}
+
+		headers := map[string]string{"Content-Type": "application/json"}
+		if err := s.config.Fetch.withDefaults().httpPost(url, payload, headers); err != nil {
+			return fmt.Errorf("sending %s notification failed: %w", notification.Type, err)
+		}
+	}
+	return nil
+}
+
+func notificationMessage(notification Notification, report Results, templatePath string, runErr error) (string, error) {
+	text := notification.Template
+	if text == "" {
+		text = defaultNotificationTemplate
+	}
+
+	tmpl, err := template.New("notification").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", InvalidNotification, err)
+	}
+
+	top := notification.Top
+	if top <= 0 {
+		top = 5
+	}
+
+	data := NotificationData{
+		Chart:   historyChartName(report.Provenance, templatePath),
+		Commit:  currentCommit(templatePath),
+		Passed:  runErr == nil,
+		Summary: report.Summary,
+	}
+
+	var violation *ViolationError
+	if runErr != nil && !errors.As(runErr, &violation) {
+		data.Error = runErr.Error()
+	}
+
+	for _, result := range report.Results {
+		if result.Result != "fail" {
+			continue
+		}
+
+		if len(data.TopFailures) < top {
+			data.TopFailures = append(data.TopFailures, result)
+		} else {
+			data.MoreFailures++
+		}
+	}
+
+	out := new(bytes.Buffer)
+	if err := tmpl.Execute(out, data); err != nil {
+		return "", fmt.Errorf("%w: %v", InvalidNotification, err)
+	}
+	return out.String(), nil
+}
+
+// notificationPayload - the message as a slack or microsoft teams incoming
+// webhook expects it
+func notificationPayload(kind, message string) ([]byte, error) {
+	switch kind {
+	case notifySlack:
+		return json.Marshal(map[string]string{"text": message})
+	case notifyTeams:
+		return json.Marshal(map[string]string{
+			"@type":    "MessageCard",
+			"@context": "https://schema.org/extensions",
+			"summary":  "hcunit results",
+			"text":     message,
+		})
+	}
+	return nil, fmt.Errorf("unsupported type %q, expected %s or %s", kind, notifySlack, notifyTeams)
+}
@@ -0,0 +1,115 @@
+package commands_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestEvalNotify(t *testing.T) {
+	received := make(map[string]map[string]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload := make(map[string]string)
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("expected a json payload: %v", err)
+		}
+		received[r.URL.Path] = payload
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "hcunit-notify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	os.Setenv("HCUNIT_TEST_TEAMS_WEBHOOK", server.URL+"/teams")
+	os.Setenv("GITHUB_SHA", "abc1234")
+	defer os.Unsetenv("HCUNIT_TEST_TEAMS_WEBHOOK")
+	defer os.Unsetenv("GITHUB_SHA")
+
+	for _, tt := range []struct {
+		name      string
+		config    string
+		policy    string
+		failsWith error
+		expected  map[string]string
+	}{
+		{
+			name: "failures are sent to every webhook",
+			config: `notifications:
+  - type: slack
+    url: ` + server.URL + `/slack
+    top: 2
+  - type: teams
+    url_env: HCUNIT_TEST_TEAMS_WEBHOOK
+    template: "{{ .Summary.Failed }} failures"
+`,
+			policy:    "testdata/policy/failing",
+			failsWith: commands.PolicyFailure,
+			expected: map[string]string{
+				"/slack": "hcunit: templates at abc1234 failed: 4 passed, 4 failed, 0 warned\n• data.main.expect[\"another force failure 123\"]\n• data.main.expect[\"another force failure\"]\n…and 2 more",
+				"/teams": "4 failures",
+			},
+		},
+		{
+			name: "passing runs are only sent to webhooks notified always",
+			config: `notifications:
+  - type: slack
+    url: ` + server.URL + `/slack
+  - type: teams
+    url: ` + server.URL + `/teams
+    on: always
+`,
+			policy:    "testdata/policy/passing",
+			failsWith: nil,
+			expected: map[string]string{
+				"/teams": "hcunit: templates at abc1234 passed: 4 passed, 0 failed, 0 warned",
+			},
+		},
+		{
+			name:      "unsupported webhook types",
+			config:    "notifications:\n  - type: irc\n    url: " + server.URL + "\n",
+			policy:    "testdata/policy/failing",
+			failsWith: commands.PolicyFailure,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			received = make(map[string]map[string]string)
+			configPath := filepath.Join(dir, ".hcunit.yaml")
+			if err := ioutil.WriteFile(configPath, []byte(tt.config), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			evalCmd := &commands.EvalCommand{
+				Stdout:   ioutil.Discard,
+				Template: "testdata/templates",
+				Values:   []string{"testdata/values.yml"},
+				Policy:   []string{tt.policy},
+				Notify:   true,
+				Config:   configPath,
+			}
+			err := evalCmd.Execute([]string{})
+			if !errors.Is(err, tt.failsWith) {
+				t.Fatalf("expected error:\n%v\ngot:\n%v", tt.failsWith, err)
+			}
+
+			if len(received) != len(tt.expected) {
+				t.Errorf("expected %d notifications, got: %v", len(tt.expected), received)
+			}
+
+			for path, expected := range tt.expected {
+				if received[path]["text"] != expected {
+					t.Errorf("expected %s to be notified with %q, got: %q", path, expected, received[path]["text"])
+				}
+			}
+		})
+	}
+}
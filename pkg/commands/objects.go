@@ -0,0 +1,165 @@
+package commands
+
+import (
+	"fmt"
+	"sort"
+)
+
+// renderedObjects - flattens the unmarshalled templates of a policy input
+// into the kubernetes objects they contain, ordered by template name
+func renderedObjects(input map[string]interface{}) []map[string]interface{} {
+	names := make([]string, 0, len(input))
+	for name := range input {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	objects := make([]map[string]interface{}, 0)
+	for _, name := range names {
+		switch doc := input[name].(type) {
+		case map[string]interface{}:
+			if isObject(doc) {
+				objects = append(objects, doc)
+			}
+		case []interface{}:
+			for _, d := range doc {
+				if obj, ok := d.(map[string]interface{}); ok && isObject(obj) {
+					objects = append(objects, obj)
+				}
+			}
+		}
+	}
+	return objects
+}
+
+func isObject(doc map[string]interface{}) bool {
+	_, ok := doc["kind"].(string)
+	return ok
+}
+
+func objectKind(obj map[string]interface{}) string {
+	return getString(obj, "kind")
+}
+
+func objectName(obj map[string]interface{}) string {
+	return getString(obj, "metadata", "name")
+}
+
+func objectNamespace(obj map[string]interface{}) string {
+	return getString(obj, "metadata", "namespace")
+}
+
+// objectRef - the Kind/name pair used to refer to an object from the
+// aggregated views we add to the policy input
+func objectRef(obj map[string]interface{}) string {
+	return fmt.Sprintf("%s/%s", objectKind(obj), objectName(obj))
+}
+
+func getField(obj map[string]interface{}, path ...string) interface{} {
+	var current interface{} = obj
+	for _, key := range path {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = m[key]
+	}
+	return current
+}
+
+func getString(obj map[string]interface{}, path ...string) string {
+	s, _ := getField(obj, path...).(string)
+	return s
+}
+
+func getMap(obj map[string]interface{}, path ...string) map[string]interface{} {
+	m, _ := getField(obj, path...).(map[string]interface{})
+	return m
+}
+
+func getSlice(obj map[string]interface{}, path ...string) []interface{} {
+	s, _ := getField(obj, path...).([]interface{})
+	return s
+}
+
+// podTemplate - returns the pod template (metadata + spec) of a workload
+// object, or nil when the object does not manage pods
+func podTemplate(obj map[string]interface{}) map[string]interface{} {
+	path, ok := podTemplatePath(objectKind(obj))
+	if !ok {
+		return nil
+	}
+	return getMap(obj, path...)
+}
+
+// podTemplatePath - the path to the pod template within an object of the
+// given kind. A Pod is its own template
+func podTemplatePath(kind string) ([]string, bool) {
+	switch kind {
+	case "Pod":
+		return []string{}, true
+	case "Deployment", "StatefulSet", "DaemonSet", "ReplicaSet", "ReplicationController", "Job":
+		return []string{"spec", "template"}, true
+	case "CronJob":
+		return []string{"spec", "jobTemplate", "spec", "template"}, true
+	}
+	return nil, false
+}
+
+// labelSelectorMatches - evaluates a metav1.LabelSelector (matchLabels and
+// matchExpressions) against a set of labels. An empty selector matches everything
+func labelSelectorMatches(selector map[string]interface{}, labels map[string]interface{}) bool {
+	if !mapSelectorMatches(getMap(selector, "matchLabels"), labels) {
+		return false
+	}
+
+	for _, e := range getSlice(selector, "matchExpressions") {
+		expression, ok := e.(map[string]interface{})
+		if !ok {
+			return false
+		}
+
+		key := getString(expression, "key")
+		value, exists := labels[key]
+		inValues := false
+		for _, v := range getSlice(expression, "values") {
+			if fmt.Sprint(v) == fmt.Sprint(value) {
+				inValues = true
+			}
+		}
+
+		switch getString(expression, "operator") {
+		case "In":
+			if !exists || !inValues {
+				return false
+			}
+		case "NotIn":
+			if exists && inValues {
+				return false
+			}
+		case "Exists":
+			if !exists {
+				return false
+			}
+		case "DoesNotExist":
+			if exists {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// mapSelectorMatches - evaluates a plain equality selector (as used by
+// Services) against a set of labels
+func mapSelectorMatches(selector map[string]interface{}, labels map[string]interface{}) bool {
+	for k, v := range selector {
+		label, ok := labels[k]
+		if !ok || fmt.Sprint(label) != fmt.Sprint(v) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+)
+
+var OfflineViolation = errors.New("network access attempted in offline mode")
+
+// offlineError - names the component which tried to reach the network so
+// the user knows which input needs to be vendored
+func offlineError(component string) error {
+	return fmt.Errorf("%w: %s", OfflineViolation, component)
+}
+
+// checkOfflinePolicy - rejects policies calling http.send before they are
+// evaluated, pointing at the offending call. Parsed modules are cached in
+// cacheDir unless it is empty
+func checkOfflinePolicy(policies []string, cacheDir string) error {
+	mods, cached, err := parsePolicies(policies, cacheDir)
+	if err != nil {
+		return nil
+	}
+
+	var violation error
+	for _, mod := range mods {
+		ast.WalkTerms(mod, func(term *ast.Term) bool {
+			if ref, ok := term.Value.(ast.Ref); ok && violation == nil && ref.Equal(ast.HTTPSend.Ref()) {
+				violation = offlineError(fmt.Sprintf("http.send called by policy at %v", term.Location))
+			}
+			return violation != nil
+		})
+	}
+
+	if violation != nil && cached {
+		// cached modules have no source locations, parse them again to
+		// point at the call
+		return checkOfflinePolicy(policies, "")
+	}
+	return violation
+}
+
+// offlineBuiltins - disables the network capable builtins at compile time as a
+// backstop to checkOfflinePolicy
+func offlineBuiltins() func(*rego.Rego) {
+	return rego.UnsafeBuiltins(map[string]struct{}{
+		ast.HTTPSend.Name: {},
+	})
+}
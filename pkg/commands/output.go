@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"regexp"
+)
+
+const (
+	outputText         = "text"
+	outputJSON         = "json"
+	outputPlainVerbose = "plain-verbose"
+)
+
+var InvalidOutput = errors.New("invalid --output")
+
+var colorCodes = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// colorlessWriter - writes through to the underlying writer without the
+// color codes of colorstring
+type colorlessWriter struct {
+	io.Writer
+}
+
+func (s colorlessWriter) Write(p []byte) (int, error) {
+	if _, err := s.Writer.Write(colorCodes.ReplaceAll(p, nil)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// output - how the results are printed: --output, or the output of the
+// config, or text, which pretty is another name of
+func (s *EvalCommand) output() string {
+	output := outputText
+	switch {
+	case s.Output != "":
+		output = s.Output
+	case s.config != nil && s.config.Defaults.Output != "":
+		output = s.config.Defaults.Output
+	}
+
+	if output == outputPretty {
+		return outputText
+	}
+	return output
+}
+
+// color - whether the output is colored: not with --no-color or
+// plain-verbose output, otherwise as the color of the config, which
+// defaults to colored
+func (s *EvalCommand) color() bool {
+	if s.output() == outputPlainVerbose {
+		return false
+	}
+
+	if s.NoColor || s.config == nil || s.config.Defaults.Color == nil {
+		return !s.NoColor
+	}
+	return *s.config.Defaults.Color
+}
+
+// ascii - whether hcunit's own symbols are limited to ascii: with --ascii,
+// or as the ascii of the config
+func (s *EvalCommand) ascii() bool {
+	return s.ASCII || s.config != nil && s.config.Defaults.ASCII
+}
+
+// outputWriter - where the text output of the run goes. With the json or
+// tap output of a Reporter it is dropped, as only the results are printed
+func (s *EvalCommand) outputWriter(stdout io.Writer) io.Writer {
+	switch {
+	case s.reporter() != nil:
+		return ioutil.Discard
+	case !s.color():
+		return colorlessWriter{stdout}
+	}
+	return stdout
+}
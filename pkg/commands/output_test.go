@@ -0,0 +1,198 @@
+package commands_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestEvalOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hcunit-output")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	defer os.Setenv("XDG_CONFIG_HOME", os.Getenv("XDG_CONFIG_HOME"))
+	os.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "xdg"))
+
+	policy, err := filepath.Abs("testdata/policy/failing")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name      string
+		config    string
+		policy    string
+		output    string
+		noColor   bool
+		failsWith error
+		check     func(t *testing.T, out string)
+	}{
+		{
+			name:      "results are printed as colored text",
+			failsWith: commands.PolicyFailure,
+			check: func(t *testing.T, out string) {
+				if !strings.Contains(out, "FAIL: \x1b[0m") {
+					t.Errorf("expected colored failures, got:\n%s", out)
+				}
+			},
+		},
+		{
+			name:      "--no-color prints the text without colors",
+			noColor:   true,
+			failsWith: commands.PolicyFailure,
+			check: func(t *testing.T, out string) {
+				if strings.Contains(out, "\x1b[") || !strings.Contains(out, "FAIL: data.main.expect") {
+					t.Errorf("expected uncolored failures, got:\n%s", out)
+				}
+			},
+		},
+		{
+			name:      "the config can turn colors off",
+			config:    "defaults:\n  color: false\n",
+			failsWith: commands.PolicyFailure,
+			check: func(t *testing.T, out string) {
+				if strings.Contains(out, "\x1b[") {
+					t.Errorf("expected uncolored failures, got:\n%s", out)
+				}
+			},
+		},
+		{
+			name:      "--output json prints only the json results",
+			output:    "json",
+			failsWith: commands.PolicyFailure,
+			check: func(t *testing.T, out string) {
+				results := commands.Results{}
+				if err := json.Unmarshal([]byte(out), &results); err != nil {
+					t.Fatalf("expected json results, got %v:\n%s", err, out)
+				}
+
+				if results.Summary.Failed != 4 || results.ExitCode != commands.ExitFailure {
+					t.Errorf("expected 4 failed rules, got: %+v", results.Summary)
+				}
+			},
+		},
+		{
+			name:      "--output takes precedence over the config",
+			config:    "defaults:\n  output: json\n",
+			output:    "text",
+			failsWith: commands.PolicyFailure,
+			check: func(t *testing.T, out string) {
+				if !strings.HasPrefix(out, "\x1b[") {
+					t.Errorf("expected text output, got:\n%s", out)
+				}
+			},
+		},
+		{
+			name:      "--output plain-verbose spells the results out in a fixed width column",
+			output:    "plain-verbose",
+			failsWith: commands.PolicyFailure,
+			check: func(t *testing.T, out string) {
+				if strings.Contains(out, "\x1b[") || !strings.Contains(out, "\nFAIL    data.main.expect") || !strings.Contains(out, "\nPASS    data.main.expect") {
+					t.Errorf("expected uncolored results in a column, got:\n%s", out)
+				}
+			},
+		},
+		{
+			name:      "plain-verbose output spells out the sides of assertion diffs",
+			output:    "plain-verbose",
+			policy:    "testdata/policy/individuals/assert_equal.rego",
+			failsWith: commands.PolicyFailure,
+			check: func(t *testing.T, out string) {
+				if !strings.Contains(out, "        expected: host: other.com\n        actual:   host: hcunit.com\n") {
+					t.Errorf("expected the diff sides spelled out, got:\n%s", out)
+				}
+			},
+		},
+		{
+			name:      "the config can select plain-verbose output",
+			config:    "defaults:\n  output: plain-verbose\n",
+			failsWith: commands.PolicyFailure,
+			check: func(t *testing.T, out string) {
+				if strings.Contains(out, "\x1b[") || !strings.Contains(out, "\nFAIL    data.main.expect") {
+					t.Errorf("expected plain-verbose output, got:\n%s", out)
+				}
+			},
+		},
+		{
+			name:      "unknown outputs are rejected",
+			output:    "xml",
+			failsWith: commands.InvalidOutput,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(dir, ".hcunit.yaml")
+			if err := ioutil.WriteFile(configPath, []byte(tt.config), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			policies := []string{policy}
+			if tt.policy != "" {
+				policies = []string{tt.policy}
+			}
+
+			stdOut := new(bytes.Buffer)
+			evalCmd := &commands.EvalCommand{
+				Stdout:   stdOut,
+				Template: "testdata/templates",
+				Values:   []string{"testdata/values.yml"},
+				Policy:   policies,
+				Config:   configPath,
+				Output:   tt.output,
+				NoColor:  tt.noColor,
+			}
+			if err := evalCmd.Execute([]string{}); !errors.Is(err, tt.failsWith) {
+				t.Fatalf("expected error:\n%v\ngot:\n%v", tt.failsWith, err)
+			}
+
+			if tt.check != nil {
+				tt.check(t, stdOut.String())
+			}
+		})
+	}
+
+	t.Run("the policies of the config are evaluated without -p", func(t *testing.T) {
+		configPath := filepath.Join(dir, ".hcunit.yaml")
+		if err := ioutil.WriteFile(configPath, []byte("defaults:\n  policies: ["+policy+"]\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		evalCmd := &commands.EvalCommand{
+			Stdout:   ioutil.Discard,
+			Template: "testdata/templates",
+			Values:   []string{"testdata/values.yml"},
+			Config:   configPath,
+		}
+		if err := evalCmd.Execute([]string{}); !errors.Is(err, commands.PolicyFailure) {
+			t.Fatalf("expected error:\n%v\ngot:\n%v", commands.PolicyFailure, err)
+		}
+	})
+	t.Run("--ascii limits the symbols of the results browser to ascii", func(t *testing.T) {
+		stdOut := new(bytes.Buffer)
+		evalCmd := &commands.EvalCommand{
+			Template:    "testdata/templates",
+			Policy:      []string{"testdata/policy/individuals/params_missing_row.rego"},
+			Values:      []string{"testdata/values.yml"},
+			Interactive: true,
+			ASCII:       true,
+			Stdout:      stdOut,
+			Stdin:       strings.NewReader("1\nq\n"),
+		}
+		if err := evalCmd.Execute([]string{}); !errors.Is(err, commands.PolicyFailure) {
+			t.Fatalf("expected error:\n%v\ngot:\n%v", commands.PolicyFailure, err)
+		}
+
+		if !strings.Contains(stdOut.String(), "labels[input.param]        | kind: Ingress") || strings.Contains(stdOut.String(), "│") {
+			t.Errorf("expected an ascii separator, got:\n%s", stdOut.String())
+		}
+	})
+}
@@ -0,0 +1,106 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/mitchellh/colorstring"
+)
+
+// unownedTeam - the group of rules whose metadata names no team
+const unownedTeam = "unowned"
+
+// ruleOwnership - the team and owner of a rule from its metadata, e.g.
+// "team payments (alice)", or empty when it has neither
+func ruleOwnership(metadata map[string]interface{}) string {
+	owner := metadataString(metadata, metadataOwner)
+	team := metadataString(metadata, metadataTeam)
+	switch {
+	case team != "" && owner != "":
+		return fmt.Sprintf("team %s (%s)", team, owner)
+	case team != "":
+		return "team " + team
+	}
+	return owner
+}
+
+// resultsByTeam - the results grouped by the team owning their rule
+func resultsByTeam(results []RuleResult) map[string][]RuleResult {
+	teams := make(map[string][]RuleResult)
+	for _, result := range results {
+		team := metadataString(result.Metadata, metadataTeam)
+		if team == "" {
+			team = unownedTeam
+		}
+		teams[team] = append(teams[team], result)
+	}
+	return teams
+}
+
+func sortedTeams(teams map[string][]RuleResult) []string {
+	names := make([]string, 0, len(teams))
+	for name := range teams {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// reportFailuresByTeam - prints the failed rules of the run grouped by the
+// team owning them, so a shared gate's output can be routed to its owners
+func reportFailuresByTeam(writer io.Writer, results []RuleResult) {
+	teams := resultsByTeam(results)
+	for _, team := range sortedTeams(teams) {
+		failed := make([]RuleResult, 0)
+		for _, result := range teams[team] {
+			if !result.Passed {
+				failed = append(failed, result)
+			}
+		}
+
+		if len(failed) == 0 {
+			continue
+		}
+
+		colorstring.Fprintln(writer, fmt.Sprintf("[bold]== %s: %d failed ==", team, len(failed)))
+		for _, result := range failed {
+			colorstring.Fprint(writer, "[red]FAIL: ")
+			fmt.Fprintln(writer, result.Name)
+			if owner := metadataString(result.Metadata, metadataOwner); owner != "" {
+				fmt.Fprintf(writer, "      owner: %s\n", owner)
+			}
+		}
+	}
+}
+
+// writeTeamReports - writes a json report per team (<team>.json) holding
+// only the results of the rules it owns
+func (s *EvalCommand) writeTeamReports() error {
+	if err := os.MkdirAll(s.TeamReports, 0755); err != nil {
+		return err
+	}
+
+	teams := resultsByTeam(s.results)
+	for _, team := range sortedTeams(teams) {
+		var teamErr error
+		if summary := summarizeResults(teams[team]); summary.Failed > 0 {
+			teamErr = &ViolationError{Failed: summary.Rules}
+		}
+
+		b, err := json.MarshalIndent(s.reportOf(teams[team], teamErr), "", "  ")
+		if err != nil {
+			return fmt.Errorf("couldnt marshal the report of team %s: %w", team, err)
+		}
+
+		path := filepath.Join(s.TeamReports, artifactName(team)+".json")
+		if err := ioutil.WriteFile(path, append(b, '\n'), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
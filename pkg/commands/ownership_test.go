@@ -0,0 +1,74 @@
+package commands_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestEvalOwnership(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hcunit-teams")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	stdOut := new(bytes.Buffer)
+	evalCmd := &commands.EvalCommand{
+		Stdout:      stdOut,
+		Template:    "testdata/templates",
+		Values:      []string{"testdata/values.yml"},
+		Policy:      []string{"testdata/policy/individuals/ownership.rego"},
+		ByTeam:      true,
+		TeamReports: dir,
+	}
+	if err := evalCmd.Execute([]string{}); !errors.Is(err, commands.PolicyFailure) {
+		t.Fatalf("expected error:\n%v\ngot:\n%v", commands.PolicyFailure, err)
+	}
+
+	for _, expected := range []string{
+		"      owned by team networking (alice)\n",
+		"      owned by bob\n",
+		"== networking: 1 failed ==\x1b[0m\n\x1b[31mFAIL: \x1b[0mdata.main.expect[\"ingress has tls\"]\n      owner: alice\n",
+		"== unowned: 1 failed ==\x1b[0m\n\x1b[31mFAIL: \x1b[0mdata.main.expect[\"ingress is an extension\"]\n      owner: bob\n",
+	} {
+		if !strings.Contains(stdOut.String(), expected) {
+			t.Errorf("expected output to contain %q, got:\n%s", expected, stdOut.String())
+		}
+	}
+
+	if strings.Contains(stdOut.String(), "== platform") {
+		t.Errorf("expected teams without failures to be left out, got:\n%s", stdOut.String())
+	}
+
+	for team, expected := range map[string]struct {
+		exitCode int
+		rules    int
+	}{
+		"networking": {commands.ExitFailure, 1},
+		"platform":   {commands.ExitOK, 1},
+		"unowned":    {commands.ExitFailure, 1},
+	} {
+		content, err := ioutil.ReadFile(filepath.Join(dir, team+".json"))
+		if err != nil {
+			t.Errorf("expected a report for team %s: %v", team, err)
+			continue
+		}
+
+		report := commands.RunReport{}
+		if err := json.Unmarshal(content, &report); err != nil {
+			t.Fatalf("expected a json report, got %q: %v", content, err)
+		}
+
+		if report.ExitCode != expected.exitCode || len(report.Results) != expected.rules {
+			t.Errorf("expected team %s's report to hold its %d rules with exit code %d, got: %+v", team, expected.rules, expected.exitCode, report)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+)
+
+const paramsRuleName = "params"
+const paramHashName = "param"
+
+// paramRun - one evaluation of a rule, against one row of its parameter table
+type paramRun struct {
+	name  string
+	input interface{}
+
+	// document - the template of the document a per document run is on
+	document string
+}
+
+// paramsOf - the table of the optional `params` rule of the policy
+// namespace, an object mapping rule names to the rows each should be
+// expanded into, e.g. params := {"required label": ["team", "owner"]}
+func paramsOf(namespace string, table map[string]interface{}) (map[string][]interface{}, error) {
+	params := make(map[string][]interface{})
+	for rule, rows := range table {
+		list, ok := rows.([]interface{})
+		if !ok {
+			query := fmt.Sprintf("data.%s.%s", namespace, paramsRuleName)
+			return nil, &EvaluationError{Query: query, Err: fmt.Errorf("%s[%q] must be an array of rows", paramsRuleName, rule)}
+		}
+		params[rule] = list
+	}
+	return params, nil
+}
+
+// loadRuleTable - evaluates an optional object of the policy namespace
+// which is keyed by rule names, like params and metadata
+func loadRuleTable(ctx context.Context, policies []string, namespace, name string, input ast.Value, options []func(*rego.Rego)) (map[string]interface{}, error) {
+	queryString := fmt.Sprintf("data.%s.%s", namespace, name)
+	query, err := rego.New(append([]func(*rego.Rego){rego.Query(queryString)}, options...)...).PrepareForEval(ctx)
+	if err != nil {
+		return nil, &PolicyCompileError{Policies: policies, Err: err}
+	}
+	return evalRuleTable(ctx, query, namespace, name, input)
+}
+
+// evalRuleTable - evaluates the prepared query of a rule table
+func evalRuleTable(ctx context.Context, query rego.PreparedEvalQuery, namespace, name string, input ast.Value) (map[string]interface{}, error) {
+	queryString := fmt.Sprintf("data.%s.%s", namespace, name)
+	resultSet, err := query.Eval(ctx, rego.EvalParsedInput(input))
+	if err != nil {
+		return nil, &EvaluationError{Query: queryString, Err: err}
+	}
+
+	table := make(map[string]interface{})
+	for _, result := range resultSet {
+		for _, expression := range result.Expressions {
+			rules, ok := expression.Value.(map[string]interface{})
+			if !ok {
+				return nil, &EvaluationError{Query: queryString, Err: fmt.Errorf("%s must be an object keyed by rule name", name)}
+			}
+
+			for rule, value := range rules {
+				table[rule] = value
+			}
+		}
+	}
+	return table, nil
+}
+
+// paramRuns - the evaluations needed for a rule: one per row of its
+// parameter table with the row available as input.param, or a single
+// evaluation when it has no table
+func paramRuns(queryString string, input interface{}, rows []interface{}) []paramRun {
+	if len(rows) == 0 {
+		return []paramRun{{name: queryString, input: input}}
+	}
+
+	runs := make([]paramRun, 0, len(rows))
+	for _, row := range rows {
+		b, _ := json.Marshal(row)
+		runs = append(runs, paramRun{
+			name:  fmt.Sprintf("%s with input.%s as %s", queryString, paramHashName, b),
+			input: withInput(input, paramHashName, row),
+		})
+	}
+	return runs
+}
+
+// withInput - a copy of the input with the given key set
+func withInput(input interface{}, key string, value interface{}) interface{} {
+	m, ok := input.(map[string]interface{})
+	if !ok {
+		return input
+	}
+
+	out := make(map[string]interface{}, len(m)+1)
+	for k, v := range m {
+		out[k] = v
+	}
+	out[key] = value
+	return out
+}
+
+// ruleKind - the kind of a rule query suffix like expect["name"]
+func ruleKind(querySuffix string) string {
+	if start := strings.Index(querySuffix, "["); start >= 0 {
+		return querySuffix[:start]
+	}
+	return querySuffix
+}
+
+// ruleKey - the name of a rule query suffix like expect["name"]
+func ruleKey(querySuffix string) string {
+	start := strings.Index(querySuffix, "[")
+	if start < 0 || !strings.HasSuffix(querySuffix, "]") {
+		return querySuffix
+	}
+
+	key := querySuffix[start+1 : len(querySuffix)-1]
+	if unquoted, err := strconv.Unquote(key); err == nil {
+		return unquoted
+	}
+	return key
+}
@@ -0,0 +1,225 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+)
+
+// partialNamespace - the package what is left of a specialized rule is
+// written under, suffixed per rule so their support rules never collide
+const partialNamespace = "hcunit_partial"
+
+// impureBuiltins - builtins whose result depends on more than their
+// arguments, or which record something about the run. Rules reaching them
+// are never specialized, as partial evaluation could fold their calls
+var impureBuiltins = map[string]bool{
+	assertEqualFunction.Name: true,
+	rbacAllowsFunction.Name:  true,
+	ast.HTTPSend.Name:        true,
+	ast.NowNanos.Name:        true,
+	ast.OPARuntime.Name:      true,
+	ast.Trace.Name:           true,
+}
+
+// hcunitFunctions - the declarations of every builtin hcunit adds to opa
+func hcunitFunctions() []*rego.Function {
+	functions := []*rego.Function{assertEqualFunction, rbacAllowsFunction, parseConfigFunction}
+	for _, function := range payloadFunctions() {
+		functions = append(functions, function)
+	}
+	return functions
+}
+
+// specializePolicies - partially evaluates the expect/assert/deny/warn rules
+// of the namespace against the values, which stay the same in every run of a
+// --kube-versions matrix or --memory-budget evaluation. Each rule is replaced
+// by its residual, what is left of it to evaluate against the rendered
+// chart, unless it or a rule it depends on reads the input by anything but
+// constant keys, uses with or calls an impure builtin; those are kept as
+// written. Returns the policies and the number of specialized rules
+func specializePolicies(loaded *loadedPolicies, namespace string, values map[string]interface{}, options []func(*rego.Rego)) (*loadedPolicies, int, error) {
+	for _, matches := range ruleQueries(loaded.modules) {
+		if matches > 1 {
+			return loaded, 0, nil
+		}
+	}
+
+	compiler := compilePolicies(loaded.modules)
+	if compiler.Failed() {
+		return nil, 0, compiler.Errors
+	}
+
+	known, err := toValue(map[string]interface{}{valuesHashName: values})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	paths := make([]string, 0, len(loaded.modules))
+	for path := range loaded.modules {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	options = append([]func(*rego.Rego){loaded.option(), new(assertionRecorder).builtin()}, options...)
+	specialized := &loadedPolicies{modules: make(map[string]*ast.Module), documents: loaded.documents, cached: loaded.cached}
+	count := 0
+	for _, path := range paths {
+		module, compiled := loaded.modules[path], compiler.Modules[path]
+		if compiled == nil || len(compiled.Rules) != len(module.Rules) || module.Package.Path.String() != "data."+namespace {
+			specialized.modules[path] = module
+			continue
+		}
+
+		rewritten := *module
+		rewritten.Rules = make([]*ast.Rule, 0, len(module.Rules))
+		for i, rule := range module.Rules {
+			unknowns, ok := residualUnknowns(compiler, rule, compiled.Rules[i])
+			if !ok {
+				rewritten.Rules = append(rewritten.Rules, rule)
+				continue
+			}
+
+			ns := fmt.Sprintf("%s_%d", partialNamespace, count)
+			query := fmt.Sprintf("data.%s.%s[%s]", namespace, rule.Head.Name, rule.Head.Key)
+			residual, err := partialRule(ns, query, known, unknowns, options)
+			if err != nil {
+				rewritten.Rules = append(rewritten.Rules, rule)
+				continue
+			}
+
+			for j, residualModule := range residual {
+				specialized.modules[fmt.Sprintf("%s/%d.rego", ns, j)] = residualModule
+			}
+			rewritten.Rules = append(rewritten.Rules, &ast.Rule{
+				Location: rule.Location,
+				Head:     rule.Head.Copy(),
+				Body:     ast.NewBody(ast.NewExpr(ast.NewTerm(ast.Ref{ast.DefaultRootDocument, ast.StringTerm(ns), ast.StringTerm("result")}))),
+				Module:   &rewritten,
+			})
+			count++
+		}
+		specialized.modules[path] = &rewritten
+	}
+	return specialized, count, nil
+}
+
+// residualUnknowns - the input documents read by a rule, the other
+// definitions of its name which could define its key and every rule they
+// depend on, as the refs to leave unknown while partially evaluating it
+// against the values. False when it can't be specialized
+func residualUnknowns(compiler *ast.Compiler, rule, compiled *ast.Rule) ([]*ast.Term, bool) {
+	if !isRuleKind(string(rule.Head.Name)) || !rule.Head.Key.IsGround() || rule.Head.Value != nil || rule.Else != nil || rule.Default {
+		return nil, false
+	}
+
+	keys := make(map[string]bool)
+	safe := true
+	seen := make(map[*ast.Rule]bool)
+	queue := make([]*ast.Rule, 0)
+	for _, definition := range compiler.GetRulesForVirtualDocument(compiled.Path()) {
+		if !definition.Head.Key.IsGround() || definition.Head.Key.Equal(compiled.Head.Key) {
+			queue = append(queue, definition)
+		}
+	}
+
+	for len(queue) > 0 && safe {
+		next := queue[0]
+		queue = queue[1:]
+		if seen[next] {
+			continue
+		}
+		seen[next] = true
+
+		ast.WalkRefs(next, func(ref ast.Ref) bool {
+			if impureBuiltins[ref.String()] {
+				safe = false
+			}
+
+			if !ref[0].Equal(ast.InputRootDocument) {
+				return false
+			}
+
+			key, ok := ast.String(""), len(ref) > 1
+			if ok {
+				key, ok = ref[1].Value.(ast.String)
+			}
+
+			switch {
+			case !ok:
+				safe = false
+			case string(key) != valuesHashName:
+				keys[string(key)] = true
+			}
+			return false
+		})
+
+		ast.WalkExprs(next, func(expr *ast.Expr) bool {
+			if len(expr.With) > 0 {
+				safe = false
+			}
+			return false
+		})
+
+		for dependency := range compiler.Graph.Dependencies(next) {
+			queue = append(queue, dependency.(*ast.Rule))
+		}
+	}
+
+	if !safe {
+		return nil, false
+	}
+
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	unknowns := make([]*ast.Term, 0, len(sorted))
+	for _, key := range sorted {
+		unknowns = append(unknowns, ast.NewTerm(ast.InputRootRef.Append(ast.StringTerm(key))))
+	}
+	return unknowns, true
+}
+
+// partialRule - partially evaluates the query of a rule, returning a module
+// of package ns defining result wherever the rule would be defined, and the
+// support modules it needs
+func partialRule(ns, query string, known ast.Value, unknowns []*ast.Term, options []func(*rego.Rego)) ([]*ast.Module, error) {
+	r := rego.New(append([]func(*rego.Rego){
+		rego.Query(query),
+		rego.ParsedInput(known),
+		rego.ParsedUnknowns(unknowns),
+		rego.PartialNamespace(ns),
+	}, options...)...)
+
+	partial, err := r.Partial(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, support := range partial.Support {
+		for _, rule := range support.Rules {
+			if isRuleKind(string(rule.Head.Name)) {
+				return nil, fmt.Errorf("%s needs rule %s in its support modules", query, rule.Head.Name)
+			}
+		}
+	}
+
+	residual := &ast.Module{Package: &ast.Package{Path: ast.Ref{ast.DefaultRootDocument, ast.StringTerm(ns)}}}
+	for _, body := range partial.Queries {
+		if len(body) == 0 {
+			body = ast.NewBody(ast.NewExpr(ast.BooleanTerm(true)))
+		}
+		residual.Rules = append(residual.Rules, &ast.Rule{
+			Head:   ast.NewHead(ast.Var("result"), nil, ast.BooleanTerm(true)),
+			Body:   body,
+			Module: residual,
+		})
+	}
+	return append([]*ast.Module{residual}, partial.Support...), nil
+}
@@ -0,0 +1,73 @@
+package commands_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestEvalPartialEval(t *testing.T) {
+	resultLines := func(output string) string {
+		lines := make([]string, 0)
+		for _, line := range strings.Split(output, "\n") {
+			if strings.Contains(line, "PASS: ") || strings.Contains(line, "FAIL: ") {
+				lines = append(lines, line)
+			}
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	for _, tt := range []struct {
+		name        string
+		values      []string
+		failsWith   error
+		specialized string
+	}{
+		{
+			name:        "rules decided by the values pass like their originals",
+			values:      []string{"testdata/values.yml"},
+			failsWith:   nil,
+			specialized: "3 of 4 rules specialized to the values",
+		},
+		{
+			name:        "rules decided by the values fail like their originals",
+			values:      []string{"testdata/values.yml", "testdata/added_values.yml"},
+			failsWith:   commands.PolicyFailure,
+			specialized: "3 of 4 rules specialized to the values",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			eval := func(partialEval bool) (string, error) {
+				stdOut := new(bytes.Buffer)
+				evalCmd := &commands.EvalCommand{
+					Writer:       stdOut,
+					Stdout:       stdOut,
+					Template:     "testdata/templates",
+					Values:       tt.values,
+					Policy:       []string{"testdata/policy/partial"},
+					KubeVersions: []string{"1.14.0,1.15.0"},
+					PartialEval:  partialEval,
+				}
+				err := evalCmd.Execute([]string{})
+				return stdOut.String(), err
+			}
+
+			specialized, err := eval(true)
+			if tt.failsWith == nil && err != nil || !errors.Is(err, tt.failsWith) {
+				t.Fatalf("expected error:\n%v\ngot:\n%v", tt.failsWith, err)
+			}
+
+			if !strings.Contains(specialized, tt.specialized) {
+				t.Errorf("expected %q in:\n%s", tt.specialized, specialized)
+			}
+
+			original, _ := eval(false)
+			if resultLines(specialized) != resultLines(original) {
+				t.Errorf("expected the results of the original rules:\n%s\ngot:\n%s", resultLines(original), resultLines(specialized))
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package commands
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/types"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// payloadParsers - parsers for the config formats commonly embedded in
+// ConfigMaps and Secrets, keyed by the format name used in builtin names
+var payloadParsers = map[string]func(string) (interface{}, error){
+	"json":       parseJSONPayload,
+	"yaml":       parseYAMLPayload,
+	"toml":       parseTOMLPayload,
+	"ini":        parseINIPayload,
+	"properties": parsePropertiesPayload,
+}
+
+// payloadFormats - maps a payload's file extension to its format
+var payloadFormats = map[string]string{
+	".json":       "json",
+	".yaml":       "yaml",
+	".yml":        "yaml",
+	".toml":       "toml",
+	".ini":        "ini",
+	".cfg":        "ini",
+	".properties": "properties",
+}
+
+// parseConfigFunction - the declaration of hcunit.parse_config
+var parseConfigFunction = &rego.Function{
+	Name: "hcunit.parse_config",
+	Decl: types.NewFunction(types.Args(types.S, types.S), types.A),
+}
+
+// payloadFunctions - the declarations of the hcunit.parse_<format>
+// builtins, keyed by format
+func payloadFunctions() map[string]*rego.Function {
+	functions := make(map[string]*rego.Function, len(payloadParsers))
+	for format := range payloadParsers {
+		if format == "json" || format == "yaml" {
+			// already provided by OPA as json.unmarshal and yaml.unmarshal
+			continue
+		}
+
+		functions[format] = &rego.Function{
+			Name: "hcunit.parse_" + format,
+			Decl: types.NewFunction(types.Args(types.S), types.A),
+		}
+	}
+	return functions
+}
+
+// payloadBuiltins - exposes the parsers to policies as hcunit.parse_<format>(s)
+// and hcunit.parse_config(filename, s), which picks the parser by extension
+func payloadBuiltins() []func(*rego.Rego) {
+	builtins := make([]func(*rego.Rego), 0, len(payloadParsers)+1)
+	for format, function := range payloadFunctions() {
+		name := function.Name
+		parse := payloadParsers[format]
+		builtins = append(builtins, rego.Function1(
+			function,
+			func(_ rego.BuiltinContext, content *ast.Term) (*ast.Term, error) {
+				str, ok := content.Value.(ast.String)
+				if !ok {
+					return nil, fmt.Errorf("%s: expected a string argument, got %v", name, content)
+				}
+				return payloadTerm(name, parse, string(str))
+			},
+		))
+	}
+
+	builtins = append(builtins, rego.Function2(
+		parseConfigFunction,
+		func(_ rego.BuiltinContext, filename, content *ast.Term) (*ast.Term, error) {
+			name, ok := filename.Value.(ast.String)
+			str, ok2 := content.Value.(ast.String)
+			if !ok || !ok2 {
+				return nil, fmt.Errorf("hcunit.parse_config: expected string arguments, got %v, %v", filename, content)
+			}
+
+			format, ok := payloadFormats[strings.ToLower(filepath.Ext(string(name)))]
+			if !ok {
+				return nil, fmt.Errorf("hcunit.parse_config: unsupported config format %q", string(name))
+			}
+			return payloadTerm("hcunit.parse_config", payloadParsers[format], string(str))
+		},
+	))
+	return builtins
+}
+
+func payloadTerm(builtin string, parse func(string) (interface{}, error), content string) (*ast.Term, error) {
+	parsed, err := parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", builtin, err)
+	}
+
+	value, err := ast.InterfaceToValue(parsed)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", builtin, err)
+	}
+	return ast.NewTerm(value), nil
+}
+
+func parseJSONPayload(content string) (interface{}, error) {
+	var out interface{}
+	err := json.Unmarshal([]byte(content), &out)
+	return out, err
+}
+
+func parseYAMLPayload(content string) (interface{}, error) {
+	var out interface{}
+	err := yaml.Unmarshal([]byte(content), &out)
+	return out, err
+}
+
+func parseTOMLPayload(content string) (interface{}, error) {
+	out := make(map[string]interface{})
+	_, err := toml.Decode(content, &out)
+	return out, err
+}
+
+// parseINIPayload - keys before the first [section] are kept at the top
+// level, every section becomes an object of its keys
+func parseINIPayload(content string) (interface{}, error) {
+	out := make(map[string]interface{})
+	current := out
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "#"):
+			continue
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			section := make(map[string]interface{})
+			out[strings.TrimSpace(line[1:len(line)-1])] = section
+			current = section
+			continue
+		}
+
+		i := strings.IndexAny(line, "=:")
+		if i < 0 {
+			return nil, fmt.Errorf("line %d: expected key = value, got %q", lineNumber, line)
+		}
+		current[strings.TrimSpace(line[:i])] = unquote(strings.TrimSpace(line[i+1:]))
+	}
+	return out, scanner.Err()
+}
+
+// parsePropertiesPayload - java .properties: `key=value`, `key: value` or
+// `key value` pairs, # and ! comments and trailing backslash continuations
+func parsePropertiesPayload(content string) (interface{}, error) {
+	out := make(map[string]interface{})
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	logical := ""
+	for scanner.Scan() {
+		line := strings.TrimLeft(scanner.Text(), " \t\f")
+		if logical == "" && (line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!")) {
+			continue
+		}
+
+		if strings.HasSuffix(line, `\`) && !strings.HasSuffix(line, `\\`) {
+			logical += strings.TrimSuffix(line, `\`)
+			continue
+		}
+
+		logical += line
+		i := strings.IndexAny(logical, "=: \t")
+		if i < 0 {
+			out[logical] = ""
+		} else {
+			value := strings.TrimLeft(logical[i+1:], " \t")
+			if logical[i] == ' ' || logical[i] == '\t' {
+				value = strings.TrimLeft(strings.TrimPrefix(strings.TrimPrefix(value, "="), ":"), " \t")
+			}
+			out[logical[:i]] = value
+		}
+		logical = ""
+	}
+	return out, scanner.Err()
+}
+
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
@@ -0,0 +1,101 @@
+package commands
+
+const podspecsHashName = "podspecs"
+
+// containerTypes - the fields of a pod spec holding containers, by the
+// containerType their containers are tagged with in podspecs
+var containerTypes = []struct {
+	Type  string
+	Field string
+}{
+	{"init", "initContainers"},
+	{"container", "containers"},
+	{"ephemeral", "ephemeralContainers"},
+}
+
+// podSpec - the pod template of a workload, the same shape whatever the kind
+// of workload it is nested in
+type podSpec struct {
+	Ref                string                   `json:"ref"`
+	Kind               string                   `json:"kind"`
+	Name               string                   `json:"name"`
+	Namespace          string                   `json:"namespace"`
+	Labels             map[string]interface{}   `json:"labels"`
+	Annotations        map[string]interface{}   `json:"annotations"`
+	ServiceAccountName string                   `json:"serviceAccountName"`
+	Containers         []map[string]interface{} `json:"containers"`
+	Spec               map[string]interface{}   `json:"spec"`
+}
+
+// buildPodSpecs - normalizes the pod templates of the rendered workloads
+// (Pods, Deployments, StatefulSets, DaemonSets, ReplicaSets,
+// ReplicationControllers, Jobs and CronJobs) so container level policies are
+// written once instead of per kind
+func buildPodSpecs(objects []map[string]interface{}) []podSpec {
+	podspecs := make([]podSpec, 0)
+	for _, obj := range objects {
+		template := podTemplate(obj)
+		if template == nil {
+			continue
+		}
+
+		spec := getMap(template, "spec")
+		if spec == nil {
+			spec = map[string]interface{}{}
+		}
+
+		podspecs = append(podspecs, podSpec{
+			Ref:                objectRef(obj),
+			Kind:               objectKind(obj),
+			Name:               objectName(obj),
+			Namespace:          objectNamespace(obj),
+			Labels:             orEmpty(getMap(template, "metadata", "labels")),
+			Annotations:        orEmpty(getMap(template, "metadata", "annotations")),
+			ServiceAccountName: serviceAccountName(spec),
+			Containers:         podContainers(spec),
+			Spec:               spec,
+		})
+	}
+	return podspecs
+}
+
+// serviceAccountName - the service account a pod runs as, including the
+// deprecated serviceAccount field and the default the api server falls back to
+func serviceAccountName(spec map[string]interface{}) string {
+	for _, field := range []string{"serviceAccountName", "serviceAccount"} {
+		if name := getString(spec, field); name != "" {
+			return name
+		}
+	}
+	return "default"
+}
+
+// podContainers - the init, regular and ephemeral containers of a pod spec,
+// each tagged with its containerType so policies can leave some out. The
+// containers are copied, the rendered templates stay as they were
+func podContainers(spec map[string]interface{}) []map[string]interface{} {
+	containers := make([]map[string]interface{}, 0)
+	for _, containerType := range containerTypes {
+		for _, c := range getSlice(spec, containerType.Field) {
+			container, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			tagged := make(map[string]interface{}, len(container)+1)
+			for key, value := range container {
+				tagged[key] = value
+			}
+			tagged["containerType"] = containerType.Type
+			containers = append(containers, tagged)
+		}
+	}
+	return containers
+}
+
+func orEmpty(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return map[string]interface{}{}
+	}
+	return m
+}
@@ -0,0 +1,341 @@
+package commands
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	securejoin "github.com/cyphar/filepath-securejoin"
+	"github.com/mitchellh/colorstring"
+	yaml "gopkg.in/yaml.v3"
+)
+
+const (
+	policyLockName  = ".hcunit.lock"
+	policyCacheName = ".hcunit/policies"
+)
+
+var PolicyLockMismatch = errors.New("policy cache does not match the lock file")
+var UnsupportedPolicySource = errors.New("unsupported policy source url")
+var InvalidPolicySourceName = errors.New("invalid policy source name")
+
+// PolicyLock - the resolved digests of every policy source, analogous to go.sum
+type PolicyLock struct {
+	Policies []LockedPolicy `yaml:"policies"`
+}
+
+type LockedPolicy struct {
+	Name   string `yaml:"name"`
+	URL    string `yaml:"url"`
+	Ref    string `yaml:"ref,omitempty"`
+	Digest string `yaml:"digest"`
+}
+
+// PolicyCommand - groups the policy dependency management subcommands
+type PolicyCommand struct{}
+
+type PolicyUpdateCommand struct {
+	Writer  io.Writer
+	Config  string        `long:"config" description:"path to the hcunit config declaring policy sources (default: .hcunit.yaml)"`
+	Offline bool          `long:"offline" description:"fail instead of fetching policy sources over the network"`
+	Retries int           `long:"retries" description:"times to retry a failed fetch, with exponential backoff (default: 3)"`
+	Timeout time.Duration `long:"timeout" description:"timeout for each fetch attempt, e.g. 30s (default: 60s)"`
+}
+
+func (s *PolicyUpdateCommand) Execute(args []string) error {
+	s.setDefaults()
+	config, err := LoadConfig(s.Config)
+	if err != nil {
+		return err
+	}
+
+	fetch := config.Fetch
+	if s.Retries > 0 {
+		fetch.Retries = s.Retries
+	}
+
+	if s.Timeout > 0 {
+		fetch.Timeout = s.Timeout
+	}
+
+	lock := PolicyLock{Policies: make([]LockedPolicy, 0, len(config.Policies))}
+	for _, source := range config.Policies {
+		if err := source.validateName(); err != nil {
+			return err
+		}
+
+		if s.Offline {
+			return offlineError(fmt.Sprintf("policy fetch of %s from %s", source.Name, source.URL))
+		}
+
+		dir := filepath.Join(config.resolve(policyCacheName), source.Name)
+		if err := fetchPolicySource(source, dir, fetch.withDefaults()); err != nil {
+			return fmt.Errorf("fetching policy %s failed: %w", source.Name, err)
+		}
+
+		digest, err := digestPath(dir)
+		if err != nil {
+			return err
+		}
+
+		lock.Policies = append(lock.Policies, LockedPolicy{
+			Name:   source.Name,
+			URL:    source.URL,
+			Ref:    source.Ref,
+			Digest: digest,
+		})
+		fmt.Fprintf(s.Writer, "%s %s\n", source.Name, digest)
+	}
+
+	b, err := yaml.Marshal(lock)
+	if err != nil {
+		return fmt.Errorf("couldnt marshal lock file: %w", err)
+	}
+	return ioutil.WriteFile(config.resolve(policyLockName), b, 0644)
+}
+
+func (s *PolicyUpdateCommand) setDefaults() {
+	if s.Writer == nil {
+		s.Writer = os.Stdout
+	}
+
+	if s.Config == "" {
+		s.Config = defaultConfigPath
+	}
+}
+
+type PolicyVerifyCommand struct {
+	Writer io.Writer
+	Config string `long:"config" description:"path to the hcunit config declaring policy sources (default: .hcunit.yaml)"`
+}
+
+func (s *PolicyVerifyCommand) Execute(args []string) error {
+	s.setDefaults()
+	config, err := LoadConfig(s.Config)
+	if err != nil {
+		return err
+	}
+
+	b, err := ioutil.ReadFile(config.resolve(policyLockName))
+	if err != nil {
+		return fmt.Errorf("reading lock file failed: %w", err)
+	}
+
+	lock := PolicyLock{}
+	if err := yaml.Unmarshal(b, &lock); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", policyLockName, err)
+	}
+
+	locked := map[string]LockedPolicy{}
+	for _, l := range lock.Policies {
+		locked[l.Name] = l
+	}
+
+	mismatch := false
+	for _, source := range config.Policies {
+		if err := source.validateName(); err != nil {
+			return err
+		}
+
+		l, ok := locked[source.Name]
+		if !ok || l.URL != source.URL || l.Ref != source.Ref {
+			mismatch = true
+			colorstring.Fprint(s.Writer, "[red]FAIL: ")
+			fmt.Fprintf(s.Writer, "%s is not locked at its configured source, run `hcunit policy update`\n", source.Name)
+			continue
+		}
+
+		digest, err := digestPath(filepath.Join(config.resolve(policyCacheName), source.Name))
+		if err != nil || digest != l.Digest {
+			mismatch = true
+			colorstring.Fprint(s.Writer, "[red]FAIL: ")
+			fmt.Fprintf(s.Writer, "%s cache does not match locked digest %s\n", source.Name, l.Digest)
+			continue
+		}
+
+		colorstring.Fprint(s.Writer, "[green]PASS: ")
+		fmt.Fprintf(s.Writer, "%s %s\n", source.Name, digest)
+	}
+
+	if mismatch {
+		return PolicyLockMismatch
+	}
+	return nil
+}
+
+func (s *PolicyVerifyCommand) setDefaults() {
+	if s.Writer == nil {
+		s.Writer = os.Stdout
+	}
+
+	if s.Config == "" {
+		s.Config = defaultConfigPath
+	}
+}
+
+// validateName - the name of a source is the directory it is cached in, so
+// it has to be a single path element
+func (s PolicySource) validateName() error {
+	if s.Name == "" || s.Name == "." || s.Name == ".." || strings.ContainsAny(s.Name, `/\`) {
+		return fmt.Errorf("%w: %q of %s has to name a single directory", InvalidPolicySourceName, s.Name, s.URL)
+	}
+	return nil
+}
+
+// fetchPolicySource - replaces dir with a fresh copy of the given source,
+// checking it against the source's pinned sha256 when one is set. The source
+// is fetched next to dir and only replaces it once fetched and verified, so
+// a failed fetch keeps the copy the lock file was written for
+func fetchPolicySource(source PolicySource, dir string, fetch FetchOptions) error {
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return err
+	}
+
+	fetched, err := ioutil.TempDir(filepath.Dir(dir), "."+filepath.Base(dir)+"-fetch")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(fetched)
+
+	if err := fetchPolicySourceInto(source, fetched, fetch); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(fetched, 0755); err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	return os.Rename(fetched, dir)
+}
+
+// fetchPolicySourceInto - fetches the source into the empty dir, checking it
+// against the source's pinned sha256 when one is set
+func fetchPolicySourceInto(source PolicySource, dir string, fetch FetchOptions) error {
+	switch {
+	case strings.HasPrefix(source.URL, "oci://"):
+		if err := fetch.run("oras", "pull", ociReference(source), "--output", dir); err != nil {
+			return err
+		}
+		return verifyDirChecksum(dir, source.SHA256)
+
+	case strings.HasPrefix(source.URL, "git+") || strings.HasSuffix(source.URL, ".git"):
+		cloneArgs := []string{"clone", "--depth", "1"}
+		if source.Ref != "" {
+			cloneArgs = append(cloneArgs, "--branch", source.Ref)
+		}
+		cloneArgs = append(cloneArgs, "--", strings.TrimPrefix(source.URL, "git+"), dir)
+		if err := fetch.run("git", cloneArgs...); err != nil {
+			return err
+		}
+
+		if err := os.RemoveAll(filepath.Join(dir, ".git")); err != nil {
+			return err
+		}
+		return verifyDirChecksum(dir, source.SHA256)
+
+	case strings.HasPrefix(source.URL, "https://") || strings.HasPrefix(source.URL, "http://"):
+		return fetchHTTPPolicy(source, dir, fetch)
+	}
+	return fmt.Errorf("%w: %s", UnsupportedPolicySource, source.URL)
+}
+
+func ociReference(source PolicySource) string {
+	ref := strings.TrimPrefix(source.URL, "oci://")
+	if source.Ref != "" {
+		ref = fmt.Sprintf("%s:%s", ref, source.Ref)
+	}
+	return ref
+}
+
+// verifyDirChecksum - git and oci sources have no single artifact, so their
+// pin is compared against the digest of the fetched tree
+func verifyDirChecksum(dir string, pinned string) error {
+	if pinned == "" {
+		return nil
+	}
+
+	digest, err := digestPath(dir)
+	if err != nil {
+		return err
+	}
+
+	if strings.TrimPrefix(pinned, "sha256:") != strings.TrimPrefix(digest, "sha256:") {
+		return fmt.Errorf("%w: expected %s got %s", ChecksumMismatch, pinned, digest)
+	}
+	return nil
+}
+
+// fetchHTTPPolicy - downloads a single policy file, or a .tar.gz/.tgz
+// archive of policies which is unpacked into dir
+func fetchHTTPPolicy(source PolicySource, dir string, fetch FetchOptions) error {
+	b, err := fetch.httpGet(source.URL)
+	if err != nil {
+		return err
+	}
+
+	if err := verifyChecksum(b, source.SHA256); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	if strings.HasSuffix(source.URL, ".tar.gz") || strings.HasSuffix(source.URL, ".tgz") {
+		return untar(bytes.NewReader(b), dir)
+	}
+	return ioutil.WriteFile(filepath.Join(dir, path.Base(source.URL)), b, 0644)
+}
+
+func untar(r io.Reader, dir string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("reading archive failed: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading archive failed: %w", err)
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		target, err := securejoin.SecureJoin(dir, header.Name)
+		if err != nil {
+			return err
+		}
+
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+
+		b, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return err
+		}
+
+		if err := ioutil.WriteFile(target, b, 0644); err != nil {
+			return err
+		}
+	}
+}
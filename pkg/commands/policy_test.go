@@ -0,0 +1,161 @@
+package commands_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestPolicyCommands(t *testing.T) {
+	server := httptest.NewServer(http.FileServer(http.Dir("testdata/policy/passing")))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "hcunit-policy")
+	if err != nil {
+		t.Fatalf("failed creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	configPath := filepath.Join(dir, ".hcunit.yaml")
+	config := "policies:\n  - name: passing\n    url: " + server.URL + "/passing.rego\n"
+	if err := ioutil.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatalf("failed writing config: %v", err)
+	}
+
+	t.Run("update fetches sources and writes the lock file", func(t *testing.T) {
+		update := &commands.PolicyUpdateCommand{Writer: ioutil.Discard, Config: configPath}
+		if err := update.Execute([]string{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if _, err := os.Stat(filepath.Join(dir, ".hcunit/policies/passing/passing.rego")); err != nil {
+			t.Errorf("expected the policy to be cached: %v", err)
+		}
+
+		if _, err := os.Stat(filepath.Join(dir, ".hcunit.lock")); err != nil {
+			t.Errorf("expected a lock file: %v", err)
+		}
+	})
+
+	t.Run("update refuses to fetch in offline mode", func(t *testing.T) {
+		update := &commands.PolicyUpdateCommand{Writer: ioutil.Discard, Config: configPath, Offline: true}
+		if err := update.Execute([]string{}); !errors.Is(err, commands.OfflineViolation) {
+			t.Errorf("expected %v, got: %v", commands.OfflineViolation, err)
+		}
+	})
+
+	t.Run("verify passes on an untouched cache", func(t *testing.T) {
+		verify := &commands.PolicyVerifyCommand{Writer: ioutil.Discard, Config: configPath}
+		if err := verify.Execute([]string{}); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("verify fails when the cache is modified", func(t *testing.T) {
+		cached := filepath.Join(dir, ".hcunit/policies/passing/passing.rego")
+		if err := ioutil.WriteFile(cached, []byte("package main\n"), 0644); err != nil {
+			t.Fatalf("failed tampering with cache: %v", err)
+		}
+
+		verify := &commands.PolicyVerifyCommand{Writer: ioutil.Discard, Config: configPath}
+		if err := verify.Execute([]string{}); !errors.Is(err, commands.PolicyLockMismatch) {
+			t.Errorf("expected %v, got: %v", commands.PolicyLockMismatch, err)
+		}
+	})
+}
+
+func TestPolicyUpdateFetching(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		http.ServeFile(w, r, "testdata/policy/passing/passing.rego")
+	}))
+	defer server.Close()
+
+	for _, tt := range []struct {
+		name      string
+		config    string
+		retries   int
+		failsWith error
+	}{
+		{
+			name:      "retries flaky sources with backoff",
+			config:    "fetch:\n  backoff: 1ms\npolicies:\n  - name: flaky\n    url: " + server.URL + "/passing.rego\n",
+			retries:   2,
+			failsWith: nil,
+		},
+		{
+			name:      "fails when the pinned checksum does not match",
+			config:    "fetch:\n  backoff: 1ms\npolicies:\n  - name: pinned\n    url: " + server.URL + "/passing.rego\n    sha256: sha256:0000\n",
+			failsWith: commands.ChecksumMismatch,
+		},
+		{
+			name:      "names escaping the policy cache are rejected",
+			config:    "policies:\n  - name: ../../escaped\n    url: " + server.URL + "/passing.rego\n",
+			failsWith: commands.InvalidPolicySourceName,
+		},
+		{
+			name:      "the policy cache itself can't be named",
+			config:    "policies:\n  - name: ..\n    url: " + server.URL + "/passing.rego\n",
+			failsWith: commands.InvalidPolicySourceName,
+		},
+		{
+			name:      "sources need a name",
+			config:    "policies:\n  - url: " + server.URL + "/passing.rego\n",
+			failsWith: commands.InvalidPolicySourceName,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "hcunit-policy")
+			if err != nil {
+				t.Fatalf("failed creating temp dir: %v", err)
+			}
+			defer os.RemoveAll(dir)
+
+			configPath := filepath.Join(dir, ".hcunit.yaml")
+			if err := ioutil.WriteFile(configPath, []byte(tt.config), 0644); err != nil {
+				t.Fatalf("failed writing config: %v", err)
+			}
+
+			attempts = 0
+			update := &commands.PolicyUpdateCommand{Writer: ioutil.Discard, Config: configPath, Retries: tt.retries}
+			err = update.Execute([]string{})
+			if !errors.Is(err, tt.failsWith) {
+				t.Errorf("expected %v, got: %v", tt.failsWith, err)
+			}
+		})
+	}
+
+	t.Run("a failed fetch keeps the cached copy", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "hcunit-policy")
+		if err != nil {
+			t.Fatalf("failed creating temp dir: %v", err)
+		}
+		defer os.RemoveAll(dir)
+
+		configPath := filepath.Join(dir, ".hcunit.yaml")
+		source := "fetch:\n  backoff: 1ms\npolicies:\n  - name: kept\n    url: " + server.URL + "/passing.rego\n"
+		for _, config := range []string{source, source + "    sha256: sha256:0000\n"} {
+			if err := ioutil.WriteFile(configPath, []byte(config), 0644); err != nil {
+				t.Fatalf("failed writing config: %v", err)
+			}
+
+			attempts = 3
+			(&commands.PolicyUpdateCommand{Writer: ioutil.Discard, Config: configPath}).Execute([]string{})
+		}
+
+		if _, err := os.Stat(filepath.Join(dir, ".hcunit", "policies", "kept", "passing.rego")); err != nil {
+			t.Errorf("expected the cached policy to be kept, got: %v", err)
+		}
+	})
+}
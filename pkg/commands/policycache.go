@@ -0,0 +1,182 @@
+package commands
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/loader"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/storage/inmem"
+)
+
+// policyCacheVersion - bumped whenever the cached form of parsed modules
+// changes, e.g. with an opa upgrade, so stale entries are never read
+const policyCacheVersion = "hcunit-policy-cache/1 opa/v0.14.2"
+
+// loadedPolicies - the parsed rego modules and data documents of the
+// policy paths, loaded once and shared by every query of a run
+type loadedPolicies struct {
+	modules   map[string]*ast.Module
+	documents map[string]interface{}
+	cached    bool
+}
+
+// defaultPolicyCacheDir - where parsed policy modules are cached between
+// runs, or empty when the platform has no user cache directory
+func defaultPolicyCacheDir() string {
+	dir := userCacheDir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, "policies")
+}
+
+// loadPolicies - parses every .rego file under the policy paths and loads
+// their json and yaml data documents, like rego.Load. Parsed modules are
+// read from and written to cacheDir unless it is empty, see parsePolicies
+func loadPolicies(policies []string, cacheDir string) (*loadedPolicies, error) {
+	data, err := loader.Filtered(policies, func(_ string, info os.FileInfo, _ int) bool {
+		return !info.IsDir() && filepath.Ext(info.Name()) == regoExt
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	modules, cached, err := parsePolicies(policies, cacheDir)
+	if err != nil {
+		return nil, err
+	}
+	return &loadedPolicies{modules: modules, documents: data.Documents, cached: cached}, nil
+}
+
+// parsePolicies - parses every .rego file under the policy paths, without
+// compiling them or loading any data, which is all discovering their rules
+// takes. Parsed modules are read from and written to cacheDir, keyed by the
+// sha256 of their source, unless it is empty; cached is true when any was
+// read from it. The cache is best effort: any entry which can't be read or
+// written is parsed from source instead
+func parsePolicies(policies []string, cacheDir string) (modules map[string]*ast.Module, cached bool, err error) {
+	modules = make(map[string]*ast.Module)
+	for _, policy := range policies {
+		err := filepath.Walk(policy, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() || filepath.Ext(path) != regoExt {
+				return err
+			}
+
+			source, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+
+			key := policyCacheKey(source)
+			if module := readCachedModule(cacheDir, key); module != nil {
+				modules[path], cached = module, true
+				return nil
+			}
+
+			module, err := ast.ParseModule(path, string(source))
+			if err != nil || module == nil {
+				return err
+			}
+			modules[path] = module
+			writeCachedModule(cacheDir, key, module)
+			return nil
+		})
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	return modules, cached, nil
+}
+
+// sourceModules - the loaded modules with their source locations, parsing
+// them again when they were read from the cache
+func (s *loadedPolicies) sourceModules(policies []string) map[string]*ast.Module {
+	if !s.cached {
+		return s.modules
+	}
+
+	modules, _, err := parsePolicies(policies, "")
+	if err != nil {
+		return s.modules
+	}
+	return modules
+}
+
+// option - adds the loaded modules and documents to a query, with a store
+// of its own like rego.Load would create
+func (s *loadedPolicies) option() func(*rego.Rego) {
+	return func(r *rego.Rego) {
+		for _, module := range s.modules {
+			rego.ParsedModule(module)(r)
+		}
+		rego.Store(inmem.NewFromObject(s.documents))(r)
+	}
+}
+
+func policyCacheKey(source []byte) string {
+	sum := sha256.Sum256(append([]byte(policyCacheVersion+"\x00"), source...))
+	return hex.EncodeToString(sum[:])
+}
+
+// readCachedModule - the cached module of the given key, or nil. Cached
+// modules carry no source locations
+func readCachedModule(cacheDir, key string) *ast.Module {
+	if cacheDir == "" {
+		return nil
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(cacheDir, key+".json"))
+	if err != nil {
+		return nil
+	}
+
+	module := new(ast.Module)
+	if err := json.Unmarshal(b, module); err != nil || module.Package == nil {
+		return nil
+	}
+
+	for _, rule := range module.Rules {
+		rule.Module = module
+	}
+	return module
+}
+
+// writeCachedModule - caches a parsed module, if it survives a round trip
+// through json unchanged: not every rego construct does in this opa version
+func writeCachedModule(cacheDir, key string, module *ast.Module) {
+	if cacheDir == "" {
+		return
+	}
+
+	b, err := json.Marshal(module)
+	if err != nil {
+		return
+	}
+
+	decoded := new(ast.Module)
+	if err := json.Unmarshal(b, decoded); err != nil || !decoded.Equal(module) {
+		return
+	}
+
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return
+	}
+
+	tmp, err := ioutil.TempFile(cacheDir, key+".*.tmp")
+	if err != nil {
+		return
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(b)
+	if closeErr := tmp.Close(); err != nil || closeErr != nil {
+		return
+	}
+	os.Rename(tmp.Name(), filepath.Join(cacheDir, key+".json"))
+}
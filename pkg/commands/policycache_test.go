@@ -0,0 +1,112 @@
+package commands_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestPolicyCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hcunit-policy-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cacheDir := filepath.Join(dir, "cache")
+	policyDir := filepath.Join(dir, "policy")
+	if err := os.MkdirAll(policyDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, policy := range map[string]string{
+		"rules.rego":   "package main\n\nexpect [\"something is an ingress\"] {\n  is_ingress(input[\"something.yml\"])\n}\n",
+		"helpers.rego": "package main\n\nis_ingress(obj) {\n  obj.kind == \"Ingress\"\n}\n",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(policyDir, name), []byte(policy), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	eval := func(noCache bool) error {
+		evalCmd := &commands.EvalCommand{
+			Stdout:        ioutil.Discard,
+			Template:      "testdata/templates",
+			Values:        []string{"testdata/values.yml"},
+			Policy:        []string{policyDir},
+			PolicyCache:   cacheDir,
+			NoPolicyCache: noCache,
+		}
+		return evalCmd.Execute([]string{})
+	}
+
+	t.Run("nothing is cached without the cache", func(t *testing.T) {
+		if err := eval(true); err != nil {
+			t.Fatalf("expected the policy to pass, got: %v", err)
+		}
+
+		if _, err := os.Stat(cacheDir); !os.IsNotExist(err) {
+			t.Errorf("expected no policy cache to be written, got: %v", err)
+		}
+	})
+
+	t.Run("parsed modules are cached and reused", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			if err := eval(false); err != nil {
+				t.Fatalf("expected the policy to pass on run %d, got: %v", i+1, err)
+			}
+		}
+
+		entries, err := ioutil.ReadDir(cacheDir)
+		if err != nil || len(entries) != 2 {
+			t.Errorf("expected a cache entry per module, got %v (%v)", len(entries), err)
+		}
+	})
+
+	t.Run("compile errors of cached modules point into the policies", func(t *testing.T) {
+		if err := os.Remove(filepath.Join(policyDir, "helpers.rego")); err != nil {
+			t.Fatal(err)
+		}
+
+		err := eval(false)
+		var compileErr *commands.PolicyCompileError
+		if !errors.As(err, &compileErr) {
+			t.Fatalf("expected a policy compile error, got: %v", err)
+		}
+
+		if !strings.Contains(err.Error(), "rules.rego:4") {
+			t.Errorf("expected the error to name the file and line, got: %v", err)
+		}
+	})
+
+	t.Run("offline violations of cached modules point into the policies", func(t *testing.T) {
+		policy := "package main\n\nexpect [\"the registry is reachable\"] {\n  http.send({\"method\": \"get\", \"url\": \"https://example.com\"})\n}\n"
+		if err := ioutil.WriteFile(filepath.Join(policyDir, "rules.rego"), []byte(policy), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		for i := 0; i < 2; i++ {
+			evalCmd := &commands.EvalCommand{
+				Stdout:      ioutil.Discard,
+				Template:    "testdata/templates",
+				Values:      []string{"testdata/values.yml"},
+				Policy:      []string{policyDir},
+				PolicyCache: cacheDir,
+				Offline:     true,
+			}
+			err := evalCmd.Execute([]string{})
+			if !errors.Is(err, commands.OfflineViolation) {
+				t.Fatalf("expected %v on run %d, got: %v", commands.OfflineViolation, i+1, err)
+			}
+
+			if !strings.Contains(err.Error(), "rules.rego:4") {
+				t.Errorf("expected the error to name the file and line on run %d, got: %v", i+1, err)
+			}
+		}
+	})
+}
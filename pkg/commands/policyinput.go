@@ -0,0 +1,81 @@
+package commands
+
+import (
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/util"
+)
+
+// policyInput - the input of a run converted to an opa value once, instead
+// of by every query evaluating it. Runs of parameterized and per document
+// rules only add input.param and input.document to the shared input, so
+// those are the only keys converted again
+type policyInput struct {
+	shared    map[string]*ast.Term
+	converted ast.Value
+}
+
+func newPolicyInput(input interface{}) (*policyInput, error) {
+	m, ok := input.(map[string]interface{})
+	if !ok {
+		converted, err := toValue(input)
+		return &policyInput{converted: converted}, err
+	}
+
+	shared := make(map[string]*ast.Term, len(m))
+	for key, value := range m {
+		converted, err := toValue(value)
+		if err != nil {
+			return nil, err
+		}
+		shared[key] = ast.NewTerm(converted)
+	}
+	return &policyInput{shared: shared, converted: objectOf(shared, nil)}, nil
+}
+
+// value - the given run input as an opa value
+func (s *policyInput) value(input interface{}) (ast.Value, error) {
+	m, ok := input.(map[string]interface{})
+	if !ok || s.shared == nil {
+		return s.converted, nil
+	}
+
+	added := make(map[string]*ast.Term)
+	for _, key := range []string{paramHashName, documentHashName} {
+		if value, ok := m[key]; ok {
+			converted, err := toValue(value)
+			if err != nil {
+				return nil, err
+			}
+			added[key] = ast.NewTerm(converted)
+		}
+	}
+
+	if len(added) == 0 {
+		return s.converted, nil
+	}
+	return objectOf(s.shared, added), nil
+}
+
+// objectOf - an object of the shared terms, with the added ones set over them
+func objectOf(shared, added map[string]*ast.Term) ast.Object {
+	pairs := make([][2]*ast.Term, 0, len(shared)+len(added))
+	for key, term := range shared {
+		if _, ok := added[key]; !ok {
+			pairs = append(pairs, [2]*ast.Term{ast.StringTerm(key), term})
+		}
+	}
+
+	for key, term := range added {
+		pairs = append(pairs, [2]*ast.Term{ast.StringTerm(key), term})
+	}
+	return ast.NewObject(pairs...)
+}
+
+// toValue - converts a go value the way opa converts raw input, through json
+func toValue(x interface{}) (ast.Value, error) {
+	ref := util.Reference(x)
+	if err := util.RoundTrip(ref); err != nil {
+		return nil, err
+	}
+	return ast.InterfaceToValue(*ref)
+}
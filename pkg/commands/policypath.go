@@ -0,0 +1,62 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const regoExt = ".rego"
+
+// validatePolicyPaths - every --policy must be a .rego file or a directory
+// holding at least one. Failures wrap InvalidPolicyPath with the reason
+func validatePolicyPaths(policies []string) error {
+	if len(policies) == 0 {
+		return fmt.Errorf("%w: no --policy given", InvalidPolicyPath)
+	}
+
+	for _, policy := range policies {
+		if strings.TrimSpace(policy) == "" {
+			return fmt.Errorf("%w: %v", InvalidPolicyPath, FilepathValueEmpty)
+		}
+
+		info, err := os.Stat(policy)
+		if err != nil {
+			return fmt.Errorf("%w: %v", InvalidPolicyPath, err)
+		}
+
+		if !info.IsDir() {
+			if ext := strings.ToLower(filepath.Ext(policy)); ext != regoExt {
+				return fmt.Errorf("%w: %s is not a %s file%s", InvalidPolicyPath, policy, regoExt, policyExtHint(ext))
+			}
+			continue
+		}
+
+		found := false
+		filepath.Walk(policy, func(path string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() && filepath.Ext(path) == regoExt {
+				found = true
+				return filepath.SkipDir
+			}
+			return nil
+		})
+
+		if !found {
+			return fmt.Errorf("%w: no %s files found in %s", InvalidPolicyPath, regoExt, policy)
+		}
+	}
+	return nil
+}
+
+func policyExtHint(ext string) string {
+	switch ext {
+	case ".yaml", ".yml":
+		return " (values files are given with -c/--values, templates with -t/--template)"
+	case ".json":
+		return " (json data documents can only be loaded from a policy directory next to your .rego files)"
+	case ".tpl", ".txt":
+		return " (templates are given with -t/--template)"
+	}
+	return ""
+}
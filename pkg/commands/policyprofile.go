@@ -0,0 +1,360 @@
+package commands
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/topdown"
+)
+
+// InvalidProfileOut - --profile-out names neither pprof nor folded
+var InvalidProfileOut = errors.New("invalid --profile-out, give pprof or folded, optionally followed by :<path>")
+
+const (
+	profilePprof  = "pprof"
+	profileFolded = "folded"
+)
+
+// defaultProfilePaths - where the profiles are written when --profile-out
+// gives no path, next to the results history
+var defaultProfilePaths = map[string]string{
+	profilePprof:  ".hcunit/profile.pb.gz",
+	profileFolded: ".hcunit/profile.folded",
+}
+
+// profileFrame - a rule or an expression of the policies on the stack of
+// an evaluation
+type profileFrame struct {
+	Name string
+	File string
+	Line int
+}
+
+// profileSample - the evaluations of a stack of frames and the time they
+// took, the stack ordered from the rule run to the expression evaluated
+type profileSample struct {
+	Stack []profileFrame
+	Count int64
+	Nanos int64
+}
+
+// policyProfile - a topdown tracer timing the evaluation of every rule
+// run, down to the rules it depends on and the expressions evaluated. The
+// time between two trace events is attributed to the stack of the first
+type policyProfile struct {
+	started time.Time
+	samples map[string]*profileSample
+	stacks  map[uint64][]profileFrame
+	run     []profileFrame
+	current []profileFrame
+	last    time.Time
+}
+
+func newPolicyProfile() *policyProfile {
+	return &policyProfile{started: time.Now(), samples: make(map[string]*profileSample)}
+}
+
+// parseProfileOut - the path of every format of --profile-out, e.g. pprof
+// or folded:/tmp/rules.folded
+func parseProfileOut(outputs []string) (map[string]string, error) {
+	paths := make(map[string]string, len(outputs))
+	for _, output := range outputs {
+		parts := strings.SplitN(output, ":", 2)
+		path, ok := defaultProfilePaths[parts[0]]
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", InvalidProfileOut, output)
+		}
+
+		if len(parts) == 2 && parts[1] != "" {
+			path = parts[1]
+		}
+		paths[parts[0]] = path
+	}
+	return paths, nil
+}
+
+// begin - starts timing a rule run
+func (p *policyProfile) begin(run string) {
+	p.run = []profileFrame{{Name: run}}
+	p.stacks = make(map[uint64][]profileFrame)
+	p.current = p.run
+	p.last = time.Now()
+}
+
+// end - attributes the time since the last event to it and stops timing
+// the rule run
+func (p *policyProfile) end() {
+	p.record(time.Now(), false)
+	p.current = nil
+}
+
+func (p *policyProfile) Enabled() bool {
+	return p != nil
+}
+
+func (p *policyProfile) Trace(event *topdown.Event) {
+	now := time.Now()
+	p.record(now, false)
+
+	stack, ok := p.stacks[event.QueryID]
+	if !ok {
+		if stack, ok = p.stacks[event.ParentID]; !ok {
+			stack = p.run
+		}
+	}
+
+	switch node := event.Node.(type) {
+	case *ast.Rule:
+		if event.Op == topdown.EnterOp {
+			stack = appendFrame(stack, ruleFrame(node))
+			p.stacks[event.QueryID] = stack
+		}
+		p.current = stack
+	case *ast.Expr:
+		p.current = appendFrame(stack, exprFrame(node))
+		if event.Op == topdown.EvalOp {
+			p.record(now, true)
+		}
+	default:
+		p.current = stack
+	}
+	p.last = now
+}
+
+// record - attributes the time since the last event to the current stack,
+// counting an evaluation of it when evaluated is set
+func (p *policyProfile) record(now time.Time, evaluated bool) {
+	if p.current == nil {
+		return
+	}
+
+	key := foldedStack(p.current)
+	sample, ok := p.samples[key]
+	if !ok {
+		sample = &profileSample{Stack: p.current}
+		p.samples[key] = sample
+	}
+
+	if evaluated {
+		sample.Count++
+		return
+	}
+	sample.Nanos += int64(now.Sub(p.last))
+}
+
+func appendFrame(stack []profileFrame, frame profileFrame) []profileFrame {
+	return append(append(make([]profileFrame, 0, len(stack)+1), stack...), frame)
+}
+
+func ruleFrame(rule *ast.Rule) profileFrame {
+	name := string(rule.Head.Name)
+	if rule.Head.Key != nil {
+		name += "[" + rule.Head.Key.String() + "]"
+	}
+	return locatedFrame(name, rule.Location)
+}
+
+func exprFrame(expr *ast.Expr) profileFrame {
+	text := expr.String()
+	if expr.Location != nil && len(expr.Location.Text) > 0 {
+		text = strings.SplitN(string(expr.Location.Text), "\n", 2)[0]
+	}
+	return locatedFrame(text, expr.Location)
+}
+
+func locatedFrame(name string, location *ast.Location) profileFrame {
+	frame := profileFrame{Name: name}
+	if location != nil && location.File != "" {
+		frame.File, frame.Line = filepath.ToSlash(location.File), location.Row
+		frame.Name = fmt.Sprintf("%s:%d %s", filepath.Base(location.File), frame.Line, name)
+	}
+	return frame
+}
+
+// foldedStack - the frames of a stack in the folded format of flame
+// graph tools, which separate frames by semicolons
+func foldedStack(stack []profileFrame) string {
+	names := make([]string, len(stack))
+	for i, frame := range stack {
+		names[i] = strings.Replace(frame.Name, ";", ",", -1)
+	}
+	return strings.Join(names, ";")
+}
+
+// sortedSamples - the samples ordered by their folded stack
+func (p *policyProfile) sortedSamples() []*profileSample {
+	keys := make([]string, 0, len(p.samples))
+	for key := range p.samples {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	samples := make([]*profileSample, len(keys))
+	for i, key := range keys {
+		samples[i] = p.samples[key]
+	}
+	return samples
+}
+
+// folded - one line of a folded stack and the nanoseconds spent in it per
+// sample, for flamegraph.pl, speedscope or inferno
+func (p *policyProfile) folded() []byte {
+	b := new(bytes.Buffer)
+	for _, sample := range p.sortedSamples() {
+		fmt.Fprintf(b, "%s %d\n", foldedStack(sample.Stack), sample.Nanos)
+	}
+	return b.Bytes()
+}
+
+// pprof - the gzipped profile.proto of the samples, with the number of
+// evaluations and the time spent in each stack as values, for go tool pprof
+func (p *policyProfile) pprof() ([]byte, error) {
+	strs := map[string]int64{"": 0}
+	table := []string{""}
+	str := func(s string) int64 {
+		if i, ok := strs[s]; ok {
+			return i
+		}
+		strs[s] = int64(len(table))
+		table = append(table, s)
+		return strs[s]
+	}
+
+	valueType := func(kind, unit string) []byte {
+		m := new(protoMessage)
+		m.varint(1, uint64(str(kind)))
+		m.varint(2, uint64(str(unit)))
+		return m.Bytes()
+	}
+
+	profile := new(protoMessage)
+	profile.bytes(1, valueType("evaluations", "count"))
+	profile.bytes(1, valueType("time", "nanoseconds"))
+
+	functions := make(map[profileFrame]uint64)
+	frames := make([]profileFrame, 0)
+	for _, sample := range p.sortedSamples() {
+		locations := make([]uint64, 0, len(sample.Stack))
+		for i := len(sample.Stack) - 1; i >= 0; i-- {
+			frame := sample.Stack[i]
+			if _, ok := functions[frame]; !ok {
+				functions[frame] = uint64(len(frames) + 1)
+				frames = append(frames, frame)
+			}
+			locations = append(locations, functions[frame])
+		}
+
+		m := new(protoMessage)
+		m.packed(1, locations)
+		m.packed(2, []uint64{uint64(sample.Count), uint64(sample.Nanos)})
+		profile.bytes(2, m.Bytes())
+	}
+
+	// every frame is a function with a location of its own
+	for i, frame := range frames {
+		id := uint64(i + 1)
+		line := new(protoMessage)
+		line.varint(1, id)
+		line.varint(2, uint64(frame.Line))
+
+		location := new(protoMessage)
+		location.varint(1, id)
+		location.bytes(4, line.Bytes())
+		profile.bytes(4, location.Bytes())
+
+		function := new(protoMessage)
+		function.varint(1, id)
+		function.varint(2, uint64(str(frame.Name)))
+		function.varint(3, uint64(str(frame.Name)))
+		function.varint(4, uint64(str(frame.File)))
+		function.varint(5, uint64(frame.Line))
+		profile.bytes(5, function.Bytes())
+	}
+
+	periodType := valueType("time", "nanoseconds")
+	for _, s := range table {
+		profile.bytes(6, []byte(s))
+	}
+	profile.varint(9, uint64(p.started.UnixNano()))
+	profile.varint(10, uint64(time.Since(p.started)))
+	profile.bytes(11, periodType)
+
+	b := new(bytes.Buffer)
+	w := gzip.NewWriter(b)
+	if _, err := w.Write(profile.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// protoMessage - encodes the varint and length delimited fields of a
+// protocol buffer message, all profile.proto needs
+type protoMessage struct {
+	bytes.Buffer
+}
+
+func (m *protoMessage) uvarint(v uint64) {
+	for v >= 0x80 {
+		m.WriteByte(byte(v) | 0x80)
+		v >>= 7
+	}
+	m.WriteByte(byte(v))
+}
+
+func (m *protoMessage) varint(field int, v uint64) {
+	m.uvarint(uint64(field)<<3 | 0)
+	m.uvarint(v)
+}
+
+func (m *protoMessage) bytes(field int, b []byte) {
+	m.uvarint(uint64(field)<<3 | 2)
+	m.uvarint(uint64(len(b)))
+	m.Write(b)
+}
+
+func (m *protoMessage) packed(field int, values []uint64) {
+	packed := new(protoMessage)
+	for _, v := range values {
+		packed.uvarint(v)
+	}
+	m.bytes(field, packed.Bytes())
+}
+
+// writeProfiles - writes the profile of the evaluation in every format of
+// --profile-out
+func (s *EvalCommand) writeProfiles() error {
+	paths, err := parseProfileOut(s.ProfileOut)
+	if err != nil {
+		return err
+	}
+
+	for format, path := range paths {
+		content := s.profile.folded()
+		if format == profilePprof {
+			if content, err = s.profile.pprof(); err != nil {
+				return err
+			}
+		}
+
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+
+		if err := ioutil.WriteFile(path, content, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
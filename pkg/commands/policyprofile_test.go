@@ -0,0 +1,81 @@
+package commands_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestEvalProfileOut(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hcunit-profile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	t.Run("the evaluation is profiled as pprof and folded stacks", func(t *testing.T) {
+		pprofPath, foldedPath := filepath.Join(dir, "profile.pb.gz"), filepath.Join(dir, "profiles", "rules.folded")
+		evalCmd := &commands.EvalCommand{
+			Stdout:     new(bytes.Buffer),
+			Template:   "testdata/templates/something.yml",
+			Values:     []string{"testdata/values.yml"},
+			Policy:     []string{"testdata/policy/passing/passing.rego"},
+			ProfileOut: []string{"pprof:" + pprofPath, "folded:" + foldedPath},
+		}
+		if err := evalCmd.Execute([]string{}); err != nil {
+			t.Fatal(err)
+		}
+
+		folded, err := ioutil.ReadFile(foldedPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		stack := `data.main.expect["force passing"];passing.rego:`
+		if !strings.Contains(string(folded), stack) {
+			t.Errorf("expected a stack starting with %s, got:\n%s", stack, folded)
+		}
+
+		f, err := os.Open(pprofPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		r, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatalf("expected a gzipped profile, got: %v", err)
+		}
+
+		profile, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, s := range []string{"evaluations", "nanoseconds", `data.main.expect["force passing"]`} {
+			if !bytes.Contains(profile, []byte(s)) {
+				t.Errorf("expected the string table of the profile to hold %s", s)
+			}
+		}
+	})
+
+	t.Run("unknown formats are rejected", func(t *testing.T) {
+		evalCmd := &commands.EvalCommand{
+			Stdout:     new(bytes.Buffer),
+			Template:   "testdata/templates/something.yml",
+			Values:     []string{"testdata/values.yml"},
+			Policy:     []string{"testdata/policy/passing/passing.rego"},
+			ProfileOut: []string{"svg"},
+		}
+		if err := evalCmd.Execute([]string{}); !errors.Is(err, commands.InvalidProfileOut) {
+			t.Errorf("expected error:\n%v\ngot:\n%v", commands.InvalidProfileOut, err)
+		}
+	})
+}
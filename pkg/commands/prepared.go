@@ -0,0 +1,237 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/mitchellh/colorstring"
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/storage/inmem"
+	"github.com/open-policy-agent/opa/topdown"
+)
+
+// preparedPolicies - the policies of a namespace compiled once, with a
+// prepared query per rule and for the params and metadata tables, to
+// evaluate any number of inputs against, e.g. every values set of a batch.
+// Evaluations share an assertion recorder, so they must not run concurrently
+type preparedPolicies struct {
+	loaded     *loadedPolicies
+	policies   []string
+	namespace  string
+	queries    map[string]rego.PreparedEvalQuery
+	params     rego.PreparedEvalQuery
+	metadata   rego.PreparedEvalQuery
+	assertions *assertionRecorder
+	locations  map[string]*ast.Location
+	profile    *policyProfile
+	options    []func(*rego.Rego)
+	explained  *ast.Compiler
+}
+
+// compilePolicies - compiles the modules with every builtin hcunit adds
+func compilePolicies(modules map[string]*ast.Module) *ast.Compiler {
+	decls := make(map[string]*ast.Builtin)
+	for _, function := range hcunitFunctions() {
+		decls[function.Name] = &ast.Builtin{Name: function.Name, Decl: function.Decl}
+	}
+
+	compiler := ast.NewCompiler().WithBuiltins(decls)
+	compiler.Compile(modules)
+	return compiler
+}
+
+// preparePolicies - compiles the loaded policies and prepares the query of
+// every expect/assert/deny/warn rule of the namespace against them
+func preparePolicies(loaded *loadedPolicies, policies []string, namespace string, options ...func(*rego.Rego)) (*preparedPolicies, error) {
+	queryList := ruleQueries(loaded.modules)
+	for querySuffix, querymatches := range queryList {
+		if querymatches > 1 {
+			colorstring.Println("[red]ERROR: you are using duplicate test names or variables. This could cause test failures to NOT be detected properly")
+			colorstring.Println(fmt.Sprintf("[yellow]DUPLICATE KEY: %s", querySuffix))
+			return nil, DuplicatePolicyFailure
+		}
+	}
+
+	compiler := compilePolicies(loaded.modules)
+	if compiler.Failed() {
+		return nil, &PolicyCompileError{Policies: policies, Err: compiler.Errors}
+	}
+
+	prepared := &preparedPolicies{
+		loaded:     loaded,
+		policies:   policies,
+		namespace:  namespace,
+		queries:    make(map[string]rego.PreparedEvalQuery, len(queryList)),
+		assertions: new(assertionRecorder),
+	}
+
+	ctx := context.Background()
+	store := inmem.NewFromObject(loaded.documents)
+	options = append([]func(*rego.Rego){rego.Compiler(compiler), rego.Store(store), prepared.assertions.builtin()}, options...)
+	prepared.options = options
+	prepare := func(queryString string) (rego.PreparedEvalQuery, error) {
+		query, err := rego.New(append([]func(*rego.Rego){rego.Query(queryString)}, options...)...).PrepareForEval(ctx)
+		if err != nil {
+			return query, &PolicyCompileError{Policies: policies, Err: err}
+		}
+		return query, nil
+	}
+
+	var err error
+	if prepared.params, err = prepare(fmt.Sprintf("data.%s.%s", namespace, paramsRuleName)); err != nil {
+		return nil, err
+	}
+
+	if prepared.metadata, err = prepare(fmt.Sprintf("data.%s.%s", namespace, metadataRuleName)); err != nil {
+		return nil, err
+	}
+
+	for querySuffix := range queryList {
+		if prepared.queries[querySuffix], err = prepare(fmt.Sprintf("data.%s.%s", namespace, querySuffix)); err != nil {
+			return nil, err
+		}
+	}
+	return prepared, nil
+}
+
+// eval - evaluates every rule against the input, calling the BeforeRule
+// hook (if any) before each run, and returns the result of each run
+// ordered by name
+func (s *preparedPolicies) eval(writer io.Writer, hooks *Hooks, input interface{}) ([]RuleResult, error) {
+	testResults := make(map[string]bool)
+	warnings := make(map[string]bool)
+	durations := make(map[string]time.Duration)
+	dryRuns := make(map[string]bool)
+	runMetadata := make(map[string]map[string]interface{})
+	assertionDiffs := make(map[string][]string)
+	failureDetails := make(map[string]FailureDetail)
+	ctx := context.Background()
+	parsedInput, err := newPolicyInput(input)
+	if err != nil {
+		return nil, &EvaluationError{Query: fmt.Sprintf("data.%s", s.namespace), Err: err}
+	}
+
+	paramsTable, err := evalRuleTable(ctx, s.params, s.namespace, paramsRuleName, parsedInput.converted)
+	if err != nil {
+		return nil, err
+	}
+
+	params, err := paramsOf(s.namespace, paramsTable)
+	if err != nil {
+		return nil, err
+	}
+
+	metadataTable, err := evalRuleTable(ctx, s.metadata, s.namespace, metadataRuleName, parsedInput.converted)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := metadataOf(s.namespace, metadataTable)
+	if err != nil {
+		return nil, err
+	}
+
+	for querySuffix, query := range s.queries {
+		queryString := fmt.Sprintf("data.%s.%s", s.namespace, querySuffix)
+		buf := topdown.NewBufferTracer()
+		ruleMetadata := metadata[ruleKey(querySuffix)]
+		runs := paramRuns(queryString, input, params[ruleKey(querySuffix)])
+		if isPerDocument(ruleMetadata) {
+			runs = documentRuns(runs, input, ruleMetadata)
+		}
+
+		for _, run := range runs {
+			if err := hooks.beforeRule(run.name); errors.Is(err, SkipRule) {
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+
+			s.assertions.reset()
+			traceStart := len(*buf)
+			runInput, err := parsedInput.value(run.input)
+			if err != nil {
+				return nil, &EvaluationError{Query: run.name, Err: err}
+			}
+
+			evalOptions := []rego.EvalOption{rego.EvalParsedInput(runInput), rego.EvalTracer(buf)}
+			if s.profile != nil {
+				s.profile.begin(run.name)
+				evalOptions = append(evalOptions, rego.EvalTracer(s.profile))
+			}
+
+			started := time.Now()
+			resultSet, err := query.Eval(ctx, evalOptions...)
+			if s.profile != nil {
+				s.profile.end()
+			}
+
+			if err != nil {
+				return nil, &EvaluationError{Query: run.name, Err: err}
+			}
+			durations[run.name] = time.Since(started)
+			assertionDiffs[run.name] = s.assertions.diffs
+
+			defined := false
+			for _, result := range resultSet {
+				for _, expression := range result.Expressions {
+					if expression.Text == queryString {
+						defined = true
+					}
+				}
+			}
+			testResults[run.name], warnings[run.name] = ruleOutcome(ruleKind(querySuffix), defined)
+			runMetadata[run.name] = ruleMetadata
+			if !testResults[run.name] && isDryRun(ruleMetadata) {
+				testResults[run.name], dryRuns[run.name] = true, true
+			}
+
+			if !testResults[run.name] {
+				if s.locations == nil {
+					s.locations = ruleLocations(s.loaded.sourceModules(s.policies))
+				}
+				detail := failureDetail(s.locations[querySuffix], (*buf)[traceStart:])
+				if run.document != "" {
+					detail.Documents = append([]string{run.document}, detail.Documents...)
+				}
+				if kind := ruleKind(querySuffix); kind != ruleDeny && kind != ruleWarn {
+					detail.Explanation = s.explain(ctx, querySuffix, runInput)
+				}
+				failureDetails[run.name] = detail
+			}
+		}
+
+		topdown.PrettyTrace(writer, *buf)
+	}
+
+	if len(s.queries) <= 0 || len(testResults) <= 0 {
+		return nil, UnmatchedQuery
+	}
+
+	ruleResults := make([]RuleResult, 0, len(testResults))
+	violation := &ViolationError{Failed: make([]string, 0), Diffs: make(map[string][]string), Details: make(map[string]FailureDetail)}
+	for testname, passed := range testResults {
+		result := RuleResult{Name: testname, Passed: passed, Warning: warnings[testname], DryRun: dryRuns[testname], Metadata: runMetadata[testname], Duration: durations[testname]}
+		if !passed {
+			result.Documents = failureDetails[testname].Documents
+			violation.Failed = append(violation.Failed, testname)
+			violation.Details[testname] = failureDetails[testname]
+			if len(assertionDiffs[testname]) > 0 {
+				violation.Diffs[testname] = assertionDiffs[testname]
+			}
+		}
+		ruleResults = append(ruleResults, result)
+	}
+
+	sort.Slice(ruleResults, func(i, j int) bool { return ruleResults[i].Name < ruleResults[j].Name })
+	if len(violation.Failed) > 0 {
+		sort.Strings(violation.Failed)
+		return ruleResults, violation
+	}
+	return ruleResults, nil
+}
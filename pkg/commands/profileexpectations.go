@@ -0,0 +1,237 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ProfileExpectationFailure - rendered objects don't meet the expectations
+// of the profile they were rendered for
+var ProfileExpectationFailure = errors.New("rendered objects don't meet the expectations of the profile")
+
+// ProfileExpectation - a lightweight assertion on the objects a profile
+// renders, for what is expected to differ between environments without
+// writing any rego, e.g. prod running at least 3 replicas and dev exactly
+// 1. The field at Path of every object of Kind (named Name, if given) must
+// equal Equals, lie within Min and Max and match the regular expression of
+// Matches, for those given
+type ProfileExpectation struct {
+	Kind    string      `yaml:"kind"`
+	Name    string      `yaml:"name"`
+	Path    string      `yaml:"path"`
+	Equals  interface{} `yaml:"equals"`
+	Min     *float64    `yaml:"min"`
+	Max     *float64    `yaml:"max"`
+	Matches string      `yaml:"matches"`
+}
+
+// expectationFinding - an object, or the lack of one, not meeting an
+// expectation of the profile
+type expectationFinding struct {
+	Location string
+	Reason   string
+}
+
+var expectationPathSegment = regexp.MustCompile(`^(?:\.?([^.\[\]]+)|\[(\d+)\]|\["([^"]*)"\])`)
+
+// expectationPath - the keys and indexes of a path like
+// spec.template.spec.containers[0].image, with keys holding dots quoted as
+// in metadata.labels["app.kubernetes.io/name"]
+func expectationPath(path string) ([]interface{}, error) {
+	segments := make([]interface{}, 0)
+	for rest := path; rest != ""; {
+		match := expectationPathSegment.FindStringSubmatch(rest)
+		if match == nil || (len(segments) == 0 && strings.HasPrefix(rest, ".")) {
+			return nil, fmt.Errorf("path %q can't be parsed at %q", path, rest)
+		}
+
+		switch {
+		case match[1] != "":
+			segments = append(segments, match[1])
+		case match[2] != "":
+			index, _ := strconv.Atoi(match[2])
+			segments = append(segments, index)
+		default:
+			segments = append(segments, match[3])
+		}
+		rest = rest[len(match[0]):]
+	}
+	return segments, nil
+}
+
+// validate - the expectation names a kind and path, and at least one
+// condition which can be checked
+func (s ProfileExpectation) validate() error {
+	if s.Kind == "" || s.Path == "" {
+		return errors.New("expectations need a kind and a path")
+	}
+
+	if s.Equals == nil && s.Min == nil && s.Max == nil && s.Matches == "" {
+		return fmt.Errorf("expectation of %s %s needs one of equals, min, max or matches", s.Kind, s.Path)
+	}
+
+	if _, err := expectationPath(s.Path); err != nil {
+		return err
+	}
+
+	if _, err := regexp.Compile(s.Matches); err != nil {
+		return fmt.Errorf("matches of %s %s: %v", s.Kind, s.Path, err)
+	}
+	return nil
+}
+
+// lookupField - the value at the path of an object, and whether it is set
+func lookupField(obj map[string]interface{}, segments []interface{}) (interface{}, bool) {
+	var current interface{} = obj
+	for _, segment := range segments {
+		switch key := segment.(type) {
+		case string:
+			m, ok := current.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+
+			if current, ok = m[key]; !ok {
+				return nil, false
+			}
+		case int:
+			list, ok := current.([]interface{})
+			if !ok || key >= len(list) {
+				return nil, false
+			}
+			current = list[key]
+		}
+	}
+	return current, true
+}
+
+// normalized - the value as it reads in json, so the ints of the config
+// compare equal to the floats of the rendered templates
+func normalized(value interface{}) interface{} {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+
+	var out interface{}
+	if json.Unmarshal(b, &out) != nil {
+		return value
+	}
+	return out
+}
+
+// check - the ways the value breaks the expectation, e.g. is 1, expected
+// at least 3
+func (s ProfileExpectation) check(value interface{}, redact func(string) string) []string {
+	value = normalized(value)
+	shown := redact(formatValue(value))
+	reasons := make([]string, 0)
+	if s.Equals != nil && !reflect.DeepEqual(value, normalized(s.Equals)) {
+		reasons = append(reasons, fmt.Sprintf("is %s, expected %s", shown, formatValue(normalized(s.Equals))))
+	}
+
+	if s.Min != nil || s.Max != nil {
+		number, ok := value.(float64)
+		switch {
+		case !ok:
+			reasons = append(reasons, fmt.Sprintf("is %s, expected a number", shown))
+		case s.Min != nil && number < *s.Min:
+			reasons = append(reasons, fmt.Sprintf("is %s, expected at least %v", shown, *s.Min))
+		case s.Max != nil && number > *s.Max:
+			reasons = append(reasons, fmt.Sprintf("is %s, expected at most %v", shown, *s.Max))
+		}
+	}
+
+	if s.Matches != "" {
+		pattern := regexp.MustCompile(s.Matches)
+		if str, ok := value.(string); !ok || !pattern.MatchString(str) {
+			reasons = append(reasons, fmt.Sprintf("is %s, expected to match %s", shown, s.Matches))
+		}
+	}
+	return reasons
+}
+
+func formatValue(value interface{}) string {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(b)
+}
+
+// checkProfileExpectations - the objects of the rendered templates in the
+// policy input not meeting the expectations of the profile, with the values
+// they hold passed through redact. Expectations no object is rendered for
+// are findings of their own
+func checkProfileExpectations(input map[string]interface{}, expectations []ProfileExpectation, redact func(string) string) []expectationFinding {
+	matched := make([]bool, len(expectations))
+	findings := checkRenderedExpectations(input, expectations, matched, redact)
+	return append(findings, unrenderedExpectations(expectations, matched)...)
+}
+
+// checkRenderedExpectations - the objects of the rendered templates in the
+// policy input not meeting the expectations of the profile, marking the
+// expectations objects were rendered for in matched, e.g. to check the
+// templates of a chart one at a time
+func checkRenderedExpectations(input map[string]interface{}, expectations []ProfileExpectation, matched []bool, redact func(string) string) []expectationFinding {
+	findings := make([]expectationFinding, 0)
+	for i, expectation := range expectations {
+		segments, _ := expectationPath(expectation.Path)
+		for _, template := range templateNames(input) {
+			for _, obj := range renderedObjects(map[string]interface{}{template: input[template]}) {
+				if !strings.EqualFold(objectKind(obj), expectation.Kind) || expectation.Name != "" && objectName(obj) != expectation.Name {
+					continue
+				}
+				matched[i] = true
+
+				location := fmt.Sprintf("%s in %s", objectRef(obj), template)
+				value, ok := lookupField(obj, segments)
+				if !ok {
+					findings = append(findings, expectationFinding{Location: location, Reason: fmt.Sprintf("%s is not set", expectation.Path)})
+					continue
+				}
+
+				for _, reason := range expectation.check(value, redact) {
+					findings = append(findings, expectationFinding{Location: location, Reason: expectation.Path + " " + reason})
+				}
+			}
+		}
+	}
+	return findings
+}
+
+// unrenderedExpectations - the expectations no object was rendered for
+func unrenderedExpectations(expectations []ProfileExpectation, matched []bool) []expectationFinding {
+	findings := make([]expectationFinding, 0)
+	for i, expectation := range expectations {
+		if matched[i] {
+			continue
+		}
+
+		ref := expectation.Kind
+		if expectation.Name != "" {
+			ref += "/" + expectation.Name
+		}
+		findings = append(findings, expectationFinding{Location: ref, Reason: "not rendered"})
+	}
+	return findings
+}
+
+// expectationResults - the findings as failed results, one per object,
+// path and reason, reported and counted along with our policy results
+func expectationResults(profile string, findings []expectationFinding) ([]RuleResult, error) {
+	results := make([]RuleResult, 0, len(findings))
+	for _, finding := range findings {
+		results = append(results, checkResult("profile "+profile+" expects", finding.Location, finding.Reason))
+	}
+
+	if len(results) > 0 {
+		return results, ProfileExpectationFailure
+	}
+	return results, nil
+}
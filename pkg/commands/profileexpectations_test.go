@@ -0,0 +1,112 @@
+package commands_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestEvalProfileExpectations(t *testing.T) {
+	for _, tt := range []struct {
+		name        string
+		config      string
+		profiles    []string
+		allProfiles bool
+		failsWith   error
+		contains    []string
+		excludes    []string
+	}{
+		{
+			name:        "the expected differences between profiles hold",
+			config:      "testdata/profiles/hcunit.yaml",
+			allProfiles: true,
+			excludes:    []string{"expects"},
+		},
+		{
+			name:      "objects not meeting the expectations fail the run",
+			config:    "testdata/profiles/expectations.yaml",
+			profiles:  []string{"understaffed"},
+			failsWith: commands.ProfileExpectationFailure,
+			contains: []string{
+				"profile understaffed expects Deployment/app-dev in deployment.yaml: spec.replicas is 1, expected at least 3\n",
+				`profile understaffed expects Deployment/app-dev in deployment.yaml: spec.template.spec.containers[0].image is "app:latest", expected to match :[0-9.]+$` + "\n",
+				`profile understaffed expects Deployment/app-dev in deployment.yaml: metadata.labels["app.kubernetes.io/name"] is not set` + "\n",
+				"profile understaffed expects HorizontalPodAutoscaler: not rendered\n",
+			},
+		},
+		{
+			name:      "paths have to parse",
+			config:    "testdata/profiles/expectations.yaml",
+			profiles:  []string{"unparsable"},
+			failsWith: commands.InvalidProfile,
+		},
+		{
+			name:      "expectations need a condition",
+			config:    "testdata/profiles/expectations.yaml",
+			profiles:  []string{"unchecked"},
+			failsWith: commands.InvalidProfile,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			stdOut := new(bytes.Buffer)
+			evalCmd := &commands.EvalCommand{
+				Stdout:      stdOut,
+				Template:    "testdata/profiles/templates",
+				Config:      tt.config,
+				Profile:     tt.profiles,
+				AllProfiles: tt.allProfiles,
+			}
+			err := evalCmd.Execute([]string{})
+			if tt.failsWith == nil && err != nil || !errors.Is(err, tt.failsWith) {
+				t.Fatalf("expected error:\n%v\ngot:\n%v\n%s", tt.failsWith, err, stdOut)
+			}
+
+			for _, s := range tt.contains {
+				if !strings.Contains(stdOut.String(), s) {
+					t.Errorf("expected %q in:\n%s", s, stdOut)
+				}
+			}
+
+			for _, s := range tt.excludes {
+				if strings.Contains(stdOut.String(), s) {
+					t.Errorf("expected no %q in:\n%s", s, stdOut)
+				}
+			}
+		})
+	}
+}
+
+func TestEvalProfileExpectationResults(t *testing.T) {
+	stdOut := new(bytes.Buffer)
+	evalCmd := &commands.EvalCommand{
+		Stdout:   stdOut,
+		Template: "testdata/profiles/templates",
+		Config:   "testdata/profiles/expectations.yaml",
+		Profile:  []string{"understaffed"},
+		Output:   "json",
+	}
+	if err := evalCmd.Execute([]string{}); !errors.Is(err, commands.ProfileExpectationFailure) {
+		t.Fatalf("expected error:\n%v\ngot:\n%v\n%s", commands.ProfileExpectationFailure, err, stdOut)
+	}
+
+	var results commands.Results
+	if err := json.Unmarshal(stdOut.Bytes(), &results); err != nil {
+		t.Fatalf("expected json results, got %v:\n%s", err, stdOut)
+	}
+
+	if results.Summary.Failed != 4 {
+		t.Errorf("expected a failed result per finding, got %+v", results.Summary)
+	}
+
+	expected := "profile understaffed expects Deployment/app-dev in deployment.yaml: spec.replicas is 1, expected at least 3"
+	for _, result := range results.Results {
+		if result.Rule == expected && result.Result == "fail" {
+			return
+		}
+	}
+	t.Errorf("expected the failed result %q in:\n%s", expected, stdOut)
+}
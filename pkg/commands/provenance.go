@@ -0,0 +1,210 @@
+package commands
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+	"k8s.io/helm/pkg/chartutil"
+)
+
+const (
+	provenanceFileName = "provenance.json"
+	bundleManifestName = ".manifest"
+)
+
+// Provenance - what a report was produced from, so report artifacts stay
+// self-describing after the CI job which produced them is gone. Inputs
+// which can't be digested (e.g. a missing values file) are left empty
+type Provenance struct {
+	HcunitVersion string             `json:"hcunitVersion"`
+	Chart         ChartProvenance    `json:"chart"`
+	Policies      []PolicyProvenance `json:"policies"`
+	PolicyDigest  string             `json:"policyDigest,omitempty"`
+	ValuesDigest  string             `json:"valuesDigest,omitempty"`
+	Flags         []string           `json:"flags"`
+}
+
+type ChartProvenance struct {
+	Name    string `json:"name,omitempty"`
+	Version string `json:"version,omitempty"`
+	Digest  string `json:"digest,omitempty"`
+}
+
+// PolicyProvenance - a policy path, its digest and, when it is an opa bundle
+// or a locked policy pack, its version
+type PolicyProvenance struct {
+	Path    string `json:"path"`
+	Version string `json:"version,omitempty"`
+	Digest  string `json:"digest,omitempty"`
+}
+
+// provenance - the provenance of this run's inputs and flags
+func (s *EvalCommand) provenance() Provenance {
+	provenance := Provenance{
+		HcunitVersion: s.Version,
+		Policies:      make([]PolicyProvenance, 0, len(s.Policy)),
+		Flags:         commandFlags(s),
+	}
+
+	if chartDir, err := findChartRoot(s.Template); err != nil {
+		provenance.Chart.Digest, _ = digestPath(s.Template)
+	} else if chart, err := chartutil.Load(chartDir); err == nil {
+		provenance.Chart.Name = chart.GetMetadata().GetName()
+		provenance.Chart.Version = chart.GetMetadata().GetVersion()
+		provenance.Chart.Digest, _ = ChartDigest(chartDir)
+	}
+
+	for _, policy := range s.Policy {
+		digest, _ := digestPath(policy)
+		provenance.Policies = append(provenance.Policies, PolicyProvenance{
+			Path:    policy,
+			Version: policyVersion(policy),
+			Digest:  digest,
+		})
+	}
+	provenance.PolicyDigest, _ = PolicySetDigest(s.Policy)
+	provenance.ValuesDigest, _ = s.valuesDigest()
+	return provenance
+}
+
+// valuesDigest - the digest of the values files with the values --set,
+// --set-string and --set-file set over them folded in, so runs with
+// different overrides never share a digest while their flags are redacted
+func (s *EvalCommand) valuesDigest() (string, error) {
+	digest, err := digestPaths(s.Values)
+	if err != nil || len(s.Set)+len(s.SetString)+len(s.SetFile) == 0 {
+		return digest, err
+	}
+
+	overrides := make(map[string]interface{})
+	if err := s.setValues(overrides); err != nil {
+		return "", err
+	}
+
+	b, err := json.Marshal(overrides)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\x00%s", digest, b)
+	return fmt.Sprintf("sha256:%x", hash.Sum(nil)), nil
+}
+
+// writeProvenance - writes the provenance of a run next to its artifacts
+func writeProvenance(dir string, provenance Provenance) error {
+	b, err := json.MarshalIndent(provenance, "", "  ")
+	if err != nil {
+		return fmt.Errorf("couldnt marshal provenance: %w", err)
+	}
+	return ioutil.WriteFile(filepath.Join(dir, provenanceFileName), append(b, '\n'), 0644)
+}
+
+// policyVersion - the revision of an opa bundle's .manifest, or the locked
+// ref of a policy pack fetched into .hcunit/policies by `hcunit policy update`
+func policyVersion(policy string) string {
+	if b, err := ioutil.ReadFile(filepath.Join(policy, bundleManifestName)); err == nil {
+		manifest := struct {
+			Revision string `json:"revision"`
+		}{}
+		if json.Unmarshal(b, &manifest) == nil && manifest.Revision != "" {
+			return manifest.Revision
+		}
+	}
+
+	dir, err := filepath.Abs(policy)
+	if err != nil {
+		return ""
+	}
+
+	cache := filepath.FromSlash(policyCacheName)
+	for filepath.Dir(dir) != dir && !strings.HasSuffix(filepath.Dir(dir), string(filepath.Separator)+cache) {
+		dir = filepath.Dir(dir)
+	}
+
+	if filepath.Dir(dir) == dir {
+		return ""
+	}
+
+	root := strings.TrimSuffix(filepath.Dir(dir), cache)
+	b, err := ioutil.ReadFile(filepath.Join(root, policyLockName))
+	if err != nil {
+		return ""
+	}
+
+	lock := PolicyLock{}
+	if err := yaml.Unmarshal(b, &lock); err != nil {
+		return ""
+	}
+
+	for _, locked := range lock.Policies {
+		if locked.Name == filepath.Base(dir) && locked.Ref != "" {
+			return locked.Ref
+		}
+	}
+	return ""
+}
+
+// commandFlags - the long flags set on a command, as they would be passed
+// on the command line. Values of flags tagged redact (e.g. auth headers or
+// --set values) are replaced with <redacted>
+func commandFlags(command interface{}) []string {
+	flags := make([]string, 0)
+	v := reflect.Indirect(reflect.ValueOf(command))
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		long := field.Tag.Get("long")
+		if long == "" || field.PkgPath != "" {
+			continue
+		}
+
+		value := v.Field(i)
+		if value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				continue
+			}
+			value = value.Elem()
+		}
+
+		if field.Tag.Get("redact") == "true" {
+			value = redactedFlagValue(value)
+		}
+
+		switch value.Kind() {
+		case reflect.Bool:
+			if value.Bool() {
+				flags = append(flags, "--"+long)
+			}
+		case reflect.Slice:
+			for j := 0; j < value.Len(); j++ {
+				flags = append(flags, fmt.Sprintf("--%s=%v", long, value.Index(j).Interface()))
+			}
+		default:
+			if !value.IsZero() {
+				flags = append(flags, fmt.Sprintf("--%s=%v", long, value.Interface()))
+			}
+		}
+	}
+	return flags
+}
+
+func redactedFlagValue(value reflect.Value) reflect.Value {
+	if value.Kind() != reflect.Slice {
+		if value.IsZero() {
+			return value
+		}
+		return reflect.ValueOf(redactedValue)
+	}
+
+	redacted := make([]string, value.Len())
+	for i := range redacted {
+		redacted[i] = redactedValue
+	}
+	return reflect.ValueOf(redacted)
+}
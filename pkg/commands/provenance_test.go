@@ -0,0 +1,70 @@
+package commands_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestProvenancePolicyVersions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hcunit-provenance")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	bundle := filepath.Join(dir, "bundle")
+	pack := filepath.Join(dir, ".hcunit", "policies", "pack")
+	for path, content := range map[string]string{
+		filepath.Join(bundle, ".manifest"):    `{"revision": "v1.2.0"}`,
+		filepath.Join(bundle, "bundle.rego"):  "package main\n\nexpect [\"from the bundle\"] { true }\n",
+		filepath.Join(pack, "pack.rego"):      "package main\n\nexpect [\"from the pack\"] { true }\n",
+		filepath.Join(dir, ".hcunit.lock"):    "policies:\n  - name: pack\n    url: git+https://example.com/pack.git\n    ref: v0.3.1\n    digest: sha256:0000\n",
+		filepath.Join(dir, "plain", "a.rego"): "package main\n\nexpect [\"from a plain dir\"] { true }\n",
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	auditLog := filepath.Join(dir, "audit.jsonl")
+	evalCmd := &commands.EvalCommand{
+		Stdout:   ioutil.Discard,
+		Version:  "1.0.0",
+		Template: "testdata/templates",
+		Values:   []string{"testdata/values.yml"},
+		Policy:   []string{bundle, pack, filepath.Join(dir, "plain")},
+		AuditLog: auditLog,
+	}
+	if err := evalCmd.Execute([]string{}); err != nil {
+		t.Fatalf("expected the policies to pass, got: %v", err)
+	}
+
+	content, err := ioutil.ReadFile(auditLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	record := commands.AuditRecord{}
+	if err := json.Unmarshal(content, &record); err != nil {
+		t.Fatalf("expected a json record, got %q: %v", content, err)
+	}
+
+	if record.Provenance.HcunitVersion != "1.0.0" {
+		t.Errorf("expected the hcunit version, got: %q", record.Provenance.HcunitVersion)
+	}
+
+	for i, expected := range []string{"v1.2.0", "v0.3.1", ""} {
+		if version := record.Provenance.Policies[i].Version; version != expected {
+			t.Errorf("expected %s to be version %q, got: %q", record.Provenance.Policies[i].Path, expected, version)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/types"
+)
+
+const rbacHashName = "rbac"
+
+type rbacModel struct {
+	Roles    []rbacRole    `json:"roles"`
+	Bindings []rbacBinding `json:"bindings"`
+	Subjects []rbacSubject `json:"subjects"`
+}
+
+type rbacRole struct {
+	Ref       string     `json:"ref"`
+	Kind      string     `json:"kind"`
+	Name      string     `json:"name"`
+	Namespace string     `json:"namespace"`
+	Rules     []rbacRule `json:"rules"`
+}
+
+type rbacRule struct {
+	APIGroups     []string `json:"apiGroups"`
+	Resources     []string `json:"resources"`
+	Verbs         []string `json:"verbs"`
+	ResourceNames []string `json:"resourceNames"`
+}
+
+type rbacBinding struct {
+	Ref       string   `json:"ref"`
+	Kind      string   `json:"kind"`
+	Name      string   `json:"name"`
+	Namespace string   `json:"namespace"`
+	RoleRef   string   `json:"roleRef"`
+	Subjects  []string `json:"subjects"`
+}
+
+type rbacSubject struct {
+	Ref         string           `json:"ref"`
+	Kind        string           `json:"kind"`
+	Name        string           `json:"name"`
+	Namespace   string           `json:"namespace"`
+	Roles       []string         `json:"roles"`
+	Permissions []rbacPermission `json:"permissions"`
+}
+
+type rbacPermission struct {
+	APIGroup      string   `json:"apiGroup"`
+	Resource      string   `json:"resource"`
+	Verb          string   `json:"verb"`
+	ResourceNames []string `json:"resourceNames"`
+	Namespace     string   `json:"namespace"`
+	Role          string   `json:"role"`
+}
+
+// builtinClusterRoles - well known cluster roles a chart binds to without
+// rendering them itself
+var builtinClusterRoles = map[string]rbacRole{
+	"ClusterRole/cluster-admin": {
+		Ref:  "ClusterRole/cluster-admin",
+		Kind: "ClusterRole",
+		Name: "cluster-admin",
+		Rules: []rbacRule{
+			{APIGroups: []string{"*"}, Resources: []string{"*"}, Verbs: []string{"*"}, ResourceNames: []string{}},
+		},
+	},
+}
+
+// buildRBACModel - resolves the rendered Roles, ClusterRoles and their
+// bindings into the effective permissions of each bound subject. Namespaced
+// objects are referred to as Kind/namespace/name when they name their
+// namespace, so same named Roles of different namespaces stay apart
+func buildRBACModel(objects []map[string]interface{}) rbacModel {
+	model := rbacModel{
+		Roles:    make([]rbacRole, 0),
+		Bindings: make([]rbacBinding, 0),
+		Subjects: make([]rbacSubject, 0),
+	}
+
+	roles := map[string]rbacRole{}
+	for ref, role := range builtinClusterRoles {
+		roles[ref] = role
+	}
+
+	for _, obj := range objects {
+		switch objectKind(obj) {
+		case "Role", "ClusterRole":
+			role := rbacRole{
+				Ref:       rbacRef(objectKind(obj), objectNamespace(obj), objectName(obj)),
+				Kind:      objectKind(obj),
+				Name:      objectName(obj),
+				Namespace: objectNamespace(obj),
+				Rules:     make([]rbacRule, 0),
+			}
+			for _, r := range getSlice(obj, "rules") {
+				rule, _ := r.(map[string]interface{})
+				role.Rules = append(role.Rules, rbacRule{
+					APIGroups:     stringSlice(getSlice(rule, "apiGroups")),
+					Resources:     stringSlice(getSlice(rule, "resources")),
+					Verbs:         stringSlice(getSlice(rule, "verbs")),
+					ResourceNames: stringSlice(getSlice(rule, "resourceNames")),
+				})
+			}
+			roles[role.Ref] = role
+			model.Roles = append(model.Roles, role)
+		}
+	}
+
+	subjects := map[string]int{}
+	for _, obj := range objects {
+		kind := objectKind(obj)
+		if kind != "RoleBinding" && kind != "ClusterRoleBinding" {
+			continue
+		}
+
+		binding := rbacBinding{
+			Ref:       rbacRef(kind, objectNamespace(obj), objectName(obj)),
+			Kind:      kind,
+			Name:      objectName(obj),
+			Namespace: objectNamespace(obj),
+			RoleRef:   rbacRef(getString(obj, "roleRef", "kind"), objectNamespace(obj), getString(obj, "roleRef", "name")),
+			Subjects:  make([]string, 0),
+		}
+
+		for _, s := range getSlice(obj, "subjects") {
+			subject, _ := s.(map[string]interface{})
+			namespace := getString(subject, "namespace")
+			if namespace == "" && getString(subject, "kind") == "ServiceAccount" {
+				namespace = binding.Namespace
+			}
+			ref := rbacRef(getString(subject, "kind"), namespace, getString(subject, "name"))
+			binding.Subjects = append(binding.Subjects, ref)
+
+			i, ok := subjects[ref]
+			if !ok {
+				i = len(model.Subjects)
+				subjects[ref] = i
+				model.Subjects = append(model.Subjects, rbacSubject{
+					Ref:         ref,
+					Kind:        getString(subject, "kind"),
+					Name:        getString(subject, "name"),
+					Namespace:   namespace,
+					Roles:       make([]string, 0),
+					Permissions: make([]rbacPermission, 0),
+				})
+			}
+
+			model.Subjects[i].Roles = append(model.Subjects[i].Roles, binding.RoleRef)
+			model.Subjects[i].Permissions = append(
+				model.Subjects[i].Permissions,
+				expandPermissions(roles[binding.RoleRef], binding)...,
+			)
+		}
+		model.Bindings = append(model.Bindings, binding)
+	}
+	return model
+}
+
+// rbacRef - Kind/name, or Kind/namespace/name for the namespaced kinds of
+// rbac (Roles, RoleBindings and ServiceAccounts) naming their namespace
+func rbacRef(kind, namespace, name string) string {
+	switch kind {
+	case "Role", "RoleBinding", "ServiceAccount":
+		if namespace != "" {
+			return fmt.Sprintf("%s/%s/%s", kind, namespace, name)
+		}
+	}
+	return fmt.Sprintf("%s/%s", kind, name)
+}
+
+// expandPermissions - flattens a role's rules into one permission per
+// apiGroup/resource/verb, scoped by the binding that grants them
+func expandPermissions(role rbacRole, binding rbacBinding) []rbacPermission {
+	permissions := make([]rbacPermission, 0)
+	for _, rule := range role.Rules {
+		groups := rule.APIGroups
+		if len(groups) == 0 {
+			groups = []string{""}
+		}
+
+		for _, group := range groups {
+			for _, resource := range rule.Resources {
+				for _, verb := range rule.Verbs {
+					permissions = append(permissions, rbacPermission{
+						APIGroup:      group,
+						Resource:      resource,
+						Verb:          verb,
+						ResourceNames: rule.ResourceNames,
+						Namespace:     binding.Namespace,
+						Role:          role.Ref,
+					})
+				}
+			}
+		}
+	}
+	return permissions
+}
+
+// allows - reports whether the given subject (Kind/name or
+// Kind/namespace/name) is granted the verb on every object of the resource,
+// honoring "*" wildcards. The resource may name its api group the way
+// kubectl does, e.g. deployments.apps. Rules scoped to resourceNames only
+// grant the named objects, so they don't count
+func (s rbacModel) allows(subject, verb, resource string) bool {
+	group, grouped := "", false
+	if i := strings.Index(resource, "."); i >= 0 {
+		resource, group, grouped = resource[:i], resource[i+1:], true
+	}
+
+	for _, sub := range s.Subjects {
+		if sub.Ref != subject {
+			continue
+		}
+
+		for _, p := range sub.Permissions {
+			if len(p.ResourceNames) > 0 || grouped && p.APIGroup != "*" && p.APIGroup != group {
+				continue
+			}
+
+			if (p.Verb == "*" || p.Verb == verb) && (p.Resource == "*" || p.Resource == resource) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// rbacAllowsFunction - the declaration of rbac.allows
+var rbacAllowsFunction = &rego.Function{
+	Name: "rbac.allows",
+	Decl: types.NewFunction(types.Args(types.S, types.S, types.S), types.B),
+}
+
+// allowsBuiltin - exposes allows to policies as rbac.allows(subject, verb,
+// resource), over whichever model s holds when a policy calls it, so
+// policies prepared once answer for the chart of the current run
+func (s *rbacModel) allowsBuiltin() func(*rego.Rego) {
+	return rego.Function3(
+		rbacAllowsFunction,
+		func(_ rego.BuiltinContext, subject, verb, resource *ast.Term) (*ast.Term, error) {
+			var args [3]string
+			for i, term := range []*ast.Term{subject, verb, resource} {
+				str, ok := term.Value.(ast.String)
+				if !ok {
+					return nil, fmt.Errorf("rbac.allows: expected string arguments, got %v", term)
+				}
+				args[i] = string(str)
+			}
+			return ast.BooleanTerm(s.allows(args[0], args[1], args[2])), nil
+		},
+	)
+}
+
+func stringSlice(in []interface{}) []string {
+	out := make([]string, 0, len(in))
+	for _, v := range in {
+		if s, ok := v.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
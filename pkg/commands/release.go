@@ -0,0 +1,74 @@
+package commands
+
+import (
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"k8s.io/helm/pkg/chartutil"
+)
+
+const releaseHashName = "release"
+
+// defaultReleaseName - the name charts are released under unless
+// --release-name is given
+const defaultReleaseName = "hcunit-name"
+
+// releaseOptions - the release the templates are rendered for, as
+// .Release and input.release
+type releaseOptions struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	IsUpgrade bool   `json:"isUpgrade"`
+	IsInstall bool   `json:"isInstall"`
+	Revision  int    `json:"revision"`
+}
+
+// newReleaseOptions - the release of the given name and namespace, an
+// install (revision 1) unless upgrade is set (revision 2, the first one an
+// upgrade can be), with hcunit's defaults for what isn't given
+func newReleaseOptions(name, namespace string, upgrade bool) releaseOptions {
+	if name == "" {
+		name = defaultReleaseName
+	}
+
+	if namespace == "" {
+		namespace = releaseNamespace
+	}
+	revision := 1
+	if upgrade {
+		revision = 2
+	}
+	return releaseOptions{Name: name, Namespace: namespace, IsUpgrade: upgrade, IsInstall: !upgrade, Revision: revision}
+}
+
+// values - the release as the .Release of the templates
+func (r releaseOptions) values() map[string]interface{} {
+	return map[string]interface{}{
+		"Name":      r.Name,
+		"Time":      new(timestamp.Timestamp),
+		"Namespace": r.Namespace,
+		"IsUpgrade": r.IsUpgrade,
+		"IsInstall": r.IsInstall,
+		"Revision":  r.Revision,
+		"Service":   "Tiller",
+	}
+}
+
+// chartutil - the release as the options of chartutil.ToRenderValuesCaps
+func (r releaseOptions) chartutil() chartutil.ReleaseOptions {
+	return chartutil.ReleaseOptions{
+		Name:      r.Name,
+		Time:      new(timestamp.Timestamp),
+		Namespace: r.Namespace,
+		IsInstall: r.IsInstall,
+		IsUpgrade: r.IsUpgrade,
+		Revision:  r.Revision,
+	}
+}
+
+// inputNamespace - the release namespace of a policy input, for objects
+// rendered without a namespace of their own
+func inputNamespace(input map[string]interface{}) string {
+	if release, ok := input[releaseHashName].(releaseOptions); ok {
+		return release.Namespace
+	}
+	return releaseNamespace
+}
@@ -0,0 +1,80 @@
+package commands_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestEvalReleaseOptions(t *testing.T) {
+	t.Run("the release and capabilities flow into the templates and input.release", func(t *testing.T) {
+		stdOut := new(bytes.Buffer)
+		evalCmd := &commands.EvalCommand{
+			Stdout:           stdOut,
+			Template:         "testdata/release",
+			Policy:           []string{"testdata/policy/individuals/release_in_input.rego"},
+			ReleaseName:      "checkout",
+			ReleaseNamespace: "payments",
+			IsUpgrade:        true,
+			APIVersions:      []string{"monitoring.coreos.com/v1"},
+		}
+		if err := evalCmd.Execute([]string{}); err != nil {
+			t.Fatalf("expected no error, got:\n%v\n%s", err, stdOut)
+		}
+
+		if !strings.Contains(stdOut.String(), `expect["services of the payments namespace are named after the release"] on Service/checkout`) {
+			t.Errorf("expected the rule to apply to the service of the release namespace, got:\n%s", stdOut)
+		}
+	})
+
+	t.Run("installs into the default release leave out what they branch away", func(t *testing.T) {
+		evalCmd := &commands.EvalCommand{
+			Stdout:   new(bytes.Buffer),
+			Template: "testdata/release",
+			Policy:   []string{"testdata/policy/individuals/release_in_input.rego"},
+		}
+		if err := evalCmd.Execute([]string{}); err == nil {
+			t.Error("expected the release rules to fail")
+		}
+	})
+
+	t.Run("repro commands render the same release", func(t *testing.T) {
+		stdOut := new(bytes.Buffer)
+		evalCmd := &commands.EvalCommand{
+			Stdout:           stdOut,
+			Template:         "testdata/release",
+			Policy:           []string{"testdata/policy/individuals/release_in_input.rego"},
+			ReleaseName:      "checkout",
+			ReleaseNamespace: "staging",
+			APIVersions:      []string{"monitoring.coreos.com/v1"},
+		}
+		evalCmd.Execute([]string{})
+		if !strings.Contains(stdOut.String(), "--api-versions monitoring.coreos.com/v1 --release-name checkout --release-namespace staging --run") {
+			t.Errorf("expected the release flags in the repro command, got:\n%s", stdOut)
+		}
+	})
+}
+
+func TestRenderReleaseOptions(t *testing.T) {
+	out := new(bytes.Buffer)
+	renderCmd := &commands.RenderCommand{
+		Writer:           out,
+		Template:         "testdata/release",
+		ReleaseName:      "checkout",
+		ReleaseNamespace: "payments",
+		IsUpgrade:        true,
+		KubeVersion:      "1.29",
+		APIVersions:      []string{"monitoring.coreos.com/v1"},
+	}
+	if err := renderCmd.Execute([]string{}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{"name: checkout-migrate", "namespace: payments", `kube: "v1.29.0"`} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("expected %q in:\n%s", expected, out)
+		}
+	}
+}
@@ -4,31 +4,68 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"path/filepath"
 )
 
 type RenderCommand struct {
 	Writer   io.Writer
 	Template string   `short:"t" long:"template" description:"path to yaml template you would like to render"`
 	Values   []string `short:"c" long:"values" description:"path to values file(s) you would like to use for rendering"`
+
+	IncludeTemplates []string `long:"include-template" description:"only render templates matching this glob (repeatable)"`
+	ExcludeTemplates []string `long:"exclude-template" description:"skip templates matching this glob (repeatable)"`
+	Fixtures         []string `long:"fixture" description:"path to caller templates rendered with the chart's named templates, e.g. to evaluate a library chart (repeatable)"`
+	Defines          []string `long:"define" description:"render only this named template (from a define block) with the given values instead of the chart (repeatable)"`
+	Golden           string   `long:"golden" description:"directory of <template or define name>.golden files the rendered output must match"`
+	UpdateGolden     bool     `long:"update-golden" description:"write the rendered output to the --golden directory instead of comparing against it"`
+	ShowSecrets      bool     `long:"show-secrets" description:"print the values of rendered Secrets instead of redacting them"`
+	KubeVersion      string   `long:"kube-version" description:"kubernetes version to render the chart's capabilities with, e.g. 1.29"`
+	APIVersions      []string `long:"api-versions" description:"api version the cluster serves besides v1, as .Capabilities.APIVersions, e.g. monitoring.coreos.com/v1 (repeatable)"`
+	ReleaseName      string   `long:"release-name" description:"name of the release the templates are rendered for, as .Release.Name (default: hcunit-name)"`
+	ReleaseNamespace string   `long:"release-namespace" description:"namespace of the release the templates are rendered for, as .Release.Namespace (default: hcunit-namespace)"`
+	IsUpgrade        bool     `long:"is-upgrade" description:"render the templates for an upgrade of the release instead of an install (.Release.IsUpgrade)"`
+	TplValues        bool     `long:"tpl-values" description:"render go template expressions in string values with the release and chart context first, the way helmfile does"`
+	SecondPass       []string `long:"second-pass" description:"render the output of templates matching this glob again with the same values and context, for output templated once more at install time, e.g. by tpl (repeatable)"`
 }
 
 func (s *RenderCommand) Execute(args []string) error {
 	s.setDefaults()
+	templatePath, err := resolveTemplatePath(s.Template)
+	if err != nil {
+		return err
+	}
+	s.Template = templatePath
+
 	valuesConfig, err := mergeValues(s.Values)
 	if err != nil {
 		return fmt.Errorf("failed merging values files %w ", err)
 	}
 
-	renderedOutput, err := validateAndRender(s.Template, valuesConfig)
+	renderedOutput, err := validateAndRender(s.Template, valuesConfig, s.renderOptions())
 	if err != nil {
-		return fmt.Errorf("error while rendering: %w", err)
+		return &RenderError{Template: s.Template, Err: err}
+	}
+
+	templates, defines := splitDefines(renderedOutput)
+	outputs := make(map[string]string)
+	for filename, renderedFile := range templates {
+		outputs[documentName(filename)] = renderedFile
 	}
 
-	for filename, renderedFile := range renderedOutput {
-		fmt.Fprintf(s.Writer, "---\n#%s\n%v\n\n", filepath.Base(filename), renderedFile)
+	for name, renderedDefine := range defines {
+		outputs[name] = renderedDefine
 	}
 
+	for name, renderedFile := range outputs {
+		if !s.ShowSecrets {
+			renderedFile = redactSecretDocuments(renderedFile)
+			outputs[name] = renderedFile
+		}
+		fmt.Fprintf(s.Writer, "---\n#%s\n%v\n\n", name, renderedFile)
+	}
+
+	if s.Golden != "" {
+		return checkGolden(s.Golden, outputs, s.UpdateGolden)
+	}
 	return nil
 }
 
@@ -37,3 +74,16 @@ func (s *RenderCommand) setDefaults() {
 		s.Writer = os.Stdout
 	}
 }
+
+func (s *RenderCommand) renderOptions() renderOptions {
+	return renderOptions{
+		Filter:      TemplateFilter{Include: s.IncludeTemplates, Exclude: s.ExcludeTemplates},
+		Fixtures:    s.Fixtures,
+		Defines:     s.Defines,
+		KubeVersion: s.KubeVersion,
+		APIVersions: s.APIVersions,
+		TplValues:   s.TplValues,
+		Release:     newReleaseOptions(s.ReleaseName, s.ReleaseNamespace, s.IsUpgrade),
+		SecondPass:  s.SecondPass,
+	}
+}
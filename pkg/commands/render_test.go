@@ -2,6 +2,10 @@ package commands_test
 
 import (
 	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -52,6 +56,191 @@ func TestRenderCommand(t *testing.T) {
 		}
 	})
 
+	t.Run("should hand values to templates typed the way helm parses them", func(t *testing.T) {
+		stdOut := new(bytes.Buffer)
+		renderer := &commands.RenderCommand{
+			Writer:   stdOut,
+			Template: "testdata/valuetypes",
+			Values:   []string{"testdata/valuetypes_values.yml"},
+		}
+		if err := renderer.Execute([]string{}); err != nil {
+			t.Fatalf("should not have errored:\n%v", err)
+		}
+
+		for _, control := range []string{
+			"replicas: 3\n",
+			"replicasKind: float64\n",
+			"big: 1e+06\n",
+			"enabled: true\n",
+			"quoted: 1.0\n",
+			"since: 2019-10-01T00:00:00Z\n",
+			"quotedBool: no\n",
+			"taggedBool: off\n",
+		} {
+			if !strings.Contains(stdOut.String(), control) {
+				t.Errorf("expected %q in:\n%s", control, stdOut.String())
+			}
+		}
+	})
+
+	t.Run("should only render templates matching the template filters", func(t *testing.T) {
+		for _, tt := range []struct {
+			name        string
+			include     []string
+			exclude     []string
+			contains    []string
+			notContains []string
+		}{
+			{"no filters renders everything", nil, nil, []string{"#something.yml", "#something_else.yml", "#NOTES.txt"}, nil},
+			{"include by file name", []string{"something.yml"}, nil, []string{"#something.yml"}, []string{"#something_else.yml", "#NOTES.txt"}},
+			{"exclude a directory", nil, []string{"nested/"}, []string{"#something.yml", "#NOTES.txt"}, []string{"#something_else.yml"}},
+			{"include and exclude together", []string{"*.yml"}, []string{"nested/**"}, []string{"#something.yml"}, []string{"#something_else.yml", "#NOTES.txt"}},
+		} {
+			t.Run(tt.name, func(t *testing.T) {
+				stdOut := new(bytes.Buffer)
+				renderer := &commands.RenderCommand{
+					Writer:           stdOut,
+					Template:         "testdata/templates",
+					Values:           []string{"testdata/values.yml"},
+					IncludeTemplates: tt.include,
+					ExcludeTemplates: tt.exclude,
+				}
+				if err := renderer.Execute([]string{}); err != nil {
+					t.Fatalf("should not have errored:\n%v", err)
+				}
+
+				for _, control := range tt.contains {
+					if !strings.Contains(stdOut.String(), control+"\n") {
+						t.Errorf("expected %s to be rendered, got:\n%s", control, stdOut.String())
+					}
+				}
+
+				for _, control := range tt.notContains {
+					if strings.Contains(stdOut.String(), control+"\n") {
+						t.Errorf("expected %s to be filtered out, got:\n%s", control, stdOut.String())
+					}
+				}
+			})
+		}
+	})
+
+	t.Run("should compare named templates against golden files", func(t *testing.T) {
+		for _, tt := range []struct {
+			name      string
+			golden    string
+			failsWith error
+		}{
+			{"matching golden files", "testdata/golden/mychart", nil},
+			{"drifted golden file", "testdata/golden/drift", commands.GoldenMismatch},
+			{"missing golden files", "testdata/golden/missing", commands.GoldenMismatch},
+		} {
+			t.Run(tt.name, func(t *testing.T) {
+				renderer := &commands.RenderCommand{
+					Writer:   new(bytes.Buffer),
+					Template: "testdata/mychart/templates",
+					Values:   []string{"testdata/mychart/values.yaml"},
+					Defines:  []string{"mychart.labels", "mychart.fullname"},
+					Golden:   tt.golden,
+				}
+				err := renderer.Execute([]string{})
+				if !errors.Is(err, tt.failsWith) {
+					t.Errorf("expected error:\n%v\ngot:\n%v", tt.failsWith, err)
+				}
+			})
+		}
+
+		t.Run("update-golden writes the golden files", func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "hcunit-golden")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			renderer := &commands.RenderCommand{
+				Writer:       new(bytes.Buffer),
+				Template:     "testdata/mychart/templates",
+				Values:       []string{"testdata/mychart/values.yaml"},
+				Defines:      []string{"mychart.fullname"},
+				Golden:       dir,
+				UpdateGolden: true,
+			}
+			if err := renderer.Execute([]string{}); err != nil {
+				t.Fatalf("should not have errored:\n%v", err)
+			}
+
+			b, err := ioutil.ReadFile(filepath.Join(dir, "mychart.fullname.golden"))
+			if err != nil || string(b) != "hcunit-name-hcunit" {
+				t.Errorf("expected the golden file to be written, got %q (%v)", b, err)
+			}
+		})
+	})
+
+	t.Run("should redact rendered secrets unless asked not to", func(t *testing.T) {
+		for _, tt := range []struct {
+			name        string
+			showSecrets bool
+			contains    []string
+			notContains []string
+		}{
+			{"redacted by default", false, []string{"password: <redacted>", "token: <redacted>", "user: admin"}, []string{"aHVudGVyMi1zM2NyM3Q=", "tok-0123456789abcdef"}},
+			{"shown with show-secrets", true, []string{"aHVudGVyMi1zM2NyM3Q=", "tok-0123456789abcdef"}, []string{"<redacted>"}},
+		} {
+			t.Run(tt.name, func(t *testing.T) {
+				stdOut := new(bytes.Buffer)
+				renderer := &commands.RenderCommand{
+					Writer:      stdOut,
+					Template:    "testdata/secrets",
+					Values:      []string{"testdata/secrets_values.yaml"},
+					ShowSecrets: tt.showSecrets,
+				}
+				if err := renderer.Execute([]string{}); err != nil {
+					t.Fatalf("should not have errored:\n%v", err)
+				}
+
+				for _, control := range tt.contains {
+					if !strings.Contains(stdOut.String(), control) {
+						t.Errorf("expected %s in the output, got:\n%s", control, stdOut.String())
+					}
+				}
+
+				for _, control := range tt.notContains {
+					if strings.Contains(stdOut.String(), control) {
+						t.Errorf("expected %s not to be in the output, got:\n%s", control, stdOut.String())
+					}
+				}
+			})
+		}
+	})
+
+	t.Run("should report every values file problem at once", func(t *testing.T) {
+		renderer := &commands.RenderCommand{
+			Writer:   new(bytes.Buffer),
+			Template: "testdata/templates",
+			Values: []string{
+				"testdata/values.yml",
+				"testdata/missing_values.yml",
+				"testdata/broken_values.yml",
+				"testdata/conflicting_values.yml",
+			},
+		}
+		err := renderer.Execute([]string{})
+		if !errors.Is(err, commands.ValuesMergeFailure) {
+			t.Fatalf("expected error:\n%v\ngot:\n%v", commands.ValuesMergeFailure, err)
+		}
+
+		for _, control := range []string{
+			"4 problem(s)",
+			"missing_values.yml: no such file",
+			"failed to parse testdata/broken_values.yml: yaml: line",
+			"conflicting_values.yml: HttpPort is a map but was a scalar",
+			"conflicting_values.yml: uiIngress is a scalar but was a map",
+		} {
+			if !strings.Contains(err.Error(), control) {
+				t.Errorf("expected %q in the error, got:\n%v", control, err)
+			}
+		}
+	})
+
 	t.Run("should validate template & values paths", func(t *testing.T) {
 		for _, tt := range []struct {
 			name        string
@@ -98,6 +287,16 @@ func TestRenderCommand(t *testing.T) {
 				render:      &commands.RenderCommand{Template: "testdata/templates", Values: []string{"testdata/values.yml"}},
 				shouldError: false,
 			},
+			{
+				name:        "chart root resolves to its templates dir",
+				render:      &commands.RenderCommand{Template: "testdata/mychart", Values: []string{"testdata/mychart/values.yaml"}},
+				shouldError: false,
+			},
+			{
+				name:        "template dir without templates",
+				render:      &commands.RenderCommand{Template: "testdata/emptytemplates", Values: []string{"testdata/values.yml"}},
+				shouldError: true,
+			},
 		} {
 			t.Run(tt.name, func(t *testing.T) {
 				err := tt.render.Execute([]string{})
@@ -0,0 +1,100 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/engine"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+)
+
+const (
+	secondPassPrefix     = "hcunit-second-pass/"
+	valuesTemplateSuffix = ".gotmpl"
+)
+
+// secondPass - renders the output of the templates whose input name
+// matches one of the globs once more, with the context of the first pass,
+// the way a tpl of the rendered file at install time would. The partials
+// of the chart can be included by the rendered output
+func secondPass(rendered map[string]string, globs []string, partials []*chart.Template, context chartutil.Values) (map[string]string, error) {
+	matchers, err := compileGlobs(globs)
+	if err != nil {
+		return nil, err
+	}
+
+	templates := append([]*chart.Template{}, partials...)
+	names := make(map[string]string)
+	for name, content := range rendered {
+		if !matchAny(matchers, []string{documentName(name)}) {
+			continue
+		}
+
+		passName := secondPassPrefix + documentName(name)
+		templates = append(templates, &chart.Template{Name: passName, Data: []byte(content)})
+		names[passName] = name
+	}
+
+	if len(names) == 0 {
+		return rendered, nil
+	}
+
+	metadata, _ := context["Chart"].(*chart.Metadata)
+	passChart := &chart.Chart{Metadata: metadata, Templates: templates}
+	passed, err := engine.New().Render(passChart, context)
+	if err != nil {
+		return nil, fmt.Errorf("second render pass failed: %w", err)
+	}
+
+	out := make(map[string]string, len(rendered))
+	for name, content := range rendered {
+		out[name] = content
+	}
+
+	for passName, name := range names {
+		out[name] = passed[path.Join(metadata.GetName(), passName)]
+	}
+	return out, nil
+}
+
+// renderValuesTemplate - renders a values file ending in .gotmpl as a go
+// template before it is parsed, the way helmfile does: with the sprig and
+// helm functions plus env and requiredEnv, and the values merged from the
+// files given before it as .Values
+func renderValuesTemplate(filePath string, data []byte, values map[string]interface{}) ([]byte, error) {
+	e := engine.New()
+	e.FuncMap["env"] = os.Getenv
+	e.FuncMap["requiredEnv"] = func(name string) (string, error) {
+		if value := os.Getenv(name); value != "" {
+			return value, nil
+		}
+		return "", fmt.Errorf("required environment variable %s is not set", name)
+	}
+
+	metadata := &chart.Metadata{Name: "hcunit"}
+	valuesChart := &chart.Chart{
+		Metadata:  metadata,
+		Templates: []*chart.Template{{Name: "values" + valuesTemplateSuffix, Data: data}},
+	}
+
+	rendered, err := e.Render(valuesChart, chartutil.Values{
+		"Chart":  metadata,
+		"Values": chartutil.Values(helmValues(values).(map[string]interface{})),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render %s: %w", filePath, err)
+	}
+
+	for _, content := range rendered {
+		return []byte(content), nil
+	}
+	return nil, nil
+}
+
+// isValuesTemplate - whether a values file is rendered before it is parsed
+func isValuesTemplate(filePath string) bool {
+	return strings.HasSuffix(filePath, valuesTemplateSuffix)
+}
@@ -0,0 +1,86 @@
+package commands_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestRenderSecondPass(t *testing.T) {
+	defer os.Unsetenv("HCUNIT_TEST_TEAM")
+	for _, tt := range []struct {
+		name       string
+		values     []string
+		env        string
+		secondPass []string
+		expected   []string
+		failsWith  error
+	}{
+		{
+			name:   "rendered template expressions are kept as is without --second-pass",
+			values: []string{"testdata/secondpass/values.yaml"},
+			expected: []string{
+				"owner: {{ .Values.team }}",
+				"legend: {{ .Labels.pod }}",
+			},
+		},
+		{
+			name:       "the output of matching templates is rendered again with the same context",
+			values:     []string{"testdata/secondpass/values.yaml"},
+			secondPass: []string{"alerts.yaml"},
+			expected: []string{
+				"owner: payments",
+				"summary: hcunit-name alerts for payments",
+				// templates not matching are left to whatever renders them later
+				"legend: {{ .Labels.pod }}",
+			},
+		},
+		{
+			name:   ".gotmpl values files are rendered with the values given before them",
+			values: []string{"testdata/secondpass/values.yaml", "testdata/secondpass/values.yaml.gotmpl"},
+			expected: []string{
+				`replicas: "3"`,
+			},
+			secondPass: []string{"alerts.yaml"},
+		},
+		{
+			name:       ".gotmpl values files can read the environment",
+			values:     []string{"testdata/secondpass/values.yaml", "testdata/secondpass/values.yaml.gotmpl"},
+			env:        "platform",
+			secondPass: []string{"alerts.yaml"},
+			expected: []string{
+				"owner: platform",
+			},
+		},
+		{
+			name:      "requiredEnv fails on unset environment variables",
+			values:    []string{"testdata/secondpass/required.yaml.gotmpl"},
+			failsWith: commands.ValuesMergeFailure,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Setenv("HCUNIT_TEST_TEAM", tt.env)
+			stdOut := new(bytes.Buffer)
+			renderCmd := &commands.RenderCommand{
+				Writer:     stdOut,
+				Template:   "testdata/secondpass/templates",
+				Values:     tt.values,
+				SecondPass: tt.secondPass,
+			}
+			err := renderCmd.Execute([]string{})
+			if tt.failsWith == nil && err != nil || !errors.Is(err, tt.failsWith) {
+				t.Fatalf("expected error:\n%v\ngot:\n%v", tt.failsWith, err)
+			}
+
+			for _, line := range tt.expected {
+				if !strings.Contains(stdOut.String(), line) {
+					t.Errorf("expected the output to contain %q, got:\n%s", line, stdOut.String())
+				}
+			}
+		})
+	}
+}
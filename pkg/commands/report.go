@@ -0,0 +1,106 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+)
+
+// reportTokenEnv - a bearer token sent with --report-url submissions, kept
+// out of the flags so it never ends up in shell history or provenance
+const reportTokenEnv = "HCUNIT_REPORT_TOKEN"
+
+var InvalidReportHeader = errors.New("--report-header must be given as 'Name: value'")
+
+// runReport - the results of this run and what it was produced from
+func (s *EvalCommand) runReport(runErr error) Results {
+	return s.reportOf(s.results, runErr)
+}
+
+// reportOf - a report of the given results of this run
+func (s *EvalCommand) reportOf(results []RuleResult, runErr error) Results {
+	report := Results{
+		Schema:        ResultsSchemaURL,
+		SchemaVersion: ResultsSchemaVersion,
+		Timestamp:     newTimestamp(time.Now(), s.TimestampFormat),
+		DurationMs:    milliseconds(time.Since(s.started)),
+		Provenance:    s.provenance(),
+		Summary:       summarizeResults(results),
+		Results:       ruleReports(results),
+		ExitCode:      ExitCode(runErr),
+	}
+
+	if runErr != nil {
+		report.Error = runErr.Error()
+	}
+	return report
+}
+
+// writeResults - writes the results of this run to --results-file, e.g. to
+// compare a later run against with `hcunit compare` or --compare-to
+func (s *EvalCommand) writeResults(runErr error) error {
+	b, err := json.MarshalIndent(s.runReport(runErr), "", "  ")
+	if err != nil {
+		return fmt.Errorf("couldnt marshal results: %w", err)
+	}
+	return ioutil.WriteFile(s.ResultsFile, append(b, '\n'), 0644)
+}
+
+// loadRunReport - reads results written by --results-file
+func loadRunReport(path string) (Results, error) {
+	report := Results{}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return report, fmt.Errorf("reading results %s failed: %w", path, err)
+	}
+
+	if err := json.Unmarshal(b, &report); err != nil {
+		return report, fmt.Errorf("failed to parse results %s: %w", path, err)
+	}
+	return report, nil
+}
+
+// reportHeaders - the --report-header flags, plus an Authorization header
+// when HCUNIT_REPORT_TOKEN is set
+func (s *EvalCommand) reportHeaders() (map[string]string, error) {
+	headers := map[string]string{"Content-Type": "application/json"}
+	if token := os.Getenv(reportTokenEnv); token != "" {
+		headers["Authorization"] = "Bearer " + token
+	}
+
+	for _, header := range s.ReportHeaders {
+		parts := strings.SplitN(header, ":", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, InvalidReportHeader
+		}
+		headers[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return headers, nil
+}
+
+// submitReport - posts the results of this run to --report-url, retrying
+// like every other remote operation, by the fetch options of the config
+func (s *EvalCommand) submitReport(runErr error) error {
+	if s.Offline {
+		return offlineError("report submission to " + s.ReportURL)
+	}
+
+	headers, err := s.reportHeaders()
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(s.runReport(runErr))
+	if err != nil {
+		return fmt.Errorf("couldnt marshal report: %w", err)
+	}
+
+	if err := s.config.Fetch.withDefaults().httpPost(s.ReportURL, b, headers); err != nil {
+		return fmt.Errorf("submitting report failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package commands_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestEvalReportURL(t *testing.T) {
+	attempts := 0
+	var received *http.Request
+	var report commands.RunReport
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if r.URL.Path == "/flaky" && attempts < 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		if r.URL.Path == "/unavailable" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		if r.URL.Path == "/forbidden" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		received = r
+		report = commands.RunReport{}
+		if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+			t.Errorf("expected a json report: %v", err)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	os.Setenv("HCUNIT_REPORT_TOKEN", "s3cr3t")
+	defer os.Unsetenv("HCUNIT_REPORT_TOKEN")
+
+	for _, tt := range []struct {
+		name      string
+		path      string
+		policy    string
+		headers   []string
+		fails     bool
+		failsWith error
+		submitted bool
+	}{
+		{
+			name:      "failing results are submitted with the auth headers",
+			path:      "/flaky",
+			policy:    "testdata/policy/failing",
+			headers:   []string{"X-Repo: hcunit"},
+			fails:     true,
+			failsWith: commands.PolicyFailure,
+			submitted: true,
+		},
+		{
+			name:   "a rejected submission fails a passing run",
+			path:   "/forbidden",
+			policy: "testdata/policy/passing",
+			fails:  true,
+		},
+		{
+			name:      "headers must be name value pairs",
+			path:      "/",
+			policy:    "testdata/policy/passing",
+			headers:   []string{"X-Repo"},
+			fails:     true,
+			failsWith: commands.InvalidReportHeader,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts, received = 0, nil
+			evalCmd := &commands.EvalCommand{
+				Stdout:        ioutil.Discard,
+				Template:      "testdata/templates",
+				Values:        []string{"testdata/values.yml"},
+				Policy:        []string{tt.policy},
+				ReportURL:     server.URL + tt.path,
+				ReportHeaders: tt.headers,
+			}
+			err := evalCmd.Execute([]string{})
+			if (err != nil) != tt.fails || (tt.failsWith != nil && !errors.Is(err, tt.failsWith)) {
+				t.Fatalf("expected error:\n%v\ngot:\n%v", tt.failsWith, err)
+			}
+
+			if (received != nil) != tt.submitted {
+				t.Fatalf("expected a submission: %v, got: %v", tt.submitted, received != nil)
+			}
+
+			if !tt.submitted {
+				return
+			}
+
+			if received.Header.Get("Authorization") != "Bearer s3cr3t" || received.Header.Get("X-Repo") != "hcunit" {
+				t.Errorf("expected the auth headers, got: %v", received.Header)
+			}
+
+			if report.ExitCode != commands.ExitFailure || report.Summary.Failed == 0 || len(report.Results) == 0 {
+				t.Errorf("expected the failing results, got: %+v", report)
+			}
+
+			for _, flag := range report.Provenance.Flags {
+				if strings.Contains(flag, "hcunit") && strings.HasPrefix(flag, "--report-header") {
+					t.Errorf("expected header values to be redacted from the flags, got: %v", report.Provenance.Flags)
+				}
+			}
+		})
+	}
+
+	t.Run("submissions retry by the fetch options of the config", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "hcunit-report")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		configPath := filepath.Join(dir, ".hcunit.yaml")
+		if err := ioutil.WriteFile(configPath, []byte("fetch:\n  retries: 1\n  backoff: 1ms\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		attempts = 0
+		evalCmd := &commands.EvalCommand{
+			Stdout:    ioutil.Discard,
+			Template:  "testdata/templates",
+			Values:    []string{"testdata/values.yml"},
+			Policy:    []string{"testdata/policy/passing"},
+			Config:    configPath,
+			ReportURL: server.URL + "/unavailable",
+		}
+		if err := evalCmd.Execute([]string{}); err == nil {
+			t.Fatal("expected the unavailable report url to fail the run")
+		}
+
+		if attempts != 2 {
+			t.Errorf("expected 2 attempts by the configured retries, got %d", attempts)
+		}
+	})
+}
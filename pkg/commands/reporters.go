@@ -0,0 +1,135 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+const (
+	outputPretty = "pretty"
+	outputTAP    = "tap"
+)
+
+// Reporter - prints the results of a run once it is over, for --output
+// formats consumed by other tools. Runs printing their results through a
+// Reporter print nothing else, so its output can be piped as is
+type Reporter interface {
+	Report(w io.Writer, results Results) error
+}
+
+// ReporterFunc - a func as a Reporter
+type ReporterFunc func(w io.Writer, results Results) error
+
+func (f ReporterFunc) Report(w io.Writer, results Results) error {
+	return f(w, results)
+}
+
+// reporters - the reporters of --output, by format. The text formats are
+// printed while the rules run instead
+var reporters = map[string]Reporter{
+	outputJSON: ReporterFunc(reportJSON),
+	outputTAP:  ReporterFunc(reportTAP),
+}
+
+// outputFormats - the formats --output accepts
+var outputFormats = []string{outputText, outputPretty, outputPlainVerbose, outputJSON, outputTAP}
+
+// checkOutput - whether the output format is one of outputFormats
+func (s *EvalCommand) checkOutput() error {
+	output := s.Output
+	if output == "" && s.config != nil {
+		output = s.config.Defaults.Output
+	}
+
+	for _, format := range outputFormats {
+		if output == "" || output == format {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %q is not one of %s", InvalidOutput, output, strings.Join(outputFormats, ", "))
+}
+
+// reporter - the Reporter of the run: the one set on the command, or the
+// one of its output format, nil for the text formats
+func (s *EvalCommand) reporter() Reporter {
+	if s.Reporter != nil {
+		return s.Reporter
+	}
+	return reporters[s.output()]
+}
+
+// reportJSON - the json results, the same as the ones written to
+// --results-file
+func reportJSON(w io.Writer, results Results) error {
+	b, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return fmt.Errorf("couldnt marshal results: %w", err)
+	}
+
+	_, err = fmt.Fprintln(w, string(b))
+	return err
+}
+
+// tapDiagnostic - the yaml block detailing a rule which didn't pass
+type tapDiagnostic struct {
+	Message   string   `yaml:"message,omitempty"`
+	Severity  string   `yaml:"severity"`
+	Resources []string `yaml:"resources,omitempty"`
+	Subcharts []string `yaml:"subcharts,omitempty"`
+	Owner     string   `yaml:"owner,omitempty"`
+	Team      string   `yaml:"team,omitempty"`
+}
+
+// reportTAP - the results as a TAP version 13 stream, a test point per rule.
+// Failed rules are not ok, fired warn rules and violations of --dryrun are
+// not ok with a TODO directive, so TAP consumers don't fail on them. Runs
+// failing without a failed rule, e.g. on a render error, bail out
+func reportTAP(w io.Writer, results Results) error {
+	b := new(strings.Builder)
+	b.WriteString("TAP version 13\n")
+	fmt.Fprintf(b, "1..%d\n", len(results.Results))
+	failed := false
+	for i, rule := range results.Results {
+		status, directive := "ok", ""
+		switch rule.Result {
+		case "fail":
+			status, failed = "not ok", true
+		case "warn", "dryrun":
+			status, directive = "not ok", " # TODO "+rule.Result
+		}
+		fmt.Fprintf(b, "%s %d - %s%s\n", status, i+1, strings.Replace(rule.Rule, "#", "\\#", -1), directive)
+
+		if status == "ok" {
+			continue
+		}
+
+		diagnostic, err := yaml.Marshal(tapDiagnostic{
+			Message:   rule.Message,
+			Severity:  rule.Result,
+			Resources: rule.Resources,
+			Subcharts: rule.Subcharts,
+			Owner:     rule.Owner,
+			Team:      rule.Team,
+		})
+		if err != nil {
+			return err
+		}
+
+		b.WriteString("  ---\n")
+		for _, line := range strings.SplitAfter(strings.TrimSuffix(string(diagnostic), "\n"), "\n") {
+			b.WriteString("  " + line)
+		}
+		b.WriteString("\n  ...\n")
+	}
+
+	if results.Error != "" && !failed {
+		fmt.Fprintf(b, "Bail out! %s\n", strings.Replace(results.Error, "\n", " ", -1))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
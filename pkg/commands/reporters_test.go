@@ -0,0 +1,121 @@
+package commands_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestEvalOutputFormats(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		output    string
+		policy    string
+		template  string
+		failsWith error
+		expected  []string
+		absent    []string
+	}{
+		{
+			name:      "tap reports failed rules as not ok with their details",
+			output:    "tap",
+			policy:    "testdata/policy/individuals/described.rego",
+			template:  "testdata/templates",
+			failsWith: commands.PolicyFailure,
+			expected: []string{
+				"TAP version 13\n1..2\n",
+				"not ok 1 - data.main.expect[\"ingress has tls\"]\n  ---\n  message: ingresses must terminate tls\n  severity: fail\n  resources:\n    - something.yml\n  team: networking\n  ...\n",
+				"ok 2 - data.main.expect[\"ingress is rendered\"]\n",
+			},
+			absent: []string{"FAIL", "REPRODUCE", "Bail out!"},
+		},
+		{
+			name:     "tap reports warnings as todo",
+			output:   "tap",
+			policy:   "testdata/policy/individuals/warn_ingress.rego",
+			template: "testdata/templates",
+			expected: []string{"1..1\nnot ok 1 - data.main.warn[\"ingress without tls\"] # TODO warn\n"},
+		},
+		{
+			name:      "tap bails out of runs failing without a failed rule",
+			output:    "tap",
+			policy:    "testdata/policy/passing/passing.rego",
+			template:  "testdata/missing",
+			failsWith: commands.TemplatePathNotFound,
+			expected:  []string{"1..0\nBail out! "},
+		},
+		{
+			name:     "pretty is the text output",
+			output:   "pretty",
+			policy:   "testdata/policy/passing/passing.rego",
+			template: "testdata/templates/something.yml",
+			expected: []string{"PASS: \x1b[0mdata.main.expect"},
+			absent:   []string{"TAP version", "\"results\""},
+		},
+		{
+			name:      "unknown formats are rejected",
+			output:    "junit",
+			policy:    "testdata/policy/passing/passing.rego",
+			template:  "testdata/templates/something.yml",
+			failsWith: commands.InvalidOutput,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			stdOut := new(bytes.Buffer)
+			evalCmd := &commands.EvalCommand{
+				Stdout:   stdOut,
+				Template: tt.template,
+				Values:   []string{"testdata/values.yml"},
+				Policy:   []string{tt.policy},
+				Output:   tt.output,
+			}
+			err := evalCmd.Execute([]string{})
+			if tt.failsWith == nil && err != nil || !errors.Is(err, tt.failsWith) {
+				t.Fatalf("expected error:\n%v\ngot:\n%v\n%s", tt.failsWith, err, stdOut)
+			}
+
+			for _, expected := range tt.expected {
+				if !strings.Contains(stdOut.String(), expected) {
+					t.Errorf("expected %q in:\n%s", expected, stdOut)
+				}
+			}
+
+			for _, absent := range tt.absent {
+				if strings.Contains(stdOut.String(), absent) {
+					t.Errorf("expected no %q in:\n%s", absent, stdOut)
+				}
+			}
+		})
+	}
+}
+
+func TestEvalCustomReporter(t *testing.T) {
+	stdOut := new(bytes.Buffer)
+	reported := commands.Results{}
+	evalCmd := &commands.EvalCommand{
+		Stdout:   stdOut,
+		Template: "testdata/templates/something.yml",
+		Values:   []string{"testdata/values.yml"},
+		Policy:   []string{"testdata/policy/failing/failing.rego"},
+		Reporter: commands.ReporterFunc(func(w io.Writer, results commands.Results) error {
+			reported = results
+			_, err := io.WriteString(w, "reported\n")
+			return err
+		}),
+	}
+	if err := evalCmd.Execute([]string{}); !errors.Is(err, commands.PolicyFailure) {
+		t.Fatalf("expected error:\n%v\ngot:\n%v", commands.PolicyFailure, err)
+	}
+
+	if stdOut.String() != "reported\n" {
+		t.Errorf("expected only the reporter's output, got:\n%s", stdOut)
+	}
+
+	if reported.ExitCode != commands.ExitFailure || reported.Summary.Failed == 0 {
+		t.Errorf("expected the failed results to be reported, got: %+v", reported)
+	}
+}
@@ -0,0 +1,112 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/mitchellh/colorstring"
+)
+
+var InvalidRunFilter = errors.New("--run is not a valid regular expression")
+
+var shellSafe = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// reproCommand - the hcunit eval invocation reproducing just the given
+// rule, for the given kubernetes version when evaluating a version matrix or
+// the one of --kube-version
+func (s *EvalCommand) reproCommand(kubeVersion, rule string) string {
+	args := []string{"hcunit", "eval", "-t", s.Template}
+	switch {
+	case s.Adapter != "":
+		args = []string{"hcunit", "eval", "--adapter", s.Adapter}
+	case s.Manifests != "":
+		args = []string{"hcunit", "eval", "--manifests", s.Manifests}
+	case s.Chart != "":
+		args = []string{"hcunit", "eval", "--chart", s.Chart}
+	}
+	for _, values := range s.Values {
+		args = append(args, "-c", values)
+	}
+
+	for _, repeated := range []struct {
+		flag   string
+		values []string
+	}{
+		{"--set", s.Set},
+		{"--set-string", s.SetString},
+		{"--set-file", s.SetFile},
+	} {
+		for _, value := range repeated.values {
+			args = append(args, repeated.flag, value)
+		}
+	}
+
+	for _, policy := range s.Policy {
+		args = append(args, "-p", policy)
+	}
+	args = append(args, "-n", s.Namespace)
+
+	for _, repeated := range []struct {
+		flag   string
+		values []string
+	}{
+		{"--include-template", s.IncludeTemplates},
+		{"--exclude-template", s.ExcludeTemplates},
+		{"--fixture", s.Fixtures},
+		{"--define", s.Defines},
+		{"--api-versions", s.APIVersions},
+	} {
+		for _, value := range repeated.values {
+			args = append(args, repeated.flag, value)
+		}
+	}
+
+	if s.IncludeTests {
+		args = append(args, "--include-tests")
+	}
+
+	if s.ReleaseName != "" {
+		args = append(args, "--release-name", s.ReleaseName)
+	}
+
+	if s.ReleaseNamespace != "" {
+		args = append(args, "--release-namespace", s.ReleaseNamespace)
+	}
+
+	if s.IsUpgrade {
+		args = append(args, "--is-upgrade")
+	}
+
+	switch {
+	case s.KubeVersion != "":
+		args = append(args, "--kube-version", s.KubeVersion)
+	case kubeVersion != "":
+		args = append(args, "--kube-versions", kubeVersion)
+	}
+	args = append(args, "--run", "^"+regexp.QuoteMeta(rule)+"$", "-v")
+
+	quoted := make([]string, 0, len(args))
+	for _, arg := range args {
+		quoted = append(quoted, shellQuote(arg))
+	}
+	return strings.Join(quoted, " ")
+}
+
+func shellQuote(arg string) string {
+	if shellSafe.MatchString(arg) {
+		return arg
+	}
+	return "'" + strings.Replace(arg, "'", `'"'"'`, -1) + "'"
+}
+
+// printReproCommands - prints a copy-pasteable command reproducing each
+// failed rule on its own
+func printReproCommands(writer io.Writer, violation *ViolationError, repro func(string) string, messages messageCatalog) {
+	for _, rule := range violation.Failed {
+		colorstring.Fprint(writer, "[yellow]"+messages.text("reproduce")+": ")
+		fmt.Fprintln(writer, repro(rule))
+	}
+}
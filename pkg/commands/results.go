@@ -0,0 +1,81 @@
+package commands
+
+import "time"
+
+// ResultsSchemaVersion - the version of the json encoding of Results. Fields
+// are only ever added to it; renaming or removing one, or changing what it
+// means, bumps the version
+const ResultsSchemaVersion = 1
+
+// ResultsSchemaURL - the published json schema of Results, also found as
+// results.schema.json at the root of the repository
+const ResultsSchemaURL = "https://raw.githubusercontent.com/xchapter7x/hcunit/master/results.schema.json"
+
+// Results - the results of a run as written to --results-file, submitted to
+// --report-url and stored in --history. Its json encoding is versioned by
+// ResultsSchemaVersion and described by the schema at ResultsSchemaURL
+type Results struct {
+	Schema        string       `json:"$schema"`
+	SchemaVersion int          `json:"schemaVersion"`
+	Timestamp     Timestamp    `json:"timestamp"`
+	DurationMs    float64      `json:"durationMs"`
+	Provenance    Provenance   `json:"provenance"`
+	Summary       AuditSummary `json:"summary"`
+	Results       []RuleReport `json:"results"`
+	ExitCode      int          `json:"exitCode"`
+	Error         string       `json:"error,omitempty"`
+}
+
+// RunReport - the name Results had before its encoding was versioned, kept
+// for programs using it
+type RunReport = Results
+
+// RuleReport - the outcome of a single rule: pass, fail, warn or dryrun,
+// with the description of the rule as its message and, when it failed, the
+// rendered templates (resources) it referenced and the subcharts of an
+// umbrella chart rendering them
+type RuleReport struct {
+	Rule        string   `json:"rule"`
+	Result      string   `json:"result"`
+	Message     string   `json:"message,omitempty"`
+	Resources   []string `json:"resources,omitempty"`
+	Subcharts   []string `json:"subcharts,omitempty"`
+	DurationMs  float64  `json:"durationMs"`
+	Owner       string   `json:"owner,omitempty"`
+	Team        string   `json:"team,omitempty"`
+	KubeVersion string   `json:"kubeVersion,omitempty"`
+	ValuesSet   string   `json:"valuesSet,omitempty"`
+}
+
+func ruleReports(results []RuleResult) []RuleReport {
+	reports := make([]RuleReport, 0, len(results))
+	for _, result := range results {
+		outcome := "pass"
+		switch {
+		case result.Warning:
+			outcome = "warn"
+		case result.DryRun:
+			outcome = "dryrun"
+		case !result.Passed:
+			outcome = "fail"
+		}
+		reports = append(reports, RuleReport{
+			Rule:        result.Name,
+			Result:      outcome,
+			Message:     metadataString(result.Metadata, metadataDescription),
+			Resources:   result.Documents,
+			Subcharts:   subchartsOf(result.Documents),
+			DurationMs:  milliseconds(result.Duration),
+			Owner:       metadataString(result.Metadata, metadataOwner),
+			Team:        metadataString(result.Metadata, metadataTeam),
+			KubeVersion: result.KubeVersion,
+			ValuesSet:   result.ValuesSet,
+		})
+	}
+	return reports
+}
+
+// milliseconds - a duration as fractional milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,165 @@
+package commands_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+// checkSchema - the ways a decoded json value breaks the subset of json
+// schema results.schema.json is written in, or fields it doesn't declare
+func checkSchema(path string, schema map[string]interface{}, value interface{}) []string {
+	problems := make([]string, 0)
+	if !schemaTypeMatches(schema["type"], value) {
+		return append(problems, fmt.Sprintf("%s: %v is not of type %v", path, value, schema["type"]))
+	}
+
+	if expected, ok := schema["const"]; ok && expected != value {
+		problems = append(problems, fmt.Sprintf("%s: expected %v, got %v", path, expected, value))
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, allowed := range enum {
+			found = found || allowed == value
+		}
+		if !found {
+			problems = append(problems, fmt.Sprintf("%s: %v is not one of %v", path, value, enum))
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		required, _ := schema["required"].([]interface{})
+		for _, key := range required {
+			if _, ok := v[key.(string)]; !ok {
+				problems = append(problems, fmt.Sprintf("%s: missing required %s", path, key))
+			}
+		}
+
+		for key, field := range v {
+			property, ok := properties[key].(map[string]interface{})
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%s: %s is not declared in the schema", path, key))
+				continue
+			}
+			problems = append(problems, checkSchema(path+"."+key, property, field)...)
+		}
+	case []interface{}:
+		items, _ := schema["items"].(map[string]interface{})
+		for i, item := range v {
+			problems = append(problems, checkSchema(fmt.Sprintf("%s[%d]", path, i), items, item)...)
+		}
+	}
+	return problems
+}
+
+func schemaTypeMatches(types interface{}, value interface{}) bool {
+	names, ok := types.([]interface{})
+	if !ok {
+		names = []interface{}{types}
+	}
+
+	for _, name := range names {
+		switch v := value.(type) {
+		case nil:
+			ok = name == "null"
+		case bool:
+			ok = name == "boolean"
+		case float64:
+			ok = name == "number" || name == "integer" && v == float64(int64(v))
+		case string:
+			ok = name == "string"
+		case []interface{}:
+			ok = name == "array"
+		case map[string]interface{}:
+			ok = name == "object"
+		}
+
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+func TestEvalResultsSchema(t *testing.T) {
+	b, err := ioutil.ReadFile("../../results.schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schema := make(map[string]interface{})
+	if err := json.Unmarshal(b, &schema); err != nil {
+		t.Fatalf("expected the published schema to be json: %v", err)
+	}
+
+	dir, err := ioutil.TempDir("", "hcunit-results-schema")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	resultsPath := filepath.Join(dir, "results.json")
+	evalCmd := &commands.EvalCommand{
+		Stdout:      ioutil.Discard,
+		Template:    "testdata/templates",
+		Values:      []string{"testdata/values.yml"},
+		Policy:      []string{"testdata/policy/individuals/described.rego"},
+		ResultsFile: resultsPath,
+	}
+	if err := evalCmd.Execute([]string{}); !errors.Is(err, commands.PolicyFailure) {
+		t.Fatalf("expected error:\n%v\ngot:\n%v", commands.PolicyFailure, err)
+	}
+
+	b, err = ioutil.ReadFile(resultsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("the results are described by the published schema", func(t *testing.T) {
+		var results interface{}
+		if err := json.Unmarshal(b, &results); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, problem := range checkSchema("results", schema, results) {
+			t.Error(problem)
+		}
+	})
+
+	t.Run("the results carry the schema version, messages and resources of failed rules", func(t *testing.T) {
+		results := commands.Results{}
+		if err := json.Unmarshal(b, &results); err != nil {
+			t.Fatal(err)
+		}
+
+		if results.SchemaVersion != commands.ResultsSchemaVersion || results.Schema != commands.ResultsSchemaURL || schema["$id"] != commands.ResultsSchemaURL {
+			t.Errorf("expected schema %s version %d, got %s version %d", commands.ResultsSchemaURL, commands.ResultsSchemaVersion, results.Schema, results.SchemaVersion)
+		}
+
+		if len(results.Results) != 2 {
+			t.Fatalf("expected 2 rule results, got: %v", results.Results)
+		}
+
+		failed := results.Results[0]
+		if failed.Result != "fail" || failed.Message != "ingresses must terminate tls" || len(failed.Resources) != 1 || failed.Resources[0] != "something.yml" {
+			t.Errorf("expected the failed rule's message and resources, got: %+v", failed)
+		}
+
+		if passed := results.Results[1]; passed.Result != "pass" || len(passed.Resources) != 0 {
+			t.Errorf("expected the passed rule without resources, got: %+v", passed)
+		}
+
+		if results.DurationMs <= 0 || results.DurationMs < failed.DurationMs {
+			t.Errorf("expected the run to take longer than its rules, got %v for the run and %v for a rule", results.DurationMs, failed.DurationMs)
+		}
+	})
+}
@@ -0,0 +1,352 @@
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const routingHashName = "routing"
+
+type routingModel struct {
+	Hosts  []string `json:"hosts"`
+	Routes []route  `json:"routes"`
+}
+
+// route - a host routed by an Ingress, a Gateway API HTTPRoute or an Istio
+// VirtualService, with whether it is served over tls
+type route struct {
+	Ref        string   `json:"ref"`
+	Kind       string   `json:"kind"`
+	Name       string   `json:"name"`
+	Namespace  string   `json:"namespace"`
+	Host       string   `json:"host"`
+	Paths      []string `json:"paths"`
+	Backends   []string `json:"backends"`
+	TLS        bool     `json:"tls"`
+	TLSSecrets []string `json:"tlsSecrets"`
+}
+
+// tlsListener - a host pattern a gateway terminates tls for, and the
+// secret holding its certificate
+type tlsListener struct {
+	Gateway string
+	Section string
+	Host    string
+	Secret  string
+}
+
+// buildRoutingModel - aggregates the hosts, paths, backends and tls of the
+// rendered Ingresses, HTTPRoutes and VirtualServices, resolving the gateways
+// terminating tls for them so policies don't have to
+func buildRoutingModel(objects []map[string]interface{}) routingModel {
+	model := routingModel{Hosts: make([]string, 0), Routes: make([]route, 0)}
+	gatewayListeners := make([]tlsListener, 0)
+	istioListeners := make([]tlsListener, 0)
+	for _, obj := range objects {
+		if objectKind(obj) != "Gateway" {
+			continue
+		}
+
+		if isIstio(obj) {
+			istioListeners = append(istioListeners, istioTLSListeners(obj)...)
+		} else {
+			gatewayListeners = append(gatewayListeners, gatewayTLSListeners(obj)...)
+		}
+	}
+
+	for _, obj := range objects {
+		switch {
+		case objectKind(obj) == "Ingress":
+			model.Routes = append(model.Routes, ingressRoutes(obj)...)
+		case objectKind(obj) == "HTTPRoute":
+			model.Routes = append(model.Routes, httpRoutes(obj, gatewayListeners)...)
+		case objectKind(obj) == "VirtualService" && isIstio(obj):
+			model.Routes = append(model.Routes, virtualServiceRoutes(obj, istioListeners)...)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, r := range model.Routes {
+		if !seen[r.Host] {
+			seen[r.Host] = true
+			model.Hosts = append(model.Hosts, r.Host)
+		}
+	}
+	sort.Strings(model.Hosts)
+	return model
+}
+
+func isIstio(obj map[string]interface{}) bool {
+	return strings.HasPrefix(getString(obj, "apiVersion"), "networking.istio.io/")
+}
+
+func newRoute(obj map[string]interface{}, host string) route {
+	if host == "" {
+		host = "*"
+	}
+
+	return route{
+		Ref:        objectRef(obj),
+		Kind:       objectKind(obj),
+		Name:       objectName(obj),
+		Namespace:  objectNamespace(obj),
+		Host:       host,
+		Paths:      make([]string, 0),
+		Backends:   make([]string, 0),
+		TLSSecrets: make([]string, 0),
+	}
+}
+
+// hostMatches - whether a host pattern (a hostname, a *.wildcard, or empty
+// or * for any host) matches a routed host
+func hostMatches(pattern, host string) bool {
+	switch {
+	case pattern == "" || pattern == "*" || pattern == host:
+		return true
+	case strings.HasPrefix(pattern, "*."):
+		return strings.HasSuffix(host, pattern[1:])
+	}
+	return false
+}
+
+func (s *route) addPath(path string) {
+	if path == "" {
+		path = "/"
+	}
+	s.Paths = appendUnique(s.Paths, path)
+}
+
+func (s *route) addBackend(service string, port interface{}) {
+	if service == "" {
+		return
+	}
+
+	if port != nil {
+		service = fmt.Sprintf("%s:%v", service, port)
+	}
+	s.Backends = appendUnique(s.Backends, service)
+}
+
+func (s *route) addTLS(listeners []tlsListener) {
+	for _, listener := range listeners {
+		if hostMatches(listener.Host, s.Host) {
+			s.TLS = true
+			if listener.Secret != "" {
+				s.TLSSecrets = appendUnique(s.TLSSecrets, listener.Secret)
+			}
+		}
+	}
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+// ingressRoutes - the routes of an Ingress of any api version, with the tls
+// of the hosts named in its tls section
+func ingressRoutes(obj map[string]interface{}) []route {
+	listeners := make([]tlsListener, 0)
+	for _, t := range getSlice(obj, "spec", "tls") {
+		tls, _ := t.(map[string]interface{})
+		for _, host := range stringSlice(getSlice(tls, "hosts")) {
+			listeners = append(listeners, tlsListener{Host: host, Secret: getString(tls, "secretName")})
+		}
+	}
+
+	routes := make([]route, 0)
+	for _, field := range []string{"defaultBackend", "backend"} {
+		if backend := getMap(obj, "spec", field); backend != nil {
+			r := newRoute(obj, "")
+			r.addPath("")
+			addIngressBackend(&r, backend)
+			routes = append(routes, r)
+		}
+	}
+
+	for _, rl := range getSlice(obj, "spec", "rules") {
+		rule, _ := rl.(map[string]interface{})
+		r := newRoute(obj, getString(rule, "host"))
+		for _, p := range getSlice(rule, "http", "paths") {
+			path, _ := p.(map[string]interface{})
+			r.addPath(getString(path, "path"))
+			addIngressBackend(&r, getMap(path, "backend"))
+		}
+		routes = append(routes, r)
+	}
+
+	for i := range routes {
+		if routes[i].Host != "*" {
+			routes[i].addTLS(listeners)
+		}
+	}
+	return routes
+}
+
+// addIngressBackend - adds a networking.k8s.io/v1 (service.name) or
+// v1beta1 (serviceName) backend
+func addIngressBackend(r *route, backend map[string]interface{}) {
+	if service := getMap(backend, "service"); service != nil {
+		port := getField(service, "port", "number")
+		if port == nil {
+			port = getField(service, "port", "name")
+		}
+		r.addBackend(getString(service, "name"), port)
+		return
+	}
+	r.addBackend(getString(backend, "serviceName"), getField(backend, "servicePort"))
+}
+
+// gatewayTLSListeners - the HTTPS and TLS listeners of a Gateway API Gateway
+func gatewayTLSListeners(obj map[string]interface{}) []tlsListener {
+	listeners := make([]tlsListener, 0)
+	for _, l := range getSlice(obj, "spec", "listeners") {
+		listener, _ := l.(map[string]interface{})
+		if protocol := getString(listener, "protocol"); protocol != "HTTPS" && protocol != "TLS" {
+			continue
+		}
+
+		secret := ""
+		for _, ref := range getSlice(listener, "tls", "certificateRefs") {
+			if certificate, ok := ref.(map[string]interface{}); ok && secret == "" {
+				secret = getString(certificate, "name")
+			}
+		}
+
+		listeners = append(listeners, tlsListener{
+			Gateway: objectNamespace(obj) + "/" + objectName(obj),
+			Section: getString(listener, "name"),
+			Host:    getString(listener, "hostname"),
+			Secret:  secret,
+		})
+	}
+	return listeners
+}
+
+// httpRoutes - the routes of a Gateway API HTTPRoute, with the tls of the
+// listeners of its parent gateways
+func httpRoutes(obj map[string]interface{}, listeners []tlsListener) []route {
+	parents := make([]tlsListener, 0)
+	for _, p := range getSlice(obj, "spec", "parentRefs") {
+		parent, _ := p.(map[string]interface{})
+		namespace := getString(parent, "namespace")
+		if namespace == "" {
+			namespace = objectNamespace(obj)
+		}
+
+		section := getString(parent, "sectionName")
+		for _, listener := range listeners {
+			if listener.Gateway == namespace+"/"+getString(parent, "name") && (section == "" || section == listener.Section) {
+				parents = append(parents, listener)
+			}
+		}
+	}
+
+	hosts := stringSlice(getSlice(obj, "spec", "hostnames"))
+	if len(hosts) == 0 {
+		hosts = []string{""}
+	}
+
+	routes := make([]route, 0, len(hosts))
+	for _, host := range hosts {
+		r := newRoute(obj, host)
+		for _, rl := range getSlice(obj, "spec", "rules") {
+			rule, _ := rl.(map[string]interface{})
+			matches := getSlice(rule, "matches")
+			if len(matches) == 0 {
+				r.addPath("")
+			}
+			for _, m := range matches {
+				match, _ := m.(map[string]interface{})
+				r.addPath(getString(match, "path", "value"))
+			}
+
+			for _, b := range getSlice(rule, "backendRefs") {
+				backend, _ := b.(map[string]interface{})
+				r.addBackend(getString(backend, "name"), getField(backend, "port"))
+			}
+		}
+		r.addTLS(parents)
+		routes = append(routes, r)
+	}
+	return routes
+}
+
+// istioTLSListeners - the HTTPS and TLS servers of an Istio Gateway. Their
+// hosts may be prefixed with the namespace of the virtual services they admit
+func istioTLSListeners(obj map[string]interface{}) []tlsListener {
+	listeners := make([]tlsListener, 0)
+	for _, s := range getSlice(obj, "spec", "servers") {
+		server, _ := s.(map[string]interface{})
+		if protocol := getString(server, "port", "protocol"); protocol != "HTTPS" && protocol != "TLS" {
+			continue
+		}
+
+		for _, host := range stringSlice(getSlice(server, "hosts")) {
+			if i := strings.Index(host, "/"); i >= 0 {
+				host = host[i+1:]
+			}
+
+			listeners = append(listeners, tlsListener{
+				Gateway: objectNamespace(obj) + "/" + objectName(obj),
+				Host:    host,
+				Secret:  getString(server, "tls", "credentialName"),
+			})
+		}
+	}
+	return listeners
+}
+
+// virtualServiceRoutes - the routes of an Istio VirtualService, with the tls
+// of the servers of the gateways it is bound to
+func virtualServiceRoutes(obj map[string]interface{}, listeners []tlsListener) []route {
+	gateways := make([]tlsListener, 0)
+	for _, gateway := range stringSlice(getSlice(obj, "spec", "gateways")) {
+		if !strings.Contains(gateway, "/") {
+			gateway = objectNamespace(obj) + "/" + gateway
+		}
+
+		for _, listener := range listeners {
+			if listener.Gateway == gateway {
+				gateways = append(gateways, listener)
+			}
+		}
+	}
+
+	routes := make([]route, 0)
+	for _, host := range stringSlice(getSlice(obj, "spec", "hosts")) {
+		r := newRoute(obj, host)
+		for _, h := range getSlice(obj, "spec", "http") {
+			http, _ := h.(map[string]interface{})
+			matches := getSlice(http, "match")
+			if len(matches) == 0 {
+				r.addPath("")
+			}
+			for _, m := range matches {
+				// a match without a uri matches every path
+				match, _ := m.(map[string]interface{})
+				path := ""
+				for _, kind := range []string{"prefix", "exact", "regex"} {
+					if uri := getString(match, "uri", kind); uri != "" {
+						path = uri
+					}
+				}
+				r.addPath(path)
+			}
+
+			for _, d := range getSlice(http, "route") {
+				destination, _ := d.(map[string]interface{})
+				destination = getMap(destination, "destination")
+				r.addBackend(getString(destination, "host"), getField(destination, "port", "number"))
+			}
+		}
+		r.addTLS(gateways)
+		routes = append(routes, r)
+	}
+	return routes
+}
@@ -0,0 +1,196 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+)
+
+// the kinds of rules hcunit evaluates. expect and assert rules must hold,
+// deny rules must not hold and warn rules are reported when they hold
+// without failing the run
+const (
+	ruleExpect = "expect"
+	ruleAssert = "assert"
+	ruleDeny   = "deny"
+	ruleWarn   = "warn"
+)
+
+const metadataRuleName = "metadata"
+const documentHashName = "document"
+
+// releaseNamespace - the namespace charts are rendered into
+const releaseNamespace = "hcunit-namespace"
+
+// metadata keys hcunit acts on
+const (
+	metadataDescription = "description"
+	metadataPerDocument = "per_document"
+	metadataAppliesTo   = "applies_to"
+	metadataOwner       = "owner"
+	metadataTeam        = "team"
+	metadataEnforcement = "enforcement"
+	metadataDeprecated  = "deprecated"
+	metadataRemoveAfter = "remove_after"
+)
+
+// enforcementDryRun - the enforcement of rules whose violations are reported
+// and counted without failing the run, e.g. while rolling out a new policy
+const enforcementDryRun = "dryrun"
+
+func isRuleKind(name string) bool {
+	switch name {
+	case ruleExpect, ruleAssert, ruleDeny, ruleWarn:
+		return true
+	}
+	return false
+}
+
+// ruleOutcome - whether a run of a rule of the given kind passed and whether
+// it raised a warning, given whether the rule was defined (held)
+func ruleOutcome(kind string, defined bool) (passed bool, warning bool) {
+	switch kind {
+	case ruleDeny:
+		return !defined, false
+	case ruleWarn:
+		return true, defined
+	}
+	return defined, false
+}
+
+// loadMetadata - evaluates the optional `metadata` rule of the policy
+// namespace, an object mapping rule names to their metadata, e.g.
+// metadata := {"replicas": {"description": "...", "per_document": true}}
+func loadMetadata(ctx context.Context, policies []string, namespace string, input ast.Value, options []func(*rego.Rego)) (map[string]map[string]interface{}, error) {
+	table, err := loadRuleTable(ctx, policies, namespace, metadataRuleName, input, options)
+	if err != nil {
+		return nil, err
+	}
+	return metadataOf(namespace, table)
+}
+
+// metadataOf - the metadata of every rule from the table of the metadata rule
+func metadataOf(namespace string, table map[string]interface{}) (map[string]map[string]interface{}, error) {
+	query := fmt.Sprintf("data.%s.%s", namespace, metadataRuleName)
+	metadata := make(map[string]map[string]interface{})
+	for rule, value := range table {
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, &EvaluationError{Query: query, Err: fmt.Errorf("%s[%q] must be an object", metadataRuleName, rule)}
+		}
+
+		if err := validateRemoveAfter(rule, fields); err != nil {
+			return nil, &EvaluationError{Query: query, Err: err}
+		}
+		metadata[rule] = fields
+	}
+	return metadata, nil
+}
+
+// documentRuns - expands runs of a per document rule into one run per
+// rendered object the rule applies to, with the object available as
+// input.document
+func documentRuns(runs []paramRun, input interface{}, metadata map[string]interface{}) []paramRun {
+	m, ok := input.(map[string]interface{})
+	if !ok {
+		return runs
+	}
+
+	templates := templateNames(m)
+	expanded := make([]paramRun, 0, len(runs))
+	for _, run := range runs {
+		for _, template := range templates {
+			for _, obj := range renderedObjects(map[string]interface{}{template: m[template]}) {
+				if !appliesTo(metadata, obj, inputNamespace(m)) {
+					continue
+				}
+
+				expanded = append(expanded, paramRun{
+					name:     fmt.Sprintf("%s on %s in %s", run.name, objectRef(obj), template),
+					input:    withInput(run.input, documentHashName, obj),
+					document: template,
+				})
+			}
+		}
+	}
+	return expanded
+}
+
+// isPerDocument - true when a rule is evaluated once per rendered object:
+// its metadata sets per_document, or applies_to
+func isPerDocument(metadata map[string]interface{}) bool {
+	return metadataBool(metadata, metadataPerDocument) || metadata[metadataAppliesTo] != nil
+}
+
+// appliesTo - whether a rendered object is one a rule applies to by the
+// applies_to of its metadata: one of its kinds, one of its namespaces (the
+// release namespace for objects without one) and all of its labels, e.g.
+// applies_to: {"kinds": ["Deployment"], "labels": {"tier": "web"}}. Rules
+// apply to every object along what isn't given
+func appliesTo(metadata map[string]interface{}, obj map[string]interface{}, namespace string) bool {
+	applicability, _ := metadata[metadataAppliesTo].(map[string]interface{})
+	if kinds, ok := applicability["kinds"].([]interface{}); ok && !containsFold(kinds, objectKind(obj)) {
+		return false
+	}
+
+	if ns := getString(obj, "metadata", "namespace"); ns != "" {
+		namespace = ns
+	}
+
+	if namespaces, ok := applicability["namespaces"].([]interface{}); ok && !containsFold(namespaces, namespace) {
+		return false
+	}
+
+	labels, _ := applicability["labels"].(map[string]interface{})
+	for key, value := range labels {
+		if label, ok := getField(obj, "metadata", "labels", key).(string); !ok || label != fmt.Sprint(value) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsFold(values []interface{}, s string) bool {
+	for _, value := range values {
+		if v, ok := value.(string); ok && strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// isDryRun - true when the metadata of a rule sets enforcement: dryrun
+func isDryRun(metadata map[string]interface{}) bool {
+	return metadataString(metadata, metadataEnforcement) == enforcementDryRun
+}
+
+// dryRunResults - turns the violations of a run into dry run results for
+// --dryrun, so they are reported without failing it. Errors other than
+// policy violations are returned untouched
+func dryRunResults(results []RuleResult, err error) ([]RuleResult, error) {
+	var violation *ViolationError
+	if !errors.As(err, &violation) {
+		return results, err
+	}
+
+	for i := range results {
+		if !results[i].Passed {
+			results[i].Passed, results[i].DryRun = true, true
+		}
+	}
+	return results, nil
+}
+
+func metadataString(metadata map[string]interface{}, key string) string {
+	s, _ := metadata[key].(string)
+	return s
+}
+
+func metadataBool(metadata map[string]interface{}, key string) bool {
+	b, _ := metadata[key].(bool)
+	return b
+}
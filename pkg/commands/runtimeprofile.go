@@ -0,0 +1,97 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"path/filepath"
+	"runtime"
+	runtimepprof "runtime/pprof"
+)
+
+// InvalidPprofAddr - --pprof-addr can't be listened on
+var InvalidPprofAddr = errors.New("invalid --pprof-addr")
+
+// startRuntimeProfiling - profiles hcunit itself, for reports of runs
+// running out of memory or time: serves net/http/pprof on --pprof-addr for
+// as long as the run lasts (e.g. --interactive or a long --values-set
+// batch) and starts the cpu profile of --cpuprofile. The returned func
+// stops both and writes the heap profile of --memprofile
+func (s *EvalCommand) startRuntimeProfiling() (func() error, error) {
+	var server *http.Server
+	if s.PprofAddr != "" {
+		listener, err := net.Listen("tcp", s.PprofAddr)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", InvalidPprofAddr, err)
+		}
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+		server = &http.Server{Handler: mux}
+		go server.Serve(listener)
+		fmt.Fprintf(s.Stdout, "serving pprof on http://%s/debug/pprof/\n", listener.Addr())
+	}
+
+	var cpuProfile *os.File
+	if s.CPUProfile != "" {
+		f, err := createProfile(s.CPUProfile)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := runtimepprof.StartCPUProfile(f); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("starting the cpu profile failed: %w", err)
+		}
+		cpuProfile = f
+	}
+
+	return func() error {
+		if server != nil {
+			server.Close()
+		}
+
+		if cpuProfile != nil {
+			runtimepprof.StopCPUProfile()
+			if err := cpuProfile.Close(); err != nil {
+				return err
+			}
+		}
+
+		if s.MemProfile != "" {
+			return writeHeapProfile(s.MemProfile)
+		}
+		return nil
+	}, nil
+}
+
+// writeHeapProfile - writes a heap profile after a garbage collection, so
+// it holds what is still in use next to everything allocated during the run
+// (go tool pprof -sample_index=alloc_space)
+func writeHeapProfile(path string) error {
+	f, err := createProfile(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	runtime.GC()
+	if err := runtimepprof.WriteHeapProfile(f); err != nil {
+		return fmt.Errorf("writing the heap profile failed: %w", err)
+	}
+	return f.Close()
+}
+
+func createProfile(path string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	return os.Create(path)
+}
@@ -0,0 +1,80 @@
+package commands_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestEvalRuntimeProfiling(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hcunit-runtime-profile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	t.Run("pprof is served during the run and cpu and heap profiles written after it", func(t *testing.T) {
+		cpuPath, memPath := filepath.Join(dir, "cpu.pprof"), filepath.Join(dir, "profiles", "mem.pprof")
+		stdOut := new(bytes.Buffer)
+		served := false
+		evalCmd := &commands.EvalCommand{
+			Stdout:     stdOut,
+			Template:   "testdata/templates/something.yml",
+			Values:     []string{"testdata/values.yml"},
+			Policy:     []string{"testdata/policy/passing/passing.rego"},
+			PprofAddr:  "127.0.0.1:0",
+			CPUProfile: cpuPath,
+			MemProfile: memPath,
+			Hooks: &commands.Hooks{
+				BeforeRender: func(string, string) error {
+					addr := regexp.MustCompile(`http://\S+/debug/pprof/`).FindString(stdOut.String())
+					res, err := http.Get(addr + "cmdline")
+					if err != nil {
+						return err
+					}
+					defer res.Body.Close()
+
+					if res.StatusCode != http.StatusOK {
+						return fmt.Errorf("pprof responded %s", res.Status)
+					}
+					served = true
+					return nil
+				},
+			},
+		}
+		if err := evalCmd.Execute([]string{}); err != nil {
+			t.Fatal(err)
+		}
+
+		if !served {
+			t.Error("expected pprof to be served during the run")
+		}
+
+		for _, path := range []string{cpuPath, memPath} {
+			if info, err := os.Stat(path); err != nil || info.Size() == 0 {
+				t.Errorf("expected a profile at %s, got: %v", path, err)
+			}
+		}
+	})
+
+	t.Run("addresses which can't be listened on are rejected", func(t *testing.T) {
+		evalCmd := &commands.EvalCommand{
+			Stdout:    new(bytes.Buffer),
+			Template:  "testdata/templates/something.yml",
+			Values:    []string{"testdata/values.yml"},
+			Policy:    []string{"testdata/policy/passing/passing.rego"},
+			PprofAddr: "127.0.0.1:notaport",
+		}
+		if err := evalCmd.Execute([]string{}); !errors.Is(err, commands.InvalidPprofAddr) {
+			t.Errorf("expected error:\n%v\ngot:\n%v", commands.InvalidPprofAddr, err)
+		}
+	})
+}
@@ -0,0 +1,212 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"k8s.io/helm/pkg/chartutil"
+)
+
+const jsonSchemaDraft = "http://json-schema.org/draft-07/schema#"
+
+// templateAction - a single {{ ... }} action of a go template
+var templateAction = regexp.MustCompile(`(?s){{-?(.*?)-?}}`)
+
+// valuesRef - a reference into the values, e.g. .Values.image.tag or $.Values.image
+var valuesRef = regexp.MustCompile(`^\$?\.Values((?:\.[A-Za-z_][A-Za-z0-9_]*)+)$`)
+
+// usageTypes - the type implied by the function or keyword a value is passed
+// to (preceding it) or piped into (following it)
+var usageTypes = map[string]string{
+	"range":   "array",
+	"with":    "object",
+	"toYaml":  "object",
+	"toJson":  "object",
+	"if":      "boolean",
+	"not":     "boolean",
+	"and":     "boolean",
+	"or":      "boolean",
+	"int":     "integer",
+	"int64":   "integer",
+	"float64": "number",
+}
+
+type SchemaCommand struct {
+	Writer   io.Writer
+	Template string   `short:"t" long:"template" description:"path to the chart, or its templates, you would like to generate a values schema for"`
+	Values   []string `short:"c" long:"values" description:"path to values file(s) whose values are used as defaults, the chart's values.yaml when not given"`
+	Output   string   `short:"o" long:"output" description:"write the schema to this file (e.g. values.schema.json) instead of stdout"`
+	Force    bool     `long:"force" description:"overwrite the --output file if it already exists"`
+}
+
+func (s *SchemaCommand) Execute(args []string) error {
+	s.setDefaults()
+	templatePath, err := resolveTemplatePath(s.Template)
+	if err != nil {
+		return err
+	}
+	s.Template = templatePath
+
+	valuesFiles := s.Values
+	if len(valuesFiles) == 0 {
+		if chartDir, err := findChartRoot(s.Template); err == nil {
+			if defaults := filepath.Join(chartDir, chartutil.ValuesfileName); fileExists(defaults) {
+				valuesFiles = []string{defaults}
+			}
+		}
+	}
+
+	valuesConfig, err := mergeValues(valuesFiles)
+	if err != nil {
+		return fmt.Errorf("failed merging values files %w ", err)
+	}
+
+	templates, err := WalkTemplatePath(s.Template)
+	if err != nil {
+		return err
+	}
+
+	schema := schemaFromValues(valuesConfig)
+	schema.Schema = jsonSchemaDraft
+	names := make([]string, 0, len(templates))
+	for name := range templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		content, err := ioutil.ReadAll(templates[name])
+		templates[name].Close()
+		if err != nil {
+			return fmt.Errorf("reading template %s failed: %w", name, err)
+		}
+		schema.addUsages(string(content))
+	}
+
+	out, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return fmt.Errorf("couldnt marshal schema: %w", err)
+	}
+	return writeOutput(s.Writer, s.Output, string(out)+"\n", s.Force)
+}
+
+func (s *SchemaCommand) setDefaults() {
+	if s.Writer == nil {
+		s.Writer = os.Stdout
+	}
+}
+
+// valuesSchema - the subset of json schema we can infer for values
+type valuesSchema struct {
+	Schema     string                   `json:"$schema,omitempty"`
+	Type       string                   `json:"type,omitempty"`
+	Properties map[string]*valuesSchema `json:"properties,omitempty"`
+	Items      *valuesSchema            `json:"items,omitempty"`
+
+	// inferred - typed by usage rather than by a default value
+	inferred bool
+}
+
+// schemaFromValues - a schema typed after the given default values
+func schemaFromValues(value interface{}) *valuesSchema {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		schema := &valuesSchema{Type: "object", Properties: make(map[string]*valuesSchema)}
+		for key, child := range v {
+			schema.Properties[key] = schemaFromValues(child)
+		}
+		return schema
+	case []interface{}:
+		schema := &valuesSchema{Type: "array"}
+		if len(v) > 0 {
+			schema.Items = schemaFromValues(v[0])
+		}
+		return schema
+	case bool:
+		return &valuesSchema{Type: "boolean"}
+	case int, int64, uint64:
+		return &valuesSchema{Type: "integer"}
+	case float64:
+		return &valuesSchema{Type: "number"}
+	case string:
+		return &valuesSchema{Type: "string"}
+	}
+	return &valuesSchema{}
+}
+
+// addUsages - adds every .Values reference of a template to the schema.
+// Values without a default are typed by how the template uses them
+func (s *valuesSchema) addUsages(template string) {
+	for _, action := range templateAction.FindAllStringSubmatch(template, -1) {
+		tokens := actionTokens(action[1])
+
+		for i, token := range tokens {
+			match := valuesRef.FindStringSubmatch(token)
+			if match == nil {
+				continue
+			}
+
+			usage := ""
+			if i > 0 {
+				usage = usageTypes[tokens[i-1]]
+			}
+			if i+2 < len(tokens) && tokens[i+1] == "|" {
+				usage = usageTypes[tokens[i+2]]
+			}
+			s.addPath(strings.Split(strings.TrimPrefix(match[1], "."), "."), usage)
+		}
+	}
+}
+
+func (s *valuesSchema) addPath(path []string, usage string) {
+	if s.Type == "" {
+		s.Type = "object"
+	}
+
+	if s.Type != "object" || len(path) == 0 {
+		return
+	}
+
+	if s.Properties == nil {
+		s.Properties = make(map[string]*valuesSchema)
+	}
+
+	child, ok := s.Properties[path[0]]
+	if !ok {
+		child = &valuesSchema{inferred: true}
+		s.Properties[path[0]] = child
+	}
+
+	if len(path) > 1 {
+		if child.inferred {
+			child.Type = "object"
+		}
+		child.addPath(path[1:], usage)
+		return
+	}
+
+	switch {
+	case child.Type == "":
+		child.Type = "string"
+		if usage != "" {
+			child.Type = usage
+		}
+	case child.inferred && child.Type == "string" && usage != "":
+		child.Type = usage
+	}
+}
+
+// actionTokens - the words of a template action, split on whitespace and
+// parentheses
+func actionTokens(action string) []string {
+	return strings.FieldsFunc(action, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '(' || r == ')'
+	})
+}
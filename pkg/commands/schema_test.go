@@ -0,0 +1,93 @@
+package commands_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestSchemaCommand(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		template  string
+		values    []string
+		failsWith error
+		types     map[string]string
+	}{
+		{
+			name:     "types are inferred from the chart's defaults and template usage",
+			template: "testdata/schemachart",
+			types: map[string]string{
+				"":                 "object",
+				"replicaCount":     "integer",
+				"image":            "object",
+				"image.repository": "string",
+				"image.tag":        "string",
+				"env":              "array",
+				"resources":        "object",
+				"metrics":          "object",
+				"metrics.enabled":  "boolean",
+				"metrics.port":     "integer",
+			},
+		},
+		{
+			name:     "given values files replace the chart's defaults",
+			template: "testdata/schemachart",
+			values:   []string{"testdata/values.yml"},
+			types: map[string]string{
+				"HttpPort":     "integer",
+				"replicaCount": "string",
+				"env":          "array",
+			},
+		},
+		{
+			name:      "a template path which doesnt exist",
+			template:  "testdata/doesnotexist",
+			failsWith: commands.TemplatePathNotFound,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			stdOut := new(bytes.Buffer)
+			schemaCmd := &commands.SchemaCommand{
+				Writer:   stdOut,
+				Template: tt.template,
+				Values:   tt.values,
+			}
+			err := schemaCmd.Execute([]string{})
+			if !errors.Is(err, tt.failsWith) {
+				t.Fatalf("expected error:\n%v\ngot:\n%v", tt.failsWith, err)
+			}
+
+			if tt.failsWith != nil {
+				return
+			}
+
+			schema := make(map[string]interface{})
+			if err := json.Unmarshal(stdOut.Bytes(), &schema); err != nil {
+				t.Fatalf("expected a json schema, got %v:\n%s", err, stdOut.String())
+			}
+
+			for path, expected := range tt.types {
+				if actual := schemaType(schema, path); actual != expected {
+					t.Errorf("expected %q to be typed %q, got %q in:\n%s", path, expected, actual, stdOut.String())
+				}
+			}
+		})
+	}
+}
+
+func schemaType(schema map[string]interface{}, path string) string {
+	current := schema
+	if path != "" {
+		for _, key := range strings.Split(path, ".") {
+			properties, _ := current["properties"].(map[string]interface{})
+			current, _ = properties[key].(map[string]interface{})
+		}
+	}
+	kind, _ := current["type"].(string)
+	return kind
+}
@@ -0,0 +1,145 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+const redactedValue = "<redacted>"
+
+// secretValues - the data and stringData values of every rendered Secret,
+// including the base64 decoded form of data values
+func secretValues(objects []map[string]interface{}) []string {
+	values := make([]string, 0)
+	for _, obj := range objects {
+		if objectKind(obj) != "Secret" {
+			continue
+		}
+
+		for _, field := range []string{"data", "stringData"} {
+			for _, v := range getMap(obj, field) {
+				value, ok := v.(string)
+				if !ok || value == "" {
+					continue
+				}
+
+				values = append(values, value)
+				if decoded, err := base64.StdEncoding.DecodeString(value); err == nil && len(decoded) > 0 {
+					values = append(values, string(decoded))
+				}
+			}
+		}
+	}
+	return values
+}
+
+// newRedactor - replaces every quoted occurrence of the given secret values,
+// raw as well as escaped the way json and go quote them, so multi-line
+// values such as PEM keys are caught in traces too. Longest first so a value
+// never leaks through a shorter one's replacement
+func newRedactor(values []string) *strings.Replacer {
+	quoted := make(map[string]bool)
+	for _, value := range values {
+		quoted[`"`+value+`"`] = true
+		quoted[strconv.Quote(value)] = true
+		if b, err := json.Marshal(value); err == nil {
+			quoted[string(b)] = true
+		}
+
+		unescaped := new(bytes.Buffer)
+		encoder := json.NewEncoder(unescaped)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(value); err == nil {
+			quoted[strings.TrimSuffix(unescaped.String(), "\n")] = true
+		}
+	}
+
+	forms := make([]string, 0, len(quoted))
+	for form := range quoted {
+		forms = append(forms, form)
+	}
+	sort.Slice(forms, func(i, j int) bool {
+		if len(forms[i]) != len(forms[j]) {
+			return len(forms[i]) > len(forms[j])
+		}
+		return forms[i] < forms[j]
+	})
+
+	pairs := make([]string, 0, len(forms)*2)
+	for _, form := range forms {
+		pairs = append(pairs, form, `"`+redactedValue+`"`)
+	}
+	return strings.NewReplacer(pairs...)
+}
+
+// redactingWriter - a writer which redacts secret values from everything
+// written through it, e.g. policy traces
+type redactingWriter struct {
+	writer   io.Writer
+	redactor *strings.Replacer
+}
+
+func (s redactingWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(s.writer, s.redactor.Replace(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// redactSecretDocuments - rewrites the Secret documents of a rendered
+// template with their data and stringData values redacted. Every other
+// document is returned untouched
+func redactSecretDocuments(rendered string) string {
+	documents := splitDocuments(rendered)
+	for i, doc := range documents {
+		node := new(yaml.Node)
+		if err := yaml.Unmarshal([]byte(doc), node); err != nil || len(node.Content) == 0 {
+			continue
+		}
+
+		root := node.Content[0]
+		if mappingValue(root, "kind") == nil || mappingValue(root, "kind").Value != "Secret" {
+			continue
+		}
+
+		for _, field := range []string{"data", "stringData"} {
+			data := mappingValue(root, field)
+			if data == nil || data.Kind != yaml.MappingNode {
+				continue
+			}
+
+			for j := 1; j < len(data.Content); j += 2 {
+				data.Content[j] = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: redactedValue}
+			}
+		}
+
+		redacted := new(bytes.Buffer)
+		encoder := yaml.NewEncoder(redacted)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(node); err != nil {
+			continue
+		}
+		documents[i] = strings.TrimSuffix(redacted.String(), "\n")
+	}
+	return strings.Join(documents, "\n---\n")
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var SecretsFound = errors.New("possible credentials found outside of Secret objects")
+
+// minEntropyLength - strings shorter than this are never considered high entropy
+const minEntropyLength = 20
+
+// credentialPatterns - well known credential formats, by description
+var credentialPatterns = []struct {
+	reason  string
+	pattern *regexp.Regexp
+}{
+	{"AWS access key id", regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"private key", regexp.MustCompile(`-----BEGIN ([A-Z]+ )?PRIVATE KEY-----`)},
+	{"GitHub token", regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`)},
+	{"Slack token", regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}\b`)},
+}
+
+var base64Token = regexp.MustCompile(`^[A-Za-z0-9+/=_-]+$`)
+var hexToken = regexp.MustCompile(`^[0-9a-fA-F]+$`)
+
+type secretFinding struct {
+	Location string
+	Reason   string
+}
+
+// scanForSecrets - looks for credential patterns and high entropy strings in
+// every rendered object except Secrets, and in the values. Values which are
+// rendered into a Secret are where they belong and are skipped
+func scanForSecrets(objects []map[string]interface{}, values map[string]interface{}) []secretFinding {
+	allowed := make(map[string]bool)
+	for _, value := range secretValues(objects) {
+		allowed[value] = true
+	}
+
+	findings := make([]secretFinding, 0)
+	for _, obj := range objects {
+		if objectKind(obj) == "Secret" {
+			continue
+		}
+		findings = append(findings, scanValue(objectRef(obj), obj, allowed)...)
+	}
+
+	findings = append(findings, scanValue(valuesHashName, values, allowed)...)
+	sort.Slice(findings, func(i, j int) bool { return findings[i].Location < findings[j].Location })
+	return findings
+}
+
+func scanValue(location string, value interface{}, allowed map[string]bool) []secretFinding {
+	findings := make([]secretFinding, 0)
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if strings.Contains(strings.ToLower(key), "checksum") {
+				continue
+			}
+			findings = append(findings, scanValue(location+"."+key, child, allowed)...)
+		}
+	case []interface{}:
+		for i, child := range v {
+			findings = append(findings, scanValue(fmt.Sprintf("%s[%d]", location, i), child, allowed)...)
+		}
+	case string:
+		if allowed[v] {
+			break
+		}
+
+		if reason := credentialReason(v); reason != "" {
+			findings = append(findings, secretFinding{Location: location, Reason: reason})
+		}
+	}
+	return findings
+}
+
+func credentialReason(value string) string {
+	for _, credential := range credentialPatterns {
+		if credential.pattern.MatchString(value) {
+			return credential.reason
+		}
+	}
+
+	for _, token := range strings.Fields(value) {
+		if highEntropy(token) {
+			return "high entropy string"
+		}
+	}
+	return ""
+}
+
+// highEntropy - true for long base64 or hex looking tokens whose shannon
+// entropy is close to random. Image digests are skipped
+func highEntropy(token string) bool {
+	if len(token) < minEntropyLength || strings.Contains(token, "sha256:") {
+		return false
+	}
+
+	switch {
+	case hexToken.MatchString(token):
+		return shannonEntropy(token) > 3.0
+	case base64Token.MatchString(token):
+		return shannonEntropy(token) > 4.5
+	}
+	return false
+}
+
+func shannonEntropy(token string) float64 {
+	counts := make(map[rune]float64)
+	for _, r := range token {
+		counts[r]++
+	}
+
+	entropy := 0.0
+	length := float64(len(token))
+	for _, count := range counts {
+		p := count / length
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// secretResults - the findings as failed results, reported and counted
+// along with our policy results, without echoing the suspected credential
+// itself
+func secretResults(findings []secretFinding) ([]RuleResult, error) {
+	results := make([]RuleResult, 0, len(findings))
+	for _, finding := range findings {
+		results = append(results, checkResult("secret scan", finding.Location, finding.Reason))
+	}
+
+	if len(results) > 0 {
+		return results, SecretsFound
+	}
+	return results, nil
+}
@@ -0,0 +1,281 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/Masterminds/semver"
+)
+
+const (
+	defaultReleasesURL   = "https://api.github.com/repos/xchapter7x/hcunit/releases"
+	releaseChecksumsName = "checksums.txt"
+	releaseSignatureName = "checksums.txt.sig"
+)
+
+var UpdateAvailable = errors.New("a newer hcunit release is available")
+var ReleaseNotFound = errors.New("no hcunit release found")
+var SignatureMismatch = errors.New("release checksums are not signed by the hcunit release key")
+var UnsignedRelease = errors.New("this build carries no release key to verify the release signature with")
+
+// releaseAssets - the binary our release script uploads for each platform
+var releaseAssets = map[string]string{
+	"linux":   "hcunit_unix",
+	"darwin":  "hcunit_osx",
+	"windows": "hcunit.exe",
+}
+
+type githubRelease struct {
+	TagName    string        `json:"tag_name"`
+	Draft      bool          `json:"draft"`
+	Prerelease bool          `json:"prerelease"`
+	Assets     []githubAsset `json:"assets"`
+}
+
+type githubAsset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+type SelfUpdateCommand struct {
+	Writer        io.Writer
+	Version       string
+	PublicKey     string
+	Check         bool   `long:"check" description:"only report whether a newer release exists, exiting 1 when one does"`
+	Prerelease    bool   `long:"prerelease" description:"consider release candidates too, they are considered anyway when there is no stable release"`
+	AllowUnsigned bool   `long:"allow-unsigned" description:"install the release although this build carries no release key to verify its signature with"`
+	Endpoint      string `long:"endpoint" default:"https://api.github.com/repos/xchapter7x/hcunit/releases" description:"github releases api url to look for new releases at"`
+	Binary        string `long:"binary" description:"path of the binary to replace (default: the running hcunit)"`
+}
+
+func (s *SelfUpdateCommand) Execute(args []string) error {
+	s.setDefaults()
+	fetch := defaultFetchOptions
+	body, err := fetch.httpGet(s.Endpoint)
+	if err != nil {
+		return fmt.Errorf("checking for releases failed: %w", err)
+	}
+
+	releases := make([]githubRelease, 0)
+	if err := json.Unmarshal(body, &releases); err != nil {
+		return fmt.Errorf("couldnt parse releases from %s: %w", s.Endpoint, err)
+	}
+
+	release, latest := latestRelease(releases, s.Prerelease)
+	if release == nil {
+		return fmt.Errorf("%w at %s", ReleaseNotFound, s.Endpoint)
+	}
+
+	current, err := semver.NewVersion(s.Version)
+	if err != nil {
+		current = semver.MustParse("0.0.0")
+	}
+
+	if !latest.GreaterThan(current) {
+		fmt.Fprintf(s.Writer, "hcunit %s is up to date\n", s.Version)
+		return nil
+	}
+
+	if s.Check {
+		fmt.Fprintf(s.Writer, "hcunit %s is available (current %s)\n", release.TagName, s.Version)
+		return UpdateAvailable
+	}
+
+	binary, err := s.downloadVerified(release, fetch)
+	if err != nil {
+		return err
+	}
+
+	if err := replaceBinary(s.Binary, binary); err != nil {
+		return fmt.Errorf("replacing %s failed: %w", s.Binary, err)
+	}
+	fmt.Fprintf(s.Writer, "updated hcunit %s to %s\n", s.Version, release.TagName)
+	return nil
+}
+
+func (s *SelfUpdateCommand) setDefaults() {
+	if s.Writer == nil {
+		s.Writer = os.Stdout
+	}
+
+	if s.Endpoint == "" {
+		s.Endpoint = defaultReleasesURL
+	}
+
+	if s.Binary == "" {
+		if executable, err := os.Executable(); err == nil {
+			s.Binary = executable
+		}
+	}
+}
+
+// downloadVerified - downloads the release's binary for this platform and
+// verifies it against the release's checksums, and the checksums against
+// their signature. Builds without a release key only install releases with
+// --allow-unsigned
+func (s *SelfUpdateCommand) downloadVerified(release *githubRelease, fetch FetchOptions) ([]byte, error) {
+	name := releaseAssets[runtime.GOOS]
+	binaryURL := assetURL(release, name)
+	checksumsURL := assetURL(release, releaseChecksumsName)
+	if binaryURL == "" || checksumsURL == "" {
+		return nil, fmt.Errorf("%w: %s has no %s binary with checksums", ReleaseNotFound, release.TagName, runtime.GOOS)
+	}
+
+	if s.PublicKey == "" && !s.AllowUnsigned {
+		return nil, fmt.Errorf("%w, install %s with --allow-unsigned to skip verifying it", UnsignedRelease, release.TagName)
+	}
+
+	checksums, err := fetch.httpGet(checksumsURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.PublicKey == "" {
+		fmt.Fprintln(s.Writer, "WARN: this build carries no release key, the release signature is not verified")
+	} else {
+		signatureURL := assetURL(release, releaseSignatureName)
+		if signatureURL == "" {
+			return nil, fmt.Errorf("%w: %s is not signed", SignatureMismatch, release.TagName)
+		}
+
+		signature, err := fetch.httpGet(signatureURL)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := verifySignature(s.PublicKey, checksums, signature); err != nil {
+			return nil, err
+		}
+	}
+
+	pinned := releaseChecksum(checksums, name)
+	if pinned == "" {
+		return nil, fmt.Errorf("%w: %s has no checksum for %s", ChecksumMismatch, release.TagName, name)
+	}
+
+	binary, err := fetch.httpGet(binaryURL)
+	if err != nil {
+		return nil, err
+	}
+	return binary, verifyChecksum(binary, pinned)
+}
+
+// latestRelease - the highest versioned release, skipping drafts and, unless
+// asked for or there is no stable release, prereleases: our release script
+// publishes release candidates only. Tags which aren't semver are ignored
+func latestRelease(releases []githubRelease, prerelease bool) (*githubRelease, *semver.Version) {
+	latest, latestVersion := highestRelease(releases, prerelease)
+	if latest == nil && !prerelease {
+		return highestRelease(releases, true)
+	}
+	return latest, latestVersion
+}
+
+// highestRelease - the highest versioned release which isn't a draft, nor a
+// prerelease unless asked for
+func highestRelease(releases []githubRelease, prerelease bool) (*githubRelease, *semver.Version) {
+	var latest *githubRelease
+	var latestVersion *semver.Version
+	for i, release := range releases {
+		if release.Draft || (release.Prerelease && !prerelease) {
+			continue
+		}
+
+		version, err := semver.NewVersion(release.TagName)
+		if err != nil || (!prerelease && version.Prerelease() != "") {
+			continue
+		}
+
+		if latestVersion == nil || version.GreaterThan(latestVersion) {
+			latest, latestVersion = &releases[i], version
+		}
+	}
+	return latest, latestVersion
+}
+
+func assetURL(release *githubRelease, name string) string {
+	for _, asset := range release.Assets {
+		if asset.Name == name {
+			return asset.URL
+		}
+	}
+	return ""
+}
+
+// releaseChecksum - looks up a file's sha256 in sha256sum formatted checksums
+func releaseChecksum(checksums []byte, name string) string {
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return fields[0]
+		}
+	}
+	return ""
+}
+
+// verifySignature - checks an ed25519 signature against the base64 encoded
+// public key the binary was built with
+func verifySignature(publicKey string, content, signature []byte) error {
+	key, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("%w: invalid release key %q", SignatureMismatch, publicKey)
+	}
+
+	if !ed25519.Verify(ed25519.PublicKey(key), content, signature) {
+		return SignatureMismatch
+	}
+	return nil
+}
+
+// replaceBinary - swaps the binary at path for content. The running binary
+// is moved aside first, as windows doesn't allow replacing it in place
+func replaceBinary(path string, content []byte) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".hcunit-update")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(tmp.Name(), info.Mode()|0755); err != nil {
+		return err
+	}
+
+	old := path + ".old"
+	os.Remove(old)
+	if err := os.Rename(path, old); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Rename(old, path)
+		return err
+	}
+	os.Remove(old)
+	return nil
+}
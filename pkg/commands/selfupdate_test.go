@@ -0,0 +1,203 @@
+package commands_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestSelfUpdateCommand(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	binary := []byte("#!/bin/sh\necho hcunit 0.2.0\n")
+	checksums := ""
+	for _, name := range []string{"hcunit_unix", "hcunit_osx", "hcunit.exe"} {
+		checksums += fmt.Sprintf("%x  %s\n", sha256.Sum256(binary), name)
+	}
+
+	var server *httptest.Server
+	var signature []byte
+	var served []byte
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assets := ""
+		for _, name := range []string{"hcunit_unix", "hcunit_osx", "hcunit.exe", "checksums.txt", "checksums.txt.sig"} {
+			assets += fmt.Sprintf(`{"name": %q, "browser_download_url": "%s/download/%s"},`, name, server.URL, name)
+		}
+
+		switch r.URL.Path {
+		case "/candidates":
+			fmt.Fprintf(w, `[
+				{"tag_name": "0.2.0-rc.1", "prerelease": true, "assets": [%[1]s {}]},
+				{"tag_name": "0.2.0-rc.0", "prerelease": true, "assets": [%[1]s {}]}
+			]`, assets)
+		case "/releases":
+			fmt.Fprintf(w, `[
+				{"tag_name": "0.3.0-rc.0", "prerelease": true, "assets": [%[1]s {}]},
+				{"tag_name": "0.2.0", "assets": [%[1]s {}]},
+				{"tag_name": "0.1.0", "assets": [%[1]s {}]}
+			]`, assets)
+		case "/download/checksums.txt":
+			fmt.Fprint(w, checksums)
+		case "/download/checksums.txt.sig":
+			w.Write(signature)
+		default:
+			w.Write(served)
+		}
+	}))
+	defer server.Close()
+
+	for _, tt := range []struct {
+		name          string
+		endpoint      string
+		version       string
+		check         bool
+		prerelease    bool
+		unsigned      bool
+		allowUnsigned bool
+		signWith      ed25519.PrivateKey
+		serve         []byte
+		failsWith     error
+		updated       bool
+	}{
+		{
+			name:      "an up to date binary is left alone",
+			version:   "0.2.0",
+			signWith:  privateKey,
+			serve:     binary,
+			failsWith: nil,
+		},
+		{
+			name:      "check reports a newer release without updating",
+			version:   "0.1.0",
+			check:     true,
+			signWith:  privateKey,
+			serve:     binary,
+			failsWith: commands.UpdateAvailable,
+		},
+		{
+			name:       "check considers prereleases when asked",
+			version:    "0.2.0",
+			check:      true,
+			prerelease: true,
+			signWith:   privateKey,
+			serve:      binary,
+			failsWith:  commands.UpdateAvailable,
+		},
+		{
+			name:      "an older binary is replaced by the verified release",
+			version:   "0.1.0",
+			signWith:  privateKey,
+			serve:     binary,
+			failsWith: nil,
+			updated:   true,
+		},
+		{
+			name:      "a binary not matching the release checksums is rejected",
+			version:   "0.1.0",
+			signWith:  privateKey,
+			serve:     []byte("tampered"),
+			failsWith: commands.ChecksumMismatch,
+		},
+		{
+			name:      "checksums not signed by the release key are rejected",
+			version:   "0.1.0",
+			signWith:  otherKey,
+			serve:     binary,
+			failsWith: commands.SignatureMismatch,
+		},
+		{
+			name:      "release candidates are updated to when there is no stable release",
+			endpoint:  "/candidates",
+			version:   "0.1.0",
+			signWith:  privateKey,
+			serve:     binary,
+			failsWith: nil,
+			updated:   true,
+		},
+		{
+			name:      "builds without a release key refuse to install unsigned releases",
+			version:   "0.1.0",
+			unsigned:  true,
+			signWith:  privateKey,
+			serve:     binary,
+			failsWith: commands.UnsignedRelease,
+		},
+		{
+			name:          "builds without a release key install releases when allowed to",
+			version:       "0.1.0",
+			unsigned:      true,
+			allowUnsigned: true,
+			signWith:      privateKey,
+			serve:         binary,
+			failsWith:     nil,
+			updated:       true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "hcunit-self-update")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			target := filepath.Join(dir, "hcunit")
+			if err := ioutil.WriteFile(target, []byte("old"), 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			endpoint := "/releases"
+			if tt.endpoint != "" {
+				endpoint = tt.endpoint
+			}
+
+			key := base64.StdEncoding.EncodeToString(publicKey)
+			if tt.unsigned {
+				key = ""
+			}
+
+			signature = ed25519.Sign(tt.signWith, []byte(checksums))
+			served = tt.serve
+			updateCmd := &commands.SelfUpdateCommand{
+				Writer:        ioutil.Discard,
+				Version:       tt.version,
+				PublicKey:     key,
+				Check:         tt.check,
+				Prerelease:    tt.prerelease,
+				AllowUnsigned: tt.allowUnsigned,
+				Endpoint:      server.URL + endpoint,
+				Binary:        target,
+			}
+			err = updateCmd.Execute([]string{})
+			if !errors.Is(err, tt.failsWith) {
+				t.Fatalf("expected error:\n%v\ngot:\n%v", tt.failsWith, err)
+			}
+
+			content, err := ioutil.ReadFile(target)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if updated := string(content) == string(binary); updated != tt.updated {
+				t.Errorf("expected binary to be updated: %v, got content:\n%s", tt.updated, content)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+
+	"k8s.io/helm/pkg/strvals"
+)
+
+// InvalidSetValue - a --set, --set-string or --set-file can't be parsed
+var InvalidSetValue = errors.New("invalid --set")
+
+// setValues - applies --set, --set-string and --set-file over the values
+// merged from the values files, in that order, the way helm does. They are
+// parsed with helm's strvals, so a.b=1,c[0]=x sets nested keys and list
+// items, --set guesses the type of a value, --set-string keeps it a string
+// and --set-file reads it from a file
+func (s *EvalCommand) setValues(values map[string]interface{}) error {
+	for _, set := range s.Set {
+		if err := strvals.ParseInto(set, values); err != nil {
+			return fmt.Errorf("%w %q: %v", InvalidSetValue, set, err)
+		}
+	}
+
+	for _, set := range s.SetString {
+		if err := strvals.ParseIntoString(set, values); err != nil {
+			return fmt.Errorf("%w-string %q: %v", InvalidSetValue, set, err)
+		}
+	}
+
+	readValue := func(path []rune) (interface{}, error) {
+		b, err := readFile(string(path))
+		return string(b), err
+	}
+
+	for _, set := range s.SetFile {
+		if err := strvals.ParseIntoFile(set, values, readValue); err != nil {
+			return fmt.Errorf("%w-file %q: %v", InvalidSetValue, set, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package commands_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestEvalSetValues(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		set       []string
+		setString []string
+		setFile   []string
+		failsWith error
+		repro     string
+	}{
+		{
+			name:      "set values are merged over the values files",
+			set:       []string{"replicas=3,image.pullPolicy=Always", "hosts[0]=a.example.com"},
+			setString: []string{"image.tag=1.10", "country=off"},
+			setFile:   []string{"config=testdata/setvalues/app.conf"},
+		},
+		{
+			name:      "set values are part of the repro command",
+			set:       []string{"replicas=3,image.pullPolicy=Never", "hosts[0]=a.example.com"},
+			setString: []string{"image.tag=1.10", "country=off"},
+			setFile:   []string{"config=testdata/setvalues/app.conf"},
+			failsWith: commands.PolicyFailure,
+			repro:     "--set replicas=3,image.pullPolicy=Never --set 'hosts[0]=a.example.com' --set-string image.tag=1.10 --set-string country=off --set-file config=testdata/setvalues/app.conf -p",
+		},
+		{
+			name:      "set values have to parse",
+			set:       []string{"hosts[x]=a.example.com"},
+			failsWith: commands.InvalidSetValue,
+		},
+		{
+			name:      "set files have to exist",
+			setFile:   []string{"config=testdata/setvalues/missing.conf"},
+			failsWith: commands.InvalidSetValue,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			stdOut := new(bytes.Buffer)
+			evalCmd := &commands.EvalCommand{
+				Stdout:    stdOut,
+				Template:  "testdata/setvalues",
+				Values:    []string{"testdata/values.yml"},
+				Policy:    []string{"testdata/policy/individuals/set_values.rego"},
+				Set:       tt.set,
+				SetString: tt.setString,
+				SetFile:   tt.setFile,
+			}
+			err := evalCmd.Execute([]string{})
+			if tt.failsWith == nil && err != nil || !errors.Is(err, tt.failsWith) {
+				t.Fatalf("expected error:\n%v\ngot:\n%v\n%s", tt.failsWith, err, stdOut)
+			}
+
+			if tt.repro != "" && !strings.Contains(stdOut.String(), tt.repro) {
+				t.Errorf("expected %q in:\n%s", tt.repro, stdOut)
+			}
+		})
+	}
+}
+
+func TestEvalSetValuesProvenance(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hcunit-set-provenance")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	provenance := func(setString ...string) commands.Provenance {
+		evalCmd := &commands.EvalCommand{
+			Stdout:      new(bytes.Buffer),
+			Template:    "testdata/setvalues",
+			Values:      []string{"testdata/values.yml"},
+			Policy:      []string{"testdata/policy/individuals/set_values.rego"},
+			Set:         []string{"replicas=3,image.pullPolicy=Always", "hosts[0]=a.example.com"},
+			SetString:   append([]string{"image.tag=1.10", "country=off"}, setString...),
+			SetFile:     []string{"config=testdata/setvalues/app.conf"},
+			ResultsFile: filepath.Join(dir, "results.json"),
+		}
+		if err := evalCmd.Execute([]string{}); err != nil {
+			t.Fatalf("should not have errored: %v", err)
+		}
+
+		content, err := ioutil.ReadFile(evalCmd.ResultsFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if strings.Contains(string(content), "s3cr3t") || strings.Contains(string(content), "a.example.com") {
+			t.Errorf("expected no set values in the results, got:\n%s", content)
+		}
+
+		results := commands.Results{}
+		if err := json.Unmarshal(content, &results); err != nil {
+			t.Fatalf("expected json results, got %q: %v", content, err)
+		}
+		return results.Provenance
+	}
+
+	first := provenance("password=s3cr3t-1")
+	second := provenance("password=s3cr3t-2")
+	for _, flag := range []string{"--set=<redacted>", "--set-string=<redacted>", "--set-file=<redacted>"} {
+		if !strings.Contains(strings.Join(first.Flags, " "), flag) {
+			t.Errorf("expected %s in the flags, got: %v", flag, first.Flags)
+		}
+	}
+
+	if first.ValuesDigest == "" || first.ValuesDigest == second.ValuesDigest {
+		t.Errorf("expected the set values to be part of the values digest, got %q and %q", first.ValuesDigest, second.ValuesDigest)
+	}
+}
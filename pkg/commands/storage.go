@@ -0,0 +1,160 @@
+package commands
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+const storageHashName = "storage"
+
+type storageModel struct {
+	Claims         []storageClaim `json:"claims"`
+	StorageClasses []storageClass `json:"storageClasses"`
+}
+
+// storageClaim - a PersistentVolumeClaim, or a volumeClaimTemplate of a
+// StatefulSet claimed once per replica
+type storageClaim struct {
+	Ref          string      `json:"ref"`
+	Name         string      `json:"name"`
+	Namespace    string      `json:"namespace"`
+	Workload     string      `json:"workload"`
+	StorageClass string      `json:"storageClass"`
+	AccessModes  []string    `json:"accessModes"`
+	Size         string      `json:"size"`
+	Bytes        interface{} `json:"bytes"`
+	Replicas     int64       `json:"replicas"`
+	TotalBytes   interface{} `json:"totalBytes"`
+}
+
+type storageClass struct {
+	Name                 string `json:"name"`
+	Provisioner          string `json:"provisioner"`
+	ReclaimPolicy        string `json:"reclaimPolicy"`
+	AllowVolumeExpansion bool   `json:"allowVolumeExpansion"`
+	Default              bool   `json:"default"`
+}
+
+// buildStorageModel - aggregates the rendered PersistentVolumeClaims,
+// StatefulSet volumeClaimTemplates and StorageClasses, with the requested
+// sizes parsed into bytes so policies can compare them without string math
+func buildStorageModel(objects []map[string]interface{}) storageModel {
+	model := storageModel{
+		Claims:         make([]storageClaim, 0),
+		StorageClasses: make([]storageClass, 0),
+	}
+
+	for _, obj := range objects {
+		switch objectKind(obj) {
+		case "PersistentVolumeClaim":
+			model.Claims = append(model.Claims, newStorageClaim(obj, objectRef(obj), objectNamespace(obj), "", 1))
+
+		case "StatefulSet":
+			replicas := int64(1)
+			if n, err := parseQuantity(getField(obj, "spec", "replicas")); err == nil {
+				replicas = n
+			}
+
+			for _, t := range getSlice(obj, "spec", "volumeClaimTemplates") {
+				if template, ok := t.(map[string]interface{}); ok {
+					ref := fmt.Sprintf("%s/%s", objectRef(obj), objectName(template))
+					model.Claims = append(model.Claims, newStorageClaim(template, ref, objectNamespace(obj), objectRef(obj), replicas))
+				}
+			}
+
+		case "StorageClass":
+			reclaimPolicy := getString(obj, "reclaimPolicy")
+			if reclaimPolicy == "" {
+				reclaimPolicy = "Delete"
+			}
+
+			expansion, _ := getField(obj, "allowVolumeExpansion").(bool)
+			model.StorageClasses = append(model.StorageClasses, storageClass{
+				Name:                 objectName(obj),
+				Provisioner:          getString(obj, "provisioner"),
+				ReclaimPolicy:        reclaimPolicy,
+				AllowVolumeExpansion: expansion,
+				Default:              getString(obj, "metadata", "annotations", "storageclass.kubernetes.io/is-default-class") == "true",
+			})
+		}
+	}
+	return model
+}
+
+func newStorageClaim(obj map[string]interface{}, ref, namespace, workload string, replicas int64) storageClaim {
+	// the storage class annotation predates storageClassName, which wins
+	storageClass := getString(obj, "metadata", "annotations", "volume.beta.kubernetes.io/storage-class")
+	if name := getString(obj, "spec", "storageClassName"); name != "" {
+		storageClass = name
+	}
+
+	claim := storageClaim{
+		Ref:          ref,
+		Name:         objectName(obj),
+		Namespace:    namespace,
+		Workload:     workload,
+		StorageClass: storageClass,
+		AccessModes:  stringSlice(getSlice(obj, "spec", "accessModes")),
+		Replicas:     replicas,
+	}
+
+	size := getField(obj, "spec", "resources", "requests", "storage")
+	if size != nil {
+		claim.Size = fmt.Sprint(size)
+	}
+
+	if bytes, err := parseQuantity(size); err == nil {
+		claim.Bytes = bytes
+		claim.TotalBytes = bytes * replicas
+	}
+	return claim
+}
+
+// quantitySuffixes - the binary and decimal suffixes of kubernetes quantities
+var quantitySuffixes = []struct {
+	suffix string
+	factor int64
+}{
+	{"Ki", 1 << 10},
+	{"Mi", 1 << 20},
+	{"Gi", 1 << 30},
+	{"Ti", 1 << 40},
+	{"Pi", 1 << 50},
+	{"Ei", 1 << 60},
+	{"k", 1e3},
+	{"M", 1e6},
+	{"G", 1e9},
+	{"T", 1e12},
+	{"P", 1e15},
+	{"E", 1e18},
+}
+
+// parseQuantity - the value of a kubernetes quantity like 10Gi, 1.5T or 3,
+// rounded up to an integer the way the api server does
+func parseQuantity(value interface{}) (int64, error) {
+	quantity := strings.TrimSpace(fmt.Sprint(value))
+	number, factor := quantity, int64(1)
+	for _, s := range quantitySuffixes {
+		if strings.HasSuffix(quantity, s.suffix) {
+			number, factor = strings.TrimSuffix(quantity, s.suffix), s.factor
+			break
+		}
+	}
+
+	n, ok := new(big.Rat).SetString(number)
+	if value == nil || !ok || n.Sign() < 0 {
+		return 0, fmt.Errorf("%q is not a quantity like 10Gi", quantity)
+	}
+
+	n.Mul(n, new(big.Rat).SetInt64(factor))
+	bytes := new(big.Int).Quo(n.Num(), n.Denom())
+	if !n.IsInt() {
+		bytes.Add(bytes, big.NewInt(1))
+	}
+
+	if !bytes.IsInt64() {
+		return 0, fmt.Errorf("%q is too large", quantity)
+	}
+	return bytes.Int64(), nil
+}
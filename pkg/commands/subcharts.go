@@ -0,0 +1,185 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+)
+
+var SubchartNotFound = errors.New("subchart not found")
+
+const (
+	subchartsHashName      = "subcharts"
+	subchartTemplatePrefix = "hcunit-subchart/"
+)
+
+// loadSubcharts - the chart owning templatePath when it vendors subcharts
+// under charts/, to render them with the chart. Nil for templates outside
+// of a chart or charts without subcharts
+func loadSubcharts(templatePath string) (*chart.Chart, error) {
+	chartDir, err := findChartRoot(templatePath)
+	if err != nil {
+		return nil, nil
+	}
+
+	if _, err := os.Stat(filepath.Join(chartDir, "charts")); err != nil {
+		return nil, nil
+	}
+
+	c, err := chartutil.Load(chartDir)
+	if err != nil {
+		return nil, fmt.Errorf("loading chart %s failed: %w", chartDir, err)
+	}
+	return c, nil
+}
+
+// enabledSubcharts - the subcharts of umbrella left enabled by the
+// conditions and tags of its requirements under the given values
+func enabledSubcharts(umbrella *chart.Chart, values map[string]interface{}) ([]*chart.Chart, error) {
+	b, err := yaml.Marshal(values)
+	if err != nil {
+		return nil, fmt.Errorf("couldnt marshal values: %w", err)
+	}
+
+	if err := chartutil.ProcessRequirementsEnabled(umbrella, &chart.Config{Raw: string(b)}); err != nil {
+		return nil, fmt.Errorf("processing the requirements of %s failed: %w", umbrella.GetMetadata().GetName(), err)
+	}
+	return umbrella.GetDependencies(), nil
+}
+
+// coalesceSubcharts - scopes the values of every subchart under its name
+// the way helm does: the values.yaml of the subchart, overridden by the
+// values given for it, with the globals of its parent
+func coalesceSubcharts(values map[string]interface{}, subcharts []*chart.Chart) error {
+	globals, _ := values["global"].(map[string]interface{})
+	for _, subchart := range subcharts {
+		name := subchart.GetMetadata().GetName()
+		defaults := make(map[string]interface{})
+		if err := unmarshalValues([]byte(subchart.GetValues().GetRaw()), &defaults); err != nil {
+			return fmt.Errorf("failed to parse the values of subchart %s: %w", name, err)
+		}
+
+		given, _ := values[name].(map[string]interface{})
+		scoped := mergeMaps(helmValues(defaults).(map[string]interface{}), given)
+		if globals != nil {
+			scopedGlobals, _ := scoped["global"].(map[string]interface{})
+			scoped["global"] = mergeMaps(scopedGlobals, globals)
+		}
+
+		values[name] = scoped
+		if err := coalesceSubcharts(scoped, subchart.GetDependencies()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renameSubchartOutputs - keys the rendered templates of subcharts, which
+// helm names <chart>/charts/<subchart>/templates/<path>, by the subchart
+// producing them as hcunit-subchart/<subchart>/templates/<path>. Nested
+// subcharts are named <subchart>/<subchart>. The templates of the chart keep
+// their names
+func renameSubchartOutputs(rendered map[string]string, chartName string, templates []*chart.Template) map[string]string {
+	own := make(map[string]bool, len(templates))
+	for _, template := range templates {
+		own[path.Join(chartName, template.Name)] = true
+	}
+
+	out := make(map[string]string, len(rendered))
+	for name, content := range rendered {
+		rest := strings.TrimPrefix(name, chartName+"/charts/")
+		i := strings.LastIndex(rest, "/templates/")
+		if own[name] || rest == name || i < 0 {
+			out[name] = content
+			continue
+		}
+
+		subchart := strings.Replace(rest[:i], "/charts/", "/", -1)
+		out[subchartTemplatePrefix+subchart+rest[i:]] = content
+	}
+	return out
+}
+
+// documentName - the name a rendered template is keyed by in the policy
+// input: its file name, prefixed by the subchart producing it if any
+func documentName(rendered string) string {
+	if i := strings.Index(rendered, subchartTemplatePrefix); i >= 0 {
+		name := rendered[i+len(subchartTemplatePrefix):]
+		if j := strings.LastIndex(name, "/templates/"); j >= 0 {
+			return path.Join(name[:j], path.Base(name))
+		}
+	}
+	return filepath.Base(rendered)
+}
+
+// subchartOf - the subchart producing the document keyed by name in the
+// policy input, or empty for the templates of the chart itself
+func subchartOf(document string) string {
+	if i := strings.LastIndex(document, "/"); i >= 0 {
+		return document[:i]
+	}
+	return ""
+}
+
+// subchartDocuments - the documents of the policy input by the subchart
+// producing them, see subchartOf
+func subchartDocuments(input map[string]interface{}) map[string][]string {
+	subcharts := make(map[string][]string)
+	for _, name := range templateNames(input) {
+		if subchart := subchartOf(name); subchart != "" {
+			subcharts[subchart] = append(subcharts[subchart], name)
+		}
+	}
+	return subcharts
+}
+
+// subchartsOf - the subcharts producing any of the documents
+func subchartsOf(documents []string) []string {
+	seen := make(map[string]bool)
+	subcharts := make([]string, 0)
+	for _, document := range documents {
+		if subchart := subchartOf(document); subchart != "" && !seen[subchart] {
+			seen[subchart] = true
+			subcharts = append(subcharts, subchart)
+		}
+	}
+	sort.Strings(subcharts)
+	return subcharts
+}
+
+// onlySubcharts - the rendered chart and helm test templates of the
+// --only-subchart subcharts. Every one of them has to render a template
+func (s *EvalCommand) onlySubcharts(chartOutput, testOutput map[string]string) (map[string]string, map[string]string, error) {
+	chartOutput = onlySubcharts(chartOutput, s.OnlySubcharts)
+	testOutput = onlySubcharts(testOutput, s.OnlySubcharts)
+	for _, subchart := range s.OnlySubcharts {
+		if len(onlySubcharts(chartOutput, []string{subchart}))+len(onlySubcharts(testOutput, []string{subchart})) == 0 {
+			return nil, nil, fmt.Errorf("%w: %s renders no templates of the chart at %s", SubchartNotFound, subchart, s.Template)
+		}
+	}
+	return chartOutput, testOutput, nil
+}
+
+// onlySubcharts - drops the rendered templates not produced by one of the
+// given subcharts or the subcharts nested in them
+func onlySubcharts(rendered map[string]string, subcharts []string) map[string]string {
+	out := make(map[string]string)
+	for name, content := range rendered {
+		producer := subchartOf(documentName(name))
+		for _, subchart := range subcharts {
+			if producer == subchart || strings.HasPrefix(producer, subchart+"/") {
+				out[name] = content
+				break
+			}
+		}
+	}
+	return out
+}
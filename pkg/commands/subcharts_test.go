@@ -0,0 +1,133 @@
+package commands_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestRenderSubcharts(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		values     []string
+		expected   []string
+		unexpected []string
+	}{
+		{
+			name:   "subcharts render with their own values, overrides and globals",
+			values: []string{"testdata/umbrellachart/values.yaml"},
+			expected: []string{
+				"#configmap.yaml\n",
+				"#backend/configmap.yaml\n",
+				"#backend/db/service.yaml\n",
+				"#frontend/deployment.yaml\n",
+				"replicas: 3",
+				"image: nginx:latest",
+				"env: prod",
+				"port: 5432",
+			},
+		},
+		{
+			name:       "subcharts disabled by their condition don't render",
+			values:     []string{"testdata/umbrellachart/values.yaml", "testdata/umbrellachart_no_frontend.yaml"},
+			expected:   []string{"#backend/configmap.yaml\n"},
+			unexpected: []string{"#frontend/deployment.yaml\n"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			stdOut := new(bytes.Buffer)
+			renderCmd := &commands.RenderCommand{
+				Writer:   stdOut,
+				Template: "testdata/umbrellachart",
+				Values:   tt.values,
+			}
+			if err := renderCmd.Execute([]string{}); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, line := range tt.expected {
+				if !strings.Contains(stdOut.String(), line) {
+					t.Errorf("expected the output to contain %q, got:\n%s", line, stdOut.String())
+				}
+			}
+
+			for _, line := range tt.unexpected {
+				if strings.Contains(stdOut.String(), line) {
+					t.Errorf("expected the output not to contain %q, got:\n%s", line, stdOut.String())
+				}
+			}
+		})
+	}
+}
+
+func TestEvalSubcharts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hcunit-subcharts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	t.Run("failures are attributed to the subchart rendering them", func(t *testing.T) {
+		stdOut := new(bytes.Buffer)
+		resultsPath := filepath.Join(dir, "results.json")
+		evalCmd := &commands.EvalCommand{
+			Stdout:      stdOut,
+			Template:    "testdata/umbrellachart",
+			Values:      []string{"testdata/umbrellachart/values.yaml"},
+			Policy:      []string{"testdata/policy/subcharts/pinned_images.rego"},
+			ResultsFile: resultsPath,
+		}
+		if err := evalCmd.Execute([]string{}); !errors.Is(err, commands.PolicyFailure) {
+			t.Fatalf("expected error:\n%v\ngot:\n%v", commands.PolicyFailure, err)
+		}
+
+		expected := "data.main.deny[\"frontend images must be pinned\"]\n      from subchart frontend\n"
+		if !strings.Contains(stdOut.String(), expected) {
+			t.Errorf("expected the output to contain %q, got:\n%s", expected, stdOut.String())
+		}
+
+		b, err := ioutil.ReadFile(resultsPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !strings.Contains(string(b), "\"subcharts\": [\n        \"frontend\"\n      ]") {
+			t.Errorf("expected the results to attribute the failure to the frontend subchart, got:\n%s", b)
+		}
+	})
+
+	for _, tt := range []struct {
+		name      string
+		only      []string
+		failsWith error
+	}{
+		{
+			name: "--only-subchart evaluates the templates of the subchart and its nested subcharts",
+			only: []string{"backend"},
+		},
+		{
+			name:      "--only-subchart needs a subchart rendering templates",
+			only:      []string{"backend", "cache"},
+			failsWith: commands.SubchartNotFound,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			evalCmd := &commands.EvalCommand{
+				Stdout:        ioutil.Discard,
+				Template:      "testdata/umbrellachart",
+				Values:        []string{"testdata/umbrellachart/values.yaml"},
+				Policy:        []string{"testdata/policy/subcharts/only_backend.rego"},
+				OnlySubcharts: tt.only,
+			}
+			if err := evalCmd.Execute([]string{}); !errors.Is(err, tt.failsWith) {
+				t.Fatalf("expected error:\n%v\ngot:\n%v", tt.failsWith, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/gobwas/glob"
+)
+
+// TemplateFilter - include/exclude globs limiting which templates get
+// rendered. Patterns are matched against the slash separated path, the path
+// relative to the template root and the file name, so `deployment.yaml`,
+// `tests/` and `templates/**/*.yaml` all work as expected
+type TemplateFilter struct {
+	Include []string
+	Exclude []string
+}
+
+func (s TemplateFilter) empty() bool {
+	return len(s.Include) == 0 && len(s.Exclude) == 0
+}
+
+// apply - drops the templates which don't pass the filter. Partials
+// (files starting with "_") are always kept since other templates need them
+func (s TemplateFilter) apply(root string, templates map[string]io.ReadCloser) (map[string]io.ReadCloser, error) {
+	if s.empty() {
+		return templates, nil
+	}
+
+	include, err := compileGlobs(s.Include)
+	if err != nil {
+		return nil, err
+	}
+
+	exclude, err := compileGlobs(s.Exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := make(map[string]io.ReadCloser)
+	for name, reader := range templates {
+		candidates := templatePathCandidates(root, name)
+		keep := strings.HasPrefix(path.Base(candidates[0]), "_") ||
+			((len(include) == 0 || matchAny(include, candidates)) && !matchAny(exclude, candidates))
+
+		if keep {
+			filtered[name] = reader
+		} else {
+			reader.Close()
+		}
+	}
+	return filtered, nil
+}
+
+func compileGlobs(patterns []string) ([]glob.Glob, error) {
+	globs := make([]glob.Glob, 0, len(patterns))
+	for _, pattern := range patterns {
+		if strings.HasSuffix(pattern, "/") {
+			pattern += "**"
+		}
+
+		g, err := glob.Compile(pattern, '/')
+		if err != nil {
+			return nil, fmt.Errorf("invalid template glob %q: %w", pattern, err)
+		}
+		globs = append(globs, g)
+	}
+	return globs, nil
+}
+
+func templatePathCandidates(root string, name string) []string {
+	candidates := []string{filepath.ToSlash(name)}
+	if rel, err := filepath.Rel(root, name); err == nil && rel != "." {
+		candidates = append(candidates, filepath.ToSlash(rel))
+	}
+	return append(candidates, path.Base(candidates[0]))
+}
+
+func matchAny(globs []glob.Glob, candidates []string) bool {
+	for _, g := range globs {
+		for _, c := range candidates {
+			if g.Match(c) {
+				return true
+			}
+		}
+	}
+	return false
+}
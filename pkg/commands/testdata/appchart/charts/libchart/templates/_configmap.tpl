@@ -0,0 +1,8 @@
+{{- define "libchart.configmap" -}}
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: {{ .Release.Name }}-config
+data:
+  greeting: {{ .Values.greeting | default "hello" | quote }}
+{{- end -}}
@@ -0,0 +1,5 @@
+package main
+
+expect ["calls a function which does not exist"] {
+  not_a_builtin(input)
+}
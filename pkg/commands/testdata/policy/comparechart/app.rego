@@ -0,0 +1,14 @@
+package main
+
+expect ["services are not exposed on node ports"] {
+  not node_port
+}
+
+expect ["images are pinned by digest"] {
+  container := input["deployment.yaml"].spec.template.spec.containers[_]
+  contains(container.image, "@sha256:")
+}
+
+node_port {
+  input[_].spec.type == "NodePort"
+}
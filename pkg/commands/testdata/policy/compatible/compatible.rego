@@ -0,0 +1,5 @@
+package main
+
+expect ["something is an ingress"] {
+  input["something.yml"].kind == "Ingress"
+}
@@ -0,0 +1,23 @@
+package main
+
+metadata := {
+  "workloads set replicas": {"applies_to": {"kinds": ["deployment", "StatefulSet"]}},
+  "web objects are labeled": {"applies_to": {"labels": {"app": "web"}, "namespaces": ["hcunit-namespace"]}},
+  "system objects are pinned": {"applies_to": {"namespaces": ["kube-system"]}},
+}
+
+expect["workloads set replicas"] {
+  input.document.spec.replicas > 0
+}
+
+expect["web objects are labeled"] {
+  input.document.metadata.labels.app == "web"
+}
+
+expect["system objects are pinned"] {
+  false
+}
+
+expect["the chart renders"] {
+  count(input) > 0
+}
@@ -0,0 +1,9 @@
+package main
+
+expect ["assert_equal passes on equal values"] {
+  hcunit.assert_equal(input["something.yml"].kind, "Ingress")
+}
+
+expect ["assert_equal reports a diff on different values"] {
+  hcunit.assert_equal(input["something.yml"].spec.rules[0], {"host": "other.com", "http": {"paths": [{"backend": {"servicePort": 8500}}]}})
+}
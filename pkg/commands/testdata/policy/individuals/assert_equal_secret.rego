@@ -0,0 +1,5 @@
+package main
+
+expect ["assert_equal diffs of secrets are redacted"] {
+  hcunit.assert_equal(input["secret.yaml"][0].stringData, {"token": "tok-expected"})
+}
@@ -0,0 +1,29 @@
+package main
+
+expect ["autoscalers are joined with the workloads they scale"] {
+  api := input.autoscaling.workloads[_]
+  api.ref == "Deployment/hcunit-name-api"
+  api.autoscalers == ["hcunit-name-api"]
+}
+
+expect ["replicas set on autoscaled workloads are found"] {
+  managed := {w.ref | w := input.autoscaling.workloads[_]; count(w.autoscalers) > 0; w.replicas != null}
+  managed == {"Deployment/hcunit-name-api"}
+}
+
+expect ["minReplicas defaults to 1"] {
+  low := {a.name | a := input.autoscaling.autoscalers[_]; a.minReplicas < 2}
+  low == {"hcunit-name-worker", "hcunit-name-legacy"}
+}
+
+expect ["autoscalers targeting missing workloads are found"] {
+  missing := {a.target | a := input.autoscaling.autoscalers[_]; not a.targetFound}
+  missing == {"Deployment/hcunit-name-legacy"}
+}
+
+expect ["v1 cpu targets are expressed as metrics"] {
+  worker := input.autoscaling.autoscalers[_]
+  worker.name == "hcunit-name-worker"
+  worker.metrics[0].resource.name == "cpu"
+  worker.metrics[0].resource.target.averageUtilization == 80
+}
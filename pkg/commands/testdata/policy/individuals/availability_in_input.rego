@@ -0,0 +1,25 @@
+package main
+
+expect ["workloads without a disruption budget are found"] {
+  uncovered := {w.ref | w := input.availability.workloads[_]; not w.covered}
+  uncovered == {"StatefulSet/hcunit-name-worker"}
+}
+
+expect ["workloads carry the budget selecting them"] {
+  cache := input.availability.workloads[_]
+  cache.ref == "Deployment/hcunit-name-cache"
+  cache.budgets == ["hcunit-name-frontend"]
+  cache.maxUnavailable == "25%"
+}
+
+expect ["workloads selected by several budgets are found"] {
+  web := input.availability.workloads[_]
+  web.ref == "Deployment/hcunit-name-web"
+  count(web.budgets) == 2
+}
+
+expect ["budgets list the workloads they select"] {
+  budget := input.availability.budgets[_]
+  budget.name == "hcunit-name-frontend"
+  budget.workloads == ["Deployment/hcunit-name-web", "Deployment/hcunit-name-cache"]
+}
@@ -0,0 +1,13 @@
+package main
+
+expect ["binary documents are only described in input.binary"] {
+  keystore := input.binary["secret.yaml"][0]
+  keystore.document == 1
+  count(keystore.sha256) == 64
+  input.binary["keystore.bin"][0].size == 9
+  not input["keystore.bin"]
+}
+
+expect ["the text documents of a template are parsed as usual"] {
+  input["secret.yaml"].kind == "ServiceAccount"
+}
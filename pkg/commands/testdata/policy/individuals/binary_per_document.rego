@@ -0,0 +1,8 @@
+package main
+
+metadata := {"binary documents are described to per document rules": {"per_document": true}}
+
+expect ["binary documents are described to per document rules"] {
+  input.binary["keystore.bin"][0].size == 9
+  input.document.kind == "ServiceAccount"
+}
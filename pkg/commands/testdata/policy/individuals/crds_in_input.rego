@@ -0,0 +1,15 @@
+package main
+
+expect ["crds of the chart, its crds directory and --crd are found"] {
+  input.crds["monitoring.coreos.com/PrometheusRule"].source == "crds/prometheusrules.yaml"
+  input.crds["example.com/Backup"].source == "backup-crd.yaml"
+  input.crds["cert-manager.io/Certificate"].plural == "certificates"
+}
+
+expect ["schemas are exposed by version"] {
+  backup := input.crds["example.com/Backup"]
+  backup.versions.v1alpha1.properties.spec.properties.retention.minimum == 1
+
+  rules := input.crds["monitoring.coreos.com/PrometheusRule"]
+  rules.versions.v1.properties.spec.properties.groups.type == "array"
+}
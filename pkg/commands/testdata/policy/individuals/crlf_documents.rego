@@ -0,0 +1,7 @@
+package main
+
+expect ["crlf separated documents should each be parsed"] {
+  count(input["configmaps.yaml"]) == 2
+  input["configmaps.yaml"][0].data.port == "8500"
+  input["configmaps.yaml"][1].metadata.name == "hcunit-name-second"
+}
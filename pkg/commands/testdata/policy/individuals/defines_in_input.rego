@@ -0,0 +1,8 @@
+package main
+
+expect ["named templates should be rendered into input.defines"] {
+  labels := yaml.unmarshal(input.defines["mychart.labels"])
+  labels["app.kubernetes.io/instance"] == "hcunit-name"
+  input.defines["mychart.fullname"] == "hcunit-name-hcunit"
+  not input["deployment.yaml"]
+}
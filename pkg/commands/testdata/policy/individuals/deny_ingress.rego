@@ -0,0 +1,5 @@
+package main
+
+deny ["ingress without tls"] {
+  not input["something.yml"].spec.tls
+}
@@ -0,0 +1,18 @@
+package main
+
+metadata := {
+  "ingress without tls": {"deprecated": true, "remove_after": "2999-12-31"},
+  "something is an ingress": {"remove_after": "2020-01-01"},
+}
+
+warn ["ingress without tls"] {
+  not input["something.yml"].spec.tls
+}
+
+expect ["something is an ingress"] {
+  input["something.yml"].kind == "Ingress"
+}
+
+expect ["something has a kind"] {
+  input["something.yml"].kind
+}
@@ -0,0 +1,13 @@
+package main
+
+metadata := {
+  "ingress has tls": {"description": "ingresses must terminate tls", "team": "networking"},
+}
+
+expect ["ingress has tls"] {
+  input["something.yml"].spec.tls
+}
+
+expect ["ingress is rendered"] {
+  input["something.yml"].kind == "Ingress"
+}
@@ -0,0 +1,13 @@
+package main
+
+metadata := {
+  "ingress without tls": {"enforcement": "dryrun", "description": "ingresses should terminate tls"},
+}
+
+deny ["ingress without tls"] {
+  not input["something.yml"].spec.tls
+}
+
+expect ["something is an ingress"] {
+  input["something.yml"].kind == "Ingress"
+}
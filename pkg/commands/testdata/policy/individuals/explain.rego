@@ -0,0 +1,25 @@
+package main
+
+settings := input["configmap.yaml"][0].data
+
+expect ["explains the comparison which failed"] {
+  settings.replicas == "1"
+  settings.replicas == "3"
+}
+
+expect ["explains references which are undefined"] {
+  settings.replicas
+  settings.missing == "value"
+}
+
+expect ["explains values of secrets redacted"] {
+  settings.password == "letmein"
+}
+
+deny ["deny rules are not explained"] {
+  settings.replicas == "1"
+}
+
+expect ["explains negated expressions"] {
+  not settings.replicas == "1"
+}
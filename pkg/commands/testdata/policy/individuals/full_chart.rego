@@ -0,0 +1,31 @@
+package main
+
+expect ["the chart's metadata and named templates are used"] {
+  deployment := input["deployment.yaml"]
+  deployment.metadata.name == "hcunit-name-fullchart"
+  deployment.metadata.labels["helm.sh/chart"] == "fullchart-0.1.0"
+  deployment.spec.template.spec.containers[0].image == "example/web:1.4.2"
+}
+
+expect ["the chart's files are available"] {
+  input["configmap.yaml"].data["app.conf"] == "listen = 0.0.0.0:8080\n"
+}
+
+expect ["the chart's values apply unless overridden"] {
+  replicas := object_default(input.values, "replicas", 2)
+  input["deployment.yaml"].spec.replicas == replicas
+}
+
+expect ["subcharts get their values scoped by name"] {
+  port := object_default(object_default(input.values, "db", {}), "port", 5432)
+  input["db/service.yaml"].metadata.name == "hcunit-name-db"
+  input["db/service.yaml"].spec.ports[0].port == port
+}
+
+object_default(obj, key, default_value) = value {
+  value := obj[key]
+}
+
+object_default(obj, key, default_value) = default_value {
+  not obj[key]
+}
@@ -0,0 +1,30 @@
+package main
+
+expect ["migrations run before the deploy hook"] {
+  migrate := input.hooks.upgrade[_]
+  migrate.name == "hcunit-name-migrate"
+  deploy := input.hooks.upgrade[_]
+  deploy.name == "hcunit-name-deploy"
+  migrate.order < deploy.order
+}
+
+expect ["install runs pre-install hooks, the release, then post-install hooks by weight and name"] {
+  steps := [[s.phase, s.ref] | s := input.hooks.install[_]]
+  steps == [
+    ["pre-install", "Job/hcunit-name-migrate"],
+    ["release", "release"],
+    ["post-install", "Job/hcunit-name-cache-warm"],
+    ["post-install", "Job/hcunit-name-seed"],
+  ]
+}
+
+expect ["hook weights and delete policies are parsed"] {
+  migrate := input.hooks.install[0]
+  migrate.weight == -5
+  migrate.deletePolicies == ["before-hook-creation", "hook-succeeded"]
+  migrate.template == "jobs.yaml"
+}
+
+expect ["test hooks are planned"] {
+  input.hooks.test[0].ref == "Pod/hcunit-name-test-connection"
+}
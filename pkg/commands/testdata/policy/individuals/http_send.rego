@@ -0,0 +1,6 @@
+package main
+
+expect ["the approved registries can be fetched"] {
+  resp := http.send({"method": "get", "url": "https://registry.example.com/approved"})
+  resp.status_code == 200
+}
@@ -0,0 +1,5 @@
+package main
+
+expect ["kube version should be in input"] {
+  input.kubeVersion == "v1.29.0"
+}
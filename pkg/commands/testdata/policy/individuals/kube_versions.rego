@@ -0,0 +1,5 @@
+package main
+
+expect ["ingress should use the GA networking api"] {
+  input["ingress.yaml"].apiVersion == "networking.k8s.io/v1"
+}
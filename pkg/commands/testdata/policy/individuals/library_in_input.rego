@@ -0,0 +1,6 @@
+package main
+
+expect ["named templates from library charts should render"] {
+  input["configmap.yaml"].kind == "ConfigMap"
+  input["configmap.yaml"].data.greeting == "hello"
+}
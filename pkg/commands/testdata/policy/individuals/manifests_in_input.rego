@@ -0,0 +1,21 @@
+package main
+
+expect ["manifests are keyed by their file name"] {
+  input["networkpolicy.json"].kind == "NetworkPolicy"
+  input["service.yaml"][1].kind == "ConfigMap"
+}
+
+expect ["manifests sharing a name are joined as documents"] {
+  names := {d.metadata.name | d := input["deployment.yaml"][_]}
+  names == {"app", "worker"}
+}
+
+expect ["hidden directories and other files are skipped"] {
+  not input["ci.yml"]
+  not input["README.md"]
+}
+
+expect ["input models are built from manifests"] {
+  refs := {p.ref | p := input.podspecs[_]}
+  refs == {"Deployment/app", "Deployment/worker"}
+}
@@ -0,0 +1,5 @@
+package main
+
+deny ["images are pinned to a version"] {
+  endswith(input.podspecs[_].containers[_].image, ":latest")
+}
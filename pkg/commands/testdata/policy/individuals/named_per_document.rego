@@ -0,0 +1,7 @@
+package main
+
+metadata := {"objects are named": {"per_document": true}}
+
+expect ["objects are named"] {
+  input.document.metadata.name
+}
@@ -0,0 +1,22 @@
+package main
+
+expect ["every workload is selected by a network policy"] {
+  unselected := [w | w := input.network.workloads[_]; count(w.policies) == 0]
+  count(unselected) == 0
+}
+
+expect ["no policy allows egress to the world"] {
+  not world_egress
+}
+
+expect ["services are joined with the workloads they select"] {
+  input.network.services[_].workloads[_] == "Deployment/hcunit-name-web"
+}
+
+world_egress {
+  input.network.policies[_].egressCIDRs[_] == "0.0.0.0/0"
+}
+
+world_egress {
+  input.network.policies[_].allowsAllEgress
+}
@@ -3,6 +3,6 @@ package main
 allow ["input object should provide values in hash"] {
   input["values"]
 }
-deny ["input object should provide values in hash"] {
+violation ["input object should provide values in hash"] {
   input["values"]
 }
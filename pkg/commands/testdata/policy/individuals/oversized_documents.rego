@@ -0,0 +1,21 @@
+package main
+
+expect ["oversized documents keep what identifies them"] {
+  firmware := input["firmware.yaml"][1]
+  firmware.kind == "ConfigMap"
+  firmware.metadata.name == "hcunit-name-firmware"
+  firmware.metadata.labels.app == "flasher"
+  firmware.hcunitOversized.size > 8192
+  count(firmware.hcunitOversized.sha256) == 64
+  not firmware.binaryData
+
+  manifest := input["manifest.json"]
+  manifest.metadata.name == "hcunit-name-manifest"
+  manifest.hcunitOversized.size > 8192
+}
+
+expect ["documents within the limit are left alone"] {
+  account := input["firmware.yaml"][0]
+  account.kind == "ServiceAccount"
+  not account.hcunitOversized
+}
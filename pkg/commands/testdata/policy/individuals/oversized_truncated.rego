@@ -0,0 +1,8 @@
+package main
+
+expect ["truncated documents keep their first bytes"] {
+  oversized := input["firmware.yaml"][1].hcunitOversized
+  oversized.strategy == "truncate"
+  startswith(oversized.content, "apiVersion: v1\nkind: ConfigMap")
+  endswith(oversized.content, "bytes kept]")
+}
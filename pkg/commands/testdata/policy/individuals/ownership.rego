@@ -0,0 +1,19 @@
+package main
+
+metadata := {
+  "ingress has tls": {"team": "networking", "owner": "alice"},
+  "ingress has a release label": {"team": "platform"},
+  "ingress is an extension": {"owner": "bob"},
+}
+
+expect ["ingress has tls"] {
+  input["something.yml"].spec.tls
+}
+
+expect ["ingress has a release label"] {
+  input["something.yml"].metadata.labels.release
+}
+
+expect ["ingress is an extension"] {
+  input["something.yml"].apiVersion == "apps/v1"
+}
@@ -0,0 +1,21 @@
+package main
+
+params := {
+  "required label": ["release", "heritage"],
+  "required label values": [
+    {"label": "release", "value": "hcunit-name"},
+    {"label": "heritage", "value": "Tiller"}
+  ]
+}
+
+expect ["required label"] {
+  input["something.yml"].metadata.labels[input.param]
+}
+
+expect ["required label values"] {
+  input["something.yml"].metadata.labels[input.param.label] == input.param.value
+}
+
+expect ["unparametrized rules still run once"] {
+  input["something.yml"]
+}
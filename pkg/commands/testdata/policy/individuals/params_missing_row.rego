@@ -0,0 +1,7 @@
+package main
+
+params := {"required label": ["release", "cost-center"]}
+
+expect ["required label"] {
+  input["something.yml"].metadata.labels[input.param]
+}
@@ -0,0 +1,25 @@
+package main
+
+data_keys := input["configmap.yaml"].data
+
+expect ["yaml payloads can be parsed"] {
+  config := hcunit.parse_config("prometheus.yml", data_keys["prometheus.yml"])
+  config.global.scrape_interval == "15s"
+}
+
+expect ["toml payloads can be parsed"] {
+  config := hcunit.parse_toml(data_keys["app.toml"])
+  config.server.port == 8500
+}
+
+expect ["ini payloads can be parsed"] {
+  config := hcunit.parse_ini(data_keys["app.ini"])
+  config.mode == "production"
+  config.database.host == "db.hcunit-namespace"
+}
+
+expect ["properties payloads can be parsed"] {
+  config := hcunit.parse_config("app.properties", data_keys["app.properties"])
+  config["server.port"] == "8500"
+  config.greeting == "hello world"
+}
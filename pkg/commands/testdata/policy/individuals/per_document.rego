@@ -0,0 +1,7 @@
+package main
+
+metadata := {"objects carry the cost-center label": {"per_document": true}}
+
+expect ["objects carry the cost-center label"] {
+  input.document.metadata.labels["cost-center"]
+}
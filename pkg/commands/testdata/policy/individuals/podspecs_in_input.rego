@@ -0,0 +1,34 @@
+package main
+
+expect ["every workload kind is normalized"] {
+  kinds := {p.kind | p := input.podspecs[_]}
+  kinds == {"Pod", "StatefulSet", "DaemonSet", "CronJob", "Job"}
+}
+
+expect ["containers are found whatever the workload kind"] {
+  images := {c.image | c := input.podspecs[_].containers[_]; c.containerType == "container"}
+  images == {"busybox:1.31", "postgres:11", "fluentd:v1.7", "migrate:v4"}
+}
+
+expect ["init and ephemeral containers are tagged with their type"] {
+  debug := input.podspecs[_]
+  debug.ref == "Pod/hcunit-name-debug"
+  containers := [[c.name, c.containerType] | c := debug.containers[_]]
+  containers == [["wait", "init"], ["shell", "container"], ["debugger", "ephemeral"]]
+}
+
+expect ["rendered templates are left untagged"] {
+  not input["pod.yml"].spec.containers[0].containerType
+}
+
+expect ["pod template labels and service accounts are normalized"] {
+  backup := input.podspecs[_]
+  backup.ref == "CronJob/hcunit-name-backup"
+  backup.labels.app == "backup"
+  backup.serviceAccountName == "default"
+  backup.spec.restartPolicy == "OnFailure"
+
+  debug := input.podspecs[_]
+  debug.ref == "Pod/hcunit-name-debug"
+  debug.serviceAccountName == "debugger"
+}
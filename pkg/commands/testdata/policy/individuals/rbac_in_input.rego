@@ -0,0 +1,22 @@
+package main
+
+expect ["service accounts are not bound to cluster-admin"] {
+  not cluster_admin
+}
+
+expect ["service accounts have no wildcard verbs"] {
+  wildcards := [p | p := input.rbac.subjects[_].permissions[_]; p.verb == "*"]
+  count(wildcards) == 0
+}
+
+expect ["web service account can read configmaps"] {
+  rbac.allows("ServiceAccount/hcunit-name-web", "get", "configmaps")
+}
+
+expect ["web service account cannot delete configmaps"] {
+  not rbac.allows("ServiceAccount/hcunit-name-web", "delete", "configmaps")
+}
+
+cluster_admin {
+  input.rbac.subjects[_].roles[_] == "ClusterRole/cluster-admin"
+}
@@ -0,0 +1,16 @@
+package main
+
+expect ["same named roles of different namespaces stay apart"] {
+  rbac.allows("ServiceAccount/team-a/app", "get", "configmaps")
+  not rbac.allows("ServiceAccount/team-a/app", "delete", "configmaps")
+}
+
+expect ["rules scoped to resource names don't allow the whole resource"] {
+  not rbac.allows("ServiceAccount/team-b/app", "get", "configmaps")
+  input.rbac.subjects[_].permissions[_].resourceNames == ["settings"]
+}
+
+expect ["rules only allow resources of their api groups"] {
+  rbac.allows("ServiceAccount/team-a/app", "create", "deployments.apps")
+  not rbac.allows("ServiceAccount/team-a/app", "create", "deployments.extensions")
+}
@@ -0,0 +1,16 @@
+package main
+
+metadata := {"services of the payments namespace are named after the release": {"applies_to": {"kinds": ["Service"], "namespaces": ["payments"]}}}
+
+expect ["upgrades render the migration job"] {
+  input.release == {"name": "checkout", "namespace": "payments", "isUpgrade": true, "isInstall": false, "revision": 2}
+  input["job.yaml"].metadata.name == "checkout-migrate"
+}
+
+expect ["the monitor renders when its api version is served"] {
+  input["monitor.yaml"].metadata.namespace == "payments"
+}
+
+expect ["services of the payments namespace are named after the release"] {
+  input.document.metadata.name == "checkout"
+}
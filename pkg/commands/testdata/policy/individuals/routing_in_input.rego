@@ -0,0 +1,31 @@
+package main
+
+expect ["hosts of every routing api are inventoried"] {
+  input.routing.hosts == ["*", "admin.example.com", "api.example.com", "shop.example.org", "status.example.com", "www.example.com"]
+}
+
+expect ["hosts without tls are found"] {
+  plain := {r.host | r := input.routing.routes[_]; not r.tls}
+  plain == {"*", "admin.example.com", "status.example.com"}
+}
+
+expect ["tls secrets are resolved from ingresses and gateways"] {
+  secrets := {[r.host, s] | r := input.routing.routes[_]; s := r.tlsSecrets[_]}
+  secrets == {["www.example.com", "www-tls"], ["api.example.com", "wildcard-tls"], ["shop.example.org", "shop-tls"]}
+}
+
+expect ["paths and backends are aggregated"] {
+  admin := input.routing.routes[_]
+  admin.host == "admin.example.com"
+  admin.paths == ["/admin"]
+  admin.backends == ["admin:http"]
+
+  shop := input.routing.routes[_]
+  shop.ref == "VirtualService/hcunit-name-shop"
+  shop.paths == ["/cart", "/"]
+  shop.backends == ["cart:9090"]
+
+  legacy := input.routing.routes[_]
+  legacy.host == "*"
+  legacy.backends == ["legacy:8080"]
+}
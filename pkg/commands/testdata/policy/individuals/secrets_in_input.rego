@@ -0,0 +1,5 @@
+package main
+
+expect ["policies should see the real secret values"] {
+  input["secret.yaml"][0].stringData.token == "tok-0123456789abcdef"
+}
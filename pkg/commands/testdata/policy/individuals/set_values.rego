@@ -0,0 +1,25 @@
+package main
+
+settings := input["configmap.yaml"].data
+
+expect ["--set overrides the values files with typed values"] {
+  settings.replicas == "3"
+  settings.replicasType == "float64"
+  input.values.image.pullPolicy == "Always"
+  settings.firstHost == "a.example.com"
+}
+
+expect ["--set-string keeps values strings"] {
+  settings.tag == "1.10"
+  settings.tagType == "string"
+}
+
+expect ["--set-string keeps yaml 1.1 booleans strings"] {
+  settings.country == "off"
+  settings.countryType == "string"
+  input.values.country == "off"
+}
+
+expect ["--set-file sets values to the content of files"] {
+  settings["app.conf"] == "listen 8080\n"
+}
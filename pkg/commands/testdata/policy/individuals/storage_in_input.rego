@@ -0,0 +1,33 @@
+package main
+
+expect ["read write many claims are found"] {
+  rwx := {c.ref | c := input.storage.claims[_]; c.accessModes[_] == "ReadWriteMany"}
+  rwx == {"PersistentVolumeClaim/hcunit-name-uploads"}
+}
+
+expect ["sizes are parsed into bytes"] {
+  uploads := input.storage.claims[_]
+  uploads.name == "hcunit-name-uploads"
+  uploads.size == "1.5Gi"
+  uploads.bytes == 1610612736
+
+  cache := input.storage.claims[_]
+  cache.name == "hcunit-name-cache"
+  cache.bytes == 500000000
+  cache.storageClass == "fast"
+}
+
+expect ["claim templates are claimed once per replica"] {
+  data := input.storage.claims[_]
+  data.ref == "StatefulSet/hcunit-name-db/data"
+  data.workload == "StatefulSet/hcunit-name-db"
+  data.totalBytes == 3 * 10737418240
+}
+
+expect ["the total size is within quota"] {
+  sum([c.totalBytes | c := input.storage.claims[_]]) < 40 * 1073741824
+}
+
+expect ["storage classes are found with their defaults"] {
+  input.storage.storageClasses == [{"name": "fast", "provisioner": "kubernetes.io/gce-pd", "reclaimPolicy": "Delete", "allowVolumeExpansion": true, "default": true}]
+}
@@ -0,0 +1,7 @@
+package main
+
+expect ["helm test hooks should be kept apart from the chart templates"] {
+  input.tests["test-connection.yaml"].kind == "Pod"
+  not input["test-connection.yaml"]
+  input["deployment.yaml"].kind == "Deployment"
+}
@@ -0,0 +1,6 @@
+package main
+
+expect ["helm test hooks should be evaluated with the chart when included"] {
+  input["test-connection.yaml"].kind == "Pod"
+  input.tests["test-connection.yaml"].kind == "Pod"
+}
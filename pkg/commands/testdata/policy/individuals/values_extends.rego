@@ -0,0 +1,14 @@
+package main
+
+expect ["values files are layered over the files they extend"] {
+  input.values.replicas == 3
+  input.values.image == {"tag": "1.0", "pullPolicy": "Always"}
+  input.values.hosts == ["eu.example.com"]
+  input.values.config == "regional"
+  not input.values.extends
+}
+
+expect ["the layered values are rendered"] {
+  input["configmap.yaml"].data.tag == "1.0"
+  input["configmap.yaml"].data.firstHost == "eu.example.com"
+}
@@ -0,0 +1,7 @@
+package main
+
+metadata := {"ingress without tls": {"description": "serve ingress traffic over tls"}}
+
+warn ["ingress without tls"] {
+  not input["something.yml"].spec.tls
+}
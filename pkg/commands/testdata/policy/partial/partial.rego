@@ -0,0 +1,24 @@
+package main
+
+ingress_enabled {
+  input.values.uiIngress.enabled
+}
+
+expect ["the ui ingress is rendered for the component"] {
+  input["something.yml"].kind == "Ingress"
+  input.values.Component == "hcunitcomp"
+}
+
+deny ["the ui ingress is enabled without tls"] {
+  ingress_enabled
+  not input["something.yml"].spec.tls
+}
+
+expect ["the ingress api is served by the kubernetes version"] {
+  input.kubeVersion != "v1.22.0"
+}
+
+expect ["every rendered template has a kind"] {
+  kinds := [name | input[name].kind]
+  count(kinds) > 0
+}
@@ -0,0 +1,6 @@
+package main
+
+expect ["objects are indexed by kind"] {
+  count(input.byKind.Ingress) == 1
+  input.byKind.Service[0].spec.type == "ClusterIP"
+}
@@ -0,0 +1,8 @@
+package main
+
+expect ["embedded configs are parsed into parsedData"] {
+  parsed := input["configmap.yaml"].parsedData
+  parsed["prometheus.yml"].global.scrape_interval == "15s"
+  parsed["app.toml"].server.port == 8500
+  parsed["app.properties"]["server.port"] == "8500"
+}
@@ -0,0 +1,11 @@
+package main
+
+expect ["secret values are redacted"] {
+  secret := input["secret.yaml"][0]
+  secret.data.password == "<redacted>"
+  secret.stringData.token == "<redacted>"
+}
+
+expect ["other objects are left as rendered"] {
+  input["secret.yaml"][1].data.user == "admin"
+}
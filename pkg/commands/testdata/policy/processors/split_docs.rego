@@ -0,0 +1,6 @@
+package main
+
+expect ["templates rendering one document are lists too"] {
+  input["something.yml"][0].kind == "Ingress"
+  input["something_else.yml"][0].kind == "Service"
+}
@@ -0,0 +1,6 @@
+package main
+
+expect ["the rendered objects are counted"] {
+  input.summary.objects == 2
+  input.summary.kinds == {"Ingress": 1, "Service": 1}
+}
@@ -0,0 +1,19 @@
+package main
+
+metadata := {"configmaps are owned by the umbrella": {"per_document": true}}
+
+deny["frontend images must be pinned"] {
+  endswith(input["frontend/deployment.yaml"].spec.template.spec.containers[_].image, ":latest")
+}
+
+expect["configmaps are owned by the umbrella"] {
+  owned_by_umbrella
+}
+
+owned_by_umbrella {
+  input.document.kind != "ConfigMap"
+}
+
+owned_by_umbrella {
+  input.document.data.owner == "umbrella"
+}
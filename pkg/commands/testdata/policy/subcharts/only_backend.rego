@@ -0,0 +1,9 @@
+package main
+
+expect["only the backend and its subcharts are evaluated"] {
+  input.subcharts == {
+    "backend": ["backend/configmap.yaml"],
+    "backend/db": ["backend/db/service.yaml"],
+  }
+  not input["configmap.yaml"]
+}
@@ -0,0 +1,9 @@
+package main
+
+deny["frontend images must be pinned"] {
+  endswith(input["frontend/deployment.yaml"].spec.template.spec.containers[_].image, ":latest")
+}
+
+expect["the backend gets the globals"] {
+  input["backend/configmap.yaml"].data.env == "prod"
+}
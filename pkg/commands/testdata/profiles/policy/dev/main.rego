@@ -0,0 +1,8 @@
+package main
+
+deployment := input["deployment.yaml"]
+
+expect ["development releases go to the dev namespace"] {
+  deployment.metadata.namespace == "dev"
+  deployment.metadata.name == "app-dev"
+}
@@ -0,0 +1,11 @@
+package prod
+
+deployment := input["deployment.yaml"]
+
+expect ["production upgrades run highly available on a pinned image"] {
+  input.release.isUpgrade
+  deployment.metadata.namespace == "prod"
+  deployment.metadata.labels.kube == "v1.29.0"
+  deployment.spec.replicas >= 3
+  deployment.spec.template.spec.containers[_].image == "app:1.0"
+}
@@ -0,0 +1,3 @@
+{{- define "secondpass.summary" -}}
+{{ .Release.Name }} alerts for {{ .Values.team }}
+{{- end -}}
@@ -0,0 +1,3 @@
+{{- define "tplvalues.suffix" -}}
+api
+{{- end -}}
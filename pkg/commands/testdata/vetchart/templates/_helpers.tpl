@@ -0,0 +1,3 @@
+{{- define "vetchart.name" -}}
+{{ .Release.Name }}-vet
+{{- end -}}
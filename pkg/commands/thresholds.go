@@ -0,0 +1,74 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mitchellh/colorstring"
+)
+
+var ThresholdExceeded = errors.New("policy results exceed the configured thresholds")
+
+// gated - true when the run is gated on thresholds instead of failing on
+// any failed rule
+func (s *EvalCommand) gated() bool {
+	return s.MaxFailures != nil || s.MaxWarnings != nil || s.MinScore > 0
+}
+
+// complianceScore - the percentage of enforced rules which didn't fail.
+// Warnings count as compliant, dry run rules don't count and a run without
+// rules is fully compliant
+func complianceScore(summary AuditSummary) float64 {
+	total := summary.Passed + summary.Failed + summary.Warned
+	if total == 0 {
+		return 100
+	}
+	return float64(summary.Passed+summary.Warned) * 100 / float64(total)
+}
+
+// applyThresholds - gates a run on --max-failures, --max-warnings and
+// --min-score when any is given: failed rules within the thresholds are
+// tolerated, warnings and the score beyond them fail the run. Errors other
+// than policy violations are returned untouched
+func (s *EvalCommand) applyThresholds(writer io.Writer, results []RuleResult, err error) error {
+	var violation *ViolationError
+	if !s.gated() || (err != nil && !errors.As(err, &violation)) {
+		return err
+	}
+
+	summary := summarizeResults(results)
+	score := complianceScore(summary)
+	colorstring.Fprint(writer, "[bold]"+s.messages.text("score")+": ")
+	fmt.Fprintln(writer, s.messages.text("score_summary", score, summary.Passed, summary.Failed, summary.Warned))
+
+	exceeded := make([]string, 0)
+	if s.MaxFailures != nil && summary.Failed > *s.MaxFailures {
+		exceeded = append(exceeded, s.messages.text("max_failures_exceeded", summary.Failed, *s.MaxFailures))
+	} else if s.MaxFailures == nil && s.MinScore == 0 && summary.Failed > 0 {
+		exceeded = append(exceeded, s.messages.text("failures_untolerated", summary.Failed))
+	}
+
+	if s.MaxWarnings != nil && summary.Warned > *s.MaxWarnings {
+		exceeded = append(exceeded, s.messages.text("max_warnings_exceeded", summary.Warned, *s.MaxWarnings))
+	}
+
+	if score < s.MinScore {
+		exceeded = append(exceeded, s.messages.text("min_score_missed", score, s.MinScore))
+	}
+
+	for _, reason := range exceeded {
+		colorstring.Fprint(writer, "[red]"+s.messages.text("threshold")+": ")
+		fmt.Fprintln(writer, reason)
+	}
+
+	if len(exceeded) > 0 {
+		return fmt.Errorf("%w: %s", ThresholdExceeded, strings.Join(exceeded, ", "))
+	}
+
+	if summary.Failed > 0 {
+		colorstring.Fprintln(writer, "[yellow]"+s.messages.text("tolerated_summary"))
+	}
+	return nil
+}
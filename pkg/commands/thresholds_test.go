@@ -0,0 +1,86 @@
+package commands_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestEvalThresholds(t *testing.T) {
+	intPtr := func(i int) *int { return &i }
+	for _, tt := range []struct {
+		name        string
+		policy      string
+		maxFailures *int
+		maxWarnings *int
+		minScore    float64
+		failsWith   error
+		contains    string
+	}{
+		{
+			name:        "failures within --max-failures are tolerated",
+			policy:      "testdata/policy/failing",
+			maxFailures: intPtr(4),
+			failsWith:   nil,
+			contains:    "SCORE: \x1b[0m50.0% compliant (4 passed, 4 failed, 0 warned)",
+		},
+		{
+			name:        "failures beyond --max-failures fail the run",
+			policy:      "testdata/policy/failing",
+			maxFailures: intPtr(3),
+			failsWith:   commands.ThresholdExceeded,
+			contains:    "4 failures exceed --max-failures 3",
+		},
+		{
+			name:      "a score above --min-score tolerates failures",
+			policy:    "testdata/policy/failing",
+			minScore:  50,
+			failsWith: nil,
+		},
+		{
+			name:      "a score below --min-score fails the run",
+			policy:    "testdata/policy/failing",
+			minScore:  90,
+			failsWith: commands.ThresholdExceeded,
+			contains:  "score 50.0% is below --min-score 90.0%",
+		},
+		{
+			name:        "warnings beyond --max-warnings fail the run",
+			policy:      "testdata/policy/individuals/warn_ingress.rego",
+			maxWarnings: intPtr(0),
+			failsWith:   commands.ThresholdExceeded,
+			contains:    "1 warnings exceed --max-warnings 0",
+		},
+		{
+			name:        "--max-warnings alone does not tolerate failures",
+			policy:      "testdata/policy/failing",
+			maxWarnings: intPtr(10),
+			failsWith:   commands.ThresholdExceeded,
+			contains:    "4 failures, give --max-failures or --min-score to tolerate them",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			stdOut := new(bytes.Buffer)
+			evalCmd := &commands.EvalCommand{
+				Stdout:      stdOut,
+				Template:    "testdata/templates",
+				Values:      []string{"testdata/values.yml"},
+				Policy:      []string{tt.policy},
+				MaxFailures: tt.maxFailures,
+				MaxWarnings: tt.maxWarnings,
+				MinScore:    tt.minScore,
+			}
+			err := evalCmd.Execute([]string{})
+			if !errors.Is(err, tt.failsWith) {
+				t.Fatalf("expected error:\n%v\ngot:\n%v", tt.failsWith, err)
+			}
+
+			if !strings.Contains(stdOut.String(), tt.contains) {
+				t.Errorf("expected output to contain %q, got:\n%s", tt.contains, stdOut.String())
+			}
+		})
+	}
+}
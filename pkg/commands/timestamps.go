@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// the --timestamp-format of the times in reports
+const (
+	timestampRFC3339     = "rfc3339"
+	timestampRFC3339Nano = "rfc3339nano"
+	timestampUnix        = "unix"
+	timestampUnixMilli   = "unix-ms"
+)
+
+var InvalidTimestampFormat = errors.New("invalid --timestamp-format")
+
+// Timestamp - a time in a report (results, audit log, attestation), always
+// in UTC and encoded in the --timestamp-format of its run, RFC3339 by
+// default, so reports don't depend on the timezone of the agent producing
+// them. Any of the formats is decoded
+type Timestamp struct {
+	time.Time
+	format string
+}
+
+func newTimestamp(t time.Time, format string) Timestamp {
+	return Timestamp{Time: t.UTC(), format: format}
+}
+
+// validateTimestampFormat - fails any format but the ones we encode
+func validateTimestampFormat(format string) error {
+	switch format {
+	case "", timestampRFC3339, timestampRFC3339Nano, timestampUnix, timestampUnixMilli:
+		return nil
+	}
+	return fmt.Errorf("%w: %q is not one of rfc3339, rfc3339nano, unix, unix-ms", InvalidTimestampFormat, format)
+}
+
+func (s Timestamp) MarshalJSON() ([]byte, error) {
+	t := s.Time.UTC()
+	switch s.format {
+	case timestampUnix:
+		return []byte(strconv.FormatInt(t.Unix(), 10)), nil
+	case timestampUnixMilli:
+		return []byte(strconv.FormatInt(t.UnixNano()/int64(time.Millisecond), 10)), nil
+	case timestampRFC3339Nano:
+		return json.Marshal(t.Format(time.RFC3339Nano))
+	}
+	return json.Marshal(t.Format(time.RFC3339))
+}
+
+// UnmarshalJSON - decodes RFC3339 strings with any offset, and unix times
+// in seconds or, when too large to be seconds, milliseconds
+func (s *Timestamp) UnmarshalJSON(b []byte) error {
+	var text string
+	if err := json.Unmarshal(b, &text); err == nil {
+		t, err := time.Parse(time.RFC3339Nano, text)
+		if err != nil {
+			return err
+		}
+		*s = newTimestamp(t, timestampRFC3339)
+		return nil
+	}
+
+	n, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil {
+		return fmt.Errorf("%s is neither an RFC3339 nor a unix time", b)
+	}
+
+	if n >= 1e11 || n <= -1e11 {
+		*s = newTimestamp(time.Unix(0, n*int64(time.Millisecond)), timestampUnixMilli)
+		return nil
+	}
+	*s = newTimestamp(time.Unix(n, 0), timestampUnix)
+	return nil
+}
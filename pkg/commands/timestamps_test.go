@@ -0,0 +1,109 @@
+package commands_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestTimestampFormat(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hcunit-timestamps")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the agent's timezone must not leak into reports
+	local := time.Local
+	time.Local = time.FixedZone("UTC+9", 9*60*60)
+	defer func() { time.Local = local }()
+
+	for _, tt := range []struct {
+		name      string
+		format    string
+		failsWith error
+		matches   *regexp.Regexp
+	}{
+		{
+			name:    "reports default to RFC3339 in UTC",
+			matches: regexp.MustCompile(`^"\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}Z"$`),
+		},
+		{
+			name:    "fractional seconds are kept with rfc3339nano",
+			format:  "rfc3339nano",
+			matches: regexp.MustCompile(`^"\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?Z"$`),
+		},
+		{
+			name:    "unix seconds",
+			format:  "unix",
+			matches: regexp.MustCompile(`^\d{10}$`),
+		},
+		{
+			name:    "unix milliseconds",
+			format:  "unix-ms",
+			matches: regexp.MustCompile(`^\d{13}$`),
+		},
+		{
+			name:      "unknown formats are rejected",
+			format:    "%Y-%m-%d",
+			failsWith: commands.InvalidTimestampFormat,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			resultsPath := filepath.Join(dir, "results.json")
+			auditPath := filepath.Join(dir, "audit.jsonl")
+			os.Remove(auditPath)
+			before := time.Now().Truncate(time.Second)
+			evalCmd := &commands.EvalCommand{
+				Stdout:          ioutil.Discard,
+				Template:        "testdata/templates",
+				Values:          []string{"testdata/values.yml"},
+				Policy:          []string{"testdata/policy/passing"},
+				ResultsFile:     resultsPath,
+				AuditLog:        auditPath,
+				TimestampFormat: tt.format,
+			}
+			err := evalCmd.Execute([]string{})
+			if tt.failsWith == nil && err != nil || !errors.Is(err, tt.failsWith) {
+				t.Fatalf("expected error:\n%v\ngot:\n%v", tt.failsWith, err)
+			}
+
+			if tt.failsWith != nil {
+				return
+			}
+
+			for _, path := range []string{resultsPath, auditPath} {
+				b, err := ioutil.ReadFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				fields := make(map[string]json.RawMessage)
+				if err := json.Unmarshal(b, &fields); err != nil {
+					t.Fatal(err)
+				}
+
+				if !tt.matches.Match(fields["timestamp"]) {
+					t.Errorf("expected the timestamp of %s to match %s, got %s", filepath.Base(path), tt.matches, fields["timestamp"])
+				}
+			}
+
+			results := commands.Results{}
+			b, _ := ioutil.ReadFile(resultsPath)
+			if err := json.Unmarshal(b, &results); err != nil {
+				t.Fatal(err)
+			}
+
+			if results.Timestamp.Location() != time.UTC || results.Timestamp.Before(before) || time.Since(results.Timestamp.Time) > time.Minute {
+				t.Errorf("expected the results to decode to the time of the run in UTC, got %v", results.Timestamp)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/engine"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+)
+
+const tplValuesPrefix = "hcunit-values/"
+
+// tplValues - renders the go template expressions of string values, e.g.
+// fullname: "{{ .Release.Name }}-api", in place with the release, chart and
+// capabilities of the render context, the way helmfile and tpl do. The
+// expressions see the values as given and can include the partials of the
+// chart
+func tplValues(values map[string]interface{}, partials []*chart.Template, context chartutil.Values) error {
+	templates := append([]*chart.Template{}, partials...)
+	setters := make(map[string]func(string))
+	collectTplValues(values, func(value string, set func(string)) {
+		name := fmt.Sprintf("%s%d", tplValuesPrefix, len(setters))
+		templates = append(templates, &chart.Template{Name: name, Data: []byte(value)})
+		setters[name] = set
+	})
+
+	if len(setters) == 0 {
+		return nil
+	}
+
+	metadata, _ := context["Chart"].(*chart.Metadata)
+	valuesChart := &chart.Chart{Metadata: metadata, Templates: templates}
+	rendered, err := engine.New().Render(valuesChart, context)
+	if err != nil {
+		return fmt.Errorf("rendering the templates in values failed: %w", err)
+	}
+
+	for name, set := range setters {
+		set(rendered[path.Join(metadata.GetName(), name)])
+	}
+	return nil
+}
+
+// collectTplValues - calls found with every string value holding a template
+// expression, and a func replacing it
+func collectTplValues(value interface{}, found func(value string, set func(string))) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, e := range v {
+			if s, ok := e.(string); ok && strings.Contains(s, "{{") {
+				key := key
+				found(s, func(rendered string) { v[key] = rendered })
+				continue
+			}
+			collectTplValues(e, found)
+		}
+	case []interface{}:
+		for i, e := range v {
+			if s, ok := e.(string); ok && strings.Contains(s, "{{") {
+				i := i
+				found(s, func(rendered string) { v[i] = rendered })
+				continue
+			}
+			collectTplValues(e, found)
+		}
+	}
+}
+
+// partialTemplates - the partials (_*.tpl) among the chart's templates
+func partialTemplates(templates []*chart.Template) []*chart.Template {
+	partials := make([]*chart.Template, 0)
+	for _, template := range templates {
+		if strings.HasPrefix(path.Base(template.Name), "_") {
+			partials = append(partials, template)
+		}
+	}
+	return partials
+}
@@ -0,0 +1,55 @@
+package commands_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestRenderTplValues(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		tplValues bool
+		expected  []string
+	}{
+		{
+			name:      "templates in values are rendered with the release and chart context",
+			tplValues: true,
+			expected: []string{
+				"name: hcunit-name-api",
+				// values referenced by templates in values are seen as given
+				`host: "{{ .Release.Name }}-{{ include \"tplvalues.suffix\" . }}.hcunit-namespace.svc"`,
+				`hosts: "hcunit.example.com,static.example.com"`,
+				`plain: "no templates here"`,
+			},
+		},
+		{
+			name: "templates in values are kept as is without --tpl-values",
+			expected: []string{
+				`name: {{ .Release.Name }}-{{ include "tplvalues.suffix" . }}`,
+				`hosts: "{{ .Chart.Name }}.example.com,static.example.com"`,
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			stdOut := new(bytes.Buffer)
+			renderCmd := &commands.RenderCommand{
+				Writer:    stdOut,
+				Template:  "testdata/tplvalues/templates",
+				Values:    []string{"testdata/tpl_values.yml"},
+				TplValues: tt.tplValues,
+			}
+			if err := renderCmd.Execute([]string{}); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, line := range tt.expected {
+				if !strings.Contains(stdOut.String(), line) {
+					t.Errorf("expected the output to contain %q, got:\n%s", line, stdOut.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/mitchellh/colorstring"
+)
+
+var UpgradeHazards = errors.New("the head chart can't be upgraded to in place")
+
+// upgradeHazard - a change between chart versions which fails a helm
+// upgrade, or makes it delete and recreate an object
+type upgradeHazard struct {
+	Resource string
+	Reason   string
+}
+
+// immutableFields - fields the api server refuses to update, by kind
+var immutableFields = map[string][][]string{
+	"Deployment":            {{"spec", "selector"}},
+	"ReplicaSet":            {{"spec", "selector"}},
+	"DaemonSet":             {{"spec", "selector"}},
+	"StatefulSet":           {{"spec", "selector"}, {"spec", "volumeClaimTemplates"}, {"spec", "serviceName"}, {"spec", "podManagementPolicy"}},
+	"Job":                   {{"spec", "selector"}, {"spec", "template"}},
+	"Service":               {{"spec", "clusterIP"}},
+	"PersistentVolumeClaim": {{"spec", "storageClassName"}, {"spec", "accessModes"}, {"spec", "selector"}, {"spec", "volumeName"}},
+}
+
+// upgradeHazards - the changes of the compared objects which a helm upgrade
+// from base to head can't apply in place: immutable fields changing,
+// Services changing type, and objects renamed, which are deleted and
+// recreated
+func upgradeHazards(base, head map[string]map[string]interface{}, comparison ResourceComparison) []upgradeHazard {
+	hazards := make([]upgradeHazard, 0)
+	for _, key := range comparison.Changed {
+		before, after := base[key], head[key]
+		for _, field := range immutableFields[objectKind(after)] {
+			if !reflect.DeepEqual(getField(before, field...), getField(after, field...)) {
+				hazards = append(hazards, upgradeHazard{
+					Resource: key,
+					Reason:   fmt.Sprintf("%s changed, it is immutable so the upgrade fails", strings.Join(field, ".")),
+				})
+			}
+		}
+
+		if objectKind(after) == "Service" && serviceType(before) != serviceType(after) {
+			hazards = append(hazards, upgradeHazard{
+				Resource: key,
+				Reason:   fmt.Sprintf("spec.type changed from %s to %s, which reallocates its cluster ip or node ports", serviceType(before), serviceType(after)),
+			})
+		}
+	}
+
+	// an object removed along with one of the same kind added in its
+	// namespace is most likely renamed
+	added := append([]string{}, comparison.Added...)
+	for _, removed := range comparison.Removed {
+		for i, key := range added {
+			if objectKind(base[removed]) == objectKind(head[key]) && objectNamespace(base[removed]) == objectNamespace(head[key]) {
+				hazards = append(hazards, upgradeHazard{
+					Resource: removed,
+					Reason:   fmt.Sprintf("renamed to %s, the upgrade deletes it and creates a new one", key),
+				})
+				added = append(added[:i], added[i+1:]...)
+				break
+			}
+		}
+	}
+	return hazards
+}
+
+// serviceType - the type of a Service, which defaults to ClusterIP
+func serviceType(obj map[string]interface{}) string {
+	if t := getString(obj, "spec", "type"); t != "" {
+		return t
+	}
+	return "ClusterIP"
+}
+
+// reportUpgradeHazards - prints the hazards in their own upgrade-safety
+// category, returning UpgradeHazards when there are any
+func reportUpgradeHazards(writer io.Writer, hazards []upgradeHazard) error {
+	for _, hazard := range hazards {
+		colorstring.Fprint(writer, "[red]UPGRADE-SAFETY: ")
+		fmt.Fprintf(writer, "%s: %s\n", hazard.Resource, hazard.Reason)
+	}
+
+	if len(hazards) > 0 {
+		return fmt.Errorf("%w: %d upgrade hazards found", UpgradeHazards, len(hazards))
+	}
+	return nil
+}
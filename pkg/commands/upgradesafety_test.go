@@ -0,0 +1,62 @@
+package commands_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestUpgradeSafety(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		base       string
+		head       string
+		failsWith  error
+		expected   []string
+		unexpected []string
+	}{
+		{
+			name:      "changes a helm upgrade can't apply in place are reported",
+			base:      "testdata/upgradesafety/base",
+			head:      "testdata/upgradesafety/head",
+			failsWith: commands.UpgradeHazards,
+			expected: []string{
+				"UPGRADE-SAFETY: \x1b[0mStatefulSet/hcunit-name-db: spec.selector changed, it is immutable so the upgrade fails\n",
+				"UPGRADE-SAFETY: \x1b[0mStatefulSet/hcunit-name-db: spec.volumeClaimTemplates changed, it is immutable so the upgrade fails\n",
+				"UPGRADE-SAFETY: \x1b[0mService/hcunit-name-db: spec.type changed from ClusterIP to LoadBalancer, which reallocates its cluster ip or node ports\n",
+				"UPGRADE-SAFETY: \x1b[0mConfigMap/hcunit-name-settings: renamed to ConfigMap/hcunit-name-config, the upgrade deletes it and creates a new one\n",
+			},
+			unexpected: []string{"spec.serviceName"},
+		},
+		{
+			name:       "mutable changes are safe",
+			base:       "testdata/comparechart/base",
+			head:       "testdata/comparechart/head",
+			unexpected: []string{"UPGRADE-SAFETY"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			stdOut := new(bytes.Buffer)
+			cmd := &commands.CompareChartCommand{Writer: stdOut, Base: tt.base, Head: tt.head}
+			err := cmd.Execute([]string{})
+			if tt.failsWith == nil && err != nil || !errors.Is(err, tt.failsWith) {
+				t.Fatalf("expected error:\n%v\ngot:\n%v", tt.failsWith, err)
+			}
+
+			for _, expected := range tt.expected {
+				if !strings.Contains(stdOut.String(), expected) {
+					t.Errorf("expected output to contain %q, got:\n%s", expected, stdOut.String())
+				}
+			}
+
+			for _, unexpected := range tt.unexpected {
+				if strings.Contains(stdOut.String(), unexpected) {
+					t.Errorf("expected output not to contain %q, got:\n%s", unexpected, stdOut.String())
+				}
+			}
+		})
+	}
+}
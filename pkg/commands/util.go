@@ -2,7 +2,7 @@ package commands
 
 import (
 	"bytes"
-	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -10,44 +10,108 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
+	"time"
 
-	"github.com/golang/protobuf/ptypes/timestamp"
-	"k8s.io/helm/pkg/renderutil"
+	"github.com/Masterminds/semver"
 	"github.com/mitchellh/colorstring"
+	"github.com/open-policy-agent/opa/ast"
 	"github.com/open-policy-agent/opa/rego"
-	"github.com/open-policy-agent/opa/tester"
-	"github.com/open-policy-agent/opa/topdown"
 	yaml "gopkg.in/yaml.v3"
 	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/engine"
 	"k8s.io/helm/pkg/proto/hapi/chart"
+	tversion "k8s.io/helm/pkg/version"
 )
 
 var FilepathValueEmpty = errors.New("given filepath value is empty")
 var FilepathDirUnexpected = errors.New("filepath given is a Dir. We expect a path to a file")
 var UnmatchedQuery = errors.New("your given query did not yield any matches")
+var OutputFileExists = errors.New("output file already exists, pass --force to overwrite it")
 var InvalidPolicyPath = errors.New("invalid policy path")
 var PolicyFailure = errors.New("your policy failed")
 var DuplicatePolicyFailure = errors.New("duplicate rule names found")
+var ValuesMergeFailure = errors.New("failed merging values files")
+var TemplatePathNotFound = errors.New("template path does not exist")
+var NoTemplatesFound = errors.New("no templates found")
 var expectQuery = regexp.MustCompile("^expect(_[a-zA-Z]+)*$")
+var documentSeparator = regexp.MustCompile(`\r?\n---[ \t]*\r?\n`)
+
+// ValuesErrors - every problem found while merging values files, so they
+// can all be fixed in one go
+type ValuesErrors []error
+
+func (s ValuesErrors) Error() string {
+	lines := make([]string, 0, len(s)+1)
+	lines = append(lines, fmt.Sprintf("%d problem(s) found in values files:", len(s)))
+	for _, err := range s {
+		lines = append(lines, "  - "+err.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (s ValuesErrors) Is(target error) bool {
+	if target == ValuesMergeFailure {
+		return true
+	}
+
+	for _, err := range s {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
 
 func mergeValues(valueFiles []string) (map[string]interface{}, error) {
 	base := map[string]interface{}{}
+	problems := make(ValuesErrors, 0)
 
 	for _, filePath := range valueFiles {
-		currentMap := map[string]interface{}{}
+		var fileProblems ValuesErrors
+		base, fileProblems = mergeValuesFile(base, filePath, nil)
+		problems = append(problems, fileProblems...)
+	}
 
-		bytes, err := readFile(filePath)
-		if err != nil {
-			return nil, err
+	if len(problems) > 0 {
+		return nil, problems
+	}
+	return base, nil
+}
+
+// typeConflicts - keys where b would replace a map with a non map (or the
+// other way around), which is almost always a mistake in a values file
+func typeConflicts(a, b map[string]interface{}, prefix string) []string {
+	conflicts := make([]string, 0)
+	for k, v := range b {
+		av, ok := a[k]
+		if !ok || av == nil || v == nil {
+			continue
 		}
 
-		if err := yaml.Unmarshal(bytes, &currentMap); err != nil {
-			return nil, fmt.Errorf("failed to parse %s: %w", filePath, err)
+		key := strings.TrimPrefix(prefix+"."+k, ".")
+		am, aIsMap := av.(map[string]interface{})
+		bm, bIsMap := v.(map[string]interface{})
+		switch {
+		case aIsMap && bIsMap:
+			conflicts = append(conflicts, typeConflicts(am, bm, key)...)
+		case valueKind(av) != valueKind(v):
+			conflicts = append(conflicts, fmt.Sprintf("%s is a %s but was a %s in a previous values file", key, valueKind(v), valueKind(av)))
 		}
-		base = mergeMaps(base, currentMap)
 	}
-	return base, nil
+	sort.Strings(conflicts)
+	return conflicts
+}
+
+func valueKind(v interface{}) string {
+	switch v.(type) {
+	case map[string]interface{}:
+		return "map"
+	case []interface{}:
+		return "list"
+	}
+	return "scalar"
 }
 
 func mergeMaps(a, b map[string]interface{}) map[string]interface{} {
@@ -76,93 +140,329 @@ func readFile(filePath string) ([]byte, error) {
 	return ioutil.ReadFile(filePath)
 }
 
-func validateAndRender(templatePath string, valuesMap map[string]interface{}) (map[string]string, error) {
+// renderOptions - everything besides the values which decides what gets
+// rendered from a template path
+type renderOptions struct {
+	Filter      TemplateFilter
+	Fixtures    []string
+	Defines     []string
+	KubeVersion string
+	APIVersions []string
+	TplValues   bool
+	SecondPass  []string
+
+	// Release - the release rendered, see newReleaseOptions
+	Release releaseOptions
+
+	// Chart - render the chart at the template path as a whole, see renderChart
+	Chart bool
+
+	// umbrella - the chart whose subcharts render along with the templates
+	umbrella *chart.Chart
+}
+
+// release - the release of the options, hcunit's default release when
+// none is given
+func (o renderOptions) release() releaseOptions {
+	if o.Release.Name == "" {
+		return newReleaseOptions("", "", false)
+	}
+	return o.Release
+}
+
+func validateAndRender(templatePath string, valuesMap map[string]interface{}, options renderOptions) (map[string]string, error) {
+	if options.Chart {
+		return renderChart(templatePath, valuesMap, options)
+	}
+
 	templateFiles, err := WalkTemplatePath(templatePath)
 	if err != nil {
 		return nil, fmt.Errorf("template validation failed: %w", err)
 	}
 
-	values, err := yaml.Marshal(valuesMap)
+	templateFiles, err = options.Filter.apply(templatePath, templateFiles)
 	if err != nil {
-		return nil, fmt.Errorf("couldnt marshal values: %w", err)
+		return nil, err
+	}
+
+	if chartDir, err := findChartRoot(templatePath); err == nil && len(options.Fixtures) == 0 && len(options.Defines) == 0 {
+		library, err := isLibraryChart(chartDir)
+		if err != nil {
+			return nil, err
+		}
+
+		if library {
+			return nil, LibraryChartWithoutFixtures
+		}
+	}
+
+	if len(options.Defines) > 0 {
+		templateFiles = onlyPartials(templateFiles)
+		for name, define := range defineTemplates(options.Defines) {
+			templateFiles[name] = define
+		}
+
+		partials, err := dependencyPartials(templatePath)
+		if err != nil {
+			return nil, err
+		}
+
+		for name, partial := range partials {
+			templateFiles[name] = partial
+		}
+	} else if options.umbrella, err = loadSubcharts(templatePath); err != nil {
+		return nil, err
 	}
 
-	valuesFile := ioutil.NopCloser(bytes.NewReader(values))
-	return render(valuesFile, templateFiles)
+	for _, fixture := range options.Fixtures {
+		fixtureFiles, err := WalkTemplatePath(fixture)
+		if err != nil {
+			return nil, fmt.Errorf("fixture validation failed: %w", err)
+		}
+
+		for name, file := range fixtureFiles {
+			templateFiles[name] = file
+		}
+	}
+
+	return render(valuesMap, templateFiles, options)
 }
 
+// UnmarshalYamlMap - parses the rendered yaml (.yml/.yaml), json (.json) and
+// json lines (.jsonl) outputs, in any letter case, into objects. Anything
+// else is kept as a string
 func UnmarshalYamlMap(in map[string]string) (map[string]interface{}, error) {
 	out := make(map[string]interface{})
 	for fpath, template := range in {
-		if filepath.Ext(fpath) == ".yml" || filepath.Ext(fpath) == ".yaml" {
-			documents := strings.Split(template, "\n---\n")
-			var configDocs []interface{}
-			for _, doc := range documents {
-				var config interface{}
-				err := yaml.Unmarshal([]byte(doc), &config)
-				if err != nil {
-					return nil, fmt.Errorf("Unmarshal '%s' failed: %v", fpath, err)
-				}
+		var unmarshal func([]byte, interface{}) error
+		var documents []string
+		switch strings.ToLower(filepath.Ext(fpath)) {
+		case ".yml", ".yaml":
+			unmarshal = yaml.Unmarshal
+			documents = splitDocuments(template)
+		case ".json":
+			unmarshal = unmarshalJSON
+			documents = []string{template}
+		case ".jsonl":
+			unmarshal = unmarshalJSON
+			documents = strings.Split(strings.Replace(template, "\r\n", "\n", -1), "\n")
+		default:
+			out[documentName(fpath)] = template
+			continue
+		}
 
-				if config != nil {
-					configDocs = append(configDocs, config)
-				}
+		var configDocs []interface{}
+		for _, doc := range documents {
+			var config interface{}
+			err := unmarshal([]byte(doc), &config)
+			if err != nil {
+				return nil, fmt.Errorf("Unmarshal '%s' failed: %v", fpath, err)
 			}
 
-			if configDocs != nil && len(configDocs) > 1 {
-				out[filepath.Base(fpath)] = configDocs
+			if config != nil {
+				configDocs = append(configDocs, config)
 			}
+		}
 
-			if configDocs != nil && len(configDocs) == 1 {
-				out[filepath.Base(fpath)] = configDocs[0]
-			}
+		if configDocs != nil && len(configDocs) > 1 {
+			out[documentName(fpath)] = configDocs
+		}
 
-		} else {
-			out[filepath.Base(fpath)] = template
+		if configDocs != nil && len(configDocs) == 1 {
+			out[documentName(fpath)] = configDocs[0]
 		}
 	}
 	return out, nil
 }
 
-func render(values io.ReadCloser, templates map[string]io.ReadCloser) (map[string]string, error) {
-	var name string
-	var reader io.ReadCloser
-	var data []byte
-	defer values.Close()
-	chartTemplates := make([]*chart.Template, 0)
-	for name, reader = range templates {
-		defer reader.Close()
-		buf := new(bytes.Buffer)
-		buf.ReadFrom(reader)
-		data = buf.Bytes()
+// splitDocuments - splits a rendered template into its yaml documents,
+// accepting CRLF line endings around the separators
+func splitDocuments(rendered string) []string {
+	return documentSeparator.Split(rendered, -1)
+}
+
+// unmarshalJSON - json.Unmarshal which treats blank documents as empty, the
+// way yaml does
+func unmarshalJSON(b []byte, v interface{}) error {
+	if len(bytes.TrimSpace(b)) == 0 {
+		return nil
+	}
+	return json.Unmarshal(b, v)
+}
+
+// render - renders the templates the way helm template does with the chart
+// name hcunit. The values are handed to the engine directly, normalized the
+// way helm would have parsed them from a values file, rather than marshaled
+// to yaml for helm to parse back. With TplValues the templates in the
+// values are rendered first, see tplValues, and with SecondPass the output
+// of the matching templates is rendered again, see secondPass
+func render(values map[string]interface{}, templates map[string]io.ReadCloser, options renderOptions) (map[string]string, error) {
+	defer func() {
+		for _, reader := range templates {
+			reader.Close()
+		}
+	}()
+
+	chartTemplates := make([]*chart.Template, 0, len(templates))
+	for name, reader := range templates {
+		data, err := ioutil.ReadAll(reader)
+		if err != nil {
+			return nil, fmt.Errorf("reading template %s failed: %w", name, err)
+		}
 		chartTemplates = append(chartTemplates, &chart.Template{Name: name, Data: data})
 	}
 
-	buf := new(bytes.Buffer)
-	buf.ReadFrom(values)
-	valuesRaw := buf.String()
 	testChart := &chart.Chart{
 		Metadata:  &chart.Metadata{Name: "hcunit"},
 		Templates: chartTemplates,
-		Values:    &chart.Config{Raw: valuesRaw},
 	}
 
-	defaultConfig := &chart.Config{Raw: ""}
-	defaultOptions := renderutil.Options{
-		ReleaseOptions: chartutil.ReleaseOptions{
-			Name:      "hcunit-name",
-			Time:      new(timestamp.Timestamp),
-			Namespace: "hcunit-namespace",
-			Revision:  1,
-			IsUpgrade: false,
-			IsInstall: true,
-		},
+	caps, err := renderCapabilities(options.KubeVersion, options.APIVersions)
+	if err != nil {
+		return nil, err
 	}
-	return renderutil.Render(testChart, defaultConfig, defaultOptions)
+
+	renderValues := chartutil.Values{
+		"Release":      options.release().values(),
+		"Chart":        testChart.Metadata,
+		"Files":        chartutil.NewFiles(nil),
+		"Capabilities": caps,
+		"Values":       chartutil.Values(helmValues(values).(map[string]interface{})),
+	}
+
+	if options.umbrella != nil {
+		subcharts, err := enabledSubcharts(options.umbrella, values)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := coalesceSubcharts(renderValues["Values"].(chartutil.Values), subcharts); err != nil {
+			return nil, err
+		}
+		testChart.Dependencies = subcharts
+	}
+
+	if options.TplValues {
+		if err := tplValues(renderValues["Values"].(chartutil.Values), partialTemplates(chartTemplates), renderValues); err != nil {
+			return nil, err
+		}
+	}
+
+	rendered, err := engine.New().Render(testChart, renderValues)
+	if err != nil {
+		return nil, err
+	}
+
+	rendered = renameSubchartOutputs(rendered, testChart.Metadata.GetName(), chartTemplates)
+	if len(options.SecondPass) > 0 {
+		return secondPass(rendered, options.SecondPass, partialTemplates(chartTemplates), renderValues)
+	}
+	return rendered, nil
+}
+
+// renderCapabilities - the capabilities of helm template for the given
+// kubernetes version, helm's default when empty, offering the given api
+// versions besides v1, e.g. monitoring.coreos.com/v1 for charts checking
+// .Capabilities.APIVersions.Has
+func renderCapabilities(kubeVersion string, apiVersions []string) (*chartutil.Capabilities, error) {
+	kube := *chartutil.DefaultKubeVersion
+	caps := &chartutil.Capabilities{
+		APIVersions:   chartutil.NewVersionSet(append([]string{"v1"}, apiVersions...)...),
+		KubeVersion:   &kube,
+		TillerVersion: tversion.GetVersionProto(),
+	}
+
+	if kubeVersion != "" {
+		kv, err := semver.NewVersion(kubeVersion)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse a kubernetes version: %v", err)
+		}
+		kube.Major = fmt.Sprint(kv.Major())
+		kube.Minor = fmt.Sprint(kv.Minor())
+		kube.GitVersion = fmt.Sprintf("v%d.%d.0", kv.Major(), kv.Minor())
+	}
+	return caps, nil
+}
+
+// helmValues - a copy of values parsed from yaml the way helm parses them:
+// through json, so numbers are float64, timestamps strings and map keys
+// strings. The yaml 1.1 booleans are resolved while parsing, see
+// unmarshalValues
+func helmValues(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, e := range v {
+			out[key] = helmValues(e)
+		}
+		return out
+	case map[interface{}]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, e := range v {
+			out[fmt.Sprint(key)] = helmValues(e)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, e := range v {
+			out[i] = helmValues(e)
+		}
+		return out
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	}
+	return value
+}
+
+// resolveTemplatePath - validates the --template path up front. A chart root
+// resolves to its templates/ directory, anything else must exist and contain
+// at least one template
+func resolveTemplatePath(templatePath string) (string, error) {
+	if strings.TrimSpace(templatePath) == "" {
+		return "", fmt.Errorf("--template: %w", FilepathValueEmpty)
+	}
+
+	info, err := os.Stat(templatePath)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", TemplatePathNotFound, templatePath)
+	}
+
+	if !info.IsDir() {
+		return templatePath, nil
+	}
+
+	templatesDir := filepath.Join(templatePath, "templates")
+	if fileExists(filepath.Join(templatePath, chartutil.ChartfileName)) && fileExists(templatesDir) {
+		return templatesDir, nil
+	}
+
+	found := false
+	filepath.Walk(templatePath, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() && !strings.HasPrefix(info.Name(), ".") {
+			found = true
+			return io.EOF
+		}
+		return nil
+	})
+
+	if !found {
+		if fileExists(templatesDir) {
+			return "", fmt.Errorf("%w in %s, did you mean %s?", NoTemplatesFound, templatePath, templatesDir)
+		}
+		return "", fmt.Errorf("%w in %s", NoTemplatesFound, templatePath)
+	}
+	return templatePath, nil
 }
 
 //WalkTemplatePath - walk a given template path to read all
-// of the templates (even nested templates) into a map
+// of the templates (even nested templates) into a map keyed by their
+// forward slash path, the way helm names templates on every platform
 func WalkTemplatePath(templatePath string) (map[string]io.ReadCloser, error) {
 	templates := make(map[string]io.ReadCloser)
 	err := filepath.Walk(templatePath, func(path string, info os.FileInfo, err error) error {
@@ -176,7 +476,7 @@ func WalkTemplatePath(templatePath string) (map[string]io.ReadCloser, error) {
 				return fmt.Errorf("reading file failed: %w", err)
 			}
 
-			templates[path] = template
+			templates[filepath.ToSlash(path)] = template
 		}
 		return nil
 	})
@@ -188,13 +488,13 @@ func WalkTemplatePath(templatePath string) (map[string]io.ReadCloser, error) {
 	return templates, nil
 }
 
-func getQueryList(policy string) map[string]int {
+// ruleQueries - the query suffix of every rule hcunit evaluates in the
+// modules, with the number of rules defining it
+func ruleQueries(mods map[string]*ast.Module) map[string]int {
 	res := map[string]int{}
-	mods, _, _ := tester.Load([]string{policy}, nil)
 	for _, mod := range mods {
 		for _, rule := range mod.Rules {
-			if strings.HasPrefix("expect[", string(rule.Head.Name)) ||
-				strings.HasPrefix("assert[", string(rule.Head.Name)) {
+			if isRuleKind(string(rule.Head.Name)) {
 				res[fmt.Sprintf("%s[%s]", rule.Head.Name, rule.Head.Key)] += 1
 			}
 		}
@@ -202,73 +502,199 @@ func getQueryList(policy string) map[string]int {
 	return res
 }
 
-func evalPolicyOnInput(writer io.Writer, policy string, namespace string, input interface{}) error {
-	testResults := make(map[string]bool)
-	ctx := context.Background()
-	var results rego.ResultSet
-	queryList := getQueryList(policy)
-	for querySuffix, querymatches := range queryList {
-		if querymatches > 1 {
-			colorstring.Println("[red]ERROR: you are using duplicate test names or variables. This could cause test failures to NOT be detected properly")
-			colorstring.Println(fmt.Sprintf("[yellow]DUPLICATE KEY: %s", querySuffix))
-			return DuplicatePolicyFailure
-		}
-
-		queryString := fmt.Sprintf("data.%s.%s", namespace, querySuffix)
-		buf := topdown.NewBufferTracer()
-		r := rego.New(
-			rego.Query(queryString),
-			rego.Tracer(buf),
-			rego.Load([]string{policy}, nil),
-		)
-		query, err := r.PrepareForEval(ctx)
-		if err != nil {
-			return fmt.Errorf("failed preparing for eval on policies: %w", err)
-		}
+// RuleResult - the outcome of a single evaluated rule (or parameter row).
+// Warning is set when a warn rule held, DryRun when a dry run rule was
+// violated. Neither fails the run. KubeVersion and ValuesSet name the
+// kubernetes version of a matrix and the values set of a batch the rule was
+// evaluated for, if any
+type RuleResult struct {
+	Name        string
+	Passed      bool
+	Warning     bool
+	DryRun      bool
+	Metadata    map[string]interface{}
+	Duration    time.Duration
+	Documents   []string
+	KubeVersion string
+	ValuesSet   string
+}
 
-		resultSet, err := query.Eval(ctx, rego.EvalInput(input))
-		if err != nil {
-			return fmt.Errorf("failed eval on policies: %w", err)
+// evalPolicyOnInput - evaluates every expect/assert/deny/warn rule of the policies,
+// calling the BeforeRule hook (if any) before each run, and returns the
+// result of each run ordered by name. Parsed policy modules are cached in
+// cacheDir unless it is empty
+func evalPolicyOnInput(writer io.Writer, policies []string, namespace string, hooks *Hooks, input interface{}, cacheDir string, options ...func(*rego.Rego)) ([]RuleResult, error) {
+	prepared, err := loadPreparedPolicies(policies, namespace, cacheDir, options...)
+	if err != nil {
+		return nil, err
+	}
+	return prepared.eval(writer, hooks, input)
+}
+
+// loadPreparedPolicies - loads the policies, caching their parsed modules in
+// cacheDir unless it is empty, and prepares them
+func loadPreparedPolicies(policies []string, namespace, cacheDir string, options ...func(*rego.Rego)) (*preparedPolicies, error) {
+	loaded, err := loadPolicies(policies, cacheDir)
+	if err != nil {
+		return nil, &PolicyCompileError{Policies: policies, Err: err}
+	}
+
+	prepared, err := preparePolicies(loaded, policies, namespace, options...)
+	var compileErr *PolicyCompileError
+	if loaded.cached && errors.As(err, &compileErr) {
+		// cached modules have no source locations, parse them again so the
+		// error points into the policies
+		return loadPreparedPolicies(policies, namespace, "", options...)
+	}
+	return prepared, err
+}
+
+// resultReporter - prints a PASS/WARN/DRYRUN/FAIL line per rule, with the
+// description and assertion diffs of failed rules, followed by the overall
+// outcome. Deprecated rules get a notice when they fire, and whenever they
+// are past their remove_after date. Its own strings come from the messages.
+// Plain reporters spell the result out in a fixed width column instead of
+// relying on colors, for screen readers
+type resultReporter struct {
+	writer   io.Writer
+	diffs    map[string][]string
+	messages messageCatalog
+	plain    bool
+	explain  map[string]*FailureExplanation
+	now      time.Time
+	failed   bool
+	dryRuns  int
+	sunsets  int
+}
+
+func newResultReporter(writer io.Writer, diffs map[string][]string, messages messageCatalog) *resultReporter {
+	return &resultReporter{writer: writer, diffs: diffs, messages: messages, now: time.Now()}
+}
+
+// hooks - the reporter as the AfterRule hook printing each result
+func (s *resultReporter) hooks() *Hooks {
+	return &Hooks{AfterRule: s.report}
+}
+
+func (s *resultReporter) report(result RuleResult) error {
+	description := metadataString(result.Metadata, metadataDescription)
+	if sunsetPassed(result.Metadata, s.now) {
+		s.sunsets++
+	}
+
+	switch {
+	case result.Warning:
+		s.label("yellow", "warn")
+	case result.DryRun:
+		s.dryRuns++
+		s.label("yellow", "dryrun")
+	case result.Passed:
+		s.label("green", "pass")
+		fmt.Fprintln(s.writer, result.Name)
+		if sunsetPassed(result.Metadata, s.now) {
+			s.detail(deprecationNotice(result.Metadata, s.now, s.messages))
 		}
+		return nil
+	default:
+		s.failed = true
+		s.label("red", "fail")
+	}
 
-		testResults[queryString] = false
-		for _, result := range resultSet {
+	fmt.Fprintln(s.writer, result.Name)
+	if description != "" {
+		s.detail(description)
+	}
 
-			for _, expression := range result.Expressions {
-				if expression.Text == queryString {
-					testResults[queryString] = true
-				}
+	if ownership := ruleOwnership(result.Metadata); ownership != "" {
+		s.detail(s.messages.text("owned_by", ownership))
+	}
+
+	if subcharts := subchartsOf(result.Documents); len(subcharts) > 0 {
+		s.detail(s.messages.text("from_subchart", strings.Join(subcharts, ", ")))
+	}
+
+	if notice := deprecationNotice(result.Metadata, s.now, s.messages); notice != "" {
+		s.detail(notice)
+	}
+
+	if explanation := s.explain[result.Name]; explanation != nil {
+		s.detail(s.messages.text("failed_at", explanation.File, explanation.Line, explanation.Expression))
+		for _, value := range explanation.Values {
+			if value.Undefined {
+				value.Value = s.messages.text("undefined")
 			}
+			s.detail("  " + value.Term + " = " + value.Value)
 		}
+	}
 
-		if len(resultSet) > 0 {
-			results = append(results, resultSet...)
+	for _, diff := range s.diffs[result.Name] {
+		if s.plain {
+			fmt.Fprint(s.writer, plainDiff(diff, s.indent(), s.messages))
+			continue
 		}
+		fmt.Fprint(s.writer, colorDiff(diff))
+	}
+	return nil
+}
 
-		topdown.PrettyTrace(writer, *buf)
+// label - prints the result of a rule: colored and followed by a colon, or
+// padded to the width of the longest result when plain
+func (s *resultReporter) label(color, key string) {
+	label := s.messages.text(key)
+	if !s.plain {
+		colorstring.Fprint(s.writer, "["+color+"]"+label+": ")
+		return
 	}
+	fmt.Fprint(s.writer, label+strings.Repeat(" ", len([]rune(s.indent()))-len([]rune(label))))
+}
 
-	if len(queryList) <= 0 {
-		return UnmatchedQuery
+// indent - the indentation of the lines detailing a result, lining them up
+// with the rule name when plain
+func (s *resultReporter) indent() string {
+	if !s.plain {
+		return "      "
 	}
 
-	testFailed := false
-	for testname, passed := range testResults {
-		if passed {
-			colorstring.Print("[green]PASS: ")
-			fmt.Println(testname)
-		} else {
-			testFailed = true
-			colorstring.Print("[red]FAIL: ")
-			fmt.Println(testname)
+	width := 0
+	for _, key := range []string{"pass", "fail", "warn", "dryrun"} {
+		if n := len([]rune(s.messages.text(key))); n > width {
+			width = n
 		}
 	}
+	return strings.Repeat(" ", width+2)
+}
+
+func (s *resultReporter) detail(line string) {
+	fmt.Fprintf(s.writer, "%s%s\n", s.indent(), line)
+}
+
+// conclude - prints the overall outcome of the reported results
+func (s *resultReporter) conclude() {
+	if s.sunsets > 0 {
+		colorstring.Fprintln(s.writer, "[yellow]"+s.messages.text("sunset_summary", s.sunsets))
+	}
 
-	if testFailed {
-		colorstring.Println("[_red_][FAILURE] Policy violations found on the Helm Chart!")
-		return PolicyFailure
+	if s.dryRuns > 0 {
+		colorstring.Fprintln(s.writer, "[yellow]"+s.messages.text("dryrun_summary", s.dryRuns))
 	}
 
-	colorstring.Println("[green][SUCCESS] Your Helm Chart complies with all policies!")
-	return nil
+	if s.failed {
+		colorstring.Fprintln(s.writer, "[_red_]"+s.messages.text("failure_summary"))
+		return
+	}
+	colorstring.Fprintln(s.writer, "[green]"+s.messages.text("success_summary"))
+}
+
+// writeOutput - writes generated content to the given file, or to the
+// writer when no file is given. Existing files are only replaced with force
+func writeOutput(writer io.Writer, path, content string, force bool) error {
+	if path == "" {
+		_, err := io.WriteString(writer, content)
+		return err
+	}
+
+	if _, err := os.Stat(path); err == nil && !force {
+		return fmt.Errorf("%w: %s", OutputFileExists, path)
+	}
+	return ioutil.WriteFile(path, []byte(content), 0644)
 }
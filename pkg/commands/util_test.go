@@ -81,6 +81,68 @@ func TestUnmarshalYamlMap(t *testing.T) {
 				return nil
 			},
 		},
+		{
+			name:    "yaml extensions should be matched case insensitively",
+			yamlMap: map[string]string{"UPPER.YAML": "something: andvalue", "mixed.Yml": "other: value"},
+			matcher: func(m map[string]interface{}) error {
+				if _, ok := m["UPPER.YAML"].(map[string]interface{}); !ok {
+					return fmt.Errorf(".YAML should be unmarshalled, instead: %#v", m["UPPER.YAML"])
+				}
+
+				if _, ok := m["mixed.Yml"].(map[string]interface{}); !ok {
+					return fmt.Errorf(".Yml should be unmarshalled, instead: %#v", m["mixed.Yml"])
+				}
+
+				return nil
+			},
+		},
+		{
+			name:    "json should show up in unmarshalled output",
+			yamlMap: map[string]string{"dashboard.json": "{\n\t\"title\": \"hcunit\",\n\t\"panels\": [1, 2]\n}"},
+			matcher: func(m map[string]interface{}) error {
+				jsonObject, ok := m["dashboard.json"].(map[string]interface{})
+				if !ok || jsonObject["title"] != "hcunit" {
+					return fmt.Errorf("unexpected values in unmarshalled object: %v", m)
+				}
+
+				return nil
+			},
+		},
+		{
+			name:    "json lines should unmarshal into a list of documents",
+			yamlMap: map[string]string{"events.jsonl": "{\"id\": 1}\n{\"id\": 2}\n"},
+			matcher: func(m map[string]interface{}) error {
+				docs, ok := m["events.jsonl"].([]interface{})
+				if !ok || len(docs) != 2 {
+					return fmt.Errorf("expected 2 documents, instead: %#v", m["events.jsonl"])
+				}
+
+				return nil
+			},
+		},
+		{
+			name:    "crlf document separators should split documents",
+			yamlMap: map[string]string{"windows.yaml": "first: 1\r\n---\r\nsecond: 2\r\n"},
+			matcher: func(m map[string]interface{}) error {
+				docs, ok := m["windows.yaml"].([]interface{})
+				if !ok || len(docs) != 2 {
+					return fmt.Errorf("expected 2 documents, instead: %#v", m["windows.yaml"])
+				}
+
+				return nil
+			},
+		},
+		{
+			name:    "keys should use the file name of slash separated paths",
+			yamlMap: map[string]string{"hcunit/templates/nested/deep.yaml": "a: b"},
+			matcher: func(m map[string]interface{}) error {
+				if _, ok := m["deep.yaml"]; !ok {
+					return fmt.Errorf("expected deep.yaml key, instead: %#v", m)
+				}
+
+				return nil
+			},
+		},
 		{
 			name:    "empty yaml should not show up in unmarshalled output",
 			yamlMap: map[string]string{"random.yml": ""},
@@ -114,12 +176,12 @@ something: otherdoc`},
 		t.Run(tt.name, func(t *testing.T) {
 			inputObject, err := commands.UnmarshalYamlMap(tt.yamlMap)
 			if err != nil {
-				t.Errorf("unexpected error while unmarshalling: %w", err)
+				t.Errorf("unexpected error while unmarshalling: %v", err)
 			}
 
 			err = tt.matcher(inputObject)
 			if err != nil {
-				t.Errorf("unexpected error %w", err)
+				t.Errorf("unexpected error %v", err)
 			}
 		})
 	}
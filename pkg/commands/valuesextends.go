@@ -0,0 +1,149 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// ValuesExtendCycle - values files extend each other, directly or through
+// other values files
+var ValuesExtendCycle = errors.New("values files extend each other")
+
+// valuesExtendsKey - the key of a values file naming the values files it
+// layers on top of
+const valuesExtendsKey = "extends"
+
+// mergeValuesFile - merges a values file over base, after the values files
+// it extends. Its extends key holds a path or a list of paths, relative to
+// the file, merged in order and resolved the same way, e.g. prod.yaml
+// extending base.yaml, so environment values files only hold what differs.
+// The key itself is dropped. extending holds the files extending this one,
+// to detect cycles
+func mergeValuesFile(base map[string]interface{}, filePath string, extending []string) (map[string]interface{}, ValuesErrors) {
+	chain := append(append([]string{}, extending...), filePath)
+	for _, extender := range extending {
+		if sameFile(extender, filePath) {
+			return base, ValuesErrors{fmt.Errorf("%w: %s", ValuesExtendCycle, strings.Join(chain, " -> "))}
+		}
+	}
+
+	bytes, err := readFile(filePath)
+	if err != nil {
+		return base, ValuesErrors{err}
+	}
+
+	if isValuesTemplate(filePath) {
+		if bytes, err = renderValuesTemplate(filePath, bytes, base); err != nil {
+			return base, ValuesErrors{err}
+		}
+	}
+
+	currentMap := map[string]interface{}{}
+	if err := unmarshalValues(bytes, &currentMap); err != nil {
+		return base, ValuesErrors{fmt.Errorf("failed to parse %s: %w", filePath, err)}
+	}
+
+	parents, err := extendedValuesFiles(filePath, currentMap[valuesExtendsKey])
+	if err != nil {
+		return base, ValuesErrors{err}
+	}
+	delete(currentMap, valuesExtendsKey)
+
+	problems := make(ValuesErrors, 0)
+	for _, parent := range parents {
+		var parentProblems ValuesErrors
+		base, parentProblems = mergeValuesFile(base, parent, chain)
+		problems = append(problems, parentProblems...)
+	}
+
+	for _, conflict := range typeConflicts(base, currentMap, "") {
+		problems = append(problems, fmt.Errorf("%s: %s", filePath, conflict))
+	}
+	return mergeMaps(base, currentMap), problems
+}
+
+// extendedValuesFiles - the paths of the extends key of a values file,
+// relative to the directory of the file unless absolute
+func extendedValuesFiles(filePath string, extends interface{}) ([]string, error) {
+	var paths []string
+	switch v := extends.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		paths = []string{v}
+	case []interface{}:
+		for _, path := range v {
+			s, ok := path.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s: %s must be a path or a list of paths, got %v", filePath, valuesExtendsKey, path)
+			}
+			paths = append(paths, s)
+		}
+	default:
+		return nil, fmt.Errorf("%s: %s must be a path or a list of paths, got %v", filePath, valuesExtendsKey, extends)
+	}
+
+	for i, path := range paths {
+		if !filepath.IsAbs(path) {
+			paths[i] = filepath.Join(filepath.Dir(filePath), path)
+		}
+	}
+	return paths, nil
+}
+
+// sameFile - whether two paths name the same file
+func sameFile(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA != nil || errB != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
+	}
+	return absA == absB
+}
+
+// yaml11Bools - the plain scalars yaml 1.1, which helm parses values files
+// with, reads as booleans, and yaml 1.2 as strings
+var yaml11Bools = map[string]bool{
+	"y": true, "Y": true, "yes": true, "Yes": true, "YES": true, "on": true, "On": true, "ON": true,
+	"n": false, "N": false, "no": false, "No": false, "NO": false, "off": false, "Off": false, "OFF": false,
+}
+
+// unmarshalValues - parses a values file the way helm does, so plain yes, no,
+// on, off and the like are booleans, and keys "true" or "false", while quoted
+// or !!str tagged ones stay strings
+func unmarshalValues(data []byte, values *map[string]interface{}) error {
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return err
+	}
+
+	if document.Kind == 0 {
+		return nil
+	}
+	resolveYAML11Bools(&document, false)
+	return document.Decode(values)
+}
+
+// resolveYAML11Bools - retags the plain untagged scalars under node that yaml
+// 1.1 reads as booleans. Mapping keys keep their string tag, since map keys
+// end up strings either way
+func resolveYAML11Bools(node *yaml.Node, key bool) {
+	if node.Kind == yaml.ScalarNode {
+		b, ok := yaml11Bools[node.Value]
+		if ok && node.Style == 0 && node.Tag == "!!str" {
+			node.Value = fmt.Sprint(b)
+			if !key {
+				node.Tag = "!!bool"
+			}
+		}
+		return
+	}
+
+	for i, child := range node.Content {
+		resolveYAML11Bools(child, node.Kind == yaml.MappingNode && i%2 == 0)
+	}
+}
@@ -0,0 +1,58 @@
+package commands_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestEvalValuesExtends(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		values    string
+		failsWith error
+		message   string
+	}{
+		{
+			name:   "values files extend other values files, recursively and relative to themselves",
+			values: "testdata/extends/regions/eu.yaml",
+		},
+		{
+			name:      "cycles are reported",
+			values:    "testdata/extends/cycle_a.yaml",
+			failsWith: commands.ValuesExtendCycle,
+			message:   "testdata/extends/cycle_a.yaml -> testdata/extends/cycle_b.yaml -> testdata/extends/cycle_a.yaml",
+		},
+		{
+			name:      "extends has to hold paths",
+			values:    "testdata/extends/invalid.yaml",
+			failsWith: commands.ValuesMergeFailure,
+			message:   "extends must be a path or a list of paths",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			stdOut := new(bytes.Buffer)
+			evalCmd := &commands.EvalCommand{
+				Stdout:   stdOut,
+				Template: "testdata/setvalues",
+				Values:   []string{tt.values},
+				Policy:   []string{"testdata/policy/individuals/values_extends.rego"},
+			}
+			err := evalCmd.Execute([]string{})
+			if tt.failsWith == nil && err != nil || !errors.Is(err, tt.failsWith) {
+				t.Fatalf("expected error:\n%v\ngot:\n%v\n%s", tt.failsWith, err, stdOut)
+			}
+
+			if tt.message != "" && !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("expected %q in:\n%v", tt.message, err)
+			}
+
+			if tt.failsWith != nil && commands.ExitCode(err) != commands.ExitUsage {
+				t.Errorf("expected exit code %d, got %d", commands.ExitUsage, commands.ExitCode(err))
+			}
+		})
+	}
+}
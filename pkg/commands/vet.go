@@ -0,0 +1,163 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mitchellh/colorstring"
+)
+
+var DeadTemplatesFound = errors.New("templates rendered empty under every values scenario")
+
+type VetCommand struct {
+	Writer    io.Writer
+	Template  string   `short:"t" long:"template" description:"path to yaml template you would like to render"`
+	Values    []string `short:"c" long:"values" description:"path to values file(s) applied to every scenario"`
+	Scenarios []string `short:"s" long:"scenario" description:"comma separated values files layered over --values, rendered as one scenario (repeatable)"`
+}
+
+func (s *VetCommand) Execute(args []string) error {
+	s.setDefaults()
+	templatePath, err := resolveTemplatePath(s.Template)
+	if err != nil {
+		return err
+	}
+	s.Template = templatePath
+
+	scenarios, err := s.renderScenarios()
+	if err != nil {
+		return err
+	}
+
+	coverage, err := branchCoverage(s.Template, scenarios)
+	if err != nil {
+		return err
+	}
+	reportBranchCoverage(s.Writer, coverage)
+
+	return reportDeadTemplates(s.Writer, deadTemplates(s.Template, scenarios), len(scenarios))
+}
+
+func (s *VetCommand) setDefaults() {
+	if s.Writer == nil {
+		s.Writer = os.Stdout
+	}
+}
+
+// scenarioValues - the values files of each scenario: --values followed by
+// the scenario's own files. Without scenarios --values is the only scenario
+func (s *VetCommand) scenarioValues() [][]string {
+	if len(s.Scenarios) == 0 {
+		return [][]string{s.Values}
+	}
+
+	scenarios := make([][]string, 0, len(s.Scenarios))
+	for _, scenario := range s.Scenarios {
+		files := append([]string{}, s.Values...)
+		for _, file := range strings.Split(scenario, ",") {
+			if file = strings.TrimSpace(file); file != "" {
+				files = append(files, file)
+			}
+		}
+		scenarios = append(scenarios, files)
+	}
+	return scenarios
+}
+
+// vetScenario - the merged values of a scenario and the templates they rendered
+type vetScenario struct {
+	Values   map[string]interface{}
+	Rendered map[string]string
+}
+
+// renderScenarios - renders the chart once per scenario
+func (s *VetCommand) renderScenarios() ([]vetScenario, error) {
+	scenarioValues := s.scenarioValues()
+	scenarios := make([]vetScenario, 0, len(scenarioValues))
+	for _, files := range scenarioValues {
+		valuesConfig, err := mergeValues(files)
+		if err != nil {
+			return nil, fmt.Errorf("failed merging values files %w ", err)
+		}
+
+		output, err := validateAndRender(s.Template, valuesConfig, renderOptions{})
+		if err != nil {
+			return nil, &RenderError{Template: s.Template, Err: err}
+		}
+		scenarios = append(scenarios, vetScenario{Values: valuesConfig, Rendered: output})
+	}
+	return scenarios, nil
+}
+
+// deadTemplates - templates which rendered no manifests under any scenario,
+// by their path relative to the template path. Partials and NOTES.txt never
+// render manifests and are skipped
+func deadTemplates(templatePath string, scenarios []vetScenario) []string {
+	live := make(map[string]bool)
+	for _, scenario := range scenarios {
+		for name, content := range scenario.Rendered {
+			base := path.Base(name)
+			if strings.HasPrefix(base, "_") || strings.EqualFold(base, "NOTES.txt") {
+				continue
+			}
+
+			template := renderedTemplateName(templatePath, name)
+			live[template] = live[template] || !blankManifest(content)
+		}
+	}
+
+	dead := make([]string, 0)
+	for template, isLive := range live {
+		if !isLive {
+			dead = append(dead, template)
+		}
+	}
+	sort.Strings(dead)
+	return dead
+}
+
+// renderedTemplateName - the path of a rendered template relative to the
+// template path, without the name of the chart we render it in
+func renderedTemplateName(templatePath, name string) string {
+	if i := strings.Index(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	return relativeTemplateName(templatePath, name)
+}
+
+func relativeTemplateName(templatePath, name string) string {
+	if rel, err := filepath.Rel(templatePath, filepath.FromSlash(name)); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return name
+}
+
+// blankManifest - true when a rendered template holds nothing but
+// whitespace, comments and document separators
+func blankManifest(content string) bool {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && line != "---" && !strings.HasPrefix(line, "#") {
+			return false
+		}
+	}
+	return true
+}
+
+func reportDeadTemplates(writer io.Writer, dead []string, scenarios int) error {
+	for _, template := range dead {
+		colorstring.Fprint(writer, "[red]FAIL: ")
+		fmt.Fprintf(writer, "dead template %s: rendered empty under all %d scenario(s)\n", template, scenarios)
+	}
+
+	if len(dead) > 0 {
+		return DeadTemplatesFound
+	}
+	return nil
+}
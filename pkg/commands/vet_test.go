@@ -0,0 +1,90 @@
+package commands_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/xchapter7x/hcunit/pkg/commands"
+)
+
+func TestVetCommand(t *testing.T) {
+	for _, tt := range []struct {
+		name        string
+		template    string
+		values      []string
+		scenarios   []string
+		failsWith   error
+		contains    []string
+		notContains []string
+	}{
+		{
+			name:      "templates which never render are reported",
+			template:  "testdata/vetchart",
+			values:    []string{"testdata/vetscenarios/defaults.yaml"},
+			scenarios: []string{"testdata/vetscenarios/ingress.yaml", ""},
+			failsWith: commands.DeadTemplatesFound,
+			contains: []string{
+				"dead template legacy.yaml: rendered empty under all 2 scenario(s)",
+				"branch coverage legacy.yaml:2 .Values.legacy.enabled is never true, add a scenario setting legacy.enabled",
+				"branch coverage 3/4 branches exercised (75%)",
+			},
+			notContains: []string{"ingress.yaml", "configmap.yaml", "_helpers.tpl"},
+		},
+		{
+			name:      "without scenarios the values are the only scenario",
+			template:  "testdata/vetchart",
+			values:    []string{"testdata/vetscenarios/defaults.yaml"},
+			failsWith: commands.DeadTemplatesFound,
+			contains:  []string{"dead template ingress.yaml", "dead template legacy.yaml"},
+		},
+		{
+			name:        "scenarios exercising every branch",
+			template:    "testdata/vetchart",
+			values:      []string{"testdata/vetscenarios/defaults.yaml"},
+			scenarios:   []string{"", "testdata/vetscenarios/ingress.yaml", "testdata/vetscenarios/legacy.yaml"},
+			failsWith:   nil,
+			contains:    []string{"branch coverage 4/4 branches exercised (100%)"},
+			notContains: []string{"WARN", "FAIL"},
+		},
+		{
+			name:      "a template rendered by any scenario is alive",
+			template:  "testdata/templates",
+			values:    []string{"testdata/values.yml"},
+			failsWith: nil,
+		},
+		{
+			name:      "a scenario with a missing values file",
+			template:  "testdata/vetchart",
+			scenarios: []string{"testdata/vetscenarios/doesnotexist.yaml"},
+			failsWith: commands.ValuesMergeFailure,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			stdOut := new(bytes.Buffer)
+			vetCmd := &commands.VetCommand{
+				Writer:    stdOut,
+				Template:  tt.template,
+				Values:    tt.values,
+				Scenarios: tt.scenarios,
+			}
+			err := vetCmd.Execute([]string{})
+			if !errors.Is(err, tt.failsWith) {
+				t.Errorf("expected error:\n%v\ngot:\n%v", tt.failsWith, err)
+			}
+
+			for _, expected := range tt.contains {
+				if !strings.Contains(stdOut.String(), expected) {
+					t.Errorf("expected output to contain %q, got:\n%s", expected, stdOut.String())
+				}
+			}
+
+			for _, unexpected := range tt.notContains {
+				if strings.Contains(stdOut.String(), unexpected) {
+					t.Errorf("expected output not to contain %q, got:\n%s", unexpected, stdOut.String())
+				}
+			}
+		})
+	}
+}